@@ -1,18 +1,23 @@
 package ansibleutils
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 
 	"encoding/json"
 	"errors"
 	"github.com/apenella/go-ansible/pkg/execute"
 	"github.com/apenella/go-ansible/pkg/options"
 	"github.com/apenella/go-ansible/pkg/playbook"
-	"github.com/apenella/go-ansible/pkg/stdoutcallback/results"
+	"github.com/apenella/go-ansible/pkg/stdoutcallback"
 	"github.com/maplelabs/opensearch-scaling-manager/config"
 	"github.com/maplelabs/opensearch-scaling-manager/logger"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 )
 
 var log = new(logger.LOG)
@@ -29,10 +34,25 @@ func init() {
 	log.Info.Println("Ansible module initiated")
 }
 
+// Directory of the task_progress callback plugin, next to the playbooks
+const callbackPluginsDir = "ansible_scripts/callback_plugins"
+
+// This struct contains the options of a run of a playbook.
+type playbookRun struct {
+	// playbook indicates the file name of the playbook.
+	playbook string
+	// inventory indicates the hosts to run the playbook on, written to the directory of the run.
+	inventory *AnsibleInventory
+	// extraVars indicates the extra variables of the playbook.
+	extraVars map[string]interface{}
+	// tags indicates the tags of the tasks to be run, all the tasks are run if empty.
+	tags []string
+}
+
 // Input:
 //
-//	username (string): Username string to be used to ssh into the host inventory
-//	hosts (string): The file name of hosts file to pass to ansible playbook
+//	ctx (context.Context): Context cancelling the playbook, with its timeout and hooks
+//	inventory (*AnsibleInventory): Hosts to run the playbook on
 //	clusterCfg (config.ClusterDetails): Opensearch cluster details for configuring
 //	operation (string): Operation called scale_up/scale_down
 //
@@ -42,8 +62,8 @@ func init() {
 //
 // Return:
 //
-//	(error): Returns error if any
-func CallAnsible(username string, hosts string, clusterCfg config.ClusterDetails, operation string) error {
+//	(*PlaybookResult, error): Returns the results of the tasks if the playbook ran, and error if any
+func CallAnsible(ctx context.Context, inventory *AnsibleInventory, clusterCfg config.ClusterDetails, operation string) (*PlaybookResult, error) {
 
 	var fileName string
 	switch operation {
@@ -53,56 +73,68 @@ func CallAnsible(username string, hosts string, clusterCfg config.ClusterDetails
 		fileName = "ansible_scripts/scaleDownPlaybook.yml"
 	}
 
-	var variablesMap map[string]interface{}
-	jsonData, err := json.Marshal(&clusterCfg)
-	if err != nil {
-		log.Error.Println("Error while Marshaling")
-		return err
-	}
-
-	err = json.Unmarshal(jsonData, &variablesMap)
+	variablesMap, err := clusterVariables(clusterCfg)
 	if err != nil {
-		log.Error.Println("json parsing error")
-		return err
+		return nil, err
 	}
 
-	ansiblePlaybookConnectionOptions := &options.AnsibleConnectionOptions{
-		User: username,
-	}
-
-	ansiblePlaybookOptions := &playbook.AnsiblePlaybookOptions{
-		Inventory: hosts,
-		ExtraVars: variablesMap,
-	}
+	return runPlaybook(ctx, playbookRun{playbook: fileName, inventory: inventory, extraVars: variablesMap})
+}
 
-	ansiblePlaybookPrivilegeEscalationOptions := &options.AnsiblePrivilegeEscalationOptions{
-		Become:       true,
-		BecomeMethod: "sudo",
+// Input:
+//
+//	ctx (context.Context): Context cancelling the playbook, with its timeout and hooks
+//	inventory (*AnsibleInventory): Inventory with the node to be restarted in the restart_node group
+//	clusterCfg (config.ClusterDetails): Opensearch cluster details for configuring
+//	updateHeap (bool): True to write the heap of the jvm_factor to jvm.options before the restart
+//
+// Description:
+//
+//	Runs the rolling restart playbook, which restarts opensearch on the node and waits for it to listen
+//
+// Return:
+//
+//	(*PlaybookResult, error): Returns the results of the tasks if the playbook ran, and error if any
+func RestartNode(ctx context.Context, inventory *AnsibleInventory, clusterCfg config.ClusterDetails, updateHeap bool) (*PlaybookResult, error) {
+	variablesMap, err := clusterVariables(clusterCfg)
+	if err != nil {
+		return nil, err
 	}
+	variablesMap["update_heap"] = updateHeap
+	return runPlaybook(ctx, playbookRun{playbook: "ansible_scripts/rollingRestartPlaybook.yml", inventory: inventory, extraVars: variablesMap})
+}
 
-	playbook := &playbook.AnsiblePlaybookCmd{
-		Playbooks:                  []string{fileName},
-		ConnectionOptions:          ansiblePlaybookConnectionOptions,
-		PrivilegeEscalationOptions: ansiblePlaybookPrivilegeEscalationOptions,
-		Options:                    ansiblePlaybookOptions,
-		Exec: execute.NewDefaultExecute(
-			execute.WithEnvVar("ANSIBLE_FORCE_COLOR", "true"),
-			execute.WithTransformers(
-				results.Prepend("Go-ansible with become"),
-			),
-		),
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Opensearch cluster details for configuring
+//
+// Description:
+//
+//	Converts the cluster details to the extra variables of the playbooks, keyed by their json names
+//
+// Return:
+//
+//	(map[string]interface{}, error): Returns the variables and error if any
+func clusterVariables(clusterCfg config.ClusterDetails) (map[string]interface{}, error) {
+	var variablesMap map[string]interface{}
+	jsonData, err := json.Marshal(&clusterCfg)
+	if err != nil {
+		log.Error.Println("Error while Marshaling")
+		return nil, err
 	}
 
-	err = playbook.Run(context.TODO())
+	err = json.Unmarshal(jsonData, &variablesMap)
 	if err != nil {
-		return maskCredentials(err)
+		log.Error.Println("json parsing error")
+		return nil, err
 	}
-	return nil
+	return variablesMap, nil
 }
 
 // Input:
 //
-//	hosts (string): The file name of hosts file to pass to ansible playbook
+//	ctx (context.Context): Context cancelling the playbook, with its timeout and hooks
+//	inventory (*AnsibleInventory): Hosts to run the playbook on
 //	tags ([]string): List of tags to call the scaling_manager
 //	clusterCfg (config.ClusterDetails): Cluster config details to read username and domain_name
 //
@@ -112,42 +144,100 @@ func CallAnsible(username string, hosts string, clusterCfg config.ClusterDetails
 //
 // Return:
 //
-//	(error): Returns error if any
-func UpdateWithTags(hosts string, clusterCfg config.ClusterDetails, tags []string) error {
-
-	fileName := "ansible_scripts/install_scaling_manager.yaml"
-	tag := strings.Join(tags, ", ")
-
-	ansiblePlaybookConnectionOptions := &options.AnsibleConnectionOptions{
-		User: clusterCfg.SshUser,
-	}
+//	(*PlaybookResult, error): Returns the results of the tasks if the playbook ran, and error if any
+func UpdateWithTags(ctx context.Context, inventory *AnsibleInventory, clusterCfg config.ClusterDetails, tags []string) (*PlaybookResult, error) {
+	return runPlaybook(ctx, playbookRun{
+		playbook:  "ansible_scripts/install_scaling_manager.yaml",
+		inventory: inventory,
+		extraVars: map[string]interface{}{"domain_name": clusterCfg.DomainName},
+		tags:      tags,
+	})
+}
 
-	ansiblePlaybookOptions := &playbook.AnsiblePlaybookOptions{
-		Inventory: hosts,
-		ExtraVars: map[string]interface{}{"domain_name": clusterCfg.DomainName},
-		Tags:      tag,
+// Input:
+//
+//	ctx (context.Context): Context cancelling the playbook, with its timeout and hooks
+//	run (playbookRun): Playbook and its options
+//
+// Description:
+//
+//	Runs the playbook with become, parsing the results of its tasks from the json stdout callback. The progress
+//	hook of the context is called with the events of the task_progress callback plugin while the playbook runs,
+//	and the done hook with the result once it ended.
+//
+// Return:
+//
+//	(*PlaybookResult, error): Returns the results of the tasks if the output could be parsed, and error if the
+//	playbook failed or was cancelled
+func runPlaybook(ctx context.Context, run playbookRun) (*PlaybookResult, error) {
+	hooks := playbookHooks(ctx)
+	// Every run has its own inventory and progress files, so the playbooks running at the same time don't share them
+	runDir, err := os.MkdirTemp("", "playbook-")
+	if err != nil {
+		return nil, err
 	}
-
-	ansiblePlaybookPrivilegeEscalationOptions := &options.AnsiblePrivilegeEscalationOptions{
-		Become:       true,
-		BecomeMethod: "sudo",
+	defer os.RemoveAll(runDir)
+	inventoryFileName, err := run.inventory.writeTo(runDir)
+	if err != nil {
+		return nil, err
 	}
+	progressFileName := filepath.Join(runDir, "progress.jsonl")
+	log.Debug.Println(fmt.Sprintf("Running the playbook %s on %s", run.playbook, run.inventory))
 
-	playbook := &playbook.AnsiblePlaybookCmd{
-		Playbooks:                  []string{fileName},
-		ConnectionOptions:          ansiblePlaybookConnectionOptions,
-		PrivilegeEscalationOptions: ansiblePlaybookPrivilegeEscalationOptions,
-		Options:                    ansiblePlaybookOptions,
+	var output bytes.Buffer
+	playbookCmd := &playbook.AnsiblePlaybookCmd{
+		Playbooks: []string{run.playbook},
+		ConnectionOptions: &options.AnsibleConnectionOptions{
+			User: run.inventory.User,
+		},
+		PrivilegeEscalationOptions: &options.AnsiblePrivilegeEscalationOptions{
+			Become:       true,
+			BecomeMethod: "sudo",
+		},
+		Options: &playbook.AnsiblePlaybookOptions{
+			Inventory: inventoryFileName,
+			ExtraVars: run.extraVars,
+			Tags:      strings.Join(run.tags, ", "),
+		},
+		StdoutCallback: stdoutcallback.JSONStdoutCallback,
 		Exec: execute.NewDefaultExecute(
-			execute.WithEnvVar("ANSIBLE_FORCE_COLOR", "true"),
-			execute.WithTransformers(
-				results.Prepend("Go-ansible with become"),
-			),
+			execute.WithWrite(&output),
+			// ansible 2.9 enables the callback plugins with the whitelist, the later versions with callbacks_enabled
+			execute.WithEnvVar("ANSIBLE_CALLBACK_PLUGINS", callbackPluginsDir),
+			execute.WithEnvVar("ANSIBLE_CALLBACK_WHITELIST", "task_progress"),
+			execute.WithEnvVar("ANSIBLE_CALLBACKS_ENABLED", "task_progress"),
+			execute.WithEnvVar(progressFileEnv, progressFileName),
 		),
 	}
 
-	err := playbook.Run(context.TODO())
-	return err
+	start := time.Now()
+	stopProgress := watchProgress(ctx, progressFileName, hooks.Progress)
+	runErr := playbookCmd.Run(ctx)
+	stopProgress()
+
+	result, parseErr := parsePlaybookResult(run.playbook, run.tags, output.Bytes())
+	if parseErr != nil {
+		log.Warn.Println(fmt.Sprintf("Unable to parse the results of the playbook %s: %v", run.playbook, parseErr))
+		result = nil
+	} else {
+		result.Duration = time.Since(start)
+		if hooks.Done != nil {
+			hooks.Done(result)
+		}
+	}
+
+	if ctx.Err() != nil {
+		return result, fmt.Errorf("the playbook %s was stopped: %w", run.playbook, ctx.Err())
+	}
+	if result != nil {
+		if failed := result.Failed(); failed != nil {
+			return result, maskCredentials(fmt.Errorf("task %q of the playbook %s is %s on %s: %s", failed.Task, run.playbook, failed.Status, failed.Host, failed.Message))
+		}
+	}
+	if runErr != nil {
+		return result, maskCredentials(runErr)
+	}
+	return result, nil
 }
 
 // Input:
@@ -162,10 +252,91 @@ func UpdateWithTags(hosts string, clusterCfg config.ClusterDetails, tags []strin
 //
 //	(error): Returns custom error
 func maskCredentials(err error) error {
-	errString := err.Error()
-	m1 := regexp.MustCompile("\"*credentials\":.*?}")
-	errString = m1.ReplaceAllString(errString, "credentials\":{*********}")
-	errString = errString + "\nCheck ansible log file for more details. (ansible_scripts/playbook.log)"
+	errString := maskCredentialsString(err.Error())
+	errString = errString + "\nCheck ansible log file for more details. (logs/playbook.log)"
 	newErr := errors.New(errString)
 	return newErr
 }
+
+// Input:
+//
+//	value (string): Output of ansible which may hold the extra variables
+//
+// Description:
+//
+//	Masks the credentials of the extra variables in the output
+//
+// Return:
+//
+//	(string): Returns the masked output
+func maskCredentialsString(value string) string {
+	m1 := regexp.MustCompile("\"*credentials\":.*?}")
+	return m1.ReplaceAllString(value, "credentials\":{*********}")
+}
+
+// The ansible node configurator runs the scale up playbook on an inventory of the nodes.
+type ansibleConfigurator struct{}
+
+// Input:
+//
+// Description:
+//
+//	Names the ansible node configurator
+//
+// Return:
+//
+//	(string): Returns ansible
+func (a ansibleConfigurator) Name() string {
+	return AnsibleConfigurator
+}
+
+// Input:
+//
+//	ctx (context.Context): Context cancelling the playbook
+//	inventory (Inventory): Current nodes and the new node
+//	clusterCfg (config.ClusterDetails): Opensearch cluster details for configuring
+//
+// Description:
+//
+//	Runs the scale up playbook on the inventory of the nodes. Every task which
+//	was not skipped is reported as a step on its host.
+//
+// Return:
+//
+//	(Report, error): Returns the result of the playbook and error if it failed
+func (a ansibleConfigurator) ConfigureNewNode(ctx context.Context, inventory Inventory, clusterCfg config.ClusterDetails) (Report, error) {
+	start := time.Now()
+	playbookResult, err := CallAnsible(ctx, scaleUpInventory(inventory, clusterCfg), clusterCfg, "scale_up")
+	var report Report
+	if playbookResult != nil {
+		report = playbookResult.Report()
+	}
+	if err == nil {
+		return report, nil
+	}
+	// The error of a playbook which did not fail in a task, like a cancelled one, is reported on the new node
+	if failed := report.Failed(); failed != nil {
+		failed.Message = err.Error()
+		return report, &StepError{Result: *failed}
+	}
+	result := StepResult{Host: inventory.NewNode.Name, Step: "scale_up_playbook", Status: StepFailed, Message: err.Error(), Duration: time.Since(start)}
+	return append(report, result), &StepError{Result: result}
+}
+
+// Input:
+//
+//	inventory (Inventory): Current nodes and the new node
+//	clusterCfg (config.ClusterDetails): Opensearch cluster details with the ssh user, pem file and bastion
+//
+// Description:
+//
+//	Builds the inventory of the scale up playbook, with the current_nodes and new_node groups
+//
+// Return:
+//
+//	(*AnsibleInventory): Returns the inventory
+func scaleUpInventory(inventory Inventory, clusterCfg config.ClusterDetails) *AnsibleInventory {
+	return NewAnsibleInventory(clusterCfg).
+		AddGroup(CurrentNodesGroup, inventory.CurrentNodes...).
+		AddGroup(NewNodeGroup, inventory.NewNode)
+}
@@ -0,0 +1,60 @@
+# Writes the progress of a playbook as JSON lines to the file of the SCALING_MANAGER_PROGRESS_FILE environment
+# variable, which the scaling manager reads while the playbook runs. The results of the tasks are printed by the
+# json stdout callback once the playbook ended.
+from __future__ import (absolute_import, division, print_function)
+__metaclass__ = type
+
+DOCUMENTATION = '''
+    callback: task_progress
+    type: aggregate
+    short_description: Writes the started and failed tasks to a file
+    description:
+      - Appends an event to the file of the SCALING_MANAGER_PROGRESS_FILE environment variable when a task starts,
+        fails or its host is unreachable.
+    requirements:
+      - enabled in callback_whitelist (callbacks_enabled in ansible 2.11 and later)
+'''
+
+import json
+import os
+
+from ansible.plugins.callback import CallbackBase
+
+
+class CallbackModule(CallbackBase):
+    CALLBACK_VERSION = 2.0
+    CALLBACK_TYPE = 'aggregate'
+    CALLBACK_NAME = 'task_progress'
+    CALLBACK_NEEDS_WHITELIST = True
+    CALLBACK_NEEDS_ENABLED = True
+
+    def __init__(self):
+        super(CallbackModule, self).__init__()
+        self.progress_file = os.environ.get('SCALING_MANAGER_PROGRESS_FILE')
+
+    def _write(self, event):
+        if not self.progress_file:
+            return
+        with open(self.progress_file, 'a') as progress:
+            progress.write(json.dumps(event) + '\n')
+
+    def v2_playbook_on_task_start(self, task, is_conditional):
+        self._write({'event': 'task_start', 'task': task.get_name()})
+
+    def v2_runner_on_failed(self, result, ignore_errors=False):
+        if ignore_errors:
+            return
+        self._write({
+            'event': 'failed',
+            'task': result._task.get_name(),
+            'host': result._host.get_name(),
+            'msg': str(result._result.get('msg') or result._result.get('stderr', '')),
+        })
+
+    def v2_runner_on_unreachable(self, result):
+        self._write({
+            'event': 'unreachable',
+            'task': result._task.get_name(),
+            'host': result._host.get_name(),
+            'msg': str(result._result.get('msg', '')),
+        })
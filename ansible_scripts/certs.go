@@ -0,0 +1,141 @@
+package ansibleutils
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+)
+
+// Validity of the node certificates, like the validityDays of the certificates generation template
+const certificateValidity = 730 * 24 * time.Hour
+
+// Object identifier of the domain component of a distinguished name
+var domainComponentOid = asn1.ObjectIdentifier{0, 9, 2342, 19200300, 100, 1, 25}
+
+// Input:
+//
+//	name (string): Common name, like node-10-81-1-225 or admin
+//	domainName (string): Domain name of the cluster
+//
+// Description:
+//
+//	Formats the distinguished name of a certificate of the cluster like the certificates generation template,
+//	CN=<name>.<domain>,OU=Ops,O=<domain>\, Inc.,DC=<domain>
+//
+// Return:
+//
+//	(string): Returns the distinguished name
+func distinguishedName(name string, domainName string) string {
+	return fmt.Sprintf("CN=%s.%s,OU=Ops,O=%s\\, Inc.,DC=%s", name, domainName, domainName, domainName)
+}
+
+// Input:
+//
+//	caCertPem ([]byte): Root CA certificate of the cluster, PEM encoded
+//	caKeyPem ([]byte): Private key of the root CA, PEM encoded
+//	name (string): Name of the node
+//	domainName (string): Domain name of the cluster
+//	ip (string): Private ip of the node
+//	now (time.Time): Start of the validity of the certificate
+//
+// Description:
+//
+//	Generates a key and a certificate for the node signed by the root CA, with the distinguished name and the
+//	subject alternative names of the certificates made by the certificates generation tool of the playbook
+//
+// Return:
+//
+//	([]byte, []byte, error): Returns the PEM encoded certificate and PKCS#8 key, and error if any
+func nodeCertificate(caCertPem []byte, caKeyPem []byte, name string, domainName string, ip string, now time.Time) ([]byte, []byte, error) {
+	caCertBlock, _ := pem.Decode(caCertPem)
+	if caCertBlock == nil {
+		return nil, nil, errors.New("the root CA certificate is not PEM encoded")
+	}
+	caCert, err := x509.ParseCertificate(caCertBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	caKey, err := parsePrivateKey(caKeyPem)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, err
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	// The RDNs are encoded in the reverse order of the distinguished name
+	subject, err := asn1.Marshal(pkix.RDNSequence{
+		{{Type: domainComponentOid, Value: domainName}},
+		{{Type: asn1.ObjectIdentifier{2, 5, 4, 10}, Value: domainName + ", Inc."}},
+		{{Type: asn1.ObjectIdentifier{2, 5, 4, 11}, Value: "Ops"}},
+		{{Type: asn1.ObjectIdentifier{2, 5, 4, 3}, Value: name + "." + domainName}},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		RawSubject:   subject,
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(certificateValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{name + "." + domainName},
+	}
+	if parsedIp := net.ParseIP(ip); parsedIp != nil {
+		template.IPAddresses = []net.IP{parsedIp}
+	}
+	certDer, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDer}), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer}), nil
+}
+
+// Input:
+//
+//	keyPem ([]byte): PEM encoded private key, PKCS#8, PKCS#1 or EC
+//
+// Description:
+//
+//	Parses the private key of the root CA
+//
+// Return:
+//
+//	(crypto.Signer, error): Returns the key and error if it can't be parsed
+func parsePrivateKey(keyPem []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(keyPem)
+	if block == nil {
+		return nil, errors.New("the root CA key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer, nil
+		}
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("the root CA key is neither a PKCS#8, PKCS#1 nor EC private key")
+}
@@ -0,0 +1,148 @@
+package ansibleutils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+)
+
+// Names of the node configurators
+const (
+	AnsibleConfigurator = "ansible"
+	NativeConfigurator  = "native"
+)
+
+// Status of the steps configuring a node
+const (
+	StepOk      = "ok"
+	StepSkipped = "skipped"
+	StepFailed  = "failed"
+)
+
+// This struct contains a node of the cluster to be configured.
+type Host struct {
+	// Name indicates the node name, like node-10-81-1-225.
+	Name string `json:"name"`
+	// Ip indicates the private ip of the node.
+	Ip string `json:"ip"`
+	// Roles indicates the opensearch roles of the node.
+	Roles []string `json:"roles"`
+	// Attributes indicates the custom node attributes of the node.
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// This struct contains the nodes of the cluster and the node which is added to it.
+type Inventory struct {
+	// CurrentNodes indicates the nodes which are part of the cluster.
+	CurrentNodes []Host `json:"current_nodes"`
+	// NewNode indicates the node which is configured to join the cluster.
+	NewNode Host `json:"new_node"`
+}
+
+// This struct contains the result of a step configuring a node.
+type StepResult struct {
+	// Host indicates the name of the node the step ran on.
+	Host string `json:"host"`
+	// Step indicates the name of the step, like opensearch_yml or heap.
+	Step string `json:"step"`
+	// Status indicates if the step was ok, skipped or failed.
+	Status string `json:"status"`
+	// Message indicates what the step did, or why it failed.
+	Message string `json:"message,omitempty"`
+	// Duration indicates how long the step took.
+	Duration time.Duration `json:"duration"`
+}
+
+// Results of the steps configuring the nodes, in the order they ran
+type Report []StepResult
+
+// Error returned when a step configuring a node failed
+type StepError struct {
+	Result StepResult
+}
+
+// A node configurator installs and configures opensearch on a new node and adds it to the configuration of the
+// current nodes, so that it joins the cluster.
+type NodeConfigurator interface {
+	// Name returns the name of the node configurator.
+	Name() string
+	// ConfigureNewNode configures the new node of the inventory, returning the results of the steps which ran.
+	ConfigureNewNode(ctx context.Context, inventory Inventory, clusterCfg config.ClusterDetails) (Report, error)
+}
+
+// Input:
+//
+//	name (string): Name of the node configurator, ansible if empty
+//
+// Description:
+//
+//	Creates the node configurator set in the config
+//
+// Return:
+//
+//	(NodeConfigurator, error): Returns the node configurator and error if the name is unknown
+func NewNodeConfigurator(name string) (NodeConfigurator, error) {
+	switch name {
+	case "", AnsibleConfigurator:
+		return ansibleConfigurator{}, nil
+	case NativeConfigurator:
+		return newNativeConfigurator(), nil
+	default:
+		return nil, fmt.Errorf("unknown node configurator %s, expected %s or %s", name, AnsibleConfigurator, NativeConfigurator)
+	}
+}
+
+// Input:
+//
+// Description:
+//
+//	Describes the failed step
+//
+// Return:
+//
+//	(string): Returns the error message
+func (e *StepError) Error() string {
+	return fmt.Sprintf("step %s failed on %s: %s", e.Result.Step, e.Result.Host, e.Result.Message)
+}
+
+// Input:
+//
+// Description:
+//
+//	Finds the step which failed
+//
+// Return:
+//
+//	(*StepResult): Returns the failed step, nil if no step failed
+func (r Report) Failed() *StepResult {
+	for i := range r {
+		if r[i].Status == StepFailed {
+			return &r[i]
+		}
+	}
+	return nil
+}
+
+// Input:
+//
+// Description:
+//
+//	Formats the report with a line for every step, like "node-10-81-1-225 heap: ok (1.2s) heap set to 4g"
+//
+// Return:
+//
+//	([]string): Returns the lines of the report
+func (r Report) Lines() []string {
+	var lines []string
+	for _, result := range r {
+		line := fmt.Sprintf("%s %s: %s (%s)", result.Host, result.Step, result.Status, result.Duration.Round(100*time.Millisecond))
+		if result.Message != "" {
+			line += " " + strings.TrimSpace(result.Message)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
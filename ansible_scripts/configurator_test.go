@@ -0,0 +1,49 @@
+package ansibleutils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewNodeConfigurator(t *testing.T) {
+	configurator, err := NewNodeConfigurator("")
+	assert.NoError(t, err)
+	assert.Equal(t, AnsibleConfigurator, configurator.Name())
+	configurator, err = NewNodeConfigurator(NativeConfigurator)
+	assert.NoError(t, err)
+	assert.Equal(t, NativeConfigurator, configurator.Name())
+	_, err = NewNodeConfigurator("salt")
+	assert.EqualError(t, err, "unknown node configurator salt, expected ansible or native")
+}
+
+func TestReportLines(t *testing.T) {
+	report := Report{
+		{Host: "node-10-0-0-3", Step: "heap", Status: StepOk, Message: "heap set to 8g", Duration: 1234 * time.Millisecond},
+		{Host: "node-10-0-0-3", Step: "security", Status: StepFailed, Message: "unable to read root-ca.pem\n", Duration: 50 * time.Millisecond},
+	}
+	assert.Equal(t, []string{
+		"node-10-0-0-3 heap: ok (1.2s) heap set to 8g",
+		"node-10-0-0-3 security: failed (100ms) unable to read root-ca.pem",
+	}, report.Lines())
+	assert.Equal(t, "security", report.Failed().Step)
+	assert.Nil(t, report[:1].Failed())
+}
+
+func TestFakeConfigurator(t *testing.T) {
+	configurator := &FakeConfigurator{}
+	report, err := configurator.ConfigureNewNode(context.Background(), testInventory(), testClusterCfg())
+	assert.NoError(t, err)
+	assert.Len(t, report, len(fakeSteps))
+	assert.Equal(t, []Inventory{testInventory()}, configurator.Inventories)
+
+	configurator = &FakeConfigurator{FailStep: "security", FailMessage: "unable to read root-ca.pem"}
+	report, err = configurator.ConfigureNewNode(context.Background(), testInventory(), testClusterCfg())
+	var stepErr *StepError
+	assert.True(t, errors.As(err, &stepErr))
+	assert.Equal(t, "step security failed on node-10-0-0-3: unable to read root-ca.pem", err.Error())
+	assert.Equal(t, "security", report[len(report)-1].Step)
+}
@@ -0,0 +1,70 @@
+package ansibleutils
+
+import (
+	"context"
+	"sync"
+
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+)
+
+// Name of the fake node configurator
+const FakeConfiguratorName = "fake"
+
+// The fake node configurator records the inventories it is called with and returns the result set on it,
+// so that the provisioning can be tested without configuring nodes.
+type FakeConfigurator struct {
+	// FailStep indicates the step which fails on the new node, no step fails if empty.
+	FailStep string
+	// FailMessage indicates the message of the failed step.
+	FailMessage string
+	// Inventories indicates the inventories the configurator was called with.
+	Inventories []Inventory
+	lock        sync.Mutex
+}
+
+// Steps reported by the fake node configurator, the steps of the native node configurator on the new node
+var fakeSteps = []string{"connect", "tune", "install", "heap", "security", "opensearch_yml", "systemd_unit", "unicast_hosts", "etc_hosts", "ownership", "start_opensearch", "wait_for_opensearch"}
+
+// Input:
+//
+// Description:
+//
+//	Names the fake node configurator
+//
+// Return:
+//
+//	(string): Returns fake
+func (f *FakeConfigurator) Name() string {
+	return FakeConfiguratorName
+}
+
+// Input:
+//
+//	ctx (context.Context): Context of the configuration
+//	inventory (Inventory): Current nodes and the new node
+//	clusterCfg (config.ClusterDetails): Opensearch cluster details
+//
+// Description:
+//
+//	Records the inventory and reports the steps on the new node as ok, up to the failing step if one is set
+//
+// Return:
+//
+//	(Report, error): Returns the results of the steps and a StepError if the failing step is reached
+func (f *FakeConfigurator) ConfigureNewNode(ctx context.Context, inventory Inventory, clusterCfg config.ClusterDetails) (Report, error) {
+	f.lock.Lock()
+	f.Inventories = append(f.Inventories, inventory)
+	f.lock.Unlock()
+
+	var report Report
+	for _, step := range fakeSteps {
+		result := StepResult{Host: inventory.NewNode.Name, Step: step, Status: StepOk}
+		if step == f.FailStep {
+			result.Status, result.Message = StepFailed, f.FailMessage
+			report = append(report, result)
+			return report, &StepError{Result: result}
+		}
+		report = append(report, result)
+	}
+	return report, ctx.Err()
+}
@@ -0,0 +1,318 @@
+package ansibleutils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
+	utils "github.com/maplelabs/opensearch-scaling-manager/utilities"
+	"gopkg.in/yaml.v3"
+)
+
+// Groups of the hosts the playbooks run on
+const (
+	CurrentNodesGroup = "current_nodes"
+	NewNodeGroup      = "new_node"
+	RemoveNodeGroup   = "remove_node"
+	RestartNodeGroup  = "restart_node"
+)
+
+// Formats of the inventory files
+const (
+	InventoryIni  = "ini"
+	InventoryYaml = "yaml"
+)
+
+// This struct contains a group of hosts of an ansible inventory.
+type HostGroup struct {
+	// Name indicates the name of the group the playbooks refer to, like current_nodes.
+	Name string
+	// Hosts indicates the nodes of the group.
+	Hosts []Host
+}
+
+// This struct contains the hosts a playbook runs on and how they are reached over SSH. It is written to a new
+// directory for every run of a playbook.
+type AnsibleInventory struct {
+	// Groups indicates the groups of hosts, in the order they are written.
+	Groups []HostGroup
+	// User indicates the SSH user of the hosts.
+	User string
+	// KeyFile indicates the private key of the SSH user.
+	KeyFile string
+	// Bastion indicates the jump host the hosts are reached through, none if its host is empty.
+	Bastion config.Bastion
+	// Format indicates if the inventory is written as ini or yaml.
+	Format string
+}
+
+// Host variables of a host in a yaml inventory, in the order of the ini inventory
+type inventoryHostVars struct {
+	AnsibleUser        string `yaml:"ansible_user"`
+	Roles              string `yaml:"roles"`
+	NodeAttributes     string `yaml:"node_attributes"`
+	AnsiblePrivateHost string `yaml:"ansible_private_host"`
+	KeyFile            string `yaml:"ansible_ssh_private_key_file"`
+}
+
+// A group of a yaml inventory
+type inventoryGroup struct {
+	Hosts map[string]inventoryHostVars `yaml:"hosts"`
+}
+
+// The all group of a yaml inventory, with the other groups as its children
+type inventoryAll struct {
+	Vars     map[string]string         `yaml:"vars,omitempty"`
+	Children map[string]inventoryGroup `yaml:"children"`
+}
+
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Cluster Level config details with the ssh user, pem file and bastion
+//
+// Description:
+//
+//	Creates an empty ini inventory reaching the hosts as the os_user, through the bastion of the config if any.
+//	The user and key of the bastion default to the ones of the nodes.
+//
+// Return:
+//
+//	(*AnsibleInventory): Returns the inventory
+func NewAnsibleInventory(clusterCfg config.ClusterDetails) *AnsibleInventory {
+	inventory := &AnsibleInventory{
+		User:    clusterCfg.SshUser,
+		KeyFile: clusterCfg.CloudCredentials.PemFilePath,
+		Bastion: clusterCfg.Bastion,
+		Format:  InventoryIni,
+	}
+	if inventory.Bastion.Host != "" {
+		if inventory.Bastion.Port == 0 {
+			inventory.Bastion.Port = 22
+		}
+		if inventory.Bastion.User == "" {
+			inventory.Bastion.User = inventory.User
+		}
+		if inventory.Bastion.PemFilePath == "" {
+			inventory.Bastion.PemFilePath = inventory.KeyFile
+		}
+	}
+	return inventory
+}
+
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Cluster Level config details
+//
+// Description:
+//
+//	Creates an inventory with the nodes currently present in the cluster in the current_nodes group
+//
+// Return:
+//
+//	(*AnsibleInventory, error): Returns the inventory and error if the nodes can't be fetched
+func ClusterInventory(clusterCfg config.ClusterDetails) (*AnsibleInventory, error) {
+	nodes, err := utils.GetNodes()
+	if err != nil {
+		return nil, err
+	}
+	return NewAnsibleInventory(clusterCfg).AddGroup(CurrentNodesGroup, NodeHosts(nodes)...), nil
+}
+
+// Input:
+//
+//	nodes (map[string]osutils.NodeStats): Nodes of the cluster by node id
+//
+// Description:
+//
+//	Converts the nodes of the cluster to hosts with their roles and attributes, sorted by name
+//
+// Return:
+//
+//	([]Host): Returns the hosts
+func NodeHosts(nodes map[string]osutils.NodeStats) []Host {
+	var hosts []Host
+	for _, node := range nodes {
+		hosts = append(hosts, Host{Name: node.Name, Ip: node.Host, Roles: node.Roles, Attributes: node.Attributes})
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		return hosts[i].Name < hosts[j].Name
+	})
+	return hosts
+}
+
+// Input:
+//
+//	ip (string): Private ip of the new node, empty if the node is not spinned yet
+//	nodeGroup (config.NodeGroup): Node group of the new node
+//
+// Description:
+//
+//	Names the new node after its ip, like node-10-81-1-225, with the roles and attributes of its node group
+//
+// Return:
+//
+//	(Host): Returns the new node, without a name if the ip is empty
+func NewNodeHost(ip string, nodeGroup config.NodeGroup) Host {
+	host := Host{Ip: ip, Roles: nodeGroup.Roles, Attributes: nodeGroup.NodeAttributes()}
+	if ip != "" {
+		host.Name = "node-" + strings.ReplaceAll(ip, ".", "-")
+	}
+	return host
+}
+
+// Input:
+//
+//	name (string): Name of the group
+//	hosts (...Host): Hosts of the group
+//
+// Description:
+//
+//	Adds a group of hosts to the inventory
+//
+// Return:
+//
+//	(*AnsibleInventory): Returns the inventory
+func (i *AnsibleInventory) AddGroup(name string, hosts ...Host) *AnsibleInventory {
+	i.Groups = append(i.Groups, HostGroup{Name: name, Hosts: hosts})
+	return i
+}
+
+// Input:
+//
+// Description:
+//
+//	Builds the SSH arguments proxying the connections to the hosts through the bastion, with the host key
+//	checks of the nodes disabled like in the ansible config
+//
+// Return:
+//
+//	(string): Returns the arguments, empty if there is no bastion
+func (i *AnsibleInventory) sshCommonArgs() string {
+	if i.Bastion.Host == "" {
+		return ""
+	}
+	return fmt.Sprintf(`-o ProxyCommand="ssh -W %%h:%%p -q -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null -i %s -p %d %s@%s"`,
+		i.Bastion.PemFilePath, i.Bastion.Port, i.Bastion.User, i.Bastion.Host)
+}
+
+// Input:
+//
+//	host (Host): Host of the inventory
+//
+// Description:
+//
+//	Builds the host variables of a host. The roles and attributes are passed as the roles and node_attributes
+//	host variables, which are written to opensearch.yml when the node is configured.
+//
+// Return:
+//
+//	(inventoryHostVars): Returns the host variables
+func (i *AnsibleInventory) hostVars(host Host) inventoryHostVars {
+	var nodeAttributes []string
+	for key, value := range host.Attributes {
+		nodeAttributes = append(nodeAttributes, key+":"+value)
+	}
+	sort.Strings(nodeAttributes)
+	return inventoryHostVars{
+		AnsibleUser:        i.User,
+		Roles:              strings.Join(host.Roles, ","),
+		NodeAttributes:     strings.Join(nodeAttributes, ","),
+		AnsiblePrivateHost: host.Ip,
+		KeyFile:            i.KeyFile,
+	}
+}
+
+// Input:
+//
+//	format (string): Format of the inventory, ini or yaml
+//
+// Description:
+//
+//	Formats the inventory with a section for every group, and the SSH arguments of the bastion in the variables
+//	of all the hosts
+//
+// Return:
+//
+//	([]byte, error): Returns the content of the inventory file and error if the format is unknown
+func (i *AnsibleInventory) Render(format string) ([]byte, error) {
+	switch format {
+	case "", InventoryIni:
+		var content strings.Builder
+		for _, group := range i.Groups {
+			content.WriteString("[" + group.Name + "]\n")
+			for _, host := range group.Hosts {
+				vars := i.hostVars(host)
+				content.WriteString(host.Name + " ansible_user=" + vars.AnsibleUser + " roles=\"" + vars.Roles + "\" node_attributes=\"" + vars.NodeAttributes + "\" ansible_private_host=" + vars.AnsiblePrivateHost + " ansible_ssh_private_key_file=" + vars.KeyFile + "\n")
+			}
+		}
+		if args := i.sshCommonArgs(); args != "" {
+			content.WriteString("[all:vars]\n")
+			content.WriteString("ansible_ssh_common_args='" + args + "'\n")
+		}
+		return []byte(content.String()), nil
+	case InventoryYaml:
+		all := inventoryAll{Children: make(map[string]inventoryGroup)}
+		for _, group := range i.Groups {
+			yamlGroup := inventoryGroup{Hosts: make(map[string]inventoryHostVars)}
+			for _, host := range group.Hosts {
+				yamlGroup.Hosts[host.Name] = i.hostVars(host)
+			}
+			all.Children[group.Name] = yamlGroup
+		}
+		if args := i.sshCommonArgs(); args != "" {
+			all.Vars = map[string]string{"ansible_ssh_common_args": args}
+		}
+		return yaml.Marshal(map[string]inventoryAll{"all": all})
+	default:
+		return nil, fmt.Errorf("unknown inventory format %s, expected %s or %s", format, InventoryIni, InventoryYaml)
+	}
+}
+
+// Input:
+//
+//	dir (string): Directory of the run of the playbook
+//
+// Description:
+//
+//	Writes the inventory file to the directory, with the extension of its format which the yaml inventory
+//	plugin expects
+//
+// Return:
+//
+//	(string, error): Returns the path of the inventory file and error if it can't be written
+func (i *AnsibleInventory) writeTo(dir string) (string, error) {
+	content, err := i.Render(i.Format)
+	if err != nil {
+		return "", err
+	}
+	fileName := filepath.Join(dir, "hosts")
+	if i.Format == InventoryYaml {
+		fileName += ".yml"
+	}
+	return fileName, os.WriteFile(fileName, content, 0600)
+}
+
+// Input:
+//
+// Description:
+//
+//	Lists the hosts of the inventory by group, for the logs
+//
+// Return:
+//
+//	(string): Returns the groups with the names of their hosts, like "current_nodes: node-1, node-2"
+func (i *AnsibleInventory) String() string {
+	var groups []string
+	for _, group := range i.Groups {
+		var names []string
+		for _, host := range group.Hosts {
+			names = append(names, host.Name)
+		}
+		groups = append(groups, group.Name+": "+strings.Join(names, ", "))
+	}
+	return strings.Join(groups, "; ")
+}
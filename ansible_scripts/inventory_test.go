@@ -0,0 +1,101 @@
+package ansibleutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderIniInventory(t *testing.T) {
+	clusterCfg := testClusterCfg()
+	clusterCfg.CloudCredentials.PemFilePath = "/usr/local/scaling_manager_lib/user.pem"
+	content, err := scaleUpInventory(testInventory(), clusterCfg).Render(InventoryIni)
+	assert.NoError(t, err)
+	assert.Equal(t, "[current_nodes]\n"+
+		"node-10-0-0-1 ansible_user=ubuntu roles=\"master,data\" node_attributes=\"\" ansible_private_host=10.0.0.1 ansible_ssh_private_key_file=/usr/local/scaling_manager_lib/user.pem\n"+
+		"node-10-0-0-2 ansible_user=ubuntu roles=\"master,data\" node_attributes=\"\" ansible_private_host=10.0.0.2 ansible_ssh_private_key_file=/usr/local/scaling_manager_lib/user.pem\n"+
+		"[new_node]\n"+
+		"node-10-0-0-3 ansible_user=ubuntu roles=\"data,ingest\" node_attributes=\"temp:hot\" ansible_private_host=10.0.0.3 ansible_ssh_private_key_file=/usr/local/scaling_manager_lib/user.pem\n",
+		string(content))
+
+	// Coordinating nodes have no roles
+	nodeGroup := config.NodeGroup{Name: "hot", Roles: []string{"data", "ingest"}, Tier: "hot"}
+	inventory := NewAnsibleInventory(clusterCfg).
+		AddGroup(NewNodeGroup, NewNodeHost("10.81.1.5", nodeGroup)).
+		AddGroup(RemoveNodeGroup, Host{Name: "coordinating-1", Ip: "10.81.1.6"})
+	content, err = inventory.Render(InventoryIni)
+	assert.NoError(t, err)
+	assert.Equal(t, "[new_node]\n"+
+		`node-10-81-1-5 ansible_user=ubuntu roles="data,ingest" node_attributes="node_group:hot,temp:hot" ansible_private_host=10.81.1.5 ansible_ssh_private_key_file=/usr/local/scaling_manager_lib/user.pem`+"\n"+
+		"[remove_node]\n"+
+		`coordinating-1 ansible_user=ubuntu roles="" node_attributes="" ansible_private_host=10.81.1.6 ansible_ssh_private_key_file=/usr/local/scaling_manager_lib/user.pem`+"\n",
+		string(content))
+	assert.Equal(t, "new_node: node-10-81-1-5; remove_node: coordinating-1", inventory.String())
+
+	_, err = inventory.Render("json")
+	assert.EqualError(t, err, "unknown inventory format json, expected ini or yaml")
+}
+
+func TestRenderInventoryWithBastion(t *testing.T) {
+	clusterCfg := testClusterCfg()
+	clusterCfg.CloudCredentials.PemFilePath = "/usr/local/scaling_manager_lib/user.pem"
+	clusterCfg.Bastion = config.Bastion{Host: "bastion.example.com", User: "ec2-user"}
+	inventory := NewAnsibleInventory(clusterCfg).AddGroup(NewNodeGroup, Host{Name: "node-10-0-0-3", Ip: "10.0.0.3", Roles: []string{"data"}})
+	assert.Equal(t, config.Bastion{Host: "bastion.example.com", Port: 22, User: "ec2-user", PemFilePath: "/usr/local/scaling_manager_lib/user.pem"}, inventory.Bastion)
+
+	content, err := inventory.Render(InventoryIni)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "[all:vars]\n"+
+		`ansible_ssh_common_args='-o ProxyCommand="ssh -W %h:%p -q -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null -i /usr/local/scaling_manager_lib/user.pem -p 22 ec2-user@bastion.example.com"'`+"\n")
+
+	content, err = inventory.Render(InventoryYaml)
+	assert.NoError(t, err)
+	assert.Equal(t, `all:
+    vars:
+        ansible_ssh_common_args: -o ProxyCommand="ssh -W %h:%p -q -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null -i /usr/local/scaling_manager_lib/user.pem -p 22 ec2-user@bastion.example.com"
+    children:
+        new_node:
+            hosts:
+                node-10-0-0-3:
+                    ansible_user: ubuntu
+                    roles: data
+                    node_attributes: ""
+                    ansible_private_host: 10.0.0.3
+                    ansible_ssh_private_key_file: /usr/local/scaling_manager_lib/user.pem
+`, string(content))
+}
+
+func TestNodeHosts(t *testing.T) {
+	nodes := map[string]osutils.NodeStats{
+		"b": {Name: "node-2", Host: "10.81.1.2", Roles: []string{"data"}, Attributes: map[string]string{"temp": "warm"}},
+		"a": {Name: "node-1", Host: "10.81.1.1", Roles: []string{"master"}},
+	}
+	assert.Equal(t, []Host{
+		{Name: "node-1", Ip: "10.81.1.1", Roles: []string{"master"}},
+		{Name: "node-2", Ip: "10.81.1.2", Roles: []string{"data"}, Attributes: map[string]string{"temp": "warm"}},
+	}, NodeHosts(nodes))
+
+	// The name of the new node is not known before it is spinned
+	assert.Equal(t, "", NewNodeHost("", config.NodeGroup{Roles: []string{"data"}}).Name)
+}
+
+func TestWriteInventory(t *testing.T) {
+	inventory := scaleUpInventory(testInventory(), testClusterCfg())
+	dir := t.TempDir()
+	fileName, err := inventory.writeTo(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "hosts"), fileName)
+	content, err := os.ReadFile(fileName)
+	assert.NoError(t, err)
+	expected, _ := inventory.Render(InventoryIni)
+	assert.Equal(t, expected, content)
+
+	inventory.Format = InventoryYaml
+	fileName, err = inventory.writeTo(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "hosts.yml"), fileName)
+}
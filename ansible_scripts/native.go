@@ -0,0 +1,717 @@
+package ansibleutils
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Templates of the scale up role, shared by the playbook and the native node configurator
+//
+//go:embed roles/scale_up/templates/jvm.options roles/scale_up/templates/opensearch.service roles/scale_up/templates/security_conf.yml roles/scale_up/templates/internal_users.yml
+var scaleUpTemplates embed.FS
+
+// Matches the variables of the templates of the scale up role, like {{ os_home }}
+var templateVariable = regexp.MustCompile(`\{\{\s*([\w.]+)\s*\}\}`)
+
+// Settings of the group_vars of the playbooks
+const (
+	osDownloadUrl = "https://artifacts.opensearch.org/releases/bundle/opensearch"
+	systemctlPath = "/etc/systemd/system"
+	osApiPort     = 9200
+	maxHeapGb     = 32
+)
+
+// Markers of the blocks added to the config files, the same as the markers of the playbooks
+const (
+	hostsBlockBegin     = "# Ansible inventory hosts BEGIN"
+	hostsBlockEnd       = "# Ansible inventory hosts END"
+	securityCommonBlock = "OpenSearch Security common configuration"
+	securityCertsBlock  = "opensearch Security Node & Admin certificates configuration"
+	auditTypeSetting    = "plugins.security.audit.type: internal_opensearch"
+)
+
+// Certificates of the cluster copied from the node running the scaling manager to the new node
+var securityFilesFromMaster = []string{"root-ca.pem", "root-ca.key", "admin.pem", "admin.key"}
+
+// opensearch.yml of a node, like the opensearch-multi-node.yml template of the scale up role
+var opensearchYmlTemplate = template.Must(template.New("opensearch.yml").Parse(`cluster.name: "{{ .ClusterName }}"
+
+node.name: "{{ .Name }}"
+
+network.host: 0.0.0.0
+network.publish_host: {{ .Ip }}
+
+http.port: 9200
+bootstrap.memory_lock: true
+discovery.seed_providers: file
+
+node.roles: [{{ .Roles }}]
+{{ range .Attributes }}node.attr.{{ . }}
+{{ end }}script.painless.regex.enabled: true
+action.auto_create_index: ".security,.monitoring*,.watches,.triggered_watches,.watcher-history*,.ml*"
+`))
+
+// The step is skipped, the error holds the reason
+type skippedStep struct {
+	reason string
+}
+
+// The native node configurator runs the steps of the scale up playbook over SSH, reporting every step.
+type nativeConfigurator struct {
+	// dial connects to a node of the cluster.
+	dial func(ctx context.Context, host Host, clusterCfg config.ClusterDetails, timeout time.Duration) (remoteHost, error)
+	// master is the node running the scaling manager, which holds the root CA of the cluster.
+	master remoteHost
+	// waitForPort waits until the port of the node accepts connections.
+	waitForPort func(ctx context.Context, ip string, port int, timeout time.Duration) error
+	// now returns the current time.
+	now func() time.Time
+	// sshTimeout is the time to wait for the SSH server of the new node, which may still be booting.
+	sshTimeout time.Duration
+	// startTimeout is the time to wait for opensearch to listen on the new node.
+	startTimeout time.Duration
+}
+
+// Input:
+//
+// Description:
+//
+//	Creates the native node configurator, connecting to the nodes with the SSH user and pem file of the config
+//
+// Return:
+//
+//	(nativeConfigurator): Returns the node configurator
+func newNativeConfigurator() nativeConfigurator {
+	return nativeConfigurator{
+		dial: func(ctx context.Context, host Host, clusterCfg config.ClusterDetails, timeout time.Duration) (remoteHost, error) {
+			return dialSsh(ctx, host.Ip, clusterCfg.SshUser, clusterCfg.CloudCredentials.PemFilePath, timeout)
+		},
+		master:       localHost{},
+		waitForPort:  waitForPort,
+		now:          time.Now,
+		sshTimeout:   10 * time.Minute,
+		startTimeout: 5 * time.Minute,
+	}
+}
+
+// Input:
+//
+// Description:
+//
+//	Names the native node configurator
+//
+// Return:
+//
+//	(string): Returns native
+func (n nativeConfigurator) Name() string {
+	return NativeConfigurator
+}
+
+// Input:
+//
+//	ctx (context.Context): Context cancelling the configuration
+//	inventory (Inventory): Current nodes and the new node
+//	clusterCfg (config.ClusterDetails): Opensearch cluster details with the decrypted credentials
+//
+// Description:
+//
+//	Configures the new node like the scale up playbook. The new node is added to /etc/hosts of the current
+//	nodes, then opensearch is installed on the new node with its opensearch.yml, heap, security certificates
+//	signed by the root CA of the cluster, systemd unit, unicast hosts and /etc/hosts. The new node is added to the
+//	unicast hosts of the current nodes before opensearch is started on it. The configuration stops at the first
+//	failed step. The custom roles of the playbook, like the sfagent installation, are not run.
+//
+// Return:
+//
+//	(Report, error): Returns the results of the steps which ran and a StepError if a step failed
+func (n nativeConfigurator) ConfigureNewNode(ctx context.Context, inventory Inventory, clusterCfg config.ClusterDetails) (Report, error) {
+	var report Report
+	newNode := inventory.NewNode
+	allNodes := append(append([]Host{}, inventory.CurrentNodes...), newNode)
+	confDir := path.Join(clusterCfg.OpensearchHome, "config")
+
+	currentHosts := make([]remoteHost, len(inventory.CurrentNodes))
+	defer func() {
+		for _, host := range currentHosts {
+			if host != nil {
+				host.Close()
+			}
+		}
+	}()
+	for i, node := range inventory.CurrentNodes {
+		err := report.run(node.Name, "connect", func() (string, error) {
+			host, err := n.dial(ctx, node, clusterCfg, time.Minute)
+			currentHosts[i] = host
+			return "", err
+		})
+		if err != nil {
+			return report, err
+		}
+		err = report.run(node.Name, "etc_hosts", func() (string, error) {
+			line := fmt.Sprintf("%s %s.%s %s", newNode.Ip, newNode.Name, clusterCfg.DomainName, newNode.Name)
+			return "", ensureLine(ctx, currentHosts[i], "/etc/hosts", line)
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+
+	var host remoteHost
+	err := report.run(newNode.Name, "connect", func() (string, error) {
+		var err error
+		host, err = n.dial(ctx, newNode, clusterCfg, n.sshTimeout)
+		return "", err
+	})
+	if err != nil {
+		return report, err
+	}
+	defer host.Close()
+
+	steps := []struct {
+		name string
+		run  func() (string, error)
+	}{
+		{"tune", func() (string, error) {
+			_, err := host.Run(ctx, "sysctl -w vm.max_map_count=262144 fs.file-max=65536 && "+
+				"printf 'vm.max_map_count = 262144\\nfs.file-max = 65536\\n' > /etc/sysctl.d/99-opensearch.conf")
+			return "", err
+		}},
+		{"install", func() (string, error) { return n.install(ctx, host, clusterCfg) }},
+		{"heap", func() (string, error) { return n.configureHeap(ctx, host, clusterCfg, confDir) }},
+		{"security", func() (string, error) { return n.configureSecurity(ctx, host, newNode, clusterCfg, confDir) }},
+		{"opensearch_yml", func() (string, error) {
+			content, err := opensearchYml(newNode, newNode.Name, clusterCfg)
+			if err != nil {
+				return "", err
+			}
+			return "", host.WriteFile(ctx, path.Join(confDir, "opensearch.yml"), []byte(content), 0600)
+		}},
+		{"systemd_unit", func() (string, error) {
+			unit, err := renderTemplate("opensearch.service", templateVariables(clusterCfg))
+			if err != nil {
+				return "", err
+			}
+			err = host.WriteFile(ctx, path.Join(systemctlPath, "opensearch.service"), []byte(unit), 0644)
+			if err != nil {
+				return "", err
+			}
+			_, err = host.Run(ctx, "systemctl daemon-reload")
+			return "", err
+		}},
+		{"unicast_hosts", func() (string, error) {
+			var ips []string
+			for _, node := range allNodes {
+				ips = append(ips, node.Ip)
+			}
+			return "", host.WriteFile(ctx, path.Join(confDir, "unicast_hosts.txt"), []byte(strings.Join(ips, "\n")+"\n"), 0644)
+		}},
+		{"etc_hosts", func() (string, error) {
+			block := hostsBlockBegin + "\n"
+			for _, node := range allNodes {
+				block += fmt.Sprintf("%s %s.%s %s\n", node.Ip, node.Name, clusterCfg.DomainName, node.Name)
+			}
+			block += hostsBlockEnd + "\n"
+			_, err := host.Run(ctx, fmt.Sprintf("sed -i '/^%s$/,/^%s$/d' /etc/hosts && printf '%%s' %s >> /etc/hosts", hostsBlockBegin, hostsBlockEnd, shellQuote(block)))
+			return "", err
+		}},
+		{"ownership", func() (string, error) {
+			_, err := host.Run(ctx, fmt.Sprintf("chown -R %s:%s %s && chmod 0700 %s", shellQuote(clusterCfg.SshUser), shellQuote(clusterCfg.OsGroup), shellQuote(clusterCfg.OpensearchHome), shellQuote(confDir)))
+			return "", err
+		}},
+	}
+	for _, step := range steps {
+		err = report.run(newNode.Name, step.name, step.run)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	for i, node := range inventory.CurrentNodes {
+		err = report.run(node.Name, "unicast_hosts", func() (string, error) {
+			return "", ensureLine(ctx, currentHosts[i], path.Join(confDir, "unicast_hosts.txt"), newNode.Ip)
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+
+	err = report.run(newNode.Name, "start_opensearch", func() (string, error) {
+		_, err := host.Run(ctx, "systemctl daemon-reload && systemctl enable opensearch && systemctl start opensearch")
+		return "", err
+	})
+	if err != nil {
+		return report, err
+	}
+	err = report.run(newNode.Name, "wait_for_opensearch", func() (string, error) {
+		return "", n.waitForPort(ctx, newNode.Ip, osApiPort, n.startTimeout)
+	})
+	return report, err
+}
+
+// Input:
+//
+//	ctx (context.Context): Context cancelling the step
+//	host (remoteHost): New node
+//	clusterCfg (config.ClusterDetails): Opensearch cluster details
+//
+// Description:
+//
+//	Downloads and extracts opensearch into the opensearch home of the new node, creating the opensearch user.
+//	The step is skipped if opensearch is already installed, like on a node made from an image.
+//
+// Return:
+//
+//	(string, error): Returns the installed version and error if any
+func (n nativeConfigurator) install(ctx context.Context, host remoteHost, clusterCfg config.ClusterDetails) (string, error) {
+	home := shellQuote(clusterCfg.OpensearchHome)
+	if _, err := host.Run(ctx, "test -x "+home+"/bin/opensearch"); err == nil {
+		return "", &skippedStep{reason: "opensearch is already installed in " + clusterCfg.OpensearchHome}
+	}
+	version := clusterCfg.OpensearchVersion
+	url := fmt.Sprintf("%s/%s/opensearch-%s-linux-x64.tar.gz", osDownloadUrl, version, version)
+	user := shellQuote(clusterCfg.SshUser)
+	_, err := host.Run(ctx, strings.Join([]string{
+		"curl -fsSL -o /tmp/opensearch.tar.gz " + shellQuote(url),
+		"(id -u " + user + " >/dev/null 2>&1 || useradd -m -s /bin/bash " + user + ")",
+		"mkdir -p " + home,
+		"chown " + user + ":" + shellQuote(clusterCfg.OsGroup) + " " + home,
+		"tar -xzf /tmp/opensearch.tar.gz -C " + home + " --strip-components=1",
+	}, " && "))
+	if err != nil {
+		return "", err
+	}
+	return "installed opensearch " + version, nil
+}
+
+// Input:
+//
+//	ctx (context.Context): Context cancelling the step
+//	host (remoteHost): New node
+//	clusterCfg (config.ClusterDetails): Opensearch cluster details
+//	confDir (string): Config directory of opensearch
+//
+// Description:
+//
+//	Sets the heap of the new node to the jvm_factor of its RAM in GB, at most 32 GB, like the scale up playbook
+//
+// Return:
+//
+//	(string, error): Returns the heap set and error if any
+func (n nativeConfigurator) configureHeap(ctx context.Context, host remoteHost, clusterCfg config.ClusterDetails, confDir string) (string, error) {
+	memInfo, err := host.Run(ctx, "grep MemTotal /proc/meminfo")
+	if err != nil {
+		return "", err
+	}
+	heapGb, err := heapSize(memInfo, clusterCfg.JvmFactor)
+	if err != nil {
+		return "", err
+	}
+	variables := templateVariables(clusterCfg)
+	variables["xms_value"] = strconv.Itoa(heapGb)
+	variables["xmx_value"] = strconv.Itoa(heapGb)
+	jvmOptions, err := renderTemplate("jvm.options", variables)
+	if err != nil {
+		return "", err
+	}
+	err = host.WriteFile(ctx, path.Join(confDir, "jvm.options"), []byte(jvmOptions), 0600)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("heap set to %dg", heapGb), nil
+}
+
+// Input:
+//
+//	ctx (context.Context): Context cancelling the step
+//	host (remoteHost): New node
+//	newNode (Host): New node of the inventory
+//	clusterCfg (config.ClusterDetails): Opensearch cluster details with the decrypted credentials
+//	confDir (string): Config directory of opensearch
+//
+// Description:
+//
+//	Copies the root CA and admin certificates of the cluster from the node running the scaling manager to the new
+//	node, with transport and HTTP certificates of the new node signed by the root CA. The internal users of the
+//	security plugin are written with the bcrypt hash of the admin password.
+//
+// Return:
+//
+//	(string, error): Returns the certificates written and error if any
+func (n nativeConfigurator) configureSecurity(ctx context.Context, host remoteHost, newNode Host, clusterCfg config.ClusterDetails, confDir string) (string, error) {
+	files, err := readMasterFiles(ctx, n.master, confDir, securityFilesFromMaster)
+	if err != nil {
+		return "", err
+	}
+	err = addNodeCertificates(files, newNode.Name, newNode.Ip, clusterCfg, n.now())
+	if err != nil {
+		return "", err
+	}
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		err := host.WriteFile(ctx, path.Join(confDir, name), files[name], 0600)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	internalUsers, err := internalUsersYml(clusterCfg)
+	if err != nil {
+		return "", err
+	}
+	err = host.WriteFile(ctx, path.Join(securityConfDir(clusterCfg), "internal_users.yml"), []byte(internalUsers), 0644)
+	if err != nil {
+		return "", err
+	}
+	return "wrote " + strings.Join(names, ", "), nil
+}
+
+// Input:
+//
+//	ctx (context.Context): Context cancelling the reads
+//	master (remoteHost): Node running the scaling manager
+//	confDir (string): Config directory of opensearch
+//	names ([]string): Names of the files in the config directory
+//
+// Description:
+//
+//	Reads the certificates of the cluster from the config directory of the node running the scaling manager
+//
+// Return:
+//
+//	(map[string][]byte, error): Returns the content of the files by name and error if a file can't be read
+func readMasterFiles(ctx context.Context, master remoteHost, confDir string, names []string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	for _, name := range names {
+		content, err := master.Run(ctx, "cat "+shellQuote(path.Join(confDir, name)))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s of the cluster on this node: %w", name, err)
+		}
+		files[name] = []byte(content)
+	}
+	return files, nil
+}
+
+// Input:
+//
+//	files (map[string][]byte): Files of the cluster with the root CA certificate and key
+//	certName (string): Name of the certificate files, the common name of the certificates
+//	ip (string): Private ip of the node, the certificates have no ip if it is empty
+//	clusterCfg (config.ClusterDetails): Opensearch cluster details
+//	now (time.Time): Start of the validity of the certificates
+//
+// Description:
+//
+//	Adds the transport and HTTP certificates and keys of a node signed by the root CA to the files
+//
+// Return:
+//
+//	(error): Returns error if the certificates can't be created
+func addNodeCertificates(files map[string][]byte, certName string, ip string, clusterCfg config.ClusterDetails, now time.Time) error {
+	for _, suffix := range []string{"", "_http"} {
+		cert, key, err := nodeCertificate(files["root-ca.pem"], files["root-ca.key"], certName, clusterCfg.DomainName, ip, now)
+		if err != nil {
+			return fmt.Errorf("unable to create the certificate of %s: %w", certName, err)
+		}
+		files[certName+suffix+".pem"], files[certName+suffix+".key"] = cert, key
+	}
+	return nil
+}
+
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Opensearch cluster details with the decrypted credentials
+//
+// Description:
+//
+//	Renders the internal users of the security plugin with the bcrypt hash of the admin password
+//
+// Return:
+//
+//	(string, error): Returns the content of internal_users.yml and error if any
+func internalUsersYml(clusterCfg config.ClusterDetails) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(clusterCfg.OsCredentials.OsAdminPassword), 12)
+	if err != nil {
+		return "", err
+	}
+	variables := templateVariables(clusterCfg)
+	variables["os_credentials.os_admin_password"] = string(hash)
+	return renderTemplate("internal_users.yml", variables)
+}
+
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Opensearch cluster details
+//
+// Description:
+//
+//	Locates the config directory of the security plugin
+//
+// Return:
+//
+//	(string): Returns the path of the directory
+func securityConfDir(clusterCfg config.ClusterDetails) string {
+	return path.Join(clusterCfg.OpensearchHome, "plugins", "opensearch-security", "securityconfig")
+}
+
+// Input:
+//
+//	newNode (Host): New node of the inventory
+//	certName (string): Name of the certificate files of the new node
+//	clusterCfg (config.ClusterDetails): Opensearch cluster details
+//
+// Description:
+//
+//	Renders opensearch.yml of the new node with the security configuration the scale up playbook adds to it
+//
+// Return:
+//
+//	(string, error): Returns the content of opensearch.yml and error if any
+func opensearchYml(newNode Host, certName string, clusterCfg config.ClusterDetails) (string, error) {
+	var attributes []string
+	for key, value := range newNode.Attributes {
+		attributes = append(attributes, key+": "+value)
+	}
+	sort.Strings(attributes)
+	var content strings.Builder
+	err := opensearchYmlTemplate.Execute(&content, map[string]interface{}{
+		"ClusterName": clusterCfg.ClusterName,
+		"Name":        newNode.Name,
+		"Ip":          newNode.Ip,
+		"Roles":       strings.Join(newNode.Roles, ","),
+		"Attributes":  attributes,
+	})
+	if err != nil {
+		return "", err
+	}
+	securityConf, err := renderTemplate("security_conf.yml", templateVariables(clusterCfg))
+	if err != nil {
+		return "", err
+	}
+	securityConf = strings.Replace(securityConf, auditTypeSetting, "#"+auditTypeSetting, 1)
+	content.WriteString("## BEGIN " + securityCommonBlock + " ##\n" + strings.TrimRight(securityConf, "\n") + "\n## END " + securityCommonBlock + " ##\n")
+
+	// The settings of the certificates generation tool, with the HTTP TLS disabled and any node accepted
+	content.WriteString("## BEGIN " + securityCertsBlock + " ##\n")
+	for _, line := range []string{
+		"plugins.security.ssl.transport.pemcert_filepath: " + certName + ".pem",
+		"plugins.security.ssl.transport.pemkey_filepath: " + certName + ".key",
+		"plugins.security.ssl.transport.pemtrustedcas_filepath: root-ca.pem",
+		"plugins.security.ssl.transport.enforce_hostname_verification: false",
+		"plugins.security.ssl.transport.resolve_hostname: false",
+		"plugins.security.ssl.http.enabled: false",
+		"plugins.security.ssl.http.pemcert_filepath: " + certName + "_http.pem",
+		"plugins.security.ssl.http.pemkey_filepath: " + certName + "_http.key",
+		"plugins.security.ssl.http.pemtrustedcas_filepath: root-ca.pem",
+		"plugins.security.nodes_dn:",
+		"- " + distinguishedName("*", clusterCfg.DomainName),
+		"plugins.security.authcz.admin_dn:",
+		"- " + distinguishedName("admin", clusterCfg.DomainName),
+	} {
+		content.WriteString(line + "\n")
+	}
+	content.WriteString("## END " + securityCertsBlock + " ##\n")
+	return content.String(), nil
+}
+
+// Input:
+//
+//	memInfo (string): MemTotal line of /proc/meminfo, like "MemTotal:       16363284 kB"
+//	jvmFactor (float64): Share of the RAM given to the heap
+//
+// Description:
+//
+//	Computes the heap like the scale up playbook: the RAM in GB rounded, times the jvm_factor, at most 32 GB
+//
+// Return:
+//
+//	(int, error): Returns the heap in GB, at least 1, and error if the RAM can't be read
+func heapSize(memInfo string, jvmFactor float64) (int, error) {
+	fields := strings.Fields(memInfo)
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("unable to read the RAM from %q", memInfo)
+	}
+	ramKb, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, fmt.Errorf("unable to read the RAM from %q", memInfo)
+	}
+	heapGb := int(math.Round(float64(ramKb)/1000000) * jvmFactor)
+	if heapGb > maxHeapGb {
+		heapGb = maxHeapGb
+	}
+	if heapGb < 1 {
+		heapGb = 1
+	}
+	return heapGb, nil
+}
+
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Opensearch cluster details
+//
+// Description:
+//
+//	Sets the variables of the templates of the scale up role which come from the config
+//
+// Return:
+//
+//	(map[string]string): Returns the variables by name
+func templateVariables(clusterCfg config.ClusterDetails) map[string]string {
+	return map[string]string{
+		"os_home":                          clusterCfg.OpensearchHome,
+		"os_user":                          clusterCfg.SshUser,
+		"os_group":                         clusterCfg.OsGroup,
+		"domain_name":                      clusterCfg.DomainName,
+		"os_credentials.os_admin_username": clusterCfg.OsCredentials.OsAdminUsername,
+		"os_credentials.os_admin_password": clusterCfg.OsCredentials.OsAdminPassword,
+	}
+}
+
+// Input:
+//
+//	name (string): File name of a template of the scale up role
+//	variables (map[string]string): Values of the variables
+//
+// Description:
+//
+//	Renders a template of the scale up role which only uses variables, without the other jinja statements
+//
+// Return:
+//
+//	(string, error): Returns the rendered template and error if a variable has no value
+func renderTemplate(name string, variables map[string]string) (string, error) {
+	content, err := scaleUpTemplates.ReadFile("roles/scale_up/templates/" + name)
+	if err != nil {
+		return "", err
+	}
+	var missing []string
+	seen := make(map[string]bool)
+	rendered := templateVariable.ReplaceAllStringFunc(string(content), func(reference string) string {
+		variable := templateVariable.FindStringSubmatch(reference)[1]
+		value, ok := variables[variable]
+		if !ok && !seen[variable] {
+			seen[variable] = true
+			missing = append(missing, variable)
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("the template %s uses the unknown variables %s", name, strings.Join(missing, ", "))
+	}
+	return rendered, nil
+}
+
+// Input:
+//
+//	ctx (context.Context): Context cancelling the command
+//	host (remoteHost): Node of the cluster
+//	file (string): Path of the file
+//	line (string): Line to be present in the file
+//
+// Description:
+//
+//	Appends the line to the file if the file does not have it, like lineinfile of the playbooks
+//
+// Return:
+//
+//	(error): Returns error if any
+func ensureLine(ctx context.Context, host remoteHost, file string, line string) error {
+	_, err := host.Run(ctx, fmt.Sprintf("touch %s && (grep -qxF %s %s || echo %s >> %s)", shellQuote(file), shellQuote(line), shellQuote(file), shellQuote(line), shellQuote(file)))
+	return err
+}
+
+// Input:
+//
+//	ctx (context.Context): Context cancelling the wait
+//	ip (string): Ip of the node
+//	port (int): Port to be checked
+//	timeout (time.Duration): Time to wait for the port
+//
+// Description:
+//
+//	Waits until the port of the node accepts connections
+//
+// Return:
+//
+//	(error): Returns error if the port is not open in time
+func waitForPort(ctx context.Context, ip string, port int, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	address := net.JoinHostPort(ip, strconv.Itoa(port))
+	for {
+		conn, err := net.DialTimeout("tcp", address, 5*time.Second)
+		if err == nil {
+			return conn.Close()
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s is not reachable after %s: %w", address, timeout, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// Input:
+//
+//	host (string): Name of the node the step runs on
+//	step (string): Name of the step
+//	run (func() (string, error)): Runs the step, returning a message
+//
+// Description:
+//
+//	Runs a step and appends its result to the report, logging it
+//
+// Return:
+//
+//	(error): Returns a StepError if the step failed
+func (r *Report) run(host string, step string, run func() (string, error)) error {
+	start := time.Now()
+	message, err := run()
+	result := StepResult{Host: host, Step: step, Status: StepOk, Message: message, Duration: time.Since(start)}
+	var skipped *skippedStep
+	if errors.As(err, &skipped) {
+		result.Status, result.Message, err = StepSkipped, skipped.reason, nil
+	} else if err != nil {
+		result.Status, result.Message = StepFailed, err.Error()
+	}
+	*r = append(*r, result)
+	line := Report{result}.Lines()[0]
+	if err != nil {
+		log.Error.Println(line)
+		return &StepError{Result: result}
+	}
+	log.Info.Println(line)
+	return nil
+}
+
+// Input:
+//
+// Description:
+//
+//	Describes why the step is skipped
+//
+// Return:
+//
+//	(string): Returns the reason
+func (s *skippedStep) Error() string {
+	return s.reason
+}
@@ -0,0 +1,275 @@
+package ansibleutils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// A node which records the commands and files, failing the commands containing one of fail
+type fakeHost struct {
+	name      string
+	commands  []string
+	files     map[string]string
+	modes     map[string]os.FileMode
+	responses map[string]string
+	fail      []string
+	closed    bool
+}
+
+func newFakeHost(name string) *fakeHost {
+	return &fakeHost{name: name, files: map[string]string{}, modes: map[string]os.FileMode{}, responses: map[string]string{}}
+}
+
+func (h *fakeHost) Run(ctx context.Context, command string) (string, error) {
+	h.commands = append(h.commands, command)
+	for _, fail := range h.fail {
+		if strings.Contains(command, fail) {
+			return "", errors.New("exit status 1: " + fail + " failed")
+		}
+	}
+	for prefix, output := range h.responses {
+		if strings.HasPrefix(command, prefix) {
+			return output, nil
+		}
+	}
+	return "", nil
+}
+
+func (h *fakeHost) WriteFile(ctx context.Context, path string, content []byte, mode os.FileMode) error {
+	h.files[path] = string(content)
+	h.modes[path] = mode
+	return nil
+}
+
+func (h *fakeHost) Close() error {
+	h.closed = true
+	return nil
+}
+
+// Creates a root CA like the one of the certificates generation tool
+func testRootCa(t *testing.T) (*x509.Certificate, string, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "root.ca.example.com", OrganizationalUnit: []string{"CA"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+	return cert, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer}))
+}
+
+func testClusterCfg() config.ClusterDetails {
+	clusterCfg := config.ClusterDetails{
+		SshUser:           "ubuntu",
+		OsGroup:           "ubuntu",
+		OpensearchVersion: "2.3.0",
+		OpensearchHome:    "/usr/share/opensearch",
+		DomainName:        "example.com",
+		JvmFactor:         0.5,
+		OsCredentials:     config.OsCredentials{OsAdminUsername: "admin", OsAdminPassword: "admin-password"},
+	}
+	clusterCfg.ClusterName = "cluster.1"
+	return clusterCfg
+}
+
+func testInventory() Inventory {
+	return Inventory{
+		CurrentNodes: []Host{
+			{Name: "node-10-0-0-1", Ip: "10.0.0.1", Roles: []string{"master", "data"}},
+			{Name: "node-10-0-0-2", Ip: "10.0.0.2", Roles: []string{"master", "data"}},
+		},
+		NewNode: Host{Name: "node-10-0-0-3", Ip: "10.0.0.3", Roles: []string{"data", "ingest"}, Attributes: map[string]string{"temp": "hot"}},
+	}
+}
+
+// Creates a native node configurator on the fake nodes
+func testNativeConfigurator(t *testing.T, hosts map[string]*fakeHost) (nativeConfigurator, *x509.Certificate) {
+	caCert, caCertPem, caKeyPem := testRootCa(t)
+	master := newFakeHost("master")
+	master.responses["cat '/usr/share/opensearch/config/root-ca.pem'"] = caCertPem
+	master.responses["cat '/usr/share/opensearch/config/root-ca.key'"] = caKeyPem
+	master.responses["cat '/usr/share/opensearch/config/admin.pem'"] = "admin certificate"
+	master.responses["cat '/usr/share/opensearch/config/admin.key'"] = "admin key"
+	return nativeConfigurator{
+		dial: func(ctx context.Context, host Host, clusterCfg config.ClusterDetails, timeout time.Duration) (remoteHost, error) {
+			if fakeHost, ok := hosts[host.Ip]; ok {
+				return fakeHost, nil
+			}
+			return nil, errors.New("unable to connect to " + host.Ip + " over ssh")
+		},
+		master:       master,
+		waitForPort:  func(ctx context.Context, ip string, port int, timeout time.Duration) error { return nil },
+		now:          time.Now,
+		sshTimeout:   time.Second,
+		startTimeout: time.Second,
+	}, caCert
+}
+
+func TestNativeConfigureNewNode(t *testing.T) {
+	hosts := map[string]*fakeHost{"10.0.0.1": newFakeHost("node-10-0-0-1"), "10.0.0.2": newFakeHost("node-10-0-0-2"), "10.0.0.3": newFakeHost("node-10-0-0-3")}
+	newHost := hosts["10.0.0.3"]
+	newHost.responses["grep MemTotal"] = "MemTotal:       16363284 kB\n"
+	// opensearch is not installed yet
+	newHost.fail = []string{"test -x"}
+	configurator, caCert := testNativeConfigurator(t, hosts)
+
+	report, err := configurator.ConfigureNewNode(context.Background(), testInventory(), testClusterCfg())
+	assert.NoError(t, err)
+	assert.Nil(t, report.Failed())
+	var steps []string
+	for _, result := range report {
+		steps = append(steps, result.Host+" "+result.Step+" "+result.Status)
+	}
+	assert.Equal(t, []string{
+		"node-10-0-0-1 connect ok",
+		"node-10-0-0-1 etc_hosts ok",
+		"node-10-0-0-2 connect ok",
+		"node-10-0-0-2 etc_hosts ok",
+		"node-10-0-0-3 connect ok",
+		"node-10-0-0-3 tune ok",
+		"node-10-0-0-3 install ok",
+		"node-10-0-0-3 heap ok",
+		"node-10-0-0-3 security ok",
+		"node-10-0-0-3 opensearch_yml ok",
+		"node-10-0-0-3 systemd_unit ok",
+		"node-10-0-0-3 unicast_hosts ok",
+		"node-10-0-0-3 etc_hosts ok",
+		"node-10-0-0-3 ownership ok",
+		"node-10-0-0-1 unicast_hosts ok",
+		"node-10-0-0-2 unicast_hosts ok",
+		"node-10-0-0-3 start_opensearch ok",
+		"node-10-0-0-3 wait_for_opensearch ok",
+	}, steps)
+	assert.Equal(t, "installed opensearch 2.3.0", report[6].Message)
+	assert.Equal(t, "heap set to 8g", report[7].Message)
+
+	opensearchYml := newHost.files["/usr/share/opensearch/config/opensearch.yml"]
+	assert.Contains(t, opensearchYml, "cluster.name: \"cluster.1\"\n\nnode.name: \"node-10-0-0-3\"\n")
+	assert.Contains(t, opensearchYml, "network.publish_host: 10.0.0.3\n")
+	assert.Contains(t, opensearchYml, "node.roles: [data,ingest]\nnode.attr.temp: hot\nscript.painless.regex.enabled: true\n")
+	assert.Contains(t, opensearchYml, "#plugins.security.audit.type: internal_opensearch\n")
+	assert.Contains(t, opensearchYml, "plugins.security.ssl.transport.pemcert_filepath: node-10-0-0-3.pem\n")
+	assert.Contains(t, opensearchYml, "plugins.security.ssl.http.enabled: false\n")
+	assert.Contains(t, opensearchYml, "plugins.security.nodes_dn:\n- CN=*.example.com,OU=Ops,O=example.com\\, Inc.,DC=example.com\n")
+	assert.Equal(t, os.FileMode(0600), newHost.modes["/usr/share/opensearch/config/opensearch.yml"])
+	assert.Contains(t, newHost.files["/usr/share/opensearch/config/jvm.options"], "\n-Xms8g\n-Xmx8g\n")
+	assert.Contains(t, newHost.files["/etc/systemd/system/opensearch.service"], "ExecStart=/usr/share/opensearch/bin/opensearch -p /usr/share/opensearch/opensearch.pid -q\n")
+	assert.Equal(t, "10.0.0.1\n10.0.0.2\n10.0.0.3\n", newHost.files["/usr/share/opensearch/config/unicast_hosts.txt"])
+	assert.Equal(t, "admin certificate", newHost.files["/usr/share/opensearch/config/admin.pem"])
+
+	internalUsers := newHost.files["/usr/share/opensearch/plugins/opensearch-security/securityconfig/internal_users.yml"]
+	assert.Contains(t, internalUsers, "\nadmin:\n  hash: \"$2a$12$")
+	hash := strings.Split(strings.Split(internalUsers, "hash: \"")[1], "\"")[0]
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte("admin-password")))
+
+	for _, name := range []string{"node-10-0-0-3", "node-10-0-0-3_http"} {
+		block, _ := pem.Decode([]byte(newHost.files["/usr/share/opensearch/config/"+name+".pem"]))
+		assert.NotNil(t, block)
+		cert, err := x509.ParseCertificate(block.Bytes)
+		assert.NoError(t, err)
+		assert.NoError(t, cert.CheckSignatureFrom(caCert))
+		assert.Equal(t, "node-10-0-0-3.example.com", cert.Subject.CommonName)
+		assert.Equal(t, []string{"node-10-0-0-3.example.com"}, cert.DNSNames)
+		assert.Equal(t, "10.0.0.3", cert.IPAddresses[0].String())
+		// The distinguished name is CN=...,OU=Ops,O=...,DC=..., encoded from the last RDN
+		var subject pkix.RDNSequence
+		_, err = asn1.Unmarshal(cert.RawSubject, &subject)
+		assert.NoError(t, err)
+		assert.Equal(t, domainComponentOid, subject[0][0].Type)
+		assert.Equal(t, "example.com, Inc.", subject[1][0].Value)
+		assert.Equal(t, "Ops", subject[2][0].Value)
+		keyBlock, _ := pem.Decode([]byte(newHost.files["/usr/share/opensearch/config/"+name+".key"]))
+		assert.Equal(t, "PRIVATE KEY", keyBlock.Type)
+	}
+
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2"} {
+		commands := strings.Join(hosts[ip].commands, "\n")
+		assert.Contains(t, commands, "grep -qxF '10.0.0.3 node-10-0-0-3.example.com node-10-0-0-3' '/etc/hosts'")
+		assert.Contains(t, commands, "grep -qxF '10.0.0.3' '/usr/share/opensearch/config/unicast_hosts.txt'")
+		assert.True(t, hosts[ip].closed)
+	}
+	assert.True(t, newHost.closed)
+}
+
+func TestNativeConfigureNewNodeFailedStep(t *testing.T) {
+	hosts := map[string]*fakeHost{"10.0.0.1": newFakeHost("node-10-0-0-1"), "10.0.0.2": newFakeHost("node-10-0-0-2"), "10.0.0.3": newFakeHost("node-10-0-0-3")}
+	hosts["10.0.0.3"].fail = []string{"sysctl"}
+	configurator, _ := testNativeConfigurator(t, hosts)
+
+	report, err := configurator.ConfigureNewNode(context.Background(), testInventory(), testClusterCfg())
+	var stepErr *StepError
+	assert.True(t, errors.As(err, &stepErr))
+	assert.Equal(t, "step tune failed on node-10-0-0-3: exit status 1: sysctl failed", err.Error())
+	assert.Equal(t, &report[len(report)-1], report.Failed())
+	assert.Equal(t, "tune", report.Failed().Step)
+	assert.Len(t, hosts["10.0.0.3"].commands, 1)
+	assert.True(t, hosts["10.0.0.1"].closed)
+	assert.True(t, hosts["10.0.0.3"].closed)
+
+	// A current node which can't be reached stops the configuration before the new node is touched
+	delete(hosts, "10.0.0.2")
+	hosts["10.0.0.3"].commands = nil
+	report, err = configurator.ConfigureNewNode(context.Background(), testInventory(), testClusterCfg())
+	assert.EqualError(t, err, "step connect failed on node-10-0-0-2: unable to connect to 10.0.0.2 over ssh")
+	assert.Len(t, report, 3)
+	assert.Empty(t, hosts["10.0.0.3"].commands)
+}
+
+func TestNativeInstallSkipped(t *testing.T) {
+	host := newFakeHost("node-10-0-0-3")
+	message, err := nativeConfigurator{}.install(context.Background(), host, testClusterCfg())
+	assert.Equal(t, "", message)
+	var report Report
+	assert.NoError(t, report.run("node-10-0-0-3", "install", func() (string, error) { return message, err }))
+	assert.Equal(t, StepSkipped, report[0].Status)
+	assert.Equal(t, "opensearch is already installed in /usr/share/opensearch", report[0].Message)
+	assert.Equal(t, []string{"test -x '/usr/share/opensearch'/bin/opensearch"}, host.commands)
+}
+
+func TestHeapSize(t *testing.T) {
+	heap, err := heapSize("MemTotal:       16363284 kB", 0.5)
+	assert.NoError(t, err)
+	assert.Equal(t, 8, heap)
+	heap, err = heapSize("MemTotal:      131000000 kB", 0.5)
+	assert.NoError(t, err)
+	assert.Equal(t, 32, heap)
+	heap, err = heapSize("MemTotal:         900000 kB", 0.5)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, heap)
+	_, err = heapSize("MemTotal:", 0.5)
+	assert.EqualError(t, err, "unable to read the RAM from \"MemTotal:\"")
+}
+
+func TestRenderTemplate(t *testing.T) {
+	_, err := renderTemplate("opensearch.service", map[string]string{"os_home": "/usr/share/opensearch"})
+	assert.EqualError(t, err, "the template opensearch.service uses the unknown variables os_user, os_group")
+	jvmOptions, err := renderTemplate("jvm.options", map[string]string{"xms_value": "4", "xmx_value": "4"})
+	assert.NoError(t, err)
+	assert.Contains(t, jvmOptions, "-Xms4g\n-Xmx4g\n")
+	assert.Contains(t, jvmOptions, "-Djava.io.tmpdir=${OPENSEARCH_TMPDIR}\n")
+}
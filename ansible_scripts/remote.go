@@ -0,0 +1,281 @@
+package ansibleutils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Number of lines of the output of a failed command kept in its error
+const errorOutputLines = 20
+
+// A host the native node configurator runs commands on, over SSH or locally on the master. The commands run
+// as root with sudo, like the become tasks of the playbooks.
+type remoteHost interface {
+	// Run runs the shell command, returning its combined output.
+	Run(ctx context.Context, command string) (string, error)
+	// WriteFile writes the content to the file with the mode.
+	WriteFile(ctx context.Context, path string, content []byte, mode os.FileMode) error
+	// Close closes the connection to the host.
+	Close() error
+}
+
+// A host reached over SSH
+type sshHost struct {
+	client *ssh.Client
+}
+
+// The node running the scaling manager
+type localHost struct{}
+
+// Input:
+//
+//	ctx (context.Context): Context cancelling the dial
+//	ip (string): Ip of the host
+//	user (string): SSH user of the host
+//	pemFilePath (string): Path of the private key of the SSH user
+//	timeout (time.Duration): Time to wait for the SSH server to be up, like on a node which is booting
+//
+// Description:
+//
+//	Connects to the SSH server of the host, retrying until it accepts the connection. The host keys are not
+//	checked, like with the host_key_checking setting of the ansible config, as the new nodes are not known yet.
+//
+// Return:
+//
+//	(*sshHost, error): Returns the connected host and error if it can't be reached in time
+func dialSsh(ctx context.Context, ip string, user string, pemFilePath string, timeout time.Duration) (*sshHost, error) {
+	key, err := os.ReadFile(pemFilePath)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the private key %s: %w", pemFilePath, err)
+	}
+	clientConfig := &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         30 * time.Second,
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		client, err := ssh.Dial("tcp", net.JoinHostPort(ip, "22"), clientConfig)
+		if err == nil {
+			return &sshHost{client: client}, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("unable to connect to %s over ssh: %w", ip, err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(10 * time.Second):
+		}
+	}
+}
+
+// Input:
+//
+//	ctx (context.Context): Context cancelling the command
+//	command (string): Shell command
+//
+// Description:
+//
+//	Runs the command as root in a new session
+//
+// Return:
+//
+//	(string, error): Returns the output and error if the command failed
+func (h *sshHost) Run(ctx context.Context, command string) (string, error) {
+	return h.run(ctx, sudoCommand(command), nil)
+}
+
+// Input:
+//
+//	ctx (context.Context): Context cancelling the write
+//	path (string): Path of the file
+//	content ([]byte): Content of the file
+//	mode (os.FileMode): Permissions of the file
+//
+// Description:
+//
+//	Writes the file as root, passing the content on the standard input
+//
+// Return:
+//
+//	(error): Returns error if the file can't be written
+func (h *sshHost) WriteFile(ctx context.Context, path string, content []byte, mode os.FileMode) error {
+	command := sudoCommand(fmt.Sprintf("cat > %s && chmod %04o %s", shellQuote(path), mode.Perm(), shellQuote(path)))
+	_, err := h.run(ctx, command, content)
+	return err
+}
+
+// Input:
+//
+// Description:
+//
+//	Closes the SSH connection
+//
+// Return:
+//
+//	(error): Returns error if any
+func (h *sshHost) Close() error {
+	return h.client.Close()
+}
+
+// Input:
+//
+//	ctx (context.Context): Context cancelling the command
+//	command (string): Command to be run
+//	stdin ([]byte): Standard input of the command, none if nil
+//
+// Description:
+//
+//	Runs the command in a new session, closing the session when the context is done
+//
+// Return:
+//
+//	(string, error): Returns the output and error with the last lines of the output if the command failed
+func (h *sshHost) run(ctx context.Context, command string, stdin []byte) (string, error) {
+	session, err := h.client.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+	var output bytes.Buffer
+	session.Stdout = &output
+	session.Stderr = &output
+	if stdin != nil {
+		session.Stdin = bytes.NewReader(stdin)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Run(command)
+	}()
+	select {
+	case <-ctx.Done():
+		session.Close()
+		return output.String(), ctx.Err()
+	case err = <-done:
+	}
+	if err != nil {
+		return output.String(), fmt.Errorf("%w: %s", err, lastLines(output.String(), errorOutputLines))
+	}
+	return output.String(), nil
+}
+
+// Input:
+//
+//	ctx (context.Context): Context cancelling the command
+//	command (string): Shell command
+//
+// Description:
+//
+//	Runs the command as root on the node running the scaling manager
+//
+// Return:
+//
+//	(string, error): Returns the output and error if the command failed
+func (localHost) Run(ctx context.Context, command string) (string, error) {
+	output, err := exec.CommandContext(ctx, "sh", "-c", sudoCommand(command)).CombinedOutput()
+	if err != nil {
+		return string(output), fmt.Errorf("%w: %s", err, lastLines(string(output), errorOutputLines))
+	}
+	return string(output), nil
+}
+
+// Input:
+//
+//	ctx (context.Context): Context cancelling the write
+//	path (string): Path of the file
+//	content ([]byte): Content of the file
+//	mode (os.FileMode): Permissions of the file
+//
+// Description:
+//
+//	Writes the file as root on the node running the scaling manager
+//
+// Return:
+//
+//	(error): Returns error if the file can't be written
+func (localHost) WriteFile(ctx context.Context, path string, content []byte, mode os.FileMode) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", sudoCommand(fmt.Sprintf("cat > %s && chmod %04o %s", shellQuote(path), mode.Perm(), shellQuote(path))))
+	cmd.Stdin = bytes.NewReader(content)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, lastLines(string(output), errorOutputLines))
+	}
+	return nil
+}
+
+// Input:
+//
+// Description:
+//
+//	Nothing to close for the local host
+//
+// Return:
+//
+//	(error): Returns nil
+func (localHost) Close() error {
+	return nil
+}
+
+// Input:
+//
+//	command (string): Shell command
+//
+// Description:
+//
+//	Wraps the command to run it as root with sudo, failing instead of asking for a password
+//
+// Return:
+//
+//	(string): Returns the wrapped command
+func sudoCommand(command string) string {
+	return "sudo -n sh -c " + shellQuote(command)
+}
+
+// Input:
+//
+//	value (string): Value to be passed to a shell
+//
+// Description:
+//
+//	Quotes the value in single quotes for a POSIX shell
+//
+// Return:
+//
+//	(string): Returns the quoted value
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'"'"'`) + "'"
+}
+
+// Input:
+//
+//	output (string): Output of a command
+//	count (int): Number of lines to keep
+//
+// Description:
+//
+//	Keeps the last lines of the output, which usually hold the error
+//
+// Return:
+//
+//	(string): Returns the last lines joined by "; "
+func lastLines(output string, count int) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) > count {
+		lines = lines[len(lines)-count:]
+	}
+	return strings.Join(lines, "; ")
+}
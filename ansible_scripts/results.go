@@ -0,0 +1,430 @@
+package ansibleutils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apenella/go-ansible/pkg/stdoutcallback/results"
+)
+
+// Status of a task of a playbook on a host
+const (
+	TaskOk          = "ok"
+	TaskChanged     = "changed"
+	TaskSkipped     = "skipped"
+	TaskIgnored     = "ignored"
+	TaskFailed      = "failed"
+	TaskUnreachable = "unreachable"
+)
+
+// Events of the task_progress callback plugin
+const (
+	ProgressTaskStart   = "task_start"
+	ProgressFailed      = "failed"
+	ProgressUnreachable = "unreachable"
+)
+
+// Environment variable of the file the task_progress callback plugin writes the progress to
+const progressFileEnv = "SCALING_MANAGER_PROGRESS_FILE"
+
+// Interval of the reads of the progress file
+var progressInterval = 2 * time.Second
+
+// This struct contains the result of a task of a playbook on a host.
+type TaskResult struct {
+	// Play indicates the name of the play of the task.
+	Play string `json:"play"`
+	// Task indicates the name of the task.
+	Task string `json:"task"`
+	// Host indicates the inventory name of the host.
+	Host string `json:"host"`
+	// Status indicates if the task was ok, changed, skipped, ignored, failed or unreachable.
+	Status string `json:"status"`
+	// Message indicates the message of the task, or its error output if it failed.
+	Message string `json:"message,omitempty"`
+	// Duration indicates how long the task took on all the hosts.
+	Duration time.Duration `json:"duration"`
+}
+
+// This struct contains the counts of the tasks of a playbook on a host.
+type HostStats struct {
+	Ok          int `json:"ok"`
+	Changed     int `json:"changed"`
+	Skipped     int `json:"skipped"`
+	Ignored     int `json:"ignored"`
+	Failures    int `json:"failures"`
+	Unreachable int `json:"unreachable"`
+}
+
+// This struct contains the result of a run of a playbook, parsed from the output of the json stdout callback.
+type PlaybookResult struct {
+	// Playbook indicates the file name of the playbook.
+	Playbook string `json:"playbook"`
+	// Tags indicates the tags the playbook ran with.
+	Tags []string `json:"tags,omitempty"`
+	// Tasks indicates the results of the tasks by host, in the order they ran.
+	Tasks []TaskResult `json:"tasks"`
+	// Stats indicates the counts of the tasks by host.
+	Stats map[string]HostStats `json:"stats"`
+	// Duration indicates how long the playbook ran.
+	Duration time.Duration `json:"duration"`
+}
+
+// This struct contains the summary of a run of a playbook added to the ProvisionStats document.
+type PlaybookSummary struct {
+	Playbook       string
+	Tags           string `json:",omitempty"`
+	Status         string
+	TimeTaken      string
+	Hosts          int
+	Ok             int
+	Changed        int
+	Skipped        int
+	Failures       int
+	Unreachable    int
+	FailedHost     string `json:",omitempty"`
+	FailedTask     string `json:",omitempty"`
+	FailureMessage string `json:",omitempty"`
+}
+
+// This struct contains an event of the task_progress callback plugin while a playbook runs.
+type TaskProgress struct {
+	// Event indicates if the task started, failed or the host was unreachable.
+	Event string `json:"event"`
+	// Task indicates the name of the task.
+	Task string `json:"task"`
+	// Host indicates the host the task failed on, empty when the task starts.
+	Host string `json:"host,omitempty"`
+	// Message indicates the error of the failed task.
+	Message string `json:"msg,omitempty"`
+}
+
+// Hooks called for the playbooks run with a context
+type PlaybookHooks struct {
+	// Progress is called when a task starts, or fails on a host.
+	Progress func(TaskProgress)
+	// Done is called with the result of every playbook which ran, even if it failed.
+	Done func(*PlaybookResult)
+}
+
+// Key of the playbook hooks in a context
+type playbookHooksKey struct{}
+
+// Input:
+//
+//	ctx (context.Context): Parent context
+//	hooks (PlaybookHooks): Hooks called for the playbooks run with the context
+//
+// Description:
+//
+//	Adds the hooks reporting the progress and the results of the playbooks to the context
+//
+// Return:
+//
+//	(context.Context): Returns the context with the hooks
+func WithPlaybookHooks(ctx context.Context, hooks PlaybookHooks) context.Context {
+	return context.WithValue(ctx, playbookHooksKey{}, hooks)
+}
+
+// Input:
+//
+//	ctx (context.Context): Context of a playbook run
+//
+// Description:
+//
+//	Reads the hooks of the context
+//
+// Return:
+//
+//	(PlaybookHooks): Returns the hooks, which are nil if the context has none
+func playbookHooks(ctx context.Context) PlaybookHooks {
+	hooks, _ := ctx.Value(playbookHooksKey{}).(PlaybookHooks)
+	return hooks
+}
+
+// Input:
+//
+//	playbook (string): File name of the playbook
+//	tags ([]string): Tags the playbook ran with
+//	output ([]byte): Output of the json stdout callback
+//
+// Description:
+//
+//	Parses the results of the tasks by host from the output of the json stdout callback. The callback marks the
+//	ignored failures as failed, so only the last failed task of a host with failures in its stats is failed.
+//
+// Return:
+//
+//	(*PlaybookResult, error): Returns the result of the playbook and error if the output can't be parsed
+func parsePlaybookResult(playbook string, tags []string, output []byte) (*PlaybookResult, error) {
+	parsed, err := results.ParseJSONResultsStream(bytes.NewReader(output))
+	if err != nil {
+		return nil, err
+	}
+	result := &PlaybookResult{Playbook: playbook, Tags: tags, Stats: make(map[string]HostStats)}
+	for host, stats := range parsed.Stats {
+		result.Stats[host] = HostStats{
+			Ok:          stats.Ok,
+			Changed:     stats.Changed,
+			Skipped:     stats.Skipped,
+			Ignored:     stats.Ignored,
+			Failures:    stats.Failures,
+			Unreachable: stats.Unreachable,
+		}
+	}
+	lastFailure := make(map[string]int)
+	for _, play := range parsed.Plays {
+		playName := ""
+		if play.Play != nil {
+			playName = play.Play.Name
+		}
+		for _, task := range play.Tasks {
+			if task.Task == nil {
+				continue
+			}
+			var duration time.Duration
+			if task.Task.Duration != nil {
+				start, startErr := time.Parse(time.RFC3339Nano, task.Task.Duration.Start)
+				end, endErr := time.Parse(time.RFC3339Nano, task.Task.Duration.End)
+				if startErr == nil && endErr == nil {
+					duration = end.Sub(start)
+				}
+			}
+			var hosts []string
+			for host := range task.Hosts {
+				hosts = append(hosts, host)
+			}
+			sort.Strings(hosts)
+			for _, host := range hosts {
+				hostResult := task.Hosts[host]
+				taskResult := TaskResult{Play: playName, Task: task.Task.Name, Host: host, Status: TaskOk, Duration: duration}
+				switch {
+				case hostResult.Unreachable:
+					taskResult.Status = TaskUnreachable
+				case hostResult.Failed:
+					taskResult.Status = TaskIgnored
+				case hostResult.Skipped:
+					taskResult.Status = TaskSkipped
+				case hostResult.Changed:
+					taskResult.Status = TaskChanged
+				}
+				taskResult.Message = taskMessage(hostResult, taskResult.Status)
+				if taskResult.Status == TaskIgnored || taskResult.Status == TaskUnreachable {
+					lastFailure[host] = len(result.Tasks)
+				}
+				result.Tasks = append(result.Tasks, taskResult)
+			}
+		}
+	}
+	for host, index := range lastFailure {
+		if result.Tasks[index].Status == TaskIgnored && result.Stats[host].Failures > 0 {
+			result.Tasks[index].Status = TaskFailed
+		}
+	}
+	return result, nil
+}
+
+// Input:
+//
+//	hostResult (*results.AnsiblePlaybookJSONResultsPlayTaskHostsItem): Result of the task on a host
+//	status (string): Status of the task
+//
+// Description:
+//
+//	Formats the message of the task, with the error output of the failed tasks, masking the credentials
+//
+// Return:
+//
+//	(string): Returns the message
+func taskMessage(hostResult *results.AnsiblePlaybookJSONResultsPlayTaskHostsItem, status string) string {
+	var parts []string
+	for _, value := range []interface{}{hostResult.Msg, hostResult.SkipReason} {
+		if text := valueText(value); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	if status == TaskIgnored || status == TaskFailed || status == TaskUnreachable {
+		if text := valueText(hostResult.Stderr); text != "" {
+			parts = append(parts, lastLines(text, errorOutputLines))
+		}
+	}
+	return maskCredentialsString(strings.Join(parts, ": "))
+}
+
+// Input:
+//
+//	value (interface{}): Value of a field of a task result, like msg which is a string or a list
+//
+// Description:
+//
+//	Formats the value as text
+//
+// Return:
+//
+//	(string): Returns the text, empty for nil or empty values
+func valueText(value interface{}) string {
+	switch typed := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(typed)
+	default:
+		content, err := json.Marshal(typed)
+		if err != nil {
+			return fmt.Sprint(typed)
+		}
+		return string(content)
+	}
+}
+
+// Input:
+//
+// Description:
+//
+//	Finds the task which failed the playbook
+//
+// Return:
+//
+//	(*TaskResult): Returns the first failed or unreachable task, nil if the playbook did not fail
+func (r *PlaybookResult) Failed() *TaskResult {
+	for i := range r.Tasks {
+		if r.Tasks[i].Status == TaskFailed || r.Tasks[i].Status == TaskUnreachable {
+			return &r.Tasks[i]
+		}
+	}
+	return nil
+}
+
+// Input:
+//
+// Description:
+//
+//	Summarizes the playbook with the counts of its tasks over all the hosts and the task which failed it
+//
+// Return:
+//
+//	(PlaybookSummary): Returns the summary
+func (r *PlaybookResult) Summary() PlaybookSummary {
+	summary := PlaybookSummary{
+		Playbook:  r.Playbook,
+		Tags:      strings.Join(r.Tags, ","),
+		Status:    TaskOk,
+		TimeTaken: fmt.Sprint(r.Duration.Round(time.Second)),
+		Hosts:     len(r.Stats),
+	}
+	for _, stats := range r.Stats {
+		summary.Ok += stats.Ok
+		summary.Changed += stats.Changed
+		summary.Skipped += stats.Skipped
+		summary.Failures += stats.Failures
+		summary.Unreachable += stats.Unreachable
+	}
+	if failed := r.Failed(); failed != nil {
+		summary.Status = failed.Status
+		summary.FailedHost, summary.FailedTask, summary.FailureMessage = failed.Host, failed.Task, failed.Message
+	}
+	return summary
+}
+
+// Input:
+//
+// Description:
+//
+//	Converts the results of the tasks to the steps of a node configurator report, the skipped tasks are left out
+//
+// Return:
+//
+//	(Report): Returns the report
+func (r *PlaybookResult) Report() Report {
+	var report Report
+	for _, task := range r.Tasks {
+		status := StepOk
+		switch task.Status {
+		case TaskSkipped:
+			continue
+		case TaskFailed, TaskUnreachable:
+			status = StepFailed
+		}
+		report = append(report, StepResult{Host: task.Host, Step: task.Task, Status: status, Message: task.Message, Duration: task.Duration})
+	}
+	return report
+}
+
+// Input:
+//
+//	ctx (context.Context): Context of the playbook run
+//	fileName (string): File the task_progress callback plugin writes to
+//	progress (func(TaskProgress)): Called for every event of the file
+//
+// Description:
+//
+//	Reads the events written to the progress file while the playbook runs
+//
+// Return:
+//
+//	(func()): Returns the function stopping the reads, after reading the last events
+func watchProgress(ctx context.Context, fileName string, progress func(TaskProgress)) func() {
+	if progress == nil {
+		return func() {}
+	}
+	var offset int64
+	read := func() {
+		file, err := os.Open(fileName)
+		if err != nil {
+			return
+		}
+		defer file.Close()
+		_, err = file.Seek(offset, io.SeekStart)
+		if err != nil {
+			return
+		}
+		reader := bufio.NewReader(file)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err != nil {
+				// A partial line is read again with the next events
+				return
+			}
+			offset += int64(len(line))
+			var event TaskProgress
+			if json.Unmarshal(line, &event) == nil {
+				event.Message = maskCredentialsString(event.Message)
+				progress(event)
+			}
+		}
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				read()
+				return
+			case <-ctx.Done():
+				read()
+				return
+			case <-ticker.C:
+				read()
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
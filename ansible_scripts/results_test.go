@@ -0,0 +1,140 @@
+package ansibleutils
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testPlaybookResult(t *testing.T) *PlaybookResult {
+	output, err := os.ReadFile("testdata/scale_up_results.json")
+	assert.NoError(t, err)
+	result, err := parsePlaybookResult("scaleUpPlaybook.yml", []string{"scale_up"}, output)
+	assert.NoError(t, err)
+	return result
+}
+
+func TestParsePlaybookResult(t *testing.T) {
+	result := testPlaybookResult(t)
+	assert.Equal(t, "scaleUpPlaybook.yml", result.Playbook)
+	assert.Equal(t, map[string]HostStats{
+		"node-1": {Ok: 2, Skipped: 1},
+		"node-2": {Ok: 2, Changed: 1, Ignored: 1, Failures: 1},
+	}, result.Stats)
+
+	var statuses []string
+	for _, task := range result.Tasks {
+		assert.Equal(t, "Scale up the opensearch cluster", task.Play)
+		statuses = append(statuses, task.Host+" "+task.Task+": "+task.Status)
+	}
+	assert.Equal(t, []string{
+		"node-1 Gathering Facts: ok",
+		"node-2 Gathering Facts: ok",
+		"node-1 Check if opensearch is running: ok",
+		"node-2 Check if opensearch is running: ignored",
+		"node-1 Configure opensearch.yml: skipped",
+		"node-2 Configure opensearch.yml: changed",
+		"node-2 Wait for the node to join the cluster: failed",
+	}, statuses)
+	assert.Equal(t, 3*time.Second, result.Tasks[0].Duration)
+	assert.Equal(t, "non-zero return code", result.Tasks[3].Message)
+	assert.Equal(t, "Conditional result was False", result.Tasks[4].Message)
+
+	failed := result.Failed()
+	assert.Equal(t, "node-2", failed.Host)
+	assert.Equal(t, "Wait for the node to join the cluster", failed.Task)
+	assert.Equal(t, `Status code was -1 and not [200]: Request failed for {credentials":{*********}}: line 1; line 2`, failed.Message)
+
+	_, err := parsePlaybookResult("scaleUpPlaybook.yml", nil, []byte("PLAY RECAP"))
+	assert.Error(t, err)
+}
+
+func TestParsePlaybookResultUnreachable(t *testing.T) {
+	output := `{"plays": [{"play": {"name": "Scale down"}, "tasks": [{"task": {"name": "Gathering Facts"},
+		"hosts": {"node-3": {"unreachable": true, "msg": "Failed to connect to the host via ssh"}}}]}],
+		"stats": {"node-3": {"unreachable": 1}}}`
+	result, err := parsePlaybookResult("scaleDownPlaybook.yml", nil, []byte(output))
+	assert.NoError(t, err)
+	assert.Equal(t, &TaskResult{Play: "Scale down", Task: "Gathering Facts", Host: "node-3", Status: TaskUnreachable, Message: "Failed to connect to the host via ssh"}, result.Failed())
+	assert.Equal(t, TaskUnreachable, result.Summary().Status)
+}
+
+func TestPlaybookSummary(t *testing.T) {
+	result := testPlaybookResult(t)
+	result.Duration = 2*time.Minute + 39400*time.Millisecond
+	summary := result.Summary()
+	assert.Equal(t, "scaleUpPlaybook.yml", summary.Playbook)
+	assert.Equal(t, "scale_up", summary.Tags)
+	assert.Equal(t, TaskFailed, summary.Status)
+	assert.Equal(t, "2m39s", summary.TimeTaken)
+	assert.Equal(t, 2, summary.Hosts)
+	assert.Equal(t, 4, summary.Ok)
+	assert.Equal(t, 1, summary.Changed)
+	assert.Equal(t, 1, summary.Skipped)
+	assert.Equal(t, 1, summary.Failures)
+	assert.Equal(t, 0, summary.Unreachable)
+	assert.Equal(t, "node-2", summary.FailedHost)
+	assert.Equal(t, "Wait for the node to join the cluster", summary.FailedTask)
+
+	result.Tasks = result.Tasks[:6]
+	summary = result.Summary()
+	assert.Equal(t, TaskOk, summary.Status)
+	assert.Empty(t, summary.FailedTask)
+}
+
+func TestPlaybookReport(t *testing.T) {
+	report := testPlaybookResult(t).Report()
+	assert.Len(t, report, 6)
+	assert.Equal(t, StepOk, report[3].Status)
+	assert.Equal(t, "Configure opensearch.yml", report[4].Step)
+	assert.Equal(t, "Wait for the node to join the cluster", report.Failed().Step)
+	assert.Equal(t, 2*time.Minute+33282263*time.Microsecond, report.Failed().Duration)
+}
+
+func TestWatchProgress(t *testing.T) {
+	interval := progressInterval
+	progressInterval = 10 * time.Millisecond
+	defer func() { progressInterval = interval }()
+
+	fileName := filepath.Join(t.TempDir(), "progress.json")
+	var mutex sync.Mutex
+	var events []TaskProgress
+	stop := watchProgress(context.Background(), fileName, func(event TaskProgress) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		events = append(events, event)
+	})
+
+	file, err := os.Create(fileName)
+	assert.NoError(t, err)
+	defer file.Close()
+	_, err = file.WriteString(`{"event": "task_start", "task": "Gathering Facts"}` + "\n")
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(events) == 1
+	}, time.Second, progressInterval)
+
+	_, err = file.WriteString(`{"event": "failed", "task": "Start opensearch", "host": "node-2", "msg": "{\"credentials\":{\"os_admin_password\":\"secret\"}}"}` + "\n" + `{"event": "task_start"`)
+	assert.NoError(t, err)
+	stop()
+	assert.Len(t, events, 2)
+	assert.Equal(t, TaskProgress{Event: ProgressTaskStart, Task: "Gathering Facts"}, events[0])
+	assert.Equal(t, ProgressFailed, events[1].Event)
+	assert.Equal(t, "node-2", events[1].Host)
+	assert.NotContains(t, events[1].Message, "secret")
+}
+
+func TestPlaybookHooks(t *testing.T) {
+	assert.Nil(t, playbookHooks(context.Background()).Progress)
+	var done *PlaybookResult
+	ctx := WithPlaybookHooks(context.Background(), PlaybookHooks{Done: func(result *PlaybookResult) { done = result }})
+	playbookHooks(ctx).Done(&PlaybookResult{Playbook: "scaleUpPlaybook.yml"})
+	assert.Equal(t, "scaleUpPlaybook.yml", done.Playbook)
+}
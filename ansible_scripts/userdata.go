@@ -0,0 +1,274 @@
+package ansibleutils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+)
+
+// Limit of the size of the user data of an EC2 instance, before it is base64 encoded
+const maxUserDataSize = 16 * 1024
+
+// Log file of the bootstrap script on the new node
+const BootstrapLogFile = "/var/log/opensearch-bootstrap.log"
+
+// Delimiter of the here documents writing the files in the bootstrap script
+const userDataDelimiter = "SCALING_MANAGER_EOF"
+
+// Placeholders of the values only known on the new node, replaced by the bootstrap script
+const (
+	nodeNamePlaceholder = "__NODE_NAME__"
+	nodeIpPlaceholder   = "__NODE_IP__"
+	heapPlaceholder     = "__HEAP_GB__"
+)
+
+// Certificates of the cluster read on this node to sign the certificates of the new node. Only the root CA
+// certificate is put in the user data, the root CA key never leaves this node.
+var caSigningFiles = []string{"root-ca.pem", "root-ca.key"}
+
+// UserData contains the bootstrap script of a new node and the secrets it fetches when it boots
+type UserData struct {
+	// Script indicates the gzip compressed bootstrap script.
+	Script []byte
+	// Secrets indicates the private keys and the internal users of the new node by parameter name. They are
+	// stored in the SSM Parameter Store before the node is launched, as the user data can be read by anyone with
+	// access to the instance metadata or the instance attributes.
+	Secrets map[string]string
+}
+
+// Bootstrap script of the new node, run by cloud-init on its first boot
+var userDataTemplate = template.Must(template.New("user-data").Parse(`#!/bin/bash
+# Bootstraps a node of the opensearch cluster {{ .ClusterName }}, rendered by the scaling manager
+set -euo pipefail
+exec >> {{ .LogFile }} 2>&1
+
+OS_HOME={{ .Home }}
+NODE_IP=$(hostname -I | awk '{print $1}')
+NODE_NAME="node-${NODE_IP//./-}"
+echo "$(date) bootstrapping $NODE_NAME"
+
+sysctl -w vm.max_map_count=262144 fs.file-max=65536
+printf 'vm.max_map_count = 262144\nfs.file-max = 65536\n' > /etc/sysctl.d/99-opensearch.conf
+
+if ! command -v aws >/dev/null 2>&1; then
+  echo "$(date) the AWS CLI is required to fetch the secrets of the node"
+  exit 1
+fi
+
+id -u {{ .User }} >/dev/null 2>&1 || useradd -m -s /bin/bash {{ .User }}
+if [ ! -x "$OS_HOME/bin/opensearch" ]; then
+  curl -fsSL -o /tmp/opensearch.tar.gz {{ .DownloadUrl }}
+  mkdir -p "$OS_HOME"
+  tar -xzf /tmp/opensearch.tar.gz -C "$OS_HOME" --strip-components=1
+fi
+{{ range .Files }}{{ if .Parameter }}
+(umask 077; aws ssm get-parameter --region {{ $.Region }} --with-decryption --name {{ .Parameter }} --query Parameter.Value --output text > {{ .Path }})
+{{ else }}
+cat > {{ .Path }} <<'{{ $.Delimiter }}'
+{{ .Content }}{{ $.Delimiter }}
+{{ end }}chmod {{ .Mode }} {{ .Path }}
+{{ end }}
+MEM_KB=$(awk '/MemTotal/ {print $2}' /proc/meminfo)
+HEAP_GB=$(awk -v kb="$MEM_KB" -v factor={{ .JvmFactor }} 'BEGIN { heap = int(int(kb / 1000000 + 0.5) * factor); if (heap > {{ .MaxHeap }}) heap = {{ .MaxHeap }}; if (heap < 1) heap = 1; print heap }')
+sed -i "s/{{ .NamePlaceholder }}/$NODE_NAME/g; s/{{ .IpPlaceholder }}/$NODE_IP/g" "$OS_HOME/config/opensearch.yml"
+sed -i "s/{{ .HeapPlaceholder }}/$HEAP_GB/g" "$OS_HOME/config/jvm.options"
+echo "$NODE_IP" >> "$OS_HOME/config/unicast_hosts.txt"
+
+sed -i '/^{{ .HostsBegin }}$/,/^{{ .HostsEnd }}$/d' /etc/hosts
+{
+  echo '{{ .HostsBegin }}'
+{{ range .Hosts }}  echo {{ . }}
+{{ end }}  echo "$NODE_IP $NODE_NAME.{{ .DomainName }} $NODE_NAME"
+  echo '{{ .HostsEnd }}'
+} >> /etc/hosts
+
+chown -R {{ .User }}:{{ .Group }} "$OS_HOME"
+chmod 0700 "$OS_HOME/config"
+systemctl daemon-reload
+systemctl enable opensearch
+systemctl start opensearch
+echo "$(date) started opensearch on $NODE_NAME with a heap of ${HEAP_GB}g"
+`))
+
+// A file written by the bootstrap script
+type userDataFile struct {
+	Path    string
+	Mode    string
+	Content string
+	// Parameter indicates the SSM parameter the content is fetched from, for the secrets left out of the user data.
+	Parameter string
+}
+
+// Input:
+//
+//	ctx (context.Context): Context cancelling the reads of the certificates
+//	inventory (Inventory): Current nodes and the roles and attributes of the new node
+//	clusterCfg (config.ClusterDetails): Opensearch cluster details with the decrypted credentials
+//	secretPath (string): SSM parameter path under which the secrets of the new node are stored
+//
+// Description:
+//
+//	Renders the user data bootstrapping a new node without SSH, with the root CA of the cluster read on this node
+//
+// Return:
+//
+//	(UserData, error): Returns the bootstrap script with the secrets of the node and error if any
+func RenderUserData(ctx context.Context, inventory Inventory, clusterCfg config.ClusterDetails, secretPath string) (UserData, error) {
+	return renderUserData(ctx, localHost{}, inventory, clusterCfg, secretPath, time.Now())
+}
+
+// Input:
+//
+//	ctx (context.Context): Context cancelling the reads of the certificates
+//	master (remoteHost): Node running the scaling manager, which holds the root CA of the cluster
+//	inventory (Inventory): Current nodes and the roles and attributes of the new node
+//	clusterCfg (config.ClusterDetails): Opensearch cluster details with the decrypted credentials
+//	secretPath (string): SSM parameter path under which the secrets of the new node are stored
+//	now (time.Time): Start of the validity of the certificates
+//
+// Description:
+//
+//	Renders the bootstrap script run by cloud-init on the first boot of the new node. It does the steps of the
+//	native node configurator on the node itself: the name and ip of the node are read when it boots, the heap is
+//	computed from its RAM and the jvm_factor, and the discovery seeds are the current nodes. The ip of the node
+//	is not known when the certificates are created, so they are issued to a random name of the domain, which the
+//	nodes_dn of the cluster accepts. The private keys of the node and the internal users are not put in the
+//	script: they are returned as secrets under the secretPath, which the node fetches from the SSM Parameter Store
+//	with the AWS CLI and the role of its instance profile. The script is compressed with gzip, which cloud-init
+//	expands.
+//
+// Return:
+//
+//	(UserData, error): Returns the bootstrap script with the secrets of the node and error if the script is larger
+//	than the user data limit
+func renderUserData(ctx context.Context, master remoteHost, inventory Inventory, clusterCfg config.ClusterDetails, secretPath string, now time.Time) (UserData, error) {
+	confDir := path.Join(clusterCfg.OpensearchHome, "config")
+	securityFiles, err := readMasterFiles(ctx, master, confDir, caSigningFiles)
+	if err != nil {
+		return UserData{}, err
+	}
+	suffix := make([]byte, 4)
+	_, err = rand.Read(suffix)
+	if err != nil {
+		return UserData{}, err
+	}
+	certName := "node-bootstrap-" + hex.EncodeToString(suffix)
+	err = addNodeCertificates(securityFiles, certName, "", clusterCfg, now)
+	if err != nil {
+		return UserData{}, err
+	}
+
+	newNode := inventory.NewNode
+	newNode.Name, newNode.Ip = nodeNamePlaceholder, nodeIpPlaceholder
+	opensearchConfig, err := opensearchYml(newNode, certName, clusterCfg)
+	if err != nil {
+		return UserData{}, err
+	}
+	variables := templateVariables(clusterCfg)
+	variables["xms_value"] = heapPlaceholder
+	variables["xmx_value"] = heapPlaceholder
+	jvmOptions, err := renderTemplate("jvm.options", variables)
+	if err != nil {
+		return UserData{}, err
+	}
+	unit, err := renderTemplate("opensearch.service", templateVariables(clusterCfg))
+	if err != nil {
+		return UserData{}, err
+	}
+	internalUsers, err := internalUsersYml(clusterCfg)
+	if err != nil {
+		return UserData{}, err
+	}
+	var unicastHosts, hosts []string
+	for _, node := range inventory.CurrentNodes {
+		unicastHosts = append(unicastHosts, node.Ip)
+		hosts = append(hosts, shellQuote(fmt.Sprintf("%s %s.%s %s", node.Ip, node.Name, clusterCfg.DomainName, node.Name)))
+	}
+
+	secretPath = strings.TrimSuffix(secretPath, "/") + "/" + certName
+	secrets := make(map[string]string)
+	secretFile := func(filePath string, mode string, content string) userDataFile {
+		parameter := secretPath + "/" + path.Base(filePath)
+		secrets[parameter] = content
+		return userDataFile{Path: filePath, Mode: mode, Parameter: shellQuote(parameter)}
+	}
+
+	files := []userDataFile{
+		{Path: path.Join(confDir, "opensearch.yml"), Mode: "0600", Content: opensearchConfig},
+		{Path: path.Join(confDir, "jvm.options"), Mode: "0600", Content: jvmOptions},
+		{Path: path.Join(confDir, "unicast_hosts.txt"), Mode: "0644", Content: strings.Join(unicastHosts, "\n")},
+		{Path: path.Join(confDir, "root-ca.pem"), Mode: "0600", Content: string(securityFiles["root-ca.pem"])},
+	}
+	for _, name := range []string{certName, certName + "_http"} {
+		files = append(files,
+			userDataFile{Path: path.Join(confDir, name+".pem"), Mode: "0600", Content: string(securityFiles[name+".pem"])},
+			secretFile(path.Join(confDir, name+".key"), "0600", string(securityFiles[name+".key"])),
+		)
+	}
+	files = append(files,
+		secretFile(path.Join(securityConfDir(clusterCfg), "internal_users.yml"), "0600", internalUsers),
+		userDataFile{Path: path.Join(systemctlPath, "opensearch.service"), Mode: "0644", Content: unit},
+	)
+	for i := range files {
+		if files[i].Content != "" && !strings.HasSuffix(files[i].Content, "\n") {
+			files[i].Content += "\n"
+		}
+		for _, line := range strings.Split(files[i].Content, "\n") {
+			if line == userDataDelimiter {
+				return UserData{}, fmt.Errorf("the content of %s has the line %s ending the here documents of the user data", files[i].Path, userDataDelimiter)
+			}
+		}
+		files[i].Path = shellQuote(files[i].Path)
+	}
+
+	version := clusterCfg.OpensearchVersion
+	var script bytes.Buffer
+	err = userDataTemplate.Execute(&script, map[string]interface{}{
+		"ClusterName":     clusterCfg.ClusterName,
+		"LogFile":         BootstrapLogFile,
+		"Home":            shellQuote(clusterCfg.OpensearchHome),
+		"User":            shellQuote(clusterCfg.SshUser),
+		"Group":           shellQuote(clusterCfg.OsGroup),
+		"DownloadUrl":     shellQuote(fmt.Sprintf("%s/%s/opensearch-%s-linux-x64.tar.gz", osDownloadUrl, version, version)),
+		"Files":           files,
+		"Delimiter":       userDataDelimiter,
+		"JvmFactor":       strconv.FormatFloat(clusterCfg.JvmFactor, 'f', -1, 64),
+		"MaxHeap":         maxHeapGb,
+		"NamePlaceholder": nodeNamePlaceholder,
+		"IpPlaceholder":   nodeIpPlaceholder,
+		"HeapPlaceholder": heapPlaceholder,
+		"HostsBegin":      hostsBlockBegin,
+		"HostsEnd":        hostsBlockEnd,
+		"Hosts":           hosts,
+		"DomainName":      clusterCfg.DomainName,
+		"Region":          shellQuote(clusterCfg.CloudCredentials.Region),
+	})
+	if err != nil {
+		return UserData{}, err
+	}
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, err = writer.Write(script.Bytes())
+	if err != nil {
+		return UserData{}, err
+	}
+	err = writer.Close()
+	if err != nil {
+		return UserData{}, err
+	}
+	if compressed.Len() > maxUserDataSize {
+		return UserData{}, fmt.Errorf("the user data has %d bytes compressed, more than the limit of %d bytes", compressed.Len(), maxUserDataSize)
+	}
+	return UserData{Script: compressed.Bytes(), Secrets: secrets}, nil
+}
@@ -0,0 +1,96 @@
+package ansibleutils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Renders the user data of the test inventory and expands it
+func testUserData(t *testing.T) (string, map[string]string, *x509.Certificate) {
+	configurator, caCert := testNativeConfigurator(t, nil)
+	inventory := testInventory()
+	inventory.NewNode.Name, inventory.NewNode.Ip = "", ""
+	userData, err := renderUserData(context.Background(), configurator.master, inventory, testClusterCfg(), "/opensearch-scaling-manager/uuid/bootstrap/", time.Now())
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, len(userData.Script), maxUserDataSize)
+	reader, err := gzip.NewReader(bytes.NewReader(userData.Script))
+	assert.NoError(t, err)
+	script, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	return string(script), userData.Secrets, caCert
+}
+
+func TestRenderUserData(t *testing.T) {
+	script, secrets, caCert := testUserData(t)
+	assert.True(t, strings.HasPrefix(script, "#!/bin/bash\n# Bootstraps a node of the opensearch cluster cluster.1"))
+	assert.Contains(t, script, "curl -fsSL -o /tmp/opensearch.tar.gz 'https://artifacts.opensearch.org/releases/bundle/opensearch/2.3.0/opensearch-2.3.0-linux-x64.tar.gz'\n")
+	assert.Contains(t, script, "cat > '/usr/share/opensearch/config/unicast_hosts.txt' <<'SCALING_MANAGER_EOF'\n10.0.0.1\n10.0.0.2\nSCALING_MANAGER_EOF\n")
+	assert.Contains(t, script, "-v factor=0.5 ")
+	assert.Contains(t, script, "  echo '10.0.0.2 node-10-0-0-2.example.com node-10-0-0-2'\n")
+	assert.Contains(t, script, "node.name: \"__NODE_NAME__\"\n")
+	assert.Contains(t, script, "network.publish_host: __NODE_IP__\n")
+	assert.Contains(t, script, "node.roles: [data,ingest]\nnode.attr.temp: hot\n")
+	assert.Contains(t, script, "\n-Xms__HEAP_GB__g\n-Xmx__HEAP_GB__g\n")
+	assert.NotContains(t, script, "root-ca.key")
+	assert.NotContains(t, script, "admin.key")
+	assert.NotContains(t, script, "PRIVATE KEY")
+	assert.NotContains(t, script, "internal_users.yml' <<")
+
+	// The certificates are issued to a random name accepted by the nodes_dn of the cluster
+	certName := regexp.MustCompile(`pemcert_filepath: (node-bootstrap-[0-9a-f]{8})\.pem`).FindStringSubmatch(script)
+	assert.Len(t, certName, 2)
+	block, _ := pem.Decode([]byte(strings.Split(script, "/"+certName[1]+".pem' <<'SCALING_MANAGER_EOF'\n")[1]))
+	assert.NotNil(t, block)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	assert.NoError(t, err)
+	assert.NoError(t, cert.CheckSignatureFrom(caCert))
+	assert.Equal(t, certName[1]+".example.com", cert.Subject.CommonName)
+	assert.Empty(t, cert.IPAddresses)
+
+	// The private keys and the internal users are fetched from the parameter store when the node boots
+	secretPath := "/opensearch-scaling-manager/uuid/bootstrap/" + certName[1] + "/"
+	assert.Len(t, secrets, 3)
+	for _, name := range []string{certName[1] + ".key", certName[1] + "_http.key", "internal_users.yml"} {
+		assert.NotEmpty(t, secrets[secretPath+name])
+		assert.Contains(t, script, "--with-decryption --name '"+secretPath+name+"' ")
+	}
+	block, _ = pem.Decode([]byte(secrets[secretPath+certName[1]+".key"]))
+	assert.NotNil(t, block)
+	assert.Contains(t, block.Type, "PRIVATE KEY")
+}
+
+func TestUserDataScript(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash is not installed")
+	}
+	script, _, _ := testUserData(t)
+	file := filepath.Join(t.TempDir(), "user-data.sh")
+	assert.NoError(t, os.WriteFile(file, []byte(script), 0700))
+	output, err := exec.Command("bash", "-n", file).CombinedOutput()
+	assert.NoError(t, err, string(output))
+
+	// The heap of the script matches the heap of the native node configurator
+	heapScript := regexp.MustCompile(`HEAP_GB=\$\(awk -v kb="\$MEM_KB" (.*)\)\n`).FindStringSubmatch(script)
+	assert.Len(t, heapScript, 2)
+	for _, ramKb := range []string{"16363284", "131000000", "900000", "7800000"} {
+		output, err := exec.Command("sh", "-c", "MEM_KB="+ramKb+"; awk -v kb=\"$MEM_KB\" "+heapScript[1]).CombinedOutput()
+		assert.NoError(t, err, string(output))
+		heap, err := heapSize("MemTotal: "+ramKb+" kB", 0.5)
+		assert.NoError(t, err)
+		assert.Equal(t, strconv.Itoa(heap), strings.TrimSpace(string(output)))
+	}
+}
@@ -12,7 +12,7 @@ package cluster
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/maplelabs/opensearch-scaling-manager/logger"
 	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
@@ -57,6 +57,12 @@ type Node struct {
 	NumShards int
 	// Number of shards per GB
 	ShardsPerGB float64
+	// NodeGroup indicates the node group the node was provisioned in, empty if it is not part of a node group.
+	NodeGroup string
+	// Attributes indicates the custom node attributes of the node, like the temp attribute of hot-warm tiers.
+	Attributes map[string]string
+	// IndexingPressure indicates the memory used by the indexing requests in percentage of the indexing pressure limit.
+	IndexingPressure float32
 }
 
 // This struct will contain the static metrics of the cluster.
@@ -100,6 +106,34 @@ type ClusterDynamic struct {
 	NumActiveDataNodes int
 	TotalShards        int
 	ShardsPerGB        int
+	// DiskTotalInBytes indicates the disk space of the data paths summed over all the nodes.
+	DiskTotalInBytes int64
+	// DiskAvailableInBytes indicates the disk space available to opensearch summed over all the nodes.
+	DiskAvailableInBytes int64
+	// HeapMaxInBytes indicates the maximum heap memory summed over all the nodes.
+	HeapMaxInBytes int64
+}
+
+// This struct contains the average of a metric over the nodes for a day.
+type DailyAvg struct {
+	// Timestamp indicates the start of the day in milliseconds.
+	Timestamp int64
+	// Avg indicates the average of the metric over the documents of the day.
+	Avg float32
+}
+
+// This struct contains the statistics of a metric over the nodes for a polling interval.
+type MetricBucket struct {
+	// Timestamp indicates the start of the interval in milliseconds.
+	Timestamp int64
+	// Count indicates the number of documents in the interval.
+	Count int
+	// Avg indicates the average of the metric over the documents of the interval.
+	Avg float32
+	// Min indicates the minimum of the metric over the documents of the interval.
+	Min float32
+	// Max indicates the maximum of the metric over the documents of the interval.
+	Max float32
 }
 
 // This struct will provide the overall cluster metrcis for a OpenSearch cluster.
@@ -166,9 +200,32 @@ type MetricViolatedCountCluster struct {
 	NodeLevel []MetricViolatedCountNode
 }
 
+// Input:
+//              nodeGroup (string): The node group whose metrics are queried, empty for all the nodes
+//
+// Description:
+//              Generates the term query restricting the node metrics to the nodes of the node group.
+//              It is appended to the must clause of the metric queries.
+//
+// Return:
+//              (string): Returns the term query prefixed with a comma, empty if the metrics of all the nodes are queried
+
+func nodeGroupFilter(nodeGroup string) string {
+	if nodeGroup == "" {
+		return ""
+	}
+	return `,
+                {
+                  "term": {
+                    "NodeGroup": "` + nodeGroup + `"
+                  }
+                }`
+}
+
 // Input:
 //              decisionPeriod (int): Time in minutes used to specify the time range for collecting data from Opensearch.
 //              pollingInterval (int): Time in seconds which is the interval between each metric is pushed into the index
+//              nodeGroup (string): The node group whose metrics are queried, empty for all the nodes
 //
 // Description:
 //              Generates the query string necessary to check if there is any datapoints between the specified time range
@@ -177,7 +234,7 @@ type MetricViolatedCountCluster struct {
 // Return:
 //              (string): Returns the query string which can be passed as OS query api
 
-func dataPointsQuery(decisionPeriod int, pollingInterval int) string {
+func dataPointsQuery(decisionPeriod int, pollingInterval int, nodeGroup string) string {
 	dataPointQuery := `{
           "query": {
             "bool": {
@@ -190,7 +247,14 @@ func dataPointsQuery(decisionPeriod int, pollingInterval int) string {
                     "to": "now-` + strconv.Itoa(decisionPeriod) + `m+` + strconv.Itoa(pollingInterval) + `s"
                   }
                 }
-              }
+              },
+              "must": [
+                {
+                  "term": {
+                    "StatTag": "NodeStatistics"
+                  }
+                }` + nodeGroupFilter(nodeGroup) + `
+              ]
             }
           }
         }`
@@ -198,9 +262,56 @@ func dataPointsQuery(decisionPeriod int, pollingInterval int) string {
 	return dataPointQuery
 }
 
+// This struct contains the part of a search response read for the data points and the aggregations.
+type searchAggregations struct {
+	Hits struct {
+		// Total indicates the number of matching documents. It is nil if the response doesn't track them.
+		Total *struct {
+			Value int `json:"value"`
+		} `json:"total"`
+	} `json:"hits"`
+	// Aggregations indicates the aggregations keyed by name. The fields not returned by an aggregation are nil or empty.
+	Aggregations map[string]struct {
+		Value   *float64 `json:"value"`
+		Avg     *float64 `json:"avg"`
+		Min     *float64 `json:"min"`
+		Max     *float64 `json:"max"`
+		Buckets []struct {
+			DocCount int `json:"doc_count"`
+		} `json:"buckets"`
+	} `json:"aggregations"`
+}
+
+// Input:
+//              ctx (context.Context): Request-scoped data that transits processes and APIs.
+//              decisionPeriod (int): Time in minutes used to specify the time range for collecting data from Opensearch.
+//              pollingInterval (int): Time in seconds which is the interval between each metric is pushed into the index
+//              nodeGroup (string): The node group whose metrics are queried, empty for all the nodes
+//
+// Description:
+//              Counts the metrics documents at the start of the decision period, to check there are enough data points
+//              to evaluate it.
+//
+// Return:
+//              (int, error): Return the number of documents and error if any.
+
+func countDataPoints(ctx context.Context, decisionPeriod int, pollingInterval int, nodeGroup string) (int, error) {
+	var dataPointsResult searchAggregations
+	dataPointsResp, err := osutils.SearchQuery(ctx, osutils.NodeStatsIndex, []byte(dataPointsQuery(decisionPeriod, pollingInterval, nodeGroup)))
+	err = osutils.DecodeResponse(dataPointsResp, err, &dataPointsResult)
+	if err != nil {
+		return 0, err
+	}
+	if dataPointsResult.Hits.Total == nil {
+		return 0, errors.New("the response has no total of hits")
+	}
+	return dataPointsResult.Hits.Total.Value, nil
+}
+
 // Input:
 //              metricName (string): The metric for which the average is needed.
 //              decisionPeriod (int): Time in minutes used to specify the time range for collecting data from Opensearch.
+//              nodeGroup (string): The node group whose metrics are queried, empty for all the nodes
 //
 // Description:
 //              Generates the query string for determining the average of the metric specified.
@@ -208,7 +319,7 @@ func dataPointsQuery(decisionPeriod int, pollingInterval int) string {
 // Return:
 //              (string): Returns the query string that can be given as an OS query api parameter.
 
-func getClusterAvgQuery(metricName string, decisionPeriod int) string {
+func getClusterAvgQuery(metricName string, decisionPeriod int, nodeGroup string) string {
 	clusterAvgQueryString := `{
           "query": {
             "bool": {
@@ -221,7 +332,14 @@ func getClusterAvgQuery(metricName string, decisionPeriod int) string {
                     "to": null
                   }
                 }
-              }
+              },
+              "must": [
+                {
+                  "term": {
+                    "StatTag": "NodeStatistics"
+                  }
+                }` + nodeGroupFilter(nodeGroup) + `
+              ]
             }
           },
           "aggs": {
@@ -239,7 +357,7 @@ func getClusterAvgQuery(metricName string, decisionPeriod int) string {
 //              metricName (string): The metric for which the count is needed.
 //              decisionPeriod (int): Time in minutes used to specify the time range for collecting data from Opensearch.
 //              limit (float32): The limit which needs to checked for the metric if it has been reached
-//              pollingInterval (int): Time in seconds which is the interval between each metric is pushed into the index
+//              nodeGroup (string): The node group whose metrics are queried, empty for all the nodes
 //
 // Description:
 //              Generates the query string for determining the number of times the limit for the defined measure has been reached.
@@ -247,7 +365,7 @@ func getClusterAvgQuery(metricName string, decisionPeriod int) string {
 // Return:
 //              (string): Returns the query string that can be given as an OS query api parameter.
 
-func getCountQuery(metricName string, decisionPeriod int, limit float32) string {
+func getCountQuery(metricName string, decisionPeriod int, limit float32, nodeGroup string) string {
 	countQueryString := `{
           "query": {
             "bool": {
@@ -263,17 +381,17 @@ func getCountQuery(metricName string, decisionPeriod int, limit float32) string
               },
               "must": [
                 {
-                  "match": {
+                  "term": {
                     "StatTag": "NodeStatistics"
                   }
-                }
+                }` + nodeGroupFilter(nodeGroup) + `
               ]
             }
           },
           "aggs": {
             "docs_count": {
               "value_count": {
-                "field": "StatTag.keyword"
+                "field": "StatTag"
               }
             },
             "` + metricName + `": {
@@ -297,6 +415,7 @@ func getCountQuery(metricName string, decisionPeriod int, limit float32) string
 //              decisionPeriod (int): The evaluation period for which the Count will be determined.
 //              limit (float32): The limit for the metric for which the count is calculated.
 //              ctx (context.Context): Request-scoped data that transits processes and APIs.
+//              nodeGroup (string): The node group whose metrics are evaluated, empty for all the nodes
 //
 // Description:
 //              GetShardsCrossed will return the number of times the shards count has reached the limit.
@@ -304,55 +423,51 @@ func getCountQuery(metricName string, decisionPeriod int, limit float32) string
 // Return:
 //              (MetricViolatedCount, error): Return populated MetricViolatedCount struct and error if any.
 
-func GetShardsPerGBLimit(ctx context.Context, metricName string, decisionPeriod int, limit float32, pollingInterval int) (MetricViolatedCount, bool, error) {
+func GetShardsPerGBLimit(ctx context.Context, metricName string, decisionPeriod int, limit float32, pollingInterval int, nodeGroup string) (MetricViolatedCount, bool, error) {
 	var metricViolatedCount MetricViolatedCount
 	var invalidDatapoints bool
 
 	// Check data points
-	dataPointsResp, dpErr := osutils.SearchQuery(ctx, []byte(dataPointsQuery(decisionPeriod, pollingInterval)))
+	dataPoints, dpErr := countDataPoints(ctx, decisionPeriod, pollingInterval, nodeGroup)
 	if dpErr != nil {
 		log.Error.Println("Can't query for data points!", dpErr)
 		return metricViolatedCount, invalidDatapoints, dpErr
 	}
-	defer dataPointsResp.Body.Close()
-
-	var dpRespInterface map[string]interface{}
-
-	decodeErr := json.NewDecoder(dataPointsResp.Body).Decode(&dpRespInterface)
-	if decodeErr != nil {
-		log.Error.Println("decode Error: ", decodeErr)
-		return metricViolatedCount, invalidDatapoints, decodeErr
-	}
 
-	if int(dpRespInterface["hits"].(map[string]interface{})["total"].(map[string]interface{})["value"].(float64)) == 0 {
+	if dataPoints == 0 {
 		invalidDatapoints = true
 		return metricViolatedCount, invalidDatapoints, nil
 	}
 
 	//Get the query and convert to json
-	var jsonQuery = []byte(getCountQuery(metricName, decisionPeriod, limit))
+	var jsonQuery = []byte(getCountQuery(metricName, decisionPeriod, limit, nodeGroup))
+
+	//Struct to decode the response
+	var queryResult searchAggregations
 
-	//create a search request and pass the query
-	searchResp, err := osutils.SearchQuery(ctx, jsonQuery)
+	//create a search request, pass the query and decode the response into the struct
+	searchResp, err := osutils.SearchQuery(ctx, osutils.NodeStatsIndex, jsonQuery)
+	err = osutils.DecodeResponse(searchResp, err, &queryResult)
 	if err != nil {
 		log.Error.Println("Cannot fetch total shards: ", err)
 		return metricViolatedCount, invalidDatapoints, err
 	}
-	defer searchResp.Body.Close()
 
-	//Interface to dump the response
-	var queryResultInterface map[string]interface{}
-
-	//decode the response into the interface
-	decodeErr = json.NewDecoder(searchResp.Body).Decode(&queryResultInterface)
-	if decodeErr != nil {
-		log.Error.Println("decode Error: ", decodeErr)
-		return metricViolatedCount, invalidDatapoints, decodeErr
+	//Populate the metricViolatedCount from the aggregations
+	metric, ok := queryResult.Aggregations[metricName]
+	if !ok || len(metric.Buckets) == 0 {
+		err = fmt.Errorf("the response has no bucket for the %s aggregation", metricName)
+		log.Error.Println("Cannot fetch total shards: ", err)
+		return metricViolatedCount, invalidDatapoints, err
 	}
-
-	//Parse the interface and populate the metricStatsCluster
-	metricViolatedCount.ViolatedCount = int(queryResultInterface["aggregations"].(map[string]interface{})[metricName].(map[string]interface{})["buckets"].([]interface{})[0].(map[string]interface{})["doc_count"].(float64))
-	metricViolatedCount.TotalCount = int(queryResultInterface["aggregations"].(map[string]interface{})["docs_count"].(map[string]interface{})["value"].(float64))
+	docsCount, ok := queryResult.Aggregations["docs_count"]
+	if !ok || docsCount.Value == nil {
+		err = errors.New("the response has no value for the docs_count aggregation")
+		log.Error.Println("Cannot fetch total shards: ", err)
+		return metricViolatedCount, invalidDatapoints, err
+	}
+	metricViolatedCount.ViolatedCount = metric.Buckets[0].DocCount
+	metricViolatedCount.TotalCount = int(*docsCount.Value)
 
 	return metricViolatedCount, invalidDatapoints, nil
 }
@@ -362,6 +477,7 @@ func GetShardsPerGBLimit(ctx context.Context, metricName string, decisionPeriod
 //              decisionPeriod (int): The evaluation time over which the Average will be computed
 //              pollingInterval (int): Time in seconds which is the interval between each metric is pushed into the index
 //              ctx (context.Context): Request-scoped data that transits processes and APIs.
+//              nodeGroup (string): The node group whose metrics are evaluated, empty for all the nodes
 //
 // Description:
 //
@@ -372,69 +488,57 @@ func GetShardsPerGBLimit(ctx context.Context, metricName string, decisionPeriod
 // Return:
 //              (MetricStats, bool, error): Return a populated (MetricStats) struct, a (bool) value indicating whether there were enough data points to find the Stats, and any (errors).
 
-func GetClusterAvg(ctx context.Context, metricName string, decisionPeriod int, pollingInterval int) (MetricStats, bool, error) {
+func GetClusterAvg(ctx context.Context, metricName string, decisionPeriod int, pollingInterval int, nodeGroup string) (MetricStats, bool, error) {
 	//Create an object of MetricStatsCluster to populate and return
 	var metricStats MetricStats
 
 	var invalidDatapoints bool
 
 	// Check data points
-	dataPointsResp, dpErr := osutils.SearchQuery(ctx, []byte(dataPointsQuery(decisionPeriod, pollingInterval)))
+	dataPoints, dpErr := countDataPoints(ctx, decisionPeriod, pollingInterval, nodeGroup)
 	if dpErr != nil {
 		log.Error.Println("Can't query for data points!", dpErr)
 		return metricStats, invalidDatapoints, dpErr
 	}
-	defer dataPointsResp.Body.Close()
-
-	var dpRespInterface map[string]interface{}
 
-	decodeErr := json.NewDecoder(dataPointsResp.Body).Decode(&dpRespInterface)
-	if decodeErr != nil {
-		log.Error.Println("decode Error: ", decodeErr)
-		return metricStats, invalidDatapoints, decodeErr
-	}
-
-	if int(dpRespInterface["hits"].(map[string]interface{})["total"].(map[string]interface{})["value"].(float64)) == 0 {
+	if dataPoints == 0 {
 		invalidDatapoints = true
 		return metricStats, invalidDatapoints, nil
 	}
 
 	//Get the query and convert to json
-	var jsonQuery = []byte(getClusterAvgQuery(metricName, decisionPeriod))
+	var jsonQuery = []byte(getClusterAvgQuery(metricName, decisionPeriod, nodeGroup))
+
+	//Struct to decode the response
+	var queryResult searchAggregations
 
-	//create a search request and pass the query
-	searchResp, err := osutils.SearchQuery(ctx, jsonQuery)
+	//create a search request, pass the query and decode the response into the struct
+	searchResp, err := osutils.SearchQuery(ctx, osutils.NodeStatsIndex, jsonQuery)
+	err = osutils.DecodeResponse(searchResp, err, &queryResult)
 	if err != nil {
 		log.Error.Println("Cannot fetch cluster average: ", err)
 		return metricStats, invalidDatapoints, err
 	}
-	defer searchResp.Body.Close()
-	//Interface to dump the response
-	var queryResultInterface map[string]interface{}
-
-	//decode the response into the interface
-	decodeErr = json.NewDecoder(searchResp.Body).Decode(&queryResultInterface)
-	if decodeErr != nil {
-		log.Error.Println("decode Error: ", decodeErr)
-		return metricStats, invalidDatapoints, decodeErr
-	}
 
-	//Parse the interface and populate the metricStatsCluster
-	avg := queryResultInterface["aggregations"].(map[string]interface{})[metricName].(map[string]interface{})["avg"]
-	if avg != nil {
-		metricStats.Avg = float32(avg.(float64))
+	//Populate the metricStats from the aggregation
+	metric, ok := queryResult.Aggregations[metricName]
+	if !ok {
+		err = fmt.Errorf("the response has no %s aggregation", metricName)
+		log.Error.Println("Cannot fetch cluster average: ", err)
+		return metricStats, invalidDatapoints, err
+	}
+	if metric.Avg != nil {
+		metricStats.Avg = float32(*metric.Avg)
 	} else {
 		log.Warn.Println(metricName, " average is nil!")
 	}
-	max := queryResultInterface["aggregations"].(map[string]interface{})[metricName].(map[string]interface{})["max"]
-	if max != nil {
-		metricStats.Max = float32(max.(float64))
+	if metric.Max != nil {
+		metricStats.Max = float32(*metric.Max)
 	} else {
 		log.Warn.Println(metricName, " max is nil!")
 	}
-	min := queryResultInterface["aggregations"].(map[string]interface{})[metricName].(map[string]interface{})["min"]
-	if min != nil {
-		metricStats.Min = float32(min.(float64))
+	if metric.Min != nil {
+		metricStats.Min = float32(*metric.Min)
 	} else {
 		log.Warn.Println(metricName, " min is nil!")
 	}
@@ -445,6 +549,7 @@ func GetClusterAvg(ctx context.Context, metricName string, decisionPeriod int, p
 //              metricName (string): The metric for which the count is needed.
 //              decisionPeriod (int): Time in minutes used to specify the time range for collecting data from Opensearch.
 //              limit (float32): The limit which needs to checked for the metric if it has been reached
+//              nodeGroup (string): The node group whose metrics are queried, empty for all the nodes
 //
 // Description:
 //              Generates the query string for determining the number of times the limit for the defined measure has been reached.
@@ -452,7 +557,7 @@ func GetClusterAvg(ctx context.Context, metricName string, decisionPeriod int, p
 // Return:
 //              (string): Returns the query string that can be given as an OS query api parameter.
 
-func getClusterCountQuery(metricName string, decisionPeriod int, limit float32, pollingInterval int, taskOperation string) string {
+func getClusterCountQuery(metricName string, decisionPeriod int, limit float32, pollingInterval int, taskOperation string, nodeGroup string) string {
 	var operator string
 	if taskOperation == "scale_up" {
 		operator = ">="
@@ -473,11 +578,11 @@ func getClusterCountQuery(metricName string, decisionPeriod int, limit float32,
                   }},
                   "must": [
                         {
-                          "match":
+                          "term":
                           {
                                 "StatTag": "NodeStatistics"
                           }
-                        }
+                        }` + nodeGroupFilter(nodeGroup) + `
                         ]
                   }
                 },
@@ -516,6 +621,7 @@ func getClusterCountQuery(metricName string, decisionPeriod int, limit float32,
 //              limit (float32): The limit for the metric for which the count is calculated.
 //              ctx (context.Context): Request-scoped data that transits processes and APIs.
 //              taskOperation (string); Recommended operation
+//              nodeGroup (string): The node group whose metrics are evaluated, empty for all the nodes
 //
 // Description:
 //              GetClusterCount will return the number of times the specified metric has reached the limit.
@@ -523,113 +629,102 @@ func getClusterCountQuery(metricName string, decisionPeriod int, limit float32,
 // Return:
 //              (MetricViolatedCount, bool, error): Return populated MetricViolatedCount struct, bool which says if there were enough datapoints to calculate the count and error if any.
 
-func GetClusterCount(ctx context.Context, metricName string, decisionPeriod int, pollingInterval int, limit float32, taskOperation string) (MetricViolatedCount, bool, error) {
+func GetClusterCount(ctx context.Context, metricName string, decisionPeriod int, pollingInterval int, limit float32, taskOperation string, nodeGroup string) (MetricViolatedCount, bool, error) {
 	var metricViolatedCount MetricViolatedCount
 	var invalidDatapoints bool
 
 	// Check data points
-	dataPointsResp, dpErr := osutils.SearchQuery(ctx, []byte(dataPointsQuery(decisionPeriod, pollingInterval)))
+	dataPoints, dpErr := countDataPoints(ctx, decisionPeriod, pollingInterval, nodeGroup)
 	if dpErr != nil {
 		log.Error.Println("Can't query for data points!", dpErr)
 		return metricViolatedCount, invalidDatapoints, dpErr
 	}
-	defer dataPointsResp.Body.Close()
-
-	var dpRespInterface map[string]interface{}
-
-	decodeErr := json.NewDecoder(dataPointsResp.Body).Decode(&dpRespInterface)
-	if decodeErr != nil {
-		log.Error.Println("decode Error: ", decodeErr)
-		return metricViolatedCount, invalidDatapoints, decodeErr
-	}
 
-	if int(dpRespInterface["hits"].(map[string]interface{})["total"].(map[string]interface{})["value"].(float64)) == 0 {
+	if dataPoints == 0 {
 		invalidDatapoints = true
 		return metricViolatedCount, invalidDatapoints, nil
 	}
 
 	//Get the query and convert to json
-	var jsonQuery = []byte(getClusterCountQuery(metricName, decisionPeriod, limit, pollingInterval, taskOperation))
+	var jsonQuery = []byte(getClusterCountQuery(metricName, decisionPeriod, limit, pollingInterval, taskOperation, nodeGroup))
+
+	//Struct to decode the response
+	var queryResult searchAggregations
 
-	//create a search request and pass the query
-	searchResp, err := osutils.SearchQuery(ctx, jsonQuery)
+	//create a search request, pass the query and decode the response into the struct
+	searchResp, err := osutils.SearchQuery(ctx, osutils.NodeStatsIndex, jsonQuery)
+	err = osutils.DecodeResponse(searchResp, err, &queryResult)
 	if err != nil {
 		log.Error.Println("Cannot fetch cluster average: ", err)
 		return metricViolatedCount, invalidDatapoints, err
 	}
-	defer searchResp.Body.Close()
-
-	//Interface to dump the response
-	var queryResultInterface map[string]interface{}
-
-	//decode the response into the interface
-	decodeErr = json.NewDecoder(searchResp.Body).Decode(&queryResultInterface)
-	if decodeErr != nil {
-		log.Error.Println("decode Error: ", decodeErr)
-		return metricViolatedCount, invalidDatapoints, decodeErr
+	//Populate the metricViolatedCount from the buckets left by the bucket selector
+	interval, ok := queryResult.Aggregations["interval"]
+	if !ok {
+		err = errors.New("the response has no interval aggregation")
+		log.Error.Println("Cannot fetch cluster count: ", err)
+		return metricViolatedCount, invalidDatapoints, err
 	}
-	//Parse the interface and populate the metricStatsCluster
-	metricViolatedCount.ViolatedCount = len(queryResultInterface["aggregations"].(map[string]interface{})["interval"].(map[string]interface{})["buckets"].([]interface{}))
+	metricViolatedCount.ViolatedCount = len(interval.Buckets)
 
 	return metricViolatedCount, invalidDatapoints, nil
 }
 
 // Input:
+//              waitForShards (bool): If true, waits for the active shards when fetching the cluster health
 //
 // Description:
 //              GetClusterCurrent returns the most recent cluster level Statistics and Health in the form of a struct.
 //
 // Return:
-//              (ClusterDynamic): Return populated ClusterDynamic struct.
+//              (ClusterDynamic, bool, error): Return populated ClusterDynamic struct, bool which says if the cluster health request timed out and error if any.
 
-func GetClusterCurrent(waitForShards bool) (ClusterDynamic, bool) {
+func GetClusterCurrent(waitForShards bool) (ClusterDynamic, bool, error) {
 	ctx := context.Background()
-	//Create an interface to capture the response from cluster health and cluster stats API
-	var clusterStatsInterface map[string]interface{}
-	var clusterHealthInterface map[string]interface{}
-
 	var clusterStats ClusterDynamic
 
-	//Create a cluster stats request and fetch the response
-	resp, err := osutils.GetClusterStats(ctx)
+	//Fetch the cluster stats
+	clusterStatsResp, err := osutils.FetchClusterStats(ctx)
 	if err != nil {
 		log.Error.Println("cluster Stats fetch ERROR:", err)
+		return clusterStats, false, err
 	}
-	defer resp.Body.Close()
-
-	//decode and dump the cluster stats response into interface
-	decodeErr := json.NewDecoder(resp.Body).Decode(&clusterStatsInterface)
-	if decodeErr != nil {
-		log.Error.Println("decode Error: ", decodeErr)
-	}
-
-	//Parse the interface and populate required fields in cluster stats
-	clusterStats.NumActiveDataNodes = int(clusterStatsInterface["nodes"].(map[string]interface{})["count"].(map[string]interface{})["data"].(float64))
-	clusterStats.NumMasterNodes = int(clusterStatsInterface["nodes"].(map[string]interface{})["count"].(map[string]interface{})["master"].(float64))
 
-	//create a cluster health request and fetch cluster health
-	clusterHealthRequest, err := osutils.GetClusterHealth(ctx, &waitForShards)
+	//Fetch the cluster health
+	clusterHealth, err := osutils.FetchClusterHealth(ctx, &waitForShards)
 	if err != nil {
 		log.Error.Println("cluster Health fetch ERROR:", err)
+		return clusterStats, false, err
 	}
-	defer clusterHealthRequest.Body.Close()
 
-	//Decode the response and dump the response into the cluster health interface
-	decodeErr2 := json.NewDecoder(clusterHealthRequest.Body).Decode(&clusterHealthInterface)
-	if decodeErr2 != nil {
-		log.Error.Println("decode Error: ", decodeErr)
-	}
+	PopulateClusterDynamic(&clusterStats, clusterStatsResp, clusterHealth)
+	return clusterStats, clusterHealth.TimedOut, nil
+}
 
-	//Parse the interface and populate required fields in cluster stats
-	clusterStats.NumNodes = int(clusterHealthInterface["number_of_nodes"].(float64))
-	clusterStats.ClusterStatus = clusterStatsInterface["status"].(string)
-	clusterStats.NumActiveShards = int(clusterHealthInterface["active_shards"].(float64))
-	clusterStats.NumActivePrimaryShards = int(clusterHealthInterface["active_primary_shards"].(float64))
-	clusterStats.NumInitializingShards = int(clusterHealthInterface["initializing_shards"].(float64))
-	clusterStats.NumUnassignedShards = int(clusterHealthInterface["unassigned_shards"].(float64))
-	clusterStats.NumRelocatingShards = int(clusterHealthInterface["relocating_shards"].(float64))
+// Input:
+//              clusterDynamic (*ClusterDynamic): The struct to be populated
+//              clusterStats (osutils.ClusterStatsResponse): The cluster stats response
+//              clusterHealth (osutils.ClusterHealthResponse): The cluster health response
+//
+// Description:
+//              Populates the cluster level Statistics and Health from the cluster stats and cluster health responses.
+//
+// Return:
 
-	return clusterStats, clusterHealthInterface["timed_out"].(bool)
+func PopulateClusterDynamic(clusterDynamic *ClusterDynamic, clusterStats osutils.ClusterStatsResponse, clusterHealth osutils.ClusterHealthResponse) {
+	clusterDynamic.NumActiveDataNodes = clusterStats.Nodes.Count.Data
+	clusterDynamic.NumMasterNodes = clusterStats.Nodes.Count.MasterEligible()
+	clusterDynamic.TotalShards = clusterStats.Indices.Shards.Total
+	clusterDynamic.ClusterStatus = clusterStats.Status
+	clusterDynamic.NumNodes = clusterHealth.NumberOfNodes
+	clusterDynamic.NumActiveShards = clusterHealth.ActiveShards
+	clusterDynamic.NumActivePrimaryShards = clusterHealth.ActivePrimaryShards
+	clusterDynamic.NumInitializingShards = clusterHealth.InitializingShards
+	clusterDynamic.NumUnassignedShards = clusterHealth.UnassignedShards
+	clusterDynamic.NumRelocatingShards = clusterHealth.RelocatingShards
+	clusterDynamic.DiskTotalInBytes = clusterStats.Nodes.Fs.TotalInBytes
+	clusterDynamic.DiskAvailableInBytes = clusterStats.Nodes.Fs.AvailableInBytes
+	clusterDynamic.HeapMaxInBytes = clusterStats.Nodes.Jvm.Mem.HeapMaxInBytes
 }
 
 // Input:
@@ -664,3 +759,197 @@ func GetClusterHistoricCount(decisionPeriod int, thresholdMap map[string]int) []
 	var metricViolatedCount []MetricViolatedCountCluster
 	return metricViolatedCount
 }
+
+// Input:
+//              metricName (string): The metric for which the daily averages are needed.
+//              days (int): Number of days, up to now, for which the averages are calculated.
+//              nodeGroup (string): The node group whose metrics are queried, empty for all the nodes
+//
+// Description:
+//              Generates the query string aggregating the average of the metric per day.
+//
+// Return:
+//              (string): Returns the query string that can be given as an OS query api parameter.
+
+func getDailyAvgQuery(metricName string, days int, nodeGroup string) string {
+	return `{
+          "size": 0,
+          "query": {
+            "bool": {
+              "filter": {
+                "range": {
+                  "Timestamp": {
+                    "gte": "now-` + strconv.Itoa(days) + `d/d"
+                  }
+                }
+              },
+              "must": [
+                {
+                  "term": {
+                    "StatTag": "NodeStatistics"
+                  }
+                }` + nodeGroupFilter(nodeGroup) + `
+              ]
+            }
+          },
+          "aggs": {
+            "daily": {
+              "date_histogram": {
+                "field": "Timestamp",
+                "calendar_interval": "1d",
+                "min_doc_count": 1
+              },
+              "aggs": {
+                "` + metricName + `": {
+                  "avg": {
+                    "field": "` + metricName + `"
+                  }
+                }
+              }
+            }
+          }
+        }`
+}
+
+// Input:
+//              ctx (context.Context): Request-scoped data that transits processes and APIs.
+//              metricName (string): The metric for which the daily averages are needed.
+//              days (int): Number of days, up to now, for which the averages are calculated.
+//              nodeGroup (string): The node group whose metrics are evaluated, empty for all the nodes
+//
+// Description:
+//              GetDailyAvg returns the average of the metric over the nodes for every day with metrics stored,
+//              which is used to find how fast a metric grows.
+//
+// Return:
+//              ([]DailyAvg, error): Return the daily averages ordered by day and error if any.
+
+func GetDailyAvg(ctx context.Context, metricName string, days int, nodeGroup string) ([]DailyAvg, error) {
+	var queryResult struct {
+		Aggregations struct {
+			Daily struct {
+				Buckets []map[string]interface{} `json:"buckets"`
+			} `json:"daily"`
+		} `json:"aggregations"`
+	}
+	searchResp, err := osutils.SearchQuery(ctx, osutils.NodeStatsIndex, []byte(getDailyAvgQuery(metricName, days, nodeGroup)))
+	err = osutils.DecodeResponse(searchResp, err, &queryResult)
+	if err != nil {
+		log.Error.Println("Cannot fetch daily averages: ", err)
+		return nil, err
+	}
+
+	var dailyAvgs []DailyAvg
+	for _, bucket := range queryResult.Aggregations.Daily.Buckets {
+		key, _ := bucket["key"].(float64)
+		metric, _ := bucket[metricName].(map[string]interface{})
+		avg, ok := metric["value"].(float64)
+		if !ok {
+			continue
+		}
+		dailyAvgs = append(dailyAvgs, DailyAvg{Timestamp: int64(key), Avg: float32(avg)})
+	}
+	return dailyAvgs, nil
+}
+
+// Input:
+//              metricName (string): The metric for which the statistics are needed.
+//              from (int64): Start of the time range in milliseconds.
+//              to (int64): End of the time range in milliseconds.
+//              pollingInterval (int): Time in seconds which is the interval between each metric is pushed into the index
+//              nodeGroup (string): The node group whose metrics are queried, empty for all the nodes
+//
+// Description:
+//              Generates the query string aggregating the statistics of the metric per polling interval.
+//
+// Return:
+//              (string): Returns the query string that can be given as an OS query api parameter.
+
+func getMetricBucketsQuery(metricName string, from int64, to int64, pollingInterval int, nodeGroup string) string {
+	return `{
+          "size": 0,
+          "query": {
+            "bool": {
+              "filter": {
+                "range": {
+                  "Timestamp": {
+                    "gte": ` + strconv.FormatInt(from, 10) + `,
+                    "lte": ` + strconv.FormatInt(to, 10) + `,
+                    "format": "epoch_millis"
+                  }
+                }
+              },
+              "must": [
+                {
+                  "term": {
+                    "StatTag": "NodeStatistics"
+                  }
+                }` + nodeGroupFilter(nodeGroup) + `
+              ]
+            }
+          },
+          "aggs": {
+            "interval": {
+              "date_histogram": {
+                "field": "Timestamp",
+                "fixed_interval": "` + strconv.Itoa(pollingInterval) + `s",
+                "min_doc_count": 1
+              },
+              "aggs": {
+                "` + metricName + `": {
+                  "stats": {
+                    "field": "` + metricName + `"
+                  }
+                }
+              }
+            }
+          }
+        }`
+}
+
+// Input:
+//              ctx (context.Context): Request-scoped data that transits processes and APIs.
+//              metricName (string): The metric for which the statistics are needed.
+//              from (int64): Start of the time range in milliseconds.
+//              to (int64): End of the time range in milliseconds.
+//              pollingInterval (int): Time in seconds which is the interval between each metric is pushed into the index
+//              nodeGroup (string): The node group whose metrics are evaluated, empty for all the nodes
+//
+// Description:
+//              GetMetricBuckets returns the statistics of the metric over the nodes for every polling interval with
+//              metrics stored in the time range, so that the rules can be evaluated at any point of the range.
+//
+// Return:
+//              ([]MetricBucket, error): Return the statistics ordered by time and error if any.
+
+func GetMetricBuckets(ctx context.Context, metricName string, from int64, to int64, pollingInterval int, nodeGroup string) ([]MetricBucket, error) {
+	var queryResult struct {
+		Aggregations struct {
+			Interval struct {
+				Buckets []map[string]interface{} `json:"buckets"`
+			} `json:"interval"`
+		} `json:"aggregations"`
+	}
+	searchResp, err := osutils.SearchQuery(ctx, osutils.NodeStatsIndex, []byte(getMetricBucketsQuery(metricName, from, to, pollingInterval, nodeGroup)))
+	err = osutils.DecodeResponse(searchResp, err, &queryResult)
+	if err != nil {
+		log.Error.Println("Cannot fetch metric statistics: ", err)
+		return nil, err
+	}
+
+	var buckets []MetricBucket
+	for _, bucket := range queryResult.Aggregations.Interval.Buckets {
+		key, _ := bucket["key"].(float64)
+		stats, _ := bucket[metricName].(map[string]interface{})
+		count, _ := stats["count"].(float64)
+		// Stats are null when no document of the interval has the metric
+		avg, ok := stats["avg"].(float64)
+		if !ok || count == 0 {
+			continue
+		}
+		min, _ := stats["min"].(float64)
+		max, _ := stats["max"].(float64)
+		buckets = append(buckets, MetricBucket{Timestamp: int64(key), Count: int(count), Avg: float32(avg), Min: float32(min), Max: float32(max)})
+	}
+	return buckets, nil
+}
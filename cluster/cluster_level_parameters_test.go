@@ -0,0 +1,89 @@
+package cluster
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
+	opensearch "github.com/opensearch-project/opensearch-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// Starts a server answering the data points query with dataPoints and the aggregation query with aggregations
+func newSearchServer(t *testing.T, dataPoints string, aggregations string) {
+	searches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The client checks the product with a GET on the root first
+		if r.URL.Path == "/" {
+			w.Write([]byte(`{"version": {"number": "2.4.0"}}`))
+			return
+		}
+		searches++
+		if searches == 1 {
+			w.Write([]byte(dataPoints))
+		} else {
+			w.Write([]byte(aggregations))
+		}
+	}))
+	client, err := osutils.NewClient(opensearch.Config{Addresses: []string{server.URL}, DisableRetry: true})
+	assert.Nil(t, err)
+	previous := osutils.SetClient(client)
+	t.Cleanup(func() {
+		osutils.SetClient(previous)
+		server.Close()
+	})
+}
+
+func TestGetClusterAvg(t *testing.T) {
+	ctx := context.Background()
+	newSearchServer(t, `{"hits": {"total": {"value": 3}}}`, `{"aggregations": {"CpuUtil": {"avg": 40.5, "min": 20, "max": 61}}}`)
+	stats, invalidDatapoints, err := GetClusterAvg(ctx, "CpuUtil", 10, 300, "")
+	assert.Nil(t, err)
+	assert.False(t, invalidDatapoints)
+	assert.Equal(t, MetricStats{Avg: 40.5, Min: 20, Max: 61}, stats)
+
+	newSearchServer(t, `{"hits": {"total": {"value": 0}}}`, `{}`)
+	_, invalidDatapoints, err = GetClusterAvg(ctx, "CpuUtil", 10, 300, "")
+	assert.Nil(t, err)
+	assert.True(t, invalidDatapoints)
+
+	// The responses missing a part return an error instead of panicking
+	newSearchServer(t, `{"hits": {}}`, `{}`)
+	_, _, err = GetClusterAvg(ctx, "CpuUtil", 10, 300, "")
+	assert.EqualError(t, err, "the response has no total of hits")
+
+	newSearchServer(t, `{"hits": {"total": {"value": 3}}}`, `{"aggregations": {}}`)
+	_, _, err = GetClusterAvg(ctx, "CpuUtil", 10, 300, "")
+	assert.EqualError(t, err, "the response has no CpuUtil aggregation")
+}
+
+func TestGetClusterCount(t *testing.T) {
+	ctx := context.Background()
+	newSearchServer(t, `{"hits": {"total": {"value": 3}}}`, `{"aggregations": {"interval": {"buckets": [{"doc_count": 3}, {"doc_count": 2}]}}}`)
+	count, _, err := GetClusterCount(ctx, "CpuUtil", 10, 300, 80, "scale_up", "")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count.ViolatedCount)
+
+	newSearchServer(t, `{"hits": {"total": {"value": 3}}}`, `{"error": "unexpected"}`)
+	_, _, err = GetClusterCount(ctx, "CpuUtil", 10, 300, 80, "scale_up", "")
+	assert.EqualError(t, err, "the response has no interval aggregation")
+}
+
+func TestGetShardsPerGBLimit(t *testing.T) {
+	ctx := context.Background()
+	newSearchServer(t, `{"hits": {"total": {"value": 3}}}`, `{"aggregations": {"ShardsPerGB": {"buckets": [{"doc_count": 4}]}, "docs_count": {"value": 9}}}`)
+	count, _, err := GetShardsPerGBLimit(ctx, "ShardsPerGB", 10, 20, 300, "")
+	assert.Nil(t, err)
+	assert.Equal(t, MetricViolatedCount{ViolatedCount: 4, TotalCount: 9}, count)
+
+	// An empty range aggregation
+	newSearchServer(t, `{"hits": {"total": {"value": 3}}}`, `{"aggregations": {"ShardsPerGB": {"buckets": []}, "docs_count": {"value": 9}}}`)
+	_, _, err = GetShardsPerGBLimit(ctx, "ShardsPerGB", 10, 20, 300, "")
+	assert.EqualError(t, err, "the response has no bucket for the ShardsPerGB aggregation")
+
+	newSearchServer(t, `{"hits": {"total": {"value": 3}}}`, `{"aggregations": {"ShardsPerGB": {"buckets": [{"doc_count": 4}]}}}`)
+	_, _, err = GetShardsPerGBLimit(ctx, "ShardsPerGB", 10, 20, 300, "")
+	assert.EqualError(t, err, "the response has no value for the docs_count aggregation")
+}
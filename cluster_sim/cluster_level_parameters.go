@@ -57,22 +57,21 @@ func GetClusterAvg(metricName string, decisionPeriod int, isAccelerated bool) (c
 	resp, err := client.Get(url)
 
 	if err != nil {
-		log.Panic.Println(err)
-		panic(err)
+		log.Error.Println(err)
+		return metricStats, err
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		response, _ := ioutil.ReadAll(resp.Body)
 		return metricStats, errors.New(string(response))
 	}
 
-	defer resp.Body.Close()
-
 	decoder := json.NewDecoder(resp.Body)
 	err = decoder.Decode(&metricStats)
 	if err != nil {
-		log.Panic.Println(err)
-		panic(err)
+		log.Error.Println(err)
+		return metricStats, err
 	}
 	log.Debug.Println(metricStats)
 
@@ -110,23 +109,22 @@ func GetClusterCount(metricName string, decisonPeriod int, limit float32, isAcce
 	resp, err := client.Get(url)
 
 	if err != nil {
-		log.Panic.Println(err)
-		panic(err)
+		log.Error.Println(err)
+		return metricViolatedCount, err
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		response, _ := ioutil.ReadAll(resp.Body)
 		return metricViolatedCount, errors.New(string(response))
 	}
 
-	defer resp.Body.Close()
-
 	decoder := json.NewDecoder(resp.Body)
 	err = decoder.Decode(&metricViolatedCount)
 
 	if err != nil {
-		log.Panic.Println(err)
-		panic(err)
+		log.Error.Println(err)
+		return metricViolatedCount, err
 	}
 	log.Debug.Println(metricViolatedCount)
 	return metricViolatedCount, nil
@@ -138,9 +136,9 @@ func GetClusterCount(metricName string, decisonPeriod int, limit float32, isAcce
 //              GetClusterCurrent returns the most recent cluster level Statistics and Health in the form of a struct.
 //
 // Return:
-//              (cluster.ClusterDynamic): Return populated ClusterDynamic struct.
+//              (cluster.ClusterDynamic, error): Return populated ClusterDynamic struct and error if any.
 
-func GetClusterCurrent(isAccelerated bool) cluster.ClusterDynamic {
+func GetClusterCurrent(isAccelerated bool) (cluster.ClusterDynamic, error) {
 	var clusterStats cluster.ClusterDynamic
 	var url string
 	if isAccelerated {
@@ -156,16 +154,22 @@ func GetClusterCurrent(isAccelerated bool) cluster.ClusterDynamic {
 	}
 	resp, err := client.Get(url)
 	if err != nil {
-		log.Panic.Println(err)
-		panic(err)
+		log.Error.Println(err)
+		return clusterStats, err
 	}
 	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		response, _ := ioutil.ReadAll(resp.Body)
+		return clusterStats, errors.New(string(response))
+	}
+
 	decoder := json.NewDecoder(resp.Body)
 	err = decoder.Decode(&clusterStats)
 	if err != nil {
-		log.Panic.Println(err)
-		panic(err)
+		log.Error.Println(err)
+		return clusterStats, err
 	}
 	log.Debug.Println(clusterStats)
-	return clusterStats
+	return clusterStats, nil
 }
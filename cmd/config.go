@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+	"github.com/maplelabs/opensearch-scaling-manager/configcheck"
+	"github.com/maplelabs/opensearch-scaling-manager/crypto"
+	"github.com/spf13/cobra"
+)
+
+// Parent command of the commands working on the config file
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Work with the config file of Opensearch Scaling Manager",
+}
+
+// Command to validate the config file
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the config file and detect conflicting tasks",
+	Long: `Validates the config file and reports every invalid field by its path in the file. The tasks are checked
+for cron expressions which don't parse, decision periods shorter than the polling interval and scale up and
+scale down limits which overlap. With --replay-hours the stored metrics of the last hours are replayed to show
+when every task would have been recommended, connecting to opensearch with the installed config file.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		file, _ := cmd.Flags().GetString("file")
+		format, _ := cmd.Flags().GetString("format")
+		replayHours, _ := cmd.Flags().GetInt("replay-hours")
+
+		isValid, err := validateConfig(file, format, replayHours)
+		if err != nil {
+			log.Error.Println(err)
+			os.Exit(1)
+		}
+		if !isValid {
+			os.Exit(1)
+		}
+	},
+}
+
+// Command to migrate the config file to the current version
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Migrate the config file to the version of this scaling manager",
+	Long: `Migrates the config file from the version in its version key, or from version 0 if it has none, to the
+version read by this scaling manager. The comments and layout of the file are kept. Older files are also
+migrated in memory when they are read, so the migration only has to be run to update the file itself.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		file, _ := cmd.Flags().GetString("file")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		err := migrateConfig(file, dryRun)
+		if err != nil {
+			log.Error.Println(err)
+			os.Exit(1)
+		}
+	},
+}
+
+// Input:
+//
+// Description:
+//
+//	Initializes the config commands, adds the required flags
+//
+// Return:
+func init() {
+	configValidateCmd.PersistentFlags().String("file", config.ConfigFileName, "Path of the config file to be validated")
+	configValidateCmd.PersistentFlags().String("format", "text", "Output format of the report, text or json")
+	configValidateCmd.PersistentFlags().Int("replay-hours", 0, "Hours of stored metrics to replay the tasks on, 0 to skip the replay")
+	configMigrateCmd.PersistentFlags().String("file", config.ConfigFileName, "Path of the config file to be migrated")
+	configMigrateCmd.PersistentFlags().Bool("dry-run", false, "Print the migrated config instead of writing it to the file")
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configMigrateCmd)
+}
+
+// Input:
+//
+//	file (string): Path of the config file to be validated
+//	format (string): Output format of the report, text or json
+//	replayHours (int): Hours of stored metrics to replay the tasks on, 0 to skip the replay
+//
+// Description:
+//
+//	Validates the config file, checks its tasks and replays the stored metrics if asked, then prints the report
+//	to the standard output. The metrics are only replayed for a valid config.
+//
+// Return:
+//
+//	(bool, error): Returns true if the config is valid and error if the report could not be made
+func validateConfig(file string, format string, replayHours int) (bool, error) {
+	if format != "text" && format != "json" {
+		return false, fmt.Errorf("unknown format %s, expected text or json", format)
+	}
+	if replayHours < 0 {
+		return false, fmt.Errorf("replay-hours should not be negative")
+	}
+	var report configcheck.Report
+	configStruct, err := config.ReadConfig(file)
+	report.Errors = configcheck.ValidationMessages(err)
+	if err == nil {
+		report.Issues = configcheck.CheckTasks(configStruct)
+	}
+	if report.IsValid() && replayHours > 0 {
+		_, err = crypto.InitializeClient()
+		if err != nil {
+			return false, err
+		}
+		report.Replay, err = configcheck.ReplayTasks(context.Background(), configStruct, replayHours)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if format == "json" {
+		reportJson, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return false, err
+		}
+		fmt.Println(string(reportJson))
+	} else {
+		report.WriteText(os.Stdout, time.Local)
+	}
+	return report.IsValid(), nil
+}
+
+// Input:
+//
+//	file (string): Path of the config file to be migrated
+//	dryRun (bool): Prints the migrated config instead of writing it to the file if true
+//
+// Description:
+//
+//	Migrates the config file to the current version and validates the migrated config. A file which is already at
+//	the current version is left as it is.
+//
+// Return:
+//
+//	(error): Returns error if the file can't be read, migrated or written
+func migrateConfig(file string, dryRun bool) error {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	migratedContent, fromVersion, err := config.MigrateConfig(content)
+	if err != nil {
+		return err
+	}
+	if fromVersion == config.ConfigVersion {
+		fmt.Printf("%s is already at version %d\n", file, config.ConfigVersion)
+		return nil
+	}
+	if dryRun {
+		fmt.Print(string(migratedContent))
+		return nil
+	}
+	fileInfo, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(file, migratedContent, fileInfo.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s migrated from version %d to %d\n", file, fromVersion, config.ConfigVersion)
+
+	_, err = config.ReadConfig(file)
+	for _, message := range configcheck.ValidationMessages(err) {
+		fmt.Println("error: " + message)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/maplelabs/opensearch-scaling-manager/crypto"
+	"github.com/maplelabs/opensearch-scaling-manager/provision"
+	"github.com/spf13/cobra"
+)
+
+// Command to compare the instances launched by the scaling manager with the nodes of the cluster
+var inventoryCmd = &cobra.Command{
+	Use:   "inventory",
+	Short: "List the instances launched by the scaling manager which are not nodes of the cluster",
+	Long: `Reconciles the instances the scaling manager launched for the cluster, found by their tags, with the
+nodes of the Opensearch cluster. The instances which are not nodes of the cluster, like the ones left behind by
+a failed provision, are listed as orphans, and the nodes of the cluster without a running instance are listed
+as well. Nothing is terminated, the orphans have to be checked and terminated by hand.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := reconcileInventory()
+		if err != nil {
+			log.Error.Println(err)
+			os.Exit(1)
+		}
+	},
+}
+
+// Input:
+//
+// Description:
+//
+//	Reconciles the instances launched by the scaling manager with the nodes of the cluster and prints the
+//	orphaned instances and the nodes without an instance.
+//
+// Return:
+//
+//	(error): Returns error upon unsuccessful execution.
+func reconcileInventory() error {
+	configStruct, err := crypto.InitializeClient()
+	if err != nil {
+		return err
+	}
+	inventory, err := provision.ReconcileInventory(configStruct.ClusterDetails)
+	if err != nil {
+		return err
+	}
+	if len(inventory.Orphans) == 0 {
+		fmt.Println("No orphaned instances")
+	} else {
+		fmt.Println("Instances launched by the scaling manager which are not nodes of the cluster:")
+		for _, instance := range inventory.Orphans {
+			fmt.Printf("  %s %s %s, launched at %s for %s\n", instance.InstanceId, instance.PrivateIp, instance.State, instance.LaunchTime, instance.LaunchReason)
+		}
+	}
+	if len(inventory.NodesWithoutInstance) == 0 {
+		fmt.Println("Every node of the cluster has a running instance")
+	} else {
+		fmt.Println("Nodes of the cluster without a running instance:")
+		for _, node := range inventory.NodesWithoutInstance {
+			fmt.Printf("  %s %s\n", node.Name, node.Host)
+		}
+	}
+	return nil
+}
@@ -34,4 +34,8 @@ func Execute() error{
 func init(){
         scaleManagerCmd.AddCommand(startCmd)
         scaleManagerCmd.AddCommand(stopCmd)
+        scaleManagerCmd.AddCommand(planCmd)
+        scaleManagerCmd.AddCommand(configCmd)
+        scaleManagerCmd.AddCommand(rollingRestartCmd)
+        scaleManagerCmd.AddCommand(inventoryCmd)
 }
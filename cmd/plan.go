@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/maplelabs/opensearch-scaling-manager/crypto"
+	"github.com/maplelabs/opensearch-scaling-manager/planner"
+	"github.com/spf13/cobra"
+)
+
+// Command to print the capacity plan of the cluster
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Print the capacity plan of the Opensearch cluster",
+	Long: `Projects the days until the disk or shards per GB limits of the scale up rules are hit at the
+growth of the stored metrics, and recommends the number of nodes for the planning horizon.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, _ := cmd.Flags().GetString("format")
+		lookbackDays, _ := cmd.Flags().GetInt("lookback-days")
+		horizonDays, _ := cmd.Flags().GetInt("horizon-days")
+
+		err := plan(format, lookbackDays, horizonDays)
+		if err != nil {
+			log.Error.Println(err)
+			os.Exit(1)
+		}
+	},
+}
+
+// Input:
+//
+// Description:
+//
+//	Initializes the plan command, adds the required flags
+//
+// Return:
+func init() {
+	planCmd.PersistentFlags().String("format", "table", "Output format of the plan, table or json")
+	planCmd.PersistentFlags().Int("lookback-days", 14, "Days of stored metrics used to find the growth")
+	planCmd.PersistentFlags().Int("horizon-days", 30, "Days for which the number of nodes is recommended")
+}
+
+// Input:
+//
+//	format (string): Output format of the plan, table or json
+//	lookbackDays (int): Days of stored metrics used to find the growth
+//	horizonDays (int): Days for which the number of nodes is recommended
+//
+// Description:
+//
+//	Builds the capacity plan of the cluster and prints it to the standard output.
+//
+// Return:
+//
+//	(error): Returns error upon unsuccessful execution.
+func plan(format string, lookbackDays int, horizonDays int) error {
+	if format != "table" && format != "json" {
+		return fmt.Errorf("unknown format %s, expected table or json", format)
+	}
+	if lookbackDays < 1 || horizonDays < 1 {
+		return fmt.Errorf("lookback-days and horizon-days should be at least 1")
+	}
+	configStruct, err := crypto.InitializeClient()
+	if err != nil {
+		return err
+	}
+	capacityPlan, err := planner.GetPlan(context.Background(), configStruct, lookbackDays, horizonDays)
+	if err != nil {
+		return err
+	}
+	if format == "json" {
+		planJson, err := json.MarshalIndent(capacityPlan, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(planJson))
+		return nil
+	}
+	return capacityPlan.WriteTable(os.Stdout)
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/maplelabs/opensearch-scaling-manager/crypto"
+	"github.com/maplelabs/opensearch-scaling-manager/provision"
+	"github.com/spf13/cobra"
+)
+
+// Command to request a rolling restart of the cluster
+var rollingRestartCmd = &cobra.Command{
+	Use:   "rolling-restart",
+	Short: "Restart the nodes of the Opensearch cluster one at a time",
+	Long: `Requests a rolling restart of the nodes, which the master of the scaling manager starts in its next
+provision check. The allocation of the replicas is disabled while a node is restarted, and the next node is
+only restarted once the node joined the cluster again and the cluster is green. Use it to apply a change of
+the opensearch settings, the plugins or, with --update-heap, the jvm_factor to all the nodes. The progress is
+kept in the provisioning state, so a new master resumes the rolling restart.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		reason, _ := cmd.Flags().GetString("reason")
+		nodeGroup, _ := cmd.Flags().GetString("node-group")
+		updateHeap, _ := cmd.Flags().GetBool("update-heap")
+
+		err := requestRollingRestart(reason, nodeGroup, updateHeap)
+		if err != nil {
+			log.Error.Println(err)
+			os.Exit(1)
+		}
+	},
+}
+
+// Input:
+//
+// Description:
+//
+//	Initializes the rolling-restart command, adds the required flags
+//
+// Return:
+func init() {
+	rollingRestartCmd.PersistentFlags().String("reason", "config_change", "Why the nodes are restarted, recorded in the ProvisionStats document")
+	rollingRestartCmd.PersistentFlags().String("node-group", "", "Node group to be restarted, all the nodes if empty")
+	rollingRestartCmd.PersistentFlags().Bool("update-heap", false, "Write the heap of the jvm_factor to jvm.options of every node before its restart")
+}
+
+// Input:
+//
+//	reason (string): Why the nodes are restarted
+//	nodeGroup (string): Node group to be restarted, all the nodes if empty
+//	updateHeap (bool): True to write the heap of the jvm_factor to jvm.options before the restarts
+//
+// Description:
+//
+//	Records the request of the rolling restart in the provisioning state.
+//
+// Return:
+//
+//	(error): Returns error upon unsuccessful execution.
+func requestRollingRestart(reason string, nodeGroup string, updateHeap bool) error {
+	configStruct, err := crypto.InitializeClient()
+	if err != nil {
+		return err
+	}
+	err = provision.RequestRollingRestart(configStruct.ClusterDetails, reason, nodeGroup, updateHeap)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Rolling restart requested, the master starts it in its next provision check")
+	return nil
+}
@@ -1,13 +1,18 @@
 package config
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
+	"reflect"
 	"regexp"
+	"strconv"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/maplelabs/opensearch-scaling-manager/cluster"
 	"github.com/maplelabs/opensearch-scaling-manager/logger"
+	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
 	"gopkg.in/yaml.v3"
 )
 
@@ -32,6 +37,8 @@ type OsCredentials struct {
 	OsAdminUsername string `yaml:"os_admin_username" validate:"required" json:"os_admin_username"`
 	// OsAdminPassword indicates the OS Admin Password via which OS client can connect to OS Cluster.
 	OsAdminPassword string `yaml:"os_admin_password" validate:"required" json:"os_admin_password"`
+	// external indicates the keys of the credentials read from the environment or a file, which are not encrypted.
+	external map[string]bool
 }
 
 // This struct contains the Cloud Secret Key and Access Key via which we can connect to the cloud.
@@ -43,6 +50,80 @@ type CloudCredentials struct {
 	AccessKey string `yaml:"access_key" validate:"required_without=RoleArn" json:"access_key"`
 	Region    string `yaml:"region" validate:"required" json:"region"`
 	RoleArn   string `yaml:"role_arn" validate:"required_without_all=SecretKey AccessKey" json:"role_arn"`
+	// external indicates the keys of the credentials read from the environment or a file, which are not encrypted.
+	external map[string]bool
+}
+
+// This struct contains the source of the key wrapping the data key which encrypts the credentials.
+type KeyManagement struct {
+	// Source indicates the key source, local keeps the data key in the secret file readable only by its owner.
+	Source string `yaml:"source,omitempty" validate:"omitempty,oneof=local aws_kms vault_transit" json:"source,omitempty"`
+	// KmsKeyId indicates the id, ARN or alias of the AWS KMS key wrapping the data key.
+	KmsKeyId string `yaml:"kms_key_id,omitempty" validate:"required_if=Source aws_kms" json:"kms_key_id,omitempty"`
+	// KmsRegion indicates the region of the KMS key, the region of the cloud credentials by default.
+	KmsRegion string `yaml:"kms_region,omitempty" json:"kms_region,omitempty"`
+	// VaultAddress indicates the address of the Vault server, like https://vault.example.com:8200.
+	VaultAddress string `yaml:"vault_address,omitempty" validate:"required_if=Source vault_transit,omitempty,url" json:"vault_address,omitempty"`
+	// VaultMount indicates the path the transit secrets engine is mounted at, transit by default.
+	VaultMount string `yaml:"vault_mount,omitempty" json:"vault_mount,omitempty"`
+	// VaultTransitKey indicates the name of the transit key wrapping the data key.
+	VaultTransitKey string `yaml:"vault_transit_key,omitempty" validate:"required_if=Source vault_transit" json:"vault_transit_key,omitempty"`
+	// VaultToken indicates the Vault token, the VAULT_TOKEN environment variable is used if it is empty.
+	VaultToken string `yaml:"vault_token,omitempty" json:"-"`
+}
+
+// This struct contains the jump host the master reaches the nodes through over SSH.
+type Bastion struct {
+	// Host indicates the address of the jump host, the nodes are reached directly if it is empty.
+	Host string `yaml:"host,omitempty" json:"host,omitempty"`
+	// Port indicates the SSH port of the jump host, 22 by default.
+	Port int `yaml:"port,omitempty" validate:"omitempty,min=1,max=65535" json:"port,omitempty"`
+	// User indicates the SSH user of the jump host, the os_user by default.
+	User string `yaml:"user,omitempty" json:"user,omitempty"`
+	// PemFilePath indicates the private key of the user of the jump host, the pem_file_path of the cloud
+	// credentials by default.
+	PemFilePath string `yaml:"pem_file_path,omitempty" json:"pem_file_path,omitempty"`
+}
+
+// This struct contains the clean up of the instances launched by the scaling manager which are not nodes of the
+// cluster, like the ones left behind by a failed scale out.
+type OrphanCleanup struct {
+	// Enabled indicates if the orphaned instances are looked for.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// DryRun indicates if the orphaned instances are only reported instead of terminated.
+	DryRun bool `yaml:"dry_run,omitempty" json:"dry_run,omitempty"`
+	// GracePeriodMinutes indicates how long an instance has to be absent from the cluster before it is terminated,
+	// 60 minutes by default.
+	GracePeriodMinutes int `yaml:"grace_period_minutes,omitempty" validate:"omitempty,min=1" json:"grace_period_minutes,omitempty"`
+	// IntervalMinutes indicates how often the instances are reconciled with the nodes, 10 minutes by default.
+	IntervalMinutes int `yaml:"interval_minutes,omitempty" validate:"omitempty,min=1" json:"interval_minutes,omitempty"`
+}
+
+// This struct contains the mix of spot and on-demand instances launched for a node group.
+type Spot struct {
+	// OnDemandBase indicates how many nodes of the group are launched on-demand before spot instances are launched.
+	OnDemandBase int `yaml:"on_demand_base,omitempty" validate:"min=0" json:"on_demand_base,omitempty"`
+	// SpotPercentage indicates the percentage of the nodes above the on-demand base which are spot instances.
+	// Only on-demand instances are launched if not set.
+	SpotPercentage int `yaml:"spot_percentage,omitempty" validate:"min=0,max=100" json:"spot_percentage,omitempty"`
+	// MaxPrice indicates the maximum hourly price paid for a spot instance, the on-demand price if not set.
+	MaxPrice string `yaml:"max_price,omitempty" validate:"omitempty,numeric" json:"max_price,omitempty"`
+	// OnDemandFallback indicates if an on-demand instance is launched when no spot capacity is available.
+	OnDemandFallback bool `yaml:"on_demand_fallback,omitempty" json:"on_demand_fallback,omitempty"`
+}
+
+// This struct contains the detection of the unhealthy nodes which are replaced by the master.
+type AutoHeal struct {
+	// Enabled indicates if the unhealthy nodes are replaced.
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// NodeMissingMinutes indicates how long a node has to be missing from the cluster before it is replaced, 10
+	// minutes by default.
+	NodeMissingMinutes int `yaml:"node_missing_minutes,omitempty" validate:"omitempty,min=1" json:"node_missing_minutes,omitempty"`
+	// MetricsMissingMinutes indicates how long the metrics of a node in the cluster have to be missing before it
+	// is replaced, 15 minutes by default.
+	MetricsMissingMinutes int `yaml:"metrics_missing_minutes,omitempty" validate:"omitempty,min=1" json:"metrics_missing_minutes,omitempty"`
+	// MaxReplacementsPerDay indicates how many nodes may be replaced within 24 hours, 2 by default.
+	MaxReplacementsPerDay int `yaml:"max_replacements_per_day,omitempty" validate:"omitempty,min=1" json:"max_replacements_per_day,omitempty"`
 }
 
 // This struct contains the data structure to parse the cluster details present in the configuration file.
@@ -59,6 +140,54 @@ type ClusterDetails struct {
 	OsCredentials         OsCredentials    `yaml:"os_credentials" json:"os_credentials"`
 	CloudCredentials      CloudCredentials `yaml:"cloud_credentials" json:"cloud_credentials"`
 	JvmFactor             float64          `yaml:"jvm_factor" validate:"required,max=0.5" json:"jvm_factor"`
+	// OsConnection indicates the endpoints, TLS and auth method used to connect to the OS cluster.
+	OsConnection osutils.ConnectionConfig `yaml:"os_connection,omitempty" json:"os_connection,omitempty"`
+	// NodeGroups indicates the groups of nodes which are scaled separately, like dedicated masters, hot data,
+	// warm data and coordinating nodes. All the nodes are scaled as master,data,ingest nodes if not set.
+	NodeGroups []NodeGroup `yaml:"node_groups,omitempty" validate:"omitempty,unique=Name,dive" json:"node_groups,omitempty"`
+	// KeyManagement indicates where the key encrypting the credentials in the config file is kept. The data key
+	// is kept in the secret file readable only by its owner if not set.
+	KeyManagement KeyManagement `yaml:"key_management,omitempty" json:"key_management,omitempty"`
+	// NodeConfigurator indicates how opensearch is configured on the new nodes, with the ansible playbook or
+	// natively over SSH. The ansible playbook is used if not set.
+	NodeConfigurator string `yaml:"node_configurator,omitempty" validate:"omitempty,oneof=ansible native" json:"node_configurator,omitempty"`
+	// ProvisioningMode indicates how a new node is bootstrapped. ssh configures it with the node configurator once
+	// it is up, user_data passes a bootstrap script to the new instance and only waits for it to join the cluster.
+	// ssh is used if not set.
+	ProvisioningMode string `yaml:"provisioning_mode,omitempty" validate:"omitempty,oneof=ssh user_data" json:"provisioning_mode,omitempty"`
+	// Bastion indicates the jump host the playbooks reach the nodes through, when the master can't reach them
+	// directly over SSH.
+	Bastion Bastion `yaml:"bastion,omitempty" json:"bastion,omitempty"`
+	// AutoHeal indicates when the nodes which left the cluster or stopped reporting metrics are replaced. The
+	// nodes are not replaced if not set.
+	AutoHeal AutoHeal `yaml:"auto_heal,omitempty" json:"auto_heal,omitempty"`
+	// Spot indicates the mix of spot and on-demand instances launched when node groups are not configured.
+	Spot Spot `yaml:"spot,omitempty" json:"spot,omitempty"`
+	// OrphanCleanup indicates when the instances launched by the scaling manager which are not nodes of the
+	// cluster are terminated. The instances are not looked for if not set.
+	OrphanCleanup OrphanCleanup `yaml:"orphan_cleanup,omitempty" json:"orphan_cleanup,omitempty"`
+}
+
+// This struct contains a group of nodes which share the roles, attributes and launch template.
+// The nodes provisioned in a group carry the group name in the node_group node attribute.
+type NodeGroup struct {
+	// Name indicates the name of the node group, which is referred by the tasks.
+	Name string `yaml:"name" validate:"required,isValidName" json:"name"`
+	// Roles indicates the opensearch roles of the nodes in the group. Coordinating nodes have no roles.
+	Roles []string `yaml:"roles" validate:"dive,oneof=master cluster_manager data ingest remote_cluster_client" json:"roles"`
+	// Attributes indicates the custom node attributes of the nodes in the group. Ex: temp: hot for hot-warm tiers
+	Attributes map[string]string `yaml:"attributes,omitempty" validate:"dive,keys,isValidAttribute,endkeys,isValidAttribute" json:"attributes,omitempty"`
+	// Tier indicates the data tier, hot or warm, of the nodes in the group. It is set as the temp node attribute
+	// and the indices allocated to a warm node are migrated before the node is removed.
+	Tier                  string `yaml:"tier,omitempty" validate:"omitempty,oneof=hot warm" json:"tier,omitempty"`
+	LaunchTemplateId      string `yaml:"launch_template_id" validate:"required" json:"launch_template_id"`
+	LaunchTemplateVersion string `yaml:"launch_template_version" validate:"required" json:"launch_template_version"`
+	// MaxNodesAllowed indicates the maximum number of nodes in the group.
+	MaxNodesAllowed int `yaml:"max_nodes_allowed" validate:"required,min=1,gtefield=MinNodesAllowed" json:"max_nodes_allowed"`
+	// MinNodesAllowed indicates the minimum number of nodes in the group.
+	MinNodesAllowed int `yaml:"min_nodes_allowed" validate:"min=0" json:"min_nodes_allowed"`
+	// Spot indicates the mix of spot and on-demand instances launched for the group.
+	Spot Spot `yaml:"spot,omitempty" json:"spot,omitempty"`
 }
 
 // Config for application behaviour from user
@@ -69,13 +198,22 @@ type UserConfig struct {
 	RecommendationPollingInterval int  `yaml:"recommendation_polling_interval_in_secs" validate:"required,min=60"`
 	FetchPollingInterval          int  `yaml:"fetchmetrics_polling_interval_in_secs" validate:"required,min=60"`
 	IsAccelerated                 bool `yaml:"is_accelerated"`
+	// FetchCollectionMode indicates how the node metrics are collected. These can be:
+	//      node: Every node indexes its own metrics (default)
+	//      master: The elected master indexes the metrics of all the nodes
+	FetchCollectionMode string `yaml:"fetchmetrics_collection_mode,omitempty" validate:"omitempty,oneof=node master"`
 }
 
 // This struct contains the data structure to parse the configuration file.
 type ConfigStruct struct {
+	// Version indicates the version of the config schema, the file is migrated to ConfigVersion when it is read.
+	Version        int            `yaml:"version,omitempty"`
 	UserConfig     UserConfig     `yaml:"user_config"`
 	ClusterDetails ClusterDetails `yaml:"cluster_details"`
 	TaskDetails    []Task         `yaml:"task_details" validate:"gt=0,dive"`
+	// externalPaths indicates the paths of the fields read from the environment or a file, which are not written
+	// to the config file.
+	externalPaths map[string]bool
 }
 
 // This struct contains the task to be perforrmed by the recommendation and set of rules wrt the action.
@@ -86,6 +224,9 @@ type Task struct {
 	Rules []Rule `yaml:"rules" validate:"gt=0,dive"`
 	// Operator indicates the logical operation needs to be performed while executing the rules
 	Operator string `yaml:"operator" validate:"required,oneof=AND OR EVENT"`
+	// NodeGroup indicates the node group which is scaled by the task and whose metrics are evaluated by the rules.
+	// It is required when node groups are configured.
+	NodeGroup string `yaml:"node_group,omitempty"`
 }
 
 // This struct contains the rule.
@@ -124,36 +265,78 @@ type Rule struct {
 type TaskDetails struct {
 	// Tasks indicates list of task.
 	// A task indicates what operation needs to be recommended by recommendation engine.
-	// As of now tasks can be of two types:
+	// As of now tasks can be of three types:
 	//
 	//      scale_up_by_1
 	//      scale_down_by_1
+	//      scale_vertical_to_3
 	Tasks []Task `yaml:"action" validate:"gt=0,dive"`
 }
 
 // Inputs:
 //
-//	path (string): The path of the configuration file.
-//
 // Description:
 //
-//	This function will be parsing the provided configuration file and populate the ConfigStruct.
+//	This function will be parsing the configuration file and populate the ConfigStruct.
+//	It panics if the file can't be read or parsed, validation errors are returned.
 func GetConfig() (ConfigStruct, error) {
-	yamlConfig, err := os.Open(ConfigFileName)
-	if err != nil {
+	config, err := ReadConfig(ConfigFileName)
+	var validationErrs validator.ValidationErrors
+	if err != nil && !errors.As(err, &validationErrs) {
 		log.Panic.Println("Unable to read the config file: ", err)
 		panic(err)
 	}
-	defer yamlConfig.Close()
-	configByte, _ := ioutil.ReadAll(yamlConfig)
-	var config = new(ConfigStruct)
-	err = yaml.Unmarshal(configByte, &config)
+	return config, err
+}
+
+// Inputs:
+//
+//	fileName (string): The path of the configuration file.
+//
+// Description:
+//
+//	This function will be parsing the provided configuration file, migrate it to the current version, resolve the
+//	${NAME} and file: references in its values, populate the ConfigStruct, apply the SCALING_MANAGER_ environment
+//	overrides and validate it.
+//
+// Return:
+//
+//	(ConfigStruct, error): Return the configuration and the error if the file can't be read, parsed or validated.
+func ReadConfig(fileName string) (ConfigStruct, error) {
+	var config ConfigStruct
+	configByte, err := os.ReadFile(fileName)
 	if err != nil {
-		log.Panic.Println("Unmarshal Error : ", err)
-		panic(err)
+		return config, err
+	}
+	document, err := parseConfigDocument(configByte)
+	if err != nil {
+		return config, err
+	}
+	// Older config files are migrated in memory, the file is only rewritten by the migrate command
+	_, err = migrateDocument(&document)
+	if err != nil {
+		return config, err
+	}
+	external := make(map[string]bool)
+	err = resolveReferences(document.Content[0], "", external)
+	if err != nil {
+		return config, err
 	}
-	err = validation(*config)
-	return *config, err
+	err = document.Decode(&config)
+	if err != nil {
+		return config, err
+	}
+	err = applyEnvOverrides(reflect.ValueOf(&config).Elem(), "", external)
+	if err != nil {
+		return config, err
+	}
+	if len(external) > 0 {
+		config.externalPaths = external
+		config.ClusterDetails.OsCredentials.external = externalFieldsOf(external, "cluster_details.os_credentials")
+		config.ClusterDetails.CloudCredentials.external = externalFieldsOf(external, "cluster_details.cloud_credentials")
+	}
+	err = validation(config)
+	return config, err
 }
 
 // Inputs:
@@ -171,7 +354,9 @@ func validation(config ConfigStruct) error {
 	validate := validator.New()
 	validate.RegisterValidation("isValidName", isValidName)
 	validate.RegisterValidation("isValidTaskName", isValidTaskName)
+	validate.RegisterValidation("isValidAttribute", isValidAttribute)
 	validate.RegisterStructValidation(RuleStructLevelValidation, Rule{})
+	validate.RegisterStructValidation(ConfigStructLevelValidation, ConfigStruct{})
 	err := validate.Struct(config)
 	return err
 }
@@ -206,10 +391,109 @@ func isValidName(fl validator.FieldLevel) bool {
 //
 //	(bool): Return true if there is a valid Task name else false.
 func isValidTaskName(fl validator.FieldLevel) bool {
-	TaskNameRegexString := `scale_(up|down)_by_[0-9]+`
-	TaskNameRegex := regexp.MustCompile(TaskNameRegexString)
+	_, err := ParseTaskName(fl.Field().String())
+	return err == nil
+}
 
-	return TaskNameRegex.MatchString(fl.Field().String())
+// Regex of the task names: scale_up_by_<n>, scale_down_by_<n> and scale_vertical_to_<launch template version>,
+// where the version is a number, $Latest or $Default
+var taskNameRegex = regexp.MustCompile(`^(?:(scale_up|scale_down)_by_([0-9]+)|(scale_vertical)_to_([0-9]+|\$Latest|\$Default))$`)
+
+// This struct contains the operation of a task, parsed from its name.
+type TaskAction struct {
+	// Operation indicates if the task is a scale_up, scale_down or scale_vertical.
+	Operation string
+	// NumNodes indicates the number of nodes added or removed by a scale_up or scale_down.
+	NumNodes int
+	// LaunchTemplateVersion indicates the version of the launch template the nodes are replaced with by a
+	// scale_vertical.
+	LaunchTemplateVersion string
+}
+
+// Inputs:
+//
+//	taskName (string): Name of the task, like scale_up_by_1 or scale_vertical_to_3
+//
+// Description:
+//
+//	Parses the operation of the task from its name
+//
+// Return:
+//
+//	(TaskAction, error): Returns the operation of the task and error if the name is invalid
+func ParseTaskName(taskName string) (TaskAction, error) {
+	subMatch := taskNameRegex.FindStringSubmatch(taskName)
+	if subMatch == nil {
+		return TaskAction{}, errors.New("invalid task name " + taskName + ", expected scale_up_by_<n>, scale_down_by_<n> or scale_vertical_to_<launch template version>")
+	}
+	if subMatch[3] != "" {
+		return TaskAction{Operation: subMatch[3], LaunchTemplateVersion: subMatch[4]}, nil
+	}
+	numNodes, _ := strconv.Atoi(subMatch[2])
+	return TaskAction{Operation: subMatch[1], NumNodes: numNodes}, nil
+}
+
+// Inputs:
+//
+// Description:
+//
+//	Returns the operation the rules of the task are evaluated for. The rules of a scale_vertical are evaluated like
+//	the ones of a scale_up: they are satisfied when the metrics are above the limits.
+//
+// Return:
+//
+//	(string): Returns scale_up or scale_down
+func (a TaskAction) RuleOperation() string {
+	if a.Operation == "scale_vertical" {
+		return "scale_up"
+	}
+	return a.Operation
+}
+
+// Inputs:
+//
+//	fl (validator.FieldLevel): The field which needs to be validated.
+//
+// Description:
+//
+//	This function will be validating the key or value of a node attribute.
+//	These are written to the ansible inventory, so only a restricted set of characters is allowed.
+//
+// Return:
+//
+//	(bool): Return true if there is a valid attribute key or value else false.
+func isValidAttribute(fl validator.FieldLevel) bool {
+	attributeRegexString := `^[a-zA-Z0-9_\-]+$`
+	attributeRegex := regexp.MustCompile(attributeRegexString)
+
+	return attributeRegex.MatchString(fl.Field().String())
+}
+
+// Inputs:
+//
+//	sl (validator.StructLevel): The ConfigStruct which needs to be validated.
+//
+// Description:
+//
+//	This function will be validating that the tasks refer to the configured node groups.
+//	When node groups are configured, every task has to specify the node group it scales.
+//
+// Return:
+func ConfigStructLevelValidation(sl validator.StructLevel) {
+	config := sl.Current().Interface().(ConfigStruct)
+
+	for i, task := range config.TaskDetails {
+		fieldName := "TaskDetails[" + strconv.Itoa(i) + "].NodeGroup"
+		if len(config.ClusterDetails.NodeGroups) == 0 {
+			if task.NodeGroup != "" {
+				sl.ReportError(task.NodeGroup, fieldName, "NodeGroup", "excluded_without", "NodeGroups")
+			}
+		} else if task.NodeGroup == "" {
+			sl.ReportError(task.NodeGroup, fieldName, "NodeGroup", "required_with", "NodeGroups")
+		} else if _, err := config.ClusterDetails.GetNodeGroup(task.NodeGroup); err != nil {
+			sl.ReportError(task.NodeGroup, fieldName, "NodeGroup", "oneof", "NodeGroups")
+		}
+	}
 }
 
 // Inputs:
@@ -229,27 +513,28 @@ func RuleStructLevelValidation(sl validator.StructLevel) {
 
 	if tasks.Operator == "AND" || tasks.Operator == "OR" {
 		if rule.Stat != "COUNT" && rule.Occurrences > 0 {
-			sl.ReportError(rule.Stat, "occurrences", "Occurrences", "excluded_unless", "")
+			sl.ReportError(rule.Occurrences, "Occurrences", "Occurrences", "excluded_unless", "Stat COUNT")
 		}
 		if rule.Metric != "CpuUtil" && rule.Metric != "RamUtil" && rule.Metric != "DiskUtil" &&
-			rule.Metric != "HeapUtil" && rule.Metric != "NumShards" && rule.Metric != "ShardsPerGB" {
-			sl.ReportError(rule.Metric, "metric", "Metric", "OneOf", "")
+			rule.Metric != "HeapUtil" && rule.Metric != "NumShards" && rule.Metric != "ShardsPerGB" &&
+			rule.Metric != "IndexingPressure" {
+			sl.ReportError(rule.Metric, "Metric", "Metric", "oneof", "CpuUtil RamUtil DiskUtil HeapUtil NumShards ShardsPerGB IndexingPressure")
 		}
 		if rule.Limit <= 0 {
-			sl.ReportError(rule.Limit, "Limit", "Limit", "required", "")
+			sl.ReportError(rule.Limit, "Limit", "Limit", "gt", "0")
 		}
 		if rule.Stat != "AVG" && rule.Stat != "COUNT" && rule.Stat != "TERM" {
-			sl.ReportError(rule.Stat, "Stat", "Stat", "OneOf", "")
+			sl.ReportError(rule.Stat, "Stat", "Stat", "oneof", "AVG COUNT TERM")
 		}
 		if rule.DecisionPeriod < 60 {
-			sl.ReportError(rule.DecisionPeriod, "DecisionPeriod", "DecisionPeriod", "required,min", "")
+			sl.ReportError(rule.DecisionPeriod, "DecisionPeriod", "DecisionPeriod", "min", "60")
 		}
 		if rule.Stat == "COUNT" && rule.Occurrences > 100 {
-			sl.ReportError(rule.Occurrences, "Occurrences", "Occurrences", "required,max", "")
+			sl.ReportError(rule.Occurrences, "Occurrences", "Occurrences", "max", "100")
 		}
 	} else if tasks.Operator == "EVENT" {
 		if rule.SchedulingTime == "" {
-			sl.ReportError(rule.SchedulingTime, "SchedulingTime", "scheduling_time", "required", "")
+			sl.ReportError(rule.SchedulingTime, "SchedulingTime", "SchedulingTime", "required", "")
 		}
 		// if rule.NumNodesRequired <= 0 {
 		//      sl.ReportError(rule.NumNodesRequired, "NumNodesRequired", "number_of_node", "required", "")
@@ -257,6 +542,278 @@ func RuleStructLevelValidation(sl validator.StructLevel) {
 	}
 }
 
+// Inputs:
+//
+//	name (string): Name of the node group, empty if node groups are not configured
+//
+// Caller:
+//
+//	Object of ClusterDetails
+//
+// Description:
+//
+//	Returns the node group with the given name. When no node groups are configured, a default group with all
+//	the nodes of the cluster is returned, scaled with the cluster level launch template and node limits.
+//
+// Return:
+//
+//	(NodeGroup, error): Returns the node group and error if it is not configured
+func (c ClusterDetails) GetNodeGroup(name string) (NodeGroup, error) {
+	if len(c.NodeGroups) == 0 {
+		if name != "" {
+			return NodeGroup{}, errors.New("node group " + name + " is not configured")
+		}
+		return NodeGroup{
+			Roles:                 []string{"master", "data", "ingest"},
+			LaunchTemplateId:      c.LaunchTemplateId,
+			LaunchTemplateVersion: c.LaunchTemplateVersion,
+			MaxNodesAllowed:       c.MaxNodesAllowed,
+			MinNodesAllowed:       c.MinNodesAllowed,
+			Spot:                  c.Spot,
+		}, nil
+	}
+	if name == "" {
+		return NodeGroup{}, errors.New("node group is required when node groups are configured")
+	}
+	for _, nodeGroup := range c.NodeGroups {
+		if nodeGroup.Name == name {
+			return nodeGroup, nil
+		}
+	}
+	return NodeGroup{}, errors.New("node group " + name + " is not configured")
+}
+
+// Inputs:
+//
+// Caller:
+//
+//	Object of ClusterDetails
+//
+// Description:
+//
+//	Checks if spot instances are launched for any node group, in which case their interruption notices are polled
+//
+// Return:
+//
+//	(bool): Returns true if a node group launches spot instances
+func (c ClusterDetails) UsesSpot() bool {
+	if len(c.NodeGroups) == 0 {
+		return c.Spot.SpotPercentage > 0
+	}
+	for _, nodeGroup := range c.NodeGroups {
+		if nodeGroup.Spot.SpotPercentage > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Inputs:
+//
+// Caller:
+//
+//	Object of OrphanCleanup
+//
+// Description:
+//
+//	Returns how long an instance has to be absent from the cluster before it is terminated
+//
+// Return:
+//
+//	(time.Duration): Returns the grace_period_minutes, 60 minutes if not set
+func (o OrphanCleanup) GracePeriod() time.Duration {
+	if o.GracePeriodMinutes == 0 {
+		return 60 * time.Minute
+	}
+	return time.Duration(o.GracePeriodMinutes) * time.Minute
+}
+
+// Inputs:
+//
+// Caller:
+//
+//	Object of OrphanCleanup
+//
+// Description:
+//
+//	Returns how often the instances are reconciled with the nodes of the cluster
+//
+// Return:
+//
+//	(time.Duration): Returns the interval_minutes, 10 minutes if not set
+func (o OrphanCleanup) Interval() time.Duration {
+	if o.IntervalMinutes == 0 {
+		return 10 * time.Minute
+	}
+	return time.Duration(o.IntervalMinutes) * time.Minute
+}
+
+// Inputs:
+//
+// Caller:
+//
+//	Object of AutoHeal
+//
+// Description:
+//
+//	Returns how long a node has to be missing from the cluster before it is replaced
+//
+// Return:
+//
+//	(time.Duration): Returns the node_missing_minutes, 10 minutes if not set
+func (a AutoHeal) NodeMissingWindow() time.Duration {
+	if a.NodeMissingMinutes == 0 {
+		return 10 * time.Minute
+	}
+	return time.Duration(a.NodeMissingMinutes) * time.Minute
+}
+
+// Inputs:
+//
+// Caller:
+//
+//	Object of AutoHeal
+//
+// Description:
+//
+//	Returns how long the metrics of a node in the cluster have to be missing before it is replaced
+//
+// Return:
+//
+//	(time.Duration): Returns the metrics_missing_minutes, 15 minutes if not set
+func (a AutoHeal) MetricsMissingWindow() time.Duration {
+	if a.MetricsMissingMinutes == 0 {
+		return 15 * time.Minute
+	}
+	return time.Duration(a.MetricsMissingMinutes) * time.Minute
+}
+
+// Inputs:
+//
+// Caller:
+//
+//	Object of AutoHeal
+//
+// Description:
+//
+//	Returns how many nodes may be replaced within 24 hours
+//
+// Return:
+//
+//	(int): Returns the max_replacements_per_day, 2 if not set
+func (a AutoHeal) ReplacementsPerDay() int {
+	if a.MaxReplacementsPerDay == 0 {
+		return 2
+	}
+	return a.MaxReplacementsPerDay
+}
+
+// Inputs:
+//
+//	node (osutils.NodeStats): Node stats of a node in the cluster
+//
+// Caller:
+//
+//	Object of NodeGroup
+//
+// Description:
+//
+//	Checks if the node belongs to the node group. The default group contains all the nodes.
+//
+// Return:
+//
+//	(bool): Returns true if the node belongs to the group
+func (g NodeGroup) HasNode(node osutils.NodeStats) bool {
+	return g.Name == "" || node.NodeGroup() == g.Name
+}
+
+// Inputs:
+//
+// Caller:
+//
+//	Object of NodeGroup
+//
+// Description:
+//
+//	Checks if the nodes of the group are master eligible
+//
+// Return:
+//
+//	(bool): Returns true if the group has the master or cluster_manager role
+func (g NodeGroup) IsMasterEligible() bool {
+	for _, role := range g.Roles {
+		if role == "master" || role == "cluster_manager" {
+			return true
+		}
+	}
+	return false
+}
+
+// Inputs:
+//
+// Caller:
+//
+//	Object of NodeGroup
+//
+// Description:
+//
+//	Returns the custom node attributes of the nodes provisioned in the group, including the node_group and temp attributes
+//
+// Return:
+//
+//	(map[string]string): Returns the node attributes
+func (g NodeGroup) NodeAttributes() map[string]string {
+	attributes := make(map[string]string)
+	for key, value := range g.Attributes {
+		attributes[key] = value
+	}
+	if g.Name != "" {
+		attributes[osutils.NodeGroupAttribute] = g.Name
+	}
+	if g.Tier != "" {
+		attributes[osutils.TierAttribute] = g.Tier
+	}
+	return attributes
+}
+
+// Input:
+//
+//	key (string): Key of a credential, like os_admin_password
+//
+// Caller:
+//
+//	Object of OsCredentials
+//
+// Description:
+//
+//	Checks if the credential was read from the environment or a file, such a credential is not encrypted
+//
+// Return:
+//
+//	(bool): Returns true if the credential does not come from the config file
+func (c OsCredentials) IsExternal(key string) bool {
+	return c.external[key]
+}
+
+// Input:
+//
+//	key (string): Key of a credential, like secret_key
+//
+// Caller:
+//
+//	Object of CloudCredentials
+//
+// Description:
+//
+//	Checks if the credential was read from the environment or a file, such a credential is not encrypted
+//
+// Return:
+//
+//	(bool): Returns true if the credential does not come from the config file
+func (c CloudCredentials) IsExternal(key string) bool {
+	return c.external[key]
+}
+
 // Inputs:
 //
 //	conf (ConfigStruct) : Credentials encrypted structure of the config.yaml file
@@ -264,19 +821,44 @@ func RuleStructLevelValidation(sl validator.StructLevel) {
 // Description:
 //
 //	This function updates the config.yaml file with encrypted credentials ConfigStruct.
+//	The values are written into the yaml nodes of the current file, so the comments of the user are kept.
+//	The fields read from the environment or a file keep their references or are left out of the file.
+//	The file is written from scratch if the current file can't be parsed.
 //
 // Return:
 //
 //	(error) : Error (if any), else nil
 func UpdateConfigFile(conf ConfigStruct) error {
-	conf_byte, err := yaml.Marshal(&conf)
+	conf.Version = ConfigVersion
+	var updated yaml.Node
+	err := updated.Encode(&conf)
+	if err != nil {
+		log.Error.Println("Error marshalling the ConfigStruct : ", err)
+		return err
+	}
+
+	document := yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{&updated}}
+	currentContent, err := os.ReadFile(ConfigFileName)
+	if err == nil {
+		currentDocument, err := parseConfigDocument(currentContent)
+		if err == nil {
+			// The current file is migrated first so that its keys line up with the updated config
+			_, err = migrateDocument(&currentDocument)
+		}
+		if err == nil {
+			mergeNodes(currentDocument.Content[0], &updated, "", conf.externalPaths)
+			document = currentDocument
+		} else {
+			log.Warn.Println("Unable to parse the current config file, its comments are not kept: ", err)
+		}
+	}
+	yaml_content, err := encodeConfigDocument(&document)
 	if err != nil {
 		log.Error.Println("Error marshalling the ConfigStruct : ", err)
 		return err
 	}
 
-	yaml_content := "---\n" + string(conf_byte)
-	err = ioutil.WriteFile(ConfigFileName, []byte(yaml_content), 0)
+	err = ioutil.WriteFile(ConfigFileName, yaml_content, 0)
 	if err != nil {
 		log.Error.Println("Error writing the config yaml file : ", err)
 		return err
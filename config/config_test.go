@@ -3,11 +3,12 @@ package config
 import (
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v3"
 )
 
 func TestMonitorWithLogs(t *testing.T) {
-	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, polling_interval_in_secs: 10, is_accelerated: false}, cluster_details: {ip_address: 10.81.1.225, cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu ,os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {secret_key: secret_key, access_key: access_key}, base_node_type: t2x.large, number_cpus_per_node: 5, ram_per_node_in_gb: 10, disk_per_node_in_gb: 100, number_max_nodes_allowed: 2}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 9}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}]}]}`
+	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, recommendation_polling_interval_in_secs: 300, fetchmetrics_polling_interval_in_secs: 300, is_accelerated: false}, cluster_details: {cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu, os_group: ubuntu, os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {pem_file_path: /usr/share/pemfile.pem, secret_key: secret_key, access_key: access_key, region: us-west-2}, launch_template_id: lt-000123f47e5c68904, launch_template_version: "1", jvm_factor: 0.5, max_nodes_allowed: 2, min_nodes_allowed: 1}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 60}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}]}]}`
 	config := new(ConfigStruct)
 	err := yaml.Unmarshal([]byte(yamlString), &config)
 	if err != nil {
@@ -23,7 +24,7 @@ func TestMonitorWithLogs(t *testing.T) {
 }
 
 func TestMonitorWithSimulator(t *testing.T) {
-	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, polling_interval_in_secs: 10, is_accelerated: false}, cluster_details: {ip_address: 10.81.1.225, cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu ,os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {secret_key: secret_key, access_key: access_key}, base_node_type: t2x.large, number_cpus_per_node: 5, ram_per_node_in_gb: 10, disk_per_node_in_gb: 100, number_max_nodes_allowed: 2}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 9}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}]}]}`
+	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, recommendation_polling_interval_in_secs: 300, fetchmetrics_polling_interval_in_secs: 300, is_accelerated: false}, cluster_details: {cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu, os_group: ubuntu, os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {pem_file_path: /usr/share/pemfile.pem, secret_key: secret_key, access_key: access_key, region: us-west-2}, launch_template_id: lt-000123f47e5c68904, launch_template_version: "1", jvm_factor: 0.5, max_nodes_allowed: 2, min_nodes_allowed: 1}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 60}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}]}]}`
 	config := new(ConfigStruct)
 	err := yaml.Unmarshal([]byte(yamlString), &config)
 	if err != nil {
@@ -39,7 +40,7 @@ func TestMonitorWithSimulator(t *testing.T) {
 }
 
 func TestPollingIntervalSecs(t *testing.T) {
-	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, polling_interval_in_secs: 10, is_accelerated: false}, cluster_details: {ip_address: 10.81.1.225, cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu ,os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {secret_key: secret_key, access_key: access_key}, base_node_type: t2x.large, number_cpus_per_node: 5, ram_per_node_in_gb: 10, disk_per_node_in_gb: 100, number_max_nodes_allowed: 2}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 9}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}]}]}`
+	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, recommendation_polling_interval_in_secs: 300, fetchmetrics_polling_interval_in_secs: 300, is_accelerated: false}, cluster_details: {cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu, os_group: ubuntu, os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {pem_file_path: /usr/share/pemfile.pem, secret_key: secret_key, access_key: access_key, region: us-west-2}, launch_template_id: lt-000123f47e5c68904, launch_template_version: "1", jvm_factor: 0.5, max_nodes_allowed: 2, min_nodes_allowed: 1}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 60}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}]}]}`
 	config := new(ConfigStruct)
 	err := yaml.Unmarshal([]byte(yamlString), &config)
 	if err != nil {
@@ -55,7 +56,7 @@ func TestPollingIntervalSecs(t *testing.T) {
 }
 
 func TestClusterName(t *testing.T) {
-	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, polling_interval_in_secs: 10, is_accelerated: false}, cluster_details: {ip_address: 10.81.1.225, cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu ,os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {secret_key: secret_key, access_key: access_key}, base_node_type: t2x.large, number_cpus_per_node: 5, ram_per_node_in_gb: 10, disk_per_node_in_gb: 100, number_max_nodes_allowed: 2}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 9}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}]}]}`
+	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, recommendation_polling_interval_in_secs: 300, fetchmetrics_polling_interval_in_secs: 300, is_accelerated: false}, cluster_details: {cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu, os_group: ubuntu, os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {pem_file_path: /usr/share/pemfile.pem, secret_key: secret_key, access_key: access_key, region: us-west-2}, launch_template_id: lt-000123f47e5c68904, launch_template_version: "1", jvm_factor: 0.5, max_nodes_allowed: 2, min_nodes_allowed: 1}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 60}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}]}]}`
 	config := new(ConfigStruct)
 	err := yaml.Unmarshal([]byte(yamlString), &config)
 	if err != nil {
@@ -71,7 +72,7 @@ func TestClusterName(t *testing.T) {
 }
 
 func TestClusterIpAddress(t *testing.T) {
-	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, polling_interval_in_secs: 10, is_accelerated: false}, cluster_details: {ip_address: 10.81.1.225, cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu ,os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {secret_key: secret_key, access_key: access_key}, base_node_type: t2x.large, number_cpus_per_node: 5, ram_per_node_in_gb: 10, disk_per_node_in_gb: 100, number_max_nodes_allowed: 2}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 9}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}]}]}`
+	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, recommendation_polling_interval_in_secs: 300, fetchmetrics_polling_interval_in_secs: 300, is_accelerated: false}, cluster_details: {cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu, os_group: ubuntu, os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {pem_file_path: /usr/share/pemfile.pem, secret_key: secret_key, access_key: access_key, region: us-west-2}, launch_template_id: lt-000123f47e5c68904, launch_template_version: "1", jvm_factor: 0.5, max_nodes_allowed: 2, min_nodes_allowed: 1}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 60}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}]}]}`
 	config := new(ConfigStruct)
 	err := yaml.Unmarshal([]byte(yamlString), &config)
 	if err != nil {
@@ -87,7 +88,7 @@ func TestClusterIpAddress(t *testing.T) {
 }
 
 func TestClusterOsCredentials(t *testing.T) {
-	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, polling_interval_in_secs: 10, is_accelerated: false}, cluster_details: {ip_address: 10.81.1.225, cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu ,os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {secret_key: secret_key, access_key: access_key}, base_node_type: t2x.large, number_cpus_per_node: 5, ram_per_node_in_gb: 10, disk_per_node_in_gb: 100, number_max_nodes_allowed: 2}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 9}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}]}]}`
+	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, recommendation_polling_interval_in_secs: 300, fetchmetrics_polling_interval_in_secs: 300, is_accelerated: false}, cluster_details: {cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu, os_group: ubuntu, os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {pem_file_path: /usr/share/pemfile.pem, secret_key: secret_key, access_key: access_key, region: us-west-2}, launch_template_id: lt-000123f47e5c68904, launch_template_version: "1", jvm_factor: 0.5, max_nodes_allowed: 2, min_nodes_allowed: 1}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 60}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}]}]}`
 	config := new(ConfigStruct)
 	err := yaml.Unmarshal([]byte(yamlString), &config)
 	if err != nil {
@@ -103,7 +104,7 @@ func TestClusterOsCredentials(t *testing.T) {
 }
 
 func TestClusterCloudCredentials(t *testing.T) {
-	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, polling_interval_in_secs: 10, is_accelerated: false}, cluster_details: {ip_address: 10.81.1.225, cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu ,os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {secret_key: secret_key, access_key: access_key}, base_node_type: t2x.large, number_cpus_per_node: 5, ram_per_node_in_gb: 10, disk_per_node_in_gb: 100, number_max_nodes_allowed: 2}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 9}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}]}]}`
+	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, recommendation_polling_interval_in_secs: 300, fetchmetrics_polling_interval_in_secs: 300, is_accelerated: false}, cluster_details: {cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu, os_group: ubuntu, os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {pem_file_path: /usr/share/pemfile.pem, secret_key: secret_key, access_key: access_key, region: us-west-2}, launch_template_id: lt-000123f47e5c68904, launch_template_version: "1", jvm_factor: 0.5, max_nodes_allowed: 2, min_nodes_allowed: 1}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 60}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}]}]}`
 	config := new(ConfigStruct)
 	err := yaml.Unmarshal([]byte(yamlString), &config)
 	if err != nil {
@@ -119,7 +120,7 @@ func TestClusterCloudCredentials(t *testing.T) {
 }
 
 func TestClusterCloudType(t *testing.T) {
-	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, polling_interval_in_secs: 10, is_accelerated: false}, cluster_details: {ip_address: 10.81.1.225, cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu ,os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {secret_key: secret_key, access_key: access_key}, base_node_type: t2x.large, number_cpus_per_node: 5, ram_per_node_in_gb: 10, disk_per_node_in_gb: 100, number_max_nodes_allowed: 2}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 9}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}]}]}`
+	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, recommendation_polling_interval_in_secs: 300, fetchmetrics_polling_interval_in_secs: 300, is_accelerated: false}, cluster_details: {cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu, os_group: ubuntu, os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {pem_file_path: /usr/share/pemfile.pem, secret_key: secret_key, access_key: access_key, region: us-west-2}, launch_template_id: lt-000123f47e5c68904, launch_template_version: "1", jvm_factor: 0.5, max_nodes_allowed: 2, min_nodes_allowed: 1}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 60}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}]}]}`
 	config := new(ConfigStruct)
 	err := yaml.Unmarshal([]byte(yamlString), &config)
 	if err != nil {
@@ -135,7 +136,7 @@ func TestClusterCloudType(t *testing.T) {
 }
 
 func TestClusterBaseNodeType(t *testing.T) {
-	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, polling_interval_in_secs: 10, is_accelerated: false}, cluster_details: {ip_address: 10.81.1.225, cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu ,os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {secret_key: secret_key, access_key: access_key}, base_node_type: t2x.large, number_cpus_per_node: 5, ram_per_node_in_gb: 10, disk_per_node_in_gb: 100, number_max_nodes_allowed: 2}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 9}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}]}]}`
+	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, recommendation_polling_interval_in_secs: 300, fetchmetrics_polling_interval_in_secs: 300, is_accelerated: false}, cluster_details: {cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu, os_group: ubuntu, os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {pem_file_path: /usr/share/pemfile.pem, secret_key: secret_key, access_key: access_key, region: us-west-2}, launch_template_id: lt-000123f47e5c68904, launch_template_version: "1", jvm_factor: 0.5, max_nodes_allowed: 2, min_nodes_allowed: 1}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 60}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}]}]}`
 	config := new(ConfigStruct)
 	err := yaml.Unmarshal([]byte(yamlString), &config)
 	if err != nil {
@@ -151,7 +152,7 @@ func TestClusterBaseNodeType(t *testing.T) {
 }
 
 func TestClusterNumCpusPerNode(t *testing.T) {
-	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, polling_interval_in_secs: 10, is_accelerated: false}, cluster_details: {ip_address: 10.81.1.225, cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu ,os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {secret_key: secret_key, access_key: access_key}, base_node_type: t2x.large, number_cpus_per_node: 5, ram_per_node_in_gb: 10, disk_per_node_in_gb: 100, number_max_nodes_allowed: 2}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 9}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}]}]}`
+	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, recommendation_polling_interval_in_secs: 300, fetchmetrics_polling_interval_in_secs: 300, is_accelerated: false}, cluster_details: {cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu, os_group: ubuntu, os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {pem_file_path: /usr/share/pemfile.pem, secret_key: secret_key, access_key: access_key, region: us-west-2}, launch_template_id: lt-000123f47e5c68904, launch_template_version: "1", jvm_factor: 0.5, max_nodes_allowed: 2, min_nodes_allowed: 1}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 60}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}]}]}`
 	config := new(ConfigStruct)
 	err := yaml.Unmarshal([]byte(yamlString), &config)
 	if err != nil {
@@ -167,7 +168,7 @@ func TestClusterNumCpusPerNode(t *testing.T) {
 }
 
 func TestClusterRAMPerNodeInGB(t *testing.T) {
-	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, polling_interval_in_secs: 10, is_accelerated: false}, cluster_details: {ip_address: 10.81.1.225, cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu ,os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {secret_key: secret_key, access_key: access_key}, base_node_type: t2x.large, number_cpus_per_node: 5, ram_per_node_in_gb: 10, disk_per_node_in_gb: 100, number_max_nodes_allowed: 2}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 9}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}]}]}`
+	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, recommendation_polling_interval_in_secs: 300, fetchmetrics_polling_interval_in_secs: 300, is_accelerated: false}, cluster_details: {cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu, os_group: ubuntu, os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {pem_file_path: /usr/share/pemfile.pem, secret_key: secret_key, access_key: access_key, region: us-west-2}, launch_template_id: lt-000123f47e5c68904, launch_template_version: "1", jvm_factor: 0.5, max_nodes_allowed: 2, min_nodes_allowed: 1}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 60}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}]}]}`
 	config := new(ConfigStruct)
 	err := yaml.Unmarshal([]byte(yamlString), &config)
 	if err != nil {
@@ -183,7 +184,7 @@ func TestClusterRAMPerNodeInGB(t *testing.T) {
 }
 
 func TestClusterDiskPerNodeInGB(t *testing.T) {
-	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, polling_interval_in_secs: 10, is_accelerated: false}, cluster_details: {ip_address: 10.81.1.225, cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu ,os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {secret_key: secret_key, access_key: access_key}, base_node_type: t2x.large, number_cpus_per_node: 5, ram_per_node_in_gb: 10, disk_per_node_in_gb: 100, number_max_nodes_allowed: 2}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 9}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}]}]}`
+	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, recommendation_polling_interval_in_secs: 300, fetchmetrics_polling_interval_in_secs: 300, is_accelerated: false}, cluster_details: {cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu, os_group: ubuntu, os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {pem_file_path: /usr/share/pemfile.pem, secret_key: secret_key, access_key: access_key, region: us-west-2}, launch_template_id: lt-000123f47e5c68904, launch_template_version: "1", jvm_factor: 0.5, max_nodes_allowed: 2, min_nodes_allowed: 1}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 60}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}]}]}`
 	config := new(ConfigStruct)
 	err := yaml.Unmarshal([]byte(yamlString), &config)
 	if err != nil {
@@ -199,7 +200,7 @@ func TestClusterDiskPerNodeInGB(t *testing.T) {
 }
 
 func TestClusterNumMaxNodesAllowed(t *testing.T) {
-	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, polling_interval_in_secs: 10, is_accelerated: false}, cluster_details: {ip_address: 10.81.1.225, cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu ,os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {secret_key: secret_key, access_key: access_key}, base_node_type: t2x.large, number_cpus_per_node: 5, ram_per_node_in_gb: 10, disk_per_node_in_gb: 100, number_max_nodes_allowed: 2}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 9}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}]}]}`
+	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, recommendation_polling_interval_in_secs: 300, fetchmetrics_polling_interval_in_secs: 300, is_accelerated: false}, cluster_details: {cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu, os_group: ubuntu, os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {pem_file_path: /usr/share/pemfile.pem, secret_key: secret_key, access_key: access_key, region: us-west-2}, launch_template_id: lt-000123f47e5c68904, launch_template_version: "1", jvm_factor: 0.5, max_nodes_allowed: 2, min_nodes_allowed: 1}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 60}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}]}]}`
 	config := new(ConfigStruct)
 	err := yaml.Unmarshal([]byte(yamlString), &config)
 	if err != nil {
@@ -215,7 +216,7 @@ func TestClusterNumMaxNodesAllowed(t *testing.T) {
 }
 
 func TestTask(t *testing.T) {
-	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, polling_interval_in_secs: 10, is_accelerated: false}, cluster_details: {ip_address: 10.81.1.225, cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu ,os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {secret_key: secret_key, access_key: access_key}, base_node_type: t2x.large, number_cpus_per_node: 5, ram_per_node_in_gb: 10, disk_per_node_in_gb: 100, number_max_nodes_allowed: 2}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 9}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}]}]}`
+	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, recommendation_polling_interval_in_secs: 300, fetchmetrics_polling_interval_in_secs: 300, is_accelerated: false}, cluster_details: {cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu, os_group: ubuntu, os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {pem_file_path: /usr/share/pemfile.pem, secret_key: secret_key, access_key: access_key, region: us-west-2}, launch_template_id: lt-000123f47e5c68904, launch_template_version: "1", jvm_factor: 0.5, max_nodes_allowed: 2, min_nodes_allowed: 1}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 60}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}]}]}`
 	config := new(ConfigStruct)
 	err := yaml.Unmarshal([]byte(yamlString), &config)
 	if err != nil {
@@ -231,7 +232,7 @@ func TestTask(t *testing.T) {
 }
 
 func TestTaskName(t *testing.T) {
-	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, polling_interval_in_secs: 10, is_accelerated: false}, cluster_details: {ip_address: 10.81.1.225, cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu ,os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {secret_key: secret_key, access_key: access_key}, base_node_type: t2x.large, number_cpus_per_node: 5, ram_per_node_in_gb: 10, disk_per_node_in_gb: 100, number_max_nodes_allowed: 2}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 9}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}]}]}`
+	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, recommendation_polling_interval_in_secs: 300, fetchmetrics_polling_interval_in_secs: 300, is_accelerated: false}, cluster_details: {cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu, os_group: ubuntu, os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {pem_file_path: /usr/share/pemfile.pem, secret_key: secret_key, access_key: access_key, region: us-west-2}, launch_template_id: lt-000123f47e5c68904, launch_template_version: "1", jvm_factor: 0.5, max_nodes_allowed: 2, min_nodes_allowed: 1}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 60}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}]}]}`
 	config := new(ConfigStruct)
 	err := yaml.Unmarshal([]byte(yamlString), &config)
 	if err != nil {
@@ -247,7 +248,7 @@ func TestTaskName(t *testing.T) {
 }
 
 func TestTaskOperator(t *testing.T) {
-	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, polling_interval_in_secs: 10, is_accelerated: false}, cluster_details: {ip_address: 10.81.1.225, cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu ,os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {secret_key: secret_key, access_key: access_key}, base_node_type: t2x.large, number_cpus_per_node: 5, ram_per_node_in_gb: 10, disk_per_node_in_gb: 100, number_max_nodes_allowed: 2}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 9}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}]}]}`
+	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, recommendation_polling_interval_in_secs: 300, fetchmetrics_polling_interval_in_secs: 300, is_accelerated: false}, cluster_details: {cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu, os_group: ubuntu, os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {pem_file_path: /usr/share/pemfile.pem, secret_key: secret_key, access_key: access_key, region: us-west-2}, launch_template_id: lt-000123f47e5c68904, launch_template_version: "1", jvm_factor: 0.5, max_nodes_allowed: 2, min_nodes_allowed: 1}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 60}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}]}]}`
 	config := new(ConfigStruct)
 	err := yaml.Unmarshal([]byte(yamlString), &config)
 	if err != nil {
@@ -263,7 +264,7 @@ func TestTaskOperator(t *testing.T) {
 }
 
 func TestRule(t *testing.T) {
-	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, polling_interval_in_secs: 10, is_accelerated: false}, cluster_details: {ip_address: 10.81.1.225, cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu ,os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {secret_key: secret_key, access_key: access_key}, base_node_type: t2x.large, number_cpus_per_node: 5, ram_per_node_in_gb: 10, disk_per_node_in_gb: 100, number_max_nodes_allowed: 2}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 9}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}]}]}`
+	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, recommendation_polling_interval_in_secs: 300, fetchmetrics_polling_interval_in_secs: 300, is_accelerated: false}, cluster_details: {cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu, os_group: ubuntu, os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {pem_file_path: /usr/share/pemfile.pem, secret_key: secret_key, access_key: access_key, region: us-west-2}, launch_template_id: lt-000123f47e5c68904, launch_template_version: "1", jvm_factor: 0.5, max_nodes_allowed: 2, min_nodes_allowed: 1}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 60}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}]}]}`
 	config := new(ConfigStruct)
 	err := yaml.Unmarshal([]byte(yamlString), &config)
 	if err != nil {
@@ -279,7 +280,7 @@ func TestRule(t *testing.T) {
 }
 
 func TestRuleMetric(t *testing.T) {
-	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, polling_interval_in_secs: 10, is_accelerated: false}, cluster_details: {ip_address: 10.81.1.225, cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu ,os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {secret_key: secret_key, access_key: access_key}, base_node_type: t2x.large, number_cpus_per_node: 5, ram_per_node_in_gb: 10, disk_per_node_in_gb: 100, number_max_nodes_allowed: 2}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 9}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}]}]}`
+	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, recommendation_polling_interval_in_secs: 300, fetchmetrics_polling_interval_in_secs: 300, is_accelerated: false}, cluster_details: {cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu, os_group: ubuntu, os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {pem_file_path: /usr/share/pemfile.pem, secret_key: secret_key, access_key: access_key, region: us-west-2}, launch_template_id: lt-000123f47e5c68904, launch_template_version: "1", jvm_factor: 0.5, max_nodes_allowed: 2, min_nodes_allowed: 1}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 60}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}]}]}`
 	config := new(ConfigStruct)
 	err := yaml.Unmarshal([]byte(yamlString), &config)
 	if err != nil {
@@ -295,7 +296,7 @@ func TestRuleMetric(t *testing.T) {
 }
 
 func TestRuleStat(t *testing.T) {
-	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, polling_interval_in_secs: 10, is_accelerated: false}, cluster_details: {ip_address: 10.81.1.225, cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu ,os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {secret_key: secret_key, access_key: access_key}, base_node_type: t2x.large, number_cpus_per_node: 5, ram_per_node_in_gb: 10, disk_per_node_in_gb: 100, number_max_nodes_allowed: 2}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 9}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}]}]}`
+	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, recommendation_polling_interval_in_secs: 300, fetchmetrics_polling_interval_in_secs: 300, is_accelerated: false}, cluster_details: {cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu, os_group: ubuntu, os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {pem_file_path: /usr/share/pemfile.pem, secret_key: secret_key, access_key: access_key, region: us-west-2}, launch_template_id: lt-000123f47e5c68904, launch_template_version: "1", jvm_factor: 0.5, max_nodes_allowed: 2, min_nodes_allowed: 1}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 60}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}]}]}`
 	config := new(ConfigStruct)
 	err := yaml.Unmarshal([]byte(yamlString), &config)
 	if err != nil {
@@ -311,7 +312,7 @@ func TestRuleStat(t *testing.T) {
 }
 
 func TestRuleDecisionPeriod(t *testing.T) {
-	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, polling_interval_in_secs: 10, is_accelerated: false}, cluster_details: {ip_address: 10.81.1.225, cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu ,os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {secret_key: secret_key, access_key: access_key}, base_node_type: t2x.large, number_cpus_per_node: 5, ram_per_node_in_gb: 10, disk_per_node_in_gb: 100, number_max_nodes_allowed: 2}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 9}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}]}]}`
+	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, recommendation_polling_interval_in_secs: 300, fetchmetrics_polling_interval_in_secs: 300, is_accelerated: false}, cluster_details: {cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu, os_group: ubuntu, os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {pem_file_path: /usr/share/pemfile.pem, secret_key: secret_key, access_key: access_key, region: us-west-2}, launch_template_id: lt-000123f47e5c68904, launch_template_version: "1", jvm_factor: 0.5, max_nodes_allowed: 2, min_nodes_allowed: 1}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 60}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}]}]}`
 	config := new(ConfigStruct)
 	err := yaml.Unmarshal([]byte(yamlString), &config)
 	if err != nil {
@@ -327,7 +328,7 @@ func TestRuleDecisionPeriod(t *testing.T) {
 }
 
 func TestRuleOccurences(t *testing.T) {
-	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, polling_interval_in_secs: 10, is_accelerated: false}, cluster_details: {ip_address: 10.81.1.225, cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu ,os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {secret_key: secret_key, access_key: access_key}, base_node_type: t2x.large, number_cpus_per_node: 5, ram_per_node_in_gb: 10, disk_per_node_in_gb: 100, number_max_nodes_allowed: 2}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 9}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 9}]}]}`
+	yamlString := `{user_config: {monitor_with_logs: true, monitor_with_simulator: false, purge_old_docs_after_hours: 50, recommendation_polling_interval_in_secs: 300, fetchmetrics_polling_interval_in_secs: 300, is_accelerated: false}, cluster_details: {cluster_name: cluster-1, os_credentials: {os_admin_username: elastic, os_admin_password: changeme}, os_user: ubuntu, os_group: ubuntu, os_version: 2.3.0, os_home: /usr/share/opensearch, domain_name: snappyflow.com, cloud_type: AWS, cloud_credentials: {pem_file_path: /usr/share/pemfile.pem, secret_key: secret_key, access_key: access_key, region: us-west-2}, launch_template_id: lt-000123f47e5c68904, launch_template_version: "1", jvm_factor: 0.5, max_nodes_allowed: 2, min_nodes_allowed: 1}, task_details: [{task_name: scale_up_by_1, operator: OR, rules: [{metric: CpuUtil, limit: 2, stat: AVG, decision_period: 60}, {metric: CpuUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}, {metric: RamUtil, limit: 1, stat: COUNT, occurrences: 10, decision_period: 60}]}]}`
 	config := new(ConfigStruct)
 	err := yaml.Unmarshal([]byte(yamlString), &config)
 	if err != nil {
@@ -343,10 +344,24 @@ func TestRuleOccurences(t *testing.T) {
 }
 
 func TestConfig(t *testing.T) {
-	config, err := GetConfig("../config.yaml")
+	config, err := ReadConfig("../config.yaml")
 	if err != nil {
 		t.Fail()
 		t.Logf("expected validation got %v", err)
 	}
 	t.Log(config)
 }
+
+func TestParseTaskName(t *testing.T) {
+	action, err := ParseTaskName("scale_up_by_2")
+	assert.Nil(t, err)
+	assert.Equal(t, TaskAction{Operation: "scale_up", NumNodes: 2}, action)
+
+	action, err = ParseTaskName("scale_vertical_to_$Latest")
+	assert.Nil(t, err)
+	assert.Equal(t, TaskAction{Operation: "scale_vertical", LaunchTemplateVersion: "$Latest"}, action)
+	assert.Equal(t, "scale_up", action.RuleOperation())
+
+	_, err = ParseTaskName("scale_vertical_to_m5.xlarge")
+	assert.EqualError(t, err, "invalid task name scale_vertical_to_m5.xlarge, expected scale_up_by_<n>, scale_down_by_<n> or scale_vertical_to_<launch template version>")
+}
@@ -0,0 +1,243 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Version of the config schema read and written by this scaling manager. Config files without a version
+// key are version 0.
+const ConfigVersion = 1
+
+// Migrations of the config file keyed by the version they upgrade from, each one upgrades to the next version.
+// They work on the yaml nodes of the file so that the comments of the user are kept. The version key is set by
+// migrateDocument after every migration.
+var migrations = map[int]func(root *yaml.Node) error{
+	0: migrateUnversioned,
+}
+
+// Input:
+//
+//	root (*yaml.Node): Mapping node at the root of a version 0 config file
+//
+// Description:
+//
+//	Upgrades a config file without a version to version 1. The unversioned files already have the schema of
+//	version 1, only the version key is added.
+//
+// Return:
+//
+//	(error): Returns error if the file can't be migrated
+func migrateUnversioned(root *yaml.Node) error {
+	return nil
+}
+
+// Input:
+//
+//	content ([]byte): Content of a config file
+//
+// Description:
+//
+//	Migrates the content of a config file to the current version, keeping its comments and layout
+//
+// Return:
+//
+//	([]byte, int, error): Returns the migrated content, the version the file had and error if any
+func MigrateConfig(content []byte) ([]byte, int, error) {
+	document, err := parseConfigDocument(content)
+	if err != nil {
+		return nil, 0, err
+	}
+	fromVersion, err := migrateDocument(&document)
+	if err != nil {
+		return nil, fromVersion, err
+	}
+	migratedContent, err := encodeConfigDocument(&document)
+	return migratedContent, fromVersion, err
+}
+
+// Input:
+//
+//	content ([]byte): Content of a config file
+//
+// Description:
+//
+//	Parses the content of a config file into yaml nodes, which keep the comments
+//
+// Return:
+//
+//	(yaml.Node, error): Returns the document node and error if the content is not a yaml mapping
+func parseConfigDocument(content []byte) (yaml.Node, error) {
+	var document yaml.Node
+	err := yaml.Unmarshal(content, &document)
+	if err != nil {
+		return document, err
+	}
+	if document.Kind != yaml.DocumentNode || len(document.Content) != 1 || document.Content[0].Kind != yaml.MappingNode {
+		return document, fmt.Errorf("the config file should be a yaml mapping")
+	}
+	return document, nil
+}
+
+// Input:
+//
+//	document (*yaml.Node): Document node of a config file
+//
+// Description:
+//
+//	Applies the migrations from the version of the document up to the current version and sets the version key
+//
+// Return:
+//
+//	(int, error): Returns the version the document had and error if it is newer than the current version or a
+//	migration fails
+func migrateDocument(document *yaml.Node) (int, error) {
+	root := document.Content[0]
+	fromVersion := 0
+	if versionNode := mappingValue(root, "version"); versionNode != nil {
+		version, err := strconv.Atoi(versionNode.Value)
+		if err != nil || version < 0 {
+			return 0, fmt.Errorf("the config version %s should be a positive number", versionNode.Value)
+		}
+		fromVersion = version
+	}
+	if fromVersion > ConfigVersion {
+		return fromVersion, fmt.Errorf("the config version %d is newer than the version %d supported by this scaling manager", fromVersion, ConfigVersion)
+	}
+	for version := fromVersion; version < ConfigVersion; version++ {
+		err := migrations[version](root)
+		if err != nil {
+			return fromVersion, fmt.Errorf("unable to migrate the config from version %d: %w", version, err)
+		}
+		setVersion(root, version+1)
+	}
+	return fromVersion, nil
+}
+
+// Input:
+//
+//	root (*yaml.Node): Mapping node at the root of a config file
+//	version (int): Version to be set
+//
+// Description:
+//
+//	Sets the version key of the config file, adding it as the first key if the file has none
+//
+// Return:
+func setVersion(root *yaml.Node, version int) {
+	if versionNode := mappingValue(root, "version"); versionNode != nil {
+		versionNode.Value = strconv.Itoa(version)
+		return
+	}
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version",
+		HeadComment: "# Version of the config schema, upgraded with: scaling_manager config migrate"}
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(version)}
+	root.Content = append([]*yaml.Node{key, value}, root.Content...)
+}
+
+// Input:
+//
+//	document (*yaml.Node): Document node of a config file
+//
+// Description:
+//
+//	Encodes the document with the layout of the shipped config.yaml, a document start and 4 spaces indentation
+//
+// Return:
+//
+//	([]byte, error): Returns the content of the config file and error if any
+func encodeConfigDocument(document *yaml.Node) ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString("---\n")
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(4)
+	err := encoder.Encode(document)
+	if err != nil {
+		return nil, err
+	}
+	err = encoder.Close()
+	return buffer.Bytes(), err
+}
+
+// Input:
+//
+//	mapping (*yaml.Node): Mapping node
+//	key (string): Key to be found
+//
+// Description:
+//
+//	Finds the value of a key in a mapping node
+//
+// Return:
+//
+//	(*yaml.Node): Returns the value node, nil if the key is not found
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// Input:
+//
+//	dst (*yaml.Node): Node parsed from the config file, which is updated
+//	src (*yaml.Node): Node encoded from the updated config
+//	path (string): Path of the nodes in the config file
+//	external (map[string]bool): Paths of the fields read from the environment or a file, which are not written
+//
+// Description:
+//
+//	Updates the parsed node with the values of the encoded node, keeping the comments, order and quoting of the
+//	values which did not change. Keys missing from the encoded node are removed and new keys are appended.
+//	The external fields keep their references, or are not added if the config file does not have them.
+//
+// Return:
+func mergeNodes(dst *yaml.Node, src *yaml.Node, path string, external map[string]bool) {
+	if external[path] {
+		return
+	}
+	if dst.Kind != src.Kind {
+		headComment, lineComment, footComment := dst.HeadComment, dst.LineComment, dst.FootComment
+		*dst = *src
+		dst.HeadComment, dst.LineComment, dst.FootComment = headComment, lineComment, footComment
+		return
+	}
+	switch dst.Kind {
+	case yaml.MappingNode:
+		var content []*yaml.Node
+		for i := 0; i+1 < len(dst.Content); i += 2 {
+			key := dst.Content[i].Value
+			if value := mappingValue(src, key); value != nil {
+				mergeNodes(dst.Content[i+1], value, childPath(path, key), external)
+				content = append(content, dst.Content[i], dst.Content[i+1])
+			} else if external[childPath(path, key)] {
+				content = append(content, dst.Content[i], dst.Content[i+1])
+			}
+		}
+		for i := 0; i+1 < len(src.Content); i += 2 {
+			key := src.Content[i].Value
+			if mappingValue(dst, key) == nil && !external[childPath(path, key)] {
+				content = append(content, src.Content[i], src.Content[i+1])
+			}
+		}
+		dst.Content = content
+	case yaml.SequenceNode:
+		for i, item := range src.Content {
+			if i < len(dst.Content) {
+				mergeNodes(dst.Content[i], item, fmt.Sprintf("%s[%d]", path, i), external)
+			} else {
+				dst.Content = append(dst.Content, item)
+			}
+		}
+		dst.Content = dst.Content[:len(src.Content)]
+	default:
+		if dst.Value != src.Value {
+			dst.Value, dst.Tag, dst.Style = src.Value, src.Tag, src.Style
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrateConfig(t *testing.T) {
+	content, err := os.ReadFile("testdata/unversioned_config.yaml")
+	assert.NoError(t, err)
+
+	migrated, fromVersion, err := MigrateConfig(content)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fromVersion)
+	expected := "---\n# Version of the config schema, upgraded with: scaling_manager config migrate\nversion: 1\n" + strings.TrimPrefix(string(content), "---\n")
+	assert.Equal(t, expected, string(migrated))
+
+	// A migrated file is left as it is
+	remigrated, fromVersion, err := MigrateConfig(migrated)
+	assert.NoError(t, err)
+	assert.Equal(t, ConfigVersion, fromVersion)
+	assert.Equal(t, string(migrated), string(remigrated))
+}
+
+func TestMigrateConfigNewerVersion(t *testing.T) {
+	_, fromVersion, err := MigrateConfig([]byte("version: 9\nuser_config: {}\n"))
+	assert.Error(t, err)
+	assert.Equal(t, 9, fromVersion)
+
+	_, _, err = MigrateConfig([]byte("version: latest\n"))
+	assert.Error(t, err)
+
+	_, _, err = MigrateConfig([]byte("- user_config\n"))
+	assert.Error(t, err)
+}
+
+func TestReadConfigUnversioned(t *testing.T) {
+	configStruct, err := ReadConfig("testdata/unversioned_config.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, ConfigVersion, configStruct.Version)
+	assert.Equal(t, "cluster.1", configStruct.ClusterDetails.ClusterName)
+}
+
+func TestUpdateConfigFileKeepsComments(t *testing.T) {
+	content, err := os.ReadFile("testdata/unversioned_config.yaml")
+	assert.NoError(t, err)
+	fileName := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(fileName, content, 0644))
+	defaultFileName := ConfigFileName
+	ConfigFileName = fileName
+	defer func() { ConfigFileName = defaultFileName }()
+
+	configStruct, err := ReadConfig(fileName)
+	assert.NoError(t, err)
+	configStruct.ClusterDetails.OsCredentials.OsAdminPassword = "ENCRYPTED=="
+	configStruct.ClusterDetails.CloudCredentials.SecretKey = "123"
+	configStruct.TaskDetails = configStruct.TaskDetails[:3]
+	assert.NoError(t, UpdateConfigFile(configStruct))
+
+	updated, err := os.ReadFile(fileName)
+	assert.NoError(t, err)
+	assert.Contains(t, string(updated), "    # opensearch cluster name\n    cluster_name: cluster.1\n")
+	assert.Contains(t, string(updated), "    # Optional, connects to http://localhost:9200 with os_credentials if not set\n")
+	assert.Contains(t, string(updated), "        os_admin_password: ENCRYPTED==\n")
+	assert.Contains(t, string(updated), "    launch_template_version: \"1\"\n")
+	assert.True(t, strings.HasPrefix(string(updated), "---\n# Version of the config schema, upgraded with: scaling_manager config migrate\nversion: 1\nuser_config:\n"))
+
+	updatedStruct, err := ReadConfig(fileName)
+	assert.NoError(t, err)
+	assert.Equal(t, configStruct, updatedStruct)
+}
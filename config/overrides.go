@@ -0,0 +1,278 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Prefix of the environment variables overriding the fields of the config file, followed by the path of the
+// field in upper case with "_" between the keys, like SCALING_MANAGER_CLUSTER_DETAILS_OS_CREDENTIALS_OS_ADMIN_PASSWORD
+const EnvOverridePrefix = "SCALING_MANAGER_"
+
+// Prefix of the values read from a file, like file:/run/credentials/scaling_manager/os_admin_password
+const fileReferencePrefix = "file:"
+
+// Matches the environment variable references in the values of the config file, like ${OS_ADMIN_PASSWORD}
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Input:
+//
+//	value (string): Value of a field in the config file or of an override
+//
+// Description:
+//
+//	Resolves the references in a value. The ${NAME} references are replaced with the environment variables, then
+//	a value starting with file: is replaced with the content of the file, without the trailing newline.
+//
+// Return:
+//
+//	(string, bool, error): Returns the resolved value, true if it had references and error if an environment
+//	variable is not set or the file can't be read
+func resolveValue(value string) (string, bool, error) {
+	hasReference := false
+	var err error
+	value = envReference.ReplaceAllStringFunc(value, func(reference string) string {
+		hasReference = true
+		name := envReference.FindStringSubmatch(reference)[1]
+		envValue, ok := os.LookupEnv(name)
+		if !ok && err == nil {
+			err = fmt.Errorf("the environment variable %s is not set", name)
+		}
+		return envValue
+	})
+	if err != nil {
+		return value, hasReference, err
+	}
+	if strings.HasPrefix(value, fileReferencePrefix) {
+		content, err := os.ReadFile(strings.TrimPrefix(value, fileReferencePrefix))
+		if err != nil {
+			return value, true, err
+		}
+		return strings.TrimRight(string(content), "\r\n"), true, nil
+	}
+	return value, hasReference, nil
+}
+
+// Input:
+//
+//	node (*yaml.Node): Node of the config file
+//	path (string): Path of the node in the config file
+//	external (map[string]bool): Paths of the fields whose values do not come from the config file, updated
+//
+// Description:
+//
+//	Resolves the references in the values of the node and its children before they are decoded, so that the
+//	references can be used for fields of any type
+//
+// Return:
+//
+//	(error): Returns error if a reference can't be resolved
+func resolveReferences(node *yaml.Node, path string, external map[string]bool) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			err := resolveReferences(node.Content[i+1], childPath(path, node.Content[i].Value), external)
+			if err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			err := resolveReferences(item, fmt.Sprintf("%s[%d]", path, i), external)
+			if err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		value, hasReference, err := resolveValue(node.Value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if hasReference {
+			// The type is resolved from the value, unless the reference was quoted as a string
+			node.Value, node.Tag = value, ""
+			external[path] = true
+		}
+	}
+	return nil
+}
+
+// Input:
+//
+//	value (reflect.Value): Settable value of a field of the config
+//	path (string): Path of the field in the config file
+//	external (map[string]bool): Paths of the fields whose values do not come from the config file, updated
+//
+// Description:
+//
+//	Overrides the fields with the SCALING_MANAGER_ environment variables named after their path. Fields in lists
+//	and maps can only be overridden for the entries in the config file, like SCALING_MANAGER_TASK_DETAILS_0_RULES_1_LIMIT,
+//	and lists of values are overridden with a comma separated list. The override values can hold references.
+//
+// Return:
+//
+//	(error): Returns error if an override can't be resolved or parsed
+func applyEnvOverrides(value reflect.Value, path string, external map[string]bool) error {
+	switch value.Kind() {
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			tag := field.Tag.Get("yaml")
+			fieldPath := path
+			// Inlined structs are left out of the path
+			if !strings.Contains(tag, "inline") {
+				name := strings.Split(tag, ",")[0]
+				if name == "-" {
+					continue
+				}
+				if name == "" {
+					name = strings.ToLower(field.Name)
+				}
+				fieldPath = childPath(path, name)
+			}
+			err := applyEnvOverrides(value.Field(i), fieldPath, external)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.Struct {
+			for i := 0; i < value.Len(); i++ {
+				err := applyEnvOverrides(value.Index(i), fmt.Sprintf("%s[%d]", path, i), external)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case reflect.Map:
+		for _, key := range value.MapKeys() {
+			element := reflect.New(value.Type().Elem()).Elem()
+			element.Set(value.MapIndex(key))
+			keyPath := childPath(path, fmt.Sprint(key.Interface()))
+			err := applyEnvOverrides(element, keyPath, external)
+			if err != nil {
+				return err
+			}
+			value.SetMapIndex(key, element)
+		}
+		return nil
+	}
+
+	name := envOverrideName(path)
+	override, ok := os.LookupEnv(name)
+	if !ok {
+		return nil
+	}
+	override, _, err := resolveValue(override)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(override)
+	case reflect.Int:
+		number, err := strconv.Atoi(override)
+		if err != nil {
+			return fmt.Errorf("%s: %s is not an integer", name, override)
+		}
+		value.SetInt(int64(number))
+	case reflect.Float32, reflect.Float64:
+		number, err := strconv.ParseFloat(override, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %s is not a number", name, override)
+		}
+		value.SetFloat(number)
+	case reflect.Bool:
+		flag, err := strconv.ParseBool(override)
+		if err != nil {
+			return fmt.Errorf("%s: %s is not a boolean", name, override)
+		}
+		value.SetBool(flag)
+	case reflect.Slice:
+		if value.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("%s: the field can't be overridden", name)
+		}
+		var items []string
+		for _, item := range strings.Split(override, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("%s: the field can't be overridden", name)
+	}
+	external[path] = true
+	return nil
+}
+
+// Input:
+//
+//	path (string): Path of a field in the config file, like cluster_details.node_groups[0].max_nodes_allowed
+//
+// Description:
+//
+//	Names the environment variable overriding the field, like SCALING_MANAGER_CLUSTER_DETAILS_NODE_GROUPS_0_MAX_NODES_ALLOWED
+//
+// Return:
+//
+//	(string): Returns the name of the environment variable
+func envOverrideName(path string) string {
+	name := strings.NewReplacer(".", "_", "[", "_", "]", "", "-", "_").Replace(path)
+	return EnvOverridePrefix + strings.ToUpper(name)
+}
+
+// Input:
+//
+//	path (string): Path of the parent, empty at the root of the config file
+//	name (string): Key of the child
+//
+// Description:
+//
+//	Appends the key of a child to the path of its parent
+//
+// Return:
+//
+//	(string): Returns the path of the child
+func childPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// Input:
+//
+//	external (map[string]bool): Paths of the fields whose values do not come from the config file
+//	parent (string): Path of a struct in the config file
+//
+// Description:
+//
+//	Selects the fields of the struct from the external paths
+//
+// Return:
+//
+//	(map[string]bool): Returns the keys of the external fields of the struct, nil if there are none
+func externalFieldsOf(external map[string]bool, parent string) map[string]bool {
+	var fields map[string]bool
+	for path := range external {
+		if field := strings.TrimPrefix(path, parent+"."); field != path && !strings.Contains(field, ".") {
+			if fields == nil {
+				fields = make(map[string]bool)
+			}
+			fields[field] = true
+		}
+	}
+	return fields
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadConfigReferences(t *testing.T) {
+	credentialsDirectory := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(credentialsDirectory, "os_admin_password"), []byte("s3cret\n"), 0600))
+	t.Setenv("CREDENTIALS_DIRECTORY", credentialsDirectory)
+	t.Setenv("OS_USER", "admin")
+	t.Setenv("POLLING_INTERVAL", "120")
+
+	configStruct, err := ReadConfig("testdata/references_config.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", configStruct.ClusterDetails.OsCredentials.OsAdminUsername)
+	assert.Equal(t, "s3cret", configStruct.ClusterDetails.OsCredentials.OsAdminPassword)
+	assert.Equal(t, 120, configStruct.UserConfig.RecommendationPollingInterval)
+	assert.True(t, configStruct.ClusterDetails.OsCredentials.IsExternal("os_admin_password"))
+	assert.False(t, configStruct.ClusterDetails.CloudCredentials.IsExternal("role_arn"))
+}
+
+func TestReadConfigMissingReference(t *testing.T) {
+	t.Setenv("OS_USER", "admin")
+	t.Setenv("POLLING_INTERVAL", "120")
+	os.Unsetenv("CREDENTIALS_DIRECTORY")
+
+	_, err := ReadConfig("testdata/references_config.yaml")
+	assert.EqualError(t, err, "cluster_details.os_credentials.os_admin_password: the environment variable CREDENTIALS_DIRECTORY is not set")
+}
+
+func TestReadConfigEnvOverrides(t *testing.T) {
+	t.Setenv("SCALING_MANAGER_CLUSTER_DETAILS_OS_CREDENTIALS_OS_ADMIN_PASSWORD", "from-env")
+	t.Setenv("SCALING_MANAGER_CLUSTER_DETAILS_CLUSTER_NAME", "cluster.2")
+	t.Setenv("SCALING_MANAGER_TASK_DETAILS_0_RULES_0_LIMIT", "85.5")
+	t.Setenv("SCALING_MANAGER_USER_CONFIG_IS_ACCELERATED", "true")
+
+	configStruct, err := ReadConfig("testdata/unversioned_config.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "from-env", configStruct.ClusterDetails.OsCredentials.OsAdminPassword)
+	assert.Equal(t, "admin", configStruct.ClusterDetails.OsCredentials.OsAdminUsername)
+	assert.Equal(t, "cluster.2", configStruct.ClusterDetails.ClusterName)
+	assert.Equal(t, float32(85.5), configStruct.TaskDetails[0].Rules[0].Limit)
+	assert.True(t, configStruct.UserConfig.IsAccelerated)
+	assert.True(t, configStruct.ClusterDetails.OsCredentials.IsExternal("os_admin_password"))
+	assert.False(t, configStruct.ClusterDetails.OsCredentials.IsExternal("os_admin_username"))
+
+	t.Setenv("SCALING_MANAGER_USER_CONFIG_PURGE_OLD_DOCS_AFTER_HOURS", "many")
+	_, err = ReadConfig("testdata/unversioned_config.yaml")
+	assert.EqualError(t, err, "SCALING_MANAGER_USER_CONFIG_PURGE_OLD_DOCS_AFTER_HOURS: many is not an integer")
+}
+
+func TestEnvOverrideName(t *testing.T) {
+	assert.Equal(t, "SCALING_MANAGER_CLUSTER_DETAILS_NODE_GROUPS_0_MAX_NODES_ALLOWED", envOverrideName("cluster_details.node_groups[0].max_nodes_allowed"))
+	assert.Equal(t, "SCALING_MANAGER_CLUSTER_DETAILS_NODE_GROUPS_1_ATTRIBUTES_RACK_ID", envOverrideName("cluster_details.node_groups[1].attributes.rack-id"))
+}
+
+func TestUpdateConfigFileKeepsReferences(t *testing.T) {
+	content, err := os.ReadFile("testdata/unversioned_config.yaml")
+	assert.NoError(t, err)
+	fileName := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(fileName, content, 0644))
+	defaultFileName := ConfigFileName
+	ConfigFileName = fileName
+	defer func() { ConfigFileName = defaultFileName }()
+	t.Setenv("SCALING_MANAGER_CLUSTER_DETAILS_OS_CREDENTIALS_OS_ADMIN_PASSWORD", "from-env")
+
+	configStruct, err := ReadConfig(fileName)
+	assert.NoError(t, err)
+	configStruct.ClusterDetails.OsCredentials.OsAdminUsername = "ENCRYPTED=="
+	assert.NoError(t, UpdateConfigFile(configStruct))
+
+	updated, err := os.ReadFile(fileName)
+	assert.NoError(t, err)
+	assert.Contains(t, string(updated), "        os_admin_username: ENCRYPTED==\n        os_admin_password: admin\n")
+}
@@ -0,0 +1,126 @@
+package configcheck
+
+import (
+	"fmt"
+
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+	cron "github.com/robfig/cron/v3"
+)
+
+// Severities of the issues found in the tasks
+const (
+	// The task can't work as configured
+	SeverityError string = "error"
+	// The task works, but likely not as intended
+	SeverityWarning string = "warning"
+)
+
+// This struct contains an issue found in the tasks of the config.
+type Issue struct {
+	// Severity indicates if the issue is an error or a warning.
+	Severity string `json:"severity"`
+	// Path indicates the path of the task or rule in the config file.
+	Path string `json:"path"`
+	// Message indicates the issue in plain words.
+	Message string `json:"message"`
+}
+
+// Input:
+//
+//	configStruct (config.ConfigStruct): Validated config of the scaling manager
+//
+// Description:
+//
+//	Detects the tasks which validate but conflict with each other or with the polling intervals:
+//	  * EVENT tasks whose scheduling_time is not a cron expression, the task is never scheduled
+//	  * Rules with a decision period shorter than the polling intervals, which have no data points to evaluate
+//	  * Scale up and scale down rules of the same node group on the same metric whose limits overlap, so that
+//	    a value between the limits can recommend both and the cluster flaps
+//
+// Return:
+//
+//	([]Issue): Returns the issues found, none if the tasks are consistent
+func CheckTasks(configStruct config.ConfigStruct) []Issue {
+	var issues []Issue
+	pollingInterval := configStruct.UserConfig.RecommendationPollingInterval
+	if configStruct.UserConfig.FetchPollingInterval > pollingInterval {
+		pollingInterval = configStruct.UserConfig.FetchPollingInterval
+	}
+	for i, task := range configStruct.TaskDetails {
+		for j, rule := range task.Rules {
+			path := fmt.Sprintf("task_details[%d].rules[%d]", i, j)
+			if task.Operator == "EVENT" {
+				if _, err := cron.ParseStandard(rule.SchedulingTime); err != nil {
+					issues = append(issues, Issue{SeverityError, path + ".scheduling_time", "is not a valid cron expression: " + err.Error()})
+				}
+				continue
+			}
+			if rule.DecisionPeriod*60 < pollingInterval {
+				issues = append(issues, Issue{SeverityWarning, path + ".decision_period",
+					fmt.Sprintf("%d minutes is shorter than the polling interval of %d seconds, the rule may have no data points to evaluate", rule.DecisionPeriod, pollingInterval)})
+			}
+		}
+	}
+	return append(issues, overlappingLimits(configStruct.TaskDetails)...)
+}
+
+// Input:
+//
+//	tasks ([]config.Task): Tasks of the config
+//
+// Description:
+//
+//	Finds the scale up and scale down rules on the same metric and node group where the scale down limit is
+//	above the scale up limit. A scale up or scale vertical rule fires above its limit and a scale down rule
+//	below its limit, so both can fire for the values between the limits.
+//
+// Return:
+//
+//	([]Issue): Returns a warning for every overlapping pair of rules
+func overlappingLimits(tasks []config.Task) []Issue {
+	var issues []Issue
+	for i, upTask := range tasks {
+		if ruleOperation(upTask) != "scale_up" {
+			continue
+		}
+		for j, downTask := range tasks {
+			if ruleOperation(downTask) != "scale_down" || downTask.NodeGroup != upTask.NodeGroup {
+				continue
+			}
+			for k, upRule := range upTask.Rules {
+				for l, downRule := range downTask.Rules {
+					if upRule.Metric != downRule.Metric || downRule.Limit <= upRule.Limit {
+						continue
+					}
+					issues = append(issues, Issue{SeverityWarning, fmt.Sprintf("task_details[%d].rules[%d]", j, l),
+						fmt.Sprintf("scale down limit %.2f of %s is above the scale up limit %.2f of task_details[%d].rules[%d], both tasks can be recommended and the cluster can flap",
+							downRule.Limit, downRule.Metric, upRule.Limit, i, k)})
+				}
+			}
+		}
+	}
+	return issues
+}
+
+// Input:
+//
+//	task (config.Task): Task of the config
+//
+// Description:
+//
+//	Returns the operation the metric based rules of the task are evaluated for, scale_up for the scale_up and
+//	scale_vertical tasks and scale_down for the scale_down tasks
+//
+// Return:
+//
+//	(string): Returns scale_up or scale_down, empty for the EVENT tasks and the invalid task names
+func ruleOperation(task config.Task) string {
+	if task.Operator == "EVENT" {
+		return ""
+	}
+	action, err := config.ParseTaskName(task.TaskName)
+	if err != nil {
+		return ""
+	}
+	return action.RuleOperation()
+}
@@ -0,0 +1,57 @@
+package configcheck
+
+import (
+	"testing"
+
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckTasks(t *testing.T) {
+	var configStruct config.ConfigStruct
+	configStruct.UserConfig.RecommendationPollingInterval = 300
+	configStruct.UserConfig.FetchPollingInterval = 60
+	configStruct.TaskDetails = []config.Task{
+		{TaskName: "scale_up_by_1", Operator: "OR", Rules: []config.Rule{
+			{Metric: "CpuUtil", Limit: 70, Stat: "AVG", DecisionPeriod: 60},
+			{Metric: "DiskUtil", Limit: 75, Stat: "AVG", DecisionPeriod: 2},
+		}},
+		{TaskName: "scale_up_by_1", Operator: "EVENT", Rules: []config.Rule{{SchedulingTime: "0 0 * * 1"}}},
+		{TaskName: "scale_down_by_1", Operator: "EVENT", Rules: []config.Rule{{SchedulingTime: "every friday"}}},
+		{TaskName: "scale_down_by_1", Operator: "AND", Rules: []config.Rule{
+			{Metric: "CpuUtil", Limit: 80, Stat: "AVG", DecisionPeriod: 60},
+			{Metric: "DiskUtil", Limit: 50, Stat: "AVG", DecisionPeriod: 60},
+		}},
+	}
+
+	issues := CheckTasks(configStruct)
+	assert.Len(t, issues, 3)
+	assert.Equal(t, SeverityWarning, issues[0].Severity)
+	assert.Equal(t, "task_details[0].rules[1].decision_period", issues[0].Path)
+	assert.Equal(t, SeverityError, issues[1].Severity)
+	assert.Equal(t, "task_details[2].rules[0].scheduling_time", issues[1].Path)
+	assert.Equal(t, SeverityWarning, issues[2].Severity)
+	assert.Equal(t, "task_details[3].rules[0]", issues[2].Path)
+	assert.Contains(t, issues[2].Message, "task_details[0].rules[0]")
+}
+
+func TestOverlappingLimitsNodeGroups(t *testing.T) {
+	tasks := []config.Task{
+		{TaskName: "scale_up_by_1", Operator: "OR", NodeGroup: "data-hot", Rules: []config.Rule{{Metric: "CpuUtil", Limit: 70}}},
+		{TaskName: "scale_down_by_1", Operator: "OR", NodeGroup: "data-warm", Rules: []config.Rule{{Metric: "CpuUtil", Limit: 80}}},
+		{TaskName: "scale_down_by_1", Operator: "OR", NodeGroup: "data-hot", Rules: []config.Rule{{Metric: "CpuUtil", Limit: 30}}},
+	}
+	assert.Empty(t, overlappingLimits(tasks))
+}
+
+func TestOverlappingLimitsVertical(t *testing.T) {
+	tasks := []config.Task{
+		{TaskName: "scale_vertical_to_3", Operator: "OR", NodeGroup: "data-hot", Rules: []config.Rule{{Metric: "MemUtil", Limit: 60}}},
+		{TaskName: "scale_vertical_to_3", Operator: "EVENT", NodeGroup: "data-hot", Rules: []config.Rule{{SchedulingTime: "0 0 * * 1"}}},
+		{TaskName: "scale_down_by_1", Operator: "OR", NodeGroup: "data-hot", Rules: []config.Rule{{Metric: "MemUtil", Limit: 70}}},
+	}
+	issues := overlappingLimits(tasks)
+	assert.Len(t, issues, 1)
+	assert.Equal(t, "task_details[2].rules[0]", issues[0].Path)
+	assert.Contains(t, issues[0].Message, "task_details[0].rules[0]")
+}
@@ -0,0 +1,155 @@
+package configcheck
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+)
+
+// Paths of the fields whose values are not written in the changes, as they hold credentials
+var hiddenPaths = map[string]bool{
+	"cluster_details.os_credentials":             true,
+	"cluster_details.cloud_credentials":          true,
+	"cluster_details.key_management.vault_token": true,
+}
+
+// Input:
+//
+//	previous (config.ConfigStruct): Config in use before the change
+//	current (config.ConfigStruct): Config read after the change
+//
+// Description:
+//
+//	Lists the fields which differ between the configs by their path in the config file, like
+//	user_config.recommendation_polling_interval_in_secs: 300 -> 600. Entries added to or removed from lists and
+//	maps are listed as a whole, and the credentials are only listed as changed.
+//
+// Return:
+//
+//	([]string): Returns one line per changed field, none if the configs are the same
+func Diff(previous config.ConfigStruct, current config.ConfigStruct) []string {
+	return diffValues("", reflect.ValueOf(previous), reflect.ValueOf(current))
+}
+
+// Input:
+//
+//	path (string): Path of the values in the config file
+//	previous (reflect.Value): Value before the change
+//	current (reflect.Value): Value after the change, of the same type
+//
+// Description:
+//
+//	Compares the values recursively, walking structs by the yaml names of their fields
+//
+// Return:
+//
+//	([]string): Returns one line per changed field
+func diffValues(path string, previous reflect.Value, current reflect.Value) []string {
+	if hiddenPaths[path] {
+		if reflect.DeepEqual(previous.Interface(), current.Interface()) {
+			return nil
+		}
+		return []string{path + ": changed"}
+	}
+	var changes []string
+	switch previous.Kind() {
+	case reflect.Struct:
+		for i := 0; i < previous.NumField(); i++ {
+			field := previous.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			tag := field.Tag.Get("yaml")
+			fieldPath := path
+			// Inlined structs are left out of the path
+			if !strings.Contains(tag, "inline") {
+				name := strings.Split(tag, ",")[0]
+				if name == "-" {
+					continue
+				}
+				if name == "" {
+					name = field.Name
+				}
+				fieldPath = joinPath(path, name)
+			}
+			changes = append(changes, diffValues(fieldPath, previous.Field(i), current.Field(i))...)
+		}
+	case reflect.Slice:
+		for i := 0; i < previous.Len() || i < current.Len(); i++ {
+			indexPath := fmt.Sprintf("%s[%d]", path, i)
+			if i >= previous.Len() {
+				changes = append(changes, indexPath+": added")
+			} else if i >= current.Len() {
+				changes = append(changes, indexPath+": removed")
+			} else {
+				changes = append(changes, diffValues(indexPath, previous.Index(i), current.Index(i))...)
+			}
+		}
+	case reflect.Map:
+		keys := make(map[string]reflect.Value)
+		for _, key := range append(previous.MapKeys(), current.MapKeys()...) {
+			keys[fmt.Sprint(key.Interface())] = key
+		}
+		var names []string
+		for name := range keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			keyPath := joinPath(path, name)
+			previousValue, currentValue := previous.MapIndex(keys[name]), current.MapIndex(keys[name])
+			if !previousValue.IsValid() {
+				changes = append(changes, keyPath+": added")
+			} else if !currentValue.IsValid() {
+				changes = append(changes, keyPath+": removed")
+			} else {
+				changes = append(changes, diffValues(keyPath, previousValue, currentValue)...)
+			}
+		}
+	default:
+		if !reflect.DeepEqual(previous.Interface(), current.Interface()) {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", path, formatValue(previous), formatValue(current)))
+		}
+	}
+	return changes
+}
+
+// Input:
+//
+//	path (string): Path of the parent, empty at the top of the config
+//	name (string): Name of the child
+//
+// Description:
+//
+//	Appends the name of a child to the path of its parent
+//
+// Return:
+//
+//	(string): Returns the path of the child
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// Input:
+//
+//	value (reflect.Value): Value of a field
+//
+// Description:
+//
+//	Formats a value of the diff, quoting strings so that empty values are visible
+//
+// Return:
+//
+//	(string): Returns the formatted value
+func formatValue(value reflect.Value) string {
+	if value.Kind() == reflect.String {
+		return fmt.Sprintf("%q", value.String())
+	}
+	return fmt.Sprint(value.Interface())
+}
@@ -0,0 +1,39 @@
+package configcheck
+
+import (
+	"testing"
+
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	previous, err := config.ReadConfig("../config.yaml")
+	assert.NoError(t, err)
+	assert.Empty(t, Diff(previous, previous))
+
+	current, err := config.ReadConfig("../config.yaml")
+	assert.NoError(t, err)
+	current.UserConfig.RecommendationPollingInterval = 600
+	current.ClusterDetails.ClusterName = "cluster.2"
+	current.ClusterDetails.OsCredentials.OsAdminPassword = "changed"
+	current.TaskDetails[0].Rules[0].Limit = 85
+	current.TaskDetails = current.TaskDetails[:3]
+	current.ClusterDetails.NodeGroups = []config.NodeGroup{{Name: "data", Attributes: map[string]string{"zone": "a"}}}
+
+	assert.Equal(t, []string{
+		"user_config.recommendation_polling_interval_in_secs: 300 -> 600",
+		"cluster_details.cluster_name: \"cluster.1\" -> \"cluster.2\"",
+		"cluster_details.os_credentials: changed",
+		"cluster_details.node_groups[0]: added",
+		"task_details[0].rules[0].limit: 80 -> 85",
+		"task_details[3]: removed",
+	}, Diff(previous, current))
+
+	previous = current
+	current.ClusterDetails.NodeGroups = []config.NodeGroup{{Name: "data", Attributes: map[string]string{"rack": "r1"}}}
+	assert.Equal(t, []string{
+		"cluster_details.node_groups[0].attributes.rack: added",
+		"cluster_details.node_groups[0].attributes.zone: removed",
+	}, Diff(previous, current))
+}
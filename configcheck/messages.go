@@ -0,0 +1,219 @@
+// This package checks the config file beyond the validation done when it is loaded. It reports the validation
+// errors with the path of the field in the config file, detects tasks which conflict with each other, replays
+// the stored metrics to show when every task would have been recommended, and lists the changes between configs.
+package configcheck
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+)
+
+// Input:
+//
+//	err (error): Error returned while reading the config file
+//
+// Description:
+//
+//	Converts the validation errors into one message per invalid field, naming the field by its path in the
+//	config file, like task_details[1].rules[0].metric. Other errors are returned as a single message.
+//
+// Return:
+//
+//	([]string): Returns the messages, none if err is nil
+func ValidationMessages(err error) []string {
+	if err == nil {
+		return nil
+	}
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return []string{err.Error()}
+	}
+	var messages []string
+	for _, fieldErr := range validationErrs {
+		messages = append(messages, configPath(fieldErr.Namespace())+": "+describe(fieldErr))
+	}
+	return messages
+}
+
+// Input:
+//
+//	namespace (string): Namespace of the field in the ConfigStruct, like ConfigStruct.TaskDetails[1].Rules[0].Metric
+//
+// Description:
+//
+//	Converts the namespace of a field to its path in the config file, using the yaml names of the fields.
+//	Inlined structs are left out of the path, and names which are not found are kept as they are.
+//
+// Return:
+//
+//	(string): Returns the path of the field in the config file
+func configPath(namespace string) string {
+	segments := strings.Split(namespace, ".")
+	fieldType := reflect.TypeOf(config.ConfigStruct{})
+	if len(segments) > 0 && segments[0] == fieldType.Name() {
+		segments = segments[1:]
+	}
+	var path []string
+	for _, segment := range segments {
+		name, index := segment, ""
+		if i := strings.Index(segment, "["); i >= 0 {
+			name, index = segment[:i], segment[i:]
+		}
+		field, ok := findField(fieldType, name)
+		if !ok {
+			path = append(path, segment)
+			fieldType = nil
+			continue
+		}
+		fieldType = field.Type
+		// Slices and maps are indexed to their elements
+		if index != "" && (fieldType.Kind() == reflect.Slice || fieldType.Kind() == reflect.Map) {
+			fieldType = fieldType.Elem()
+		}
+		yamlName := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if strings.Contains(field.Tag.Get("yaml"), "inline") {
+			continue
+		}
+		if yamlName == "" {
+			yamlName = field.Name
+		}
+		path = append(path, yamlName+index)
+	}
+	return strings.Join(path, ".")
+}
+
+// Input:
+//
+//	structType (reflect.Type): Type of the struct holding the field, nil if unknown
+//	name (string): Go or yaml name of the field, struct level validations report either of them
+//
+// Description:
+//
+//	Finds a field of the struct by its Go name, ignoring the case, or by its yaml name
+//
+// Return:
+//
+//	(reflect.StructField, bool): Returns the field and false if it is not found
+func findField(structType reflect.Type, name string) (reflect.StructField, bool) {
+	if structType == nil || structType.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		yamlName := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if strings.EqualFold(field.Name, name) || yamlName == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// Input:
+//
+//	fieldErr (validator.FieldError): Validation error of a field
+//
+// Description:
+//
+//	Describes the validation which failed for the field in plain words
+//
+// Return:
+//
+//	(string): Returns the description
+func describe(fieldErr validator.FieldError) string {
+	param := fieldErr.Param()
+	var description string
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "required_with":
+		return "is required when " + snakeCase(param) + " are set"
+	case "required_if":
+		// The param is the field and the value which makes the field required, like Source aws_kms
+		condition := strings.Fields(param)
+		if len(condition) == 2 {
+			return "is required when " + snakeCase(condition[0]) + " is " + condition[1]
+		}
+		return "is required"
+	case "excluded_without":
+		return "is not allowed without " + snakeCase(param)
+	case "excluded_unless":
+		// The param is the field and the value it has to be set to, like Stat COUNT
+		condition := strings.Fields(param)
+		if len(condition) == 2 {
+			description = "is only allowed when " + snakeCase(condition[0]) + " is " + condition[1]
+		} else {
+			description = "is not allowed here"
+		}
+	case "url":
+		description = "should be a url"
+	case "numeric":
+		description = "should be a number"
+	case "oneof":
+		if param == "NodeGroups" {
+			description = "should be one of the configured node_groups"
+		} else {
+			description = "should be one of " + strings.Join(strings.Fields(param), ", ")
+		}
+	case "min":
+		if fieldErr.Kind() == reflect.Slice || fieldErr.Kind() == reflect.Map {
+			description = "should have at least " + param + " entries"
+		} else {
+			description = "should be at least " + param
+		}
+	case "max":
+		description = "should be at most " + param
+	case "gt":
+		if fieldErr.Kind() == reflect.Slice || fieldErr.Kind() == reflect.Map {
+			description = "should have more than " + param + " entries"
+		} else {
+			description = "should be greater than " + param
+		}
+	case "gtefield":
+		description = "should be greater than or equal to " + snakeCase(param)
+	case "unique":
+		description = "should have a unique " + snakeCase(param) + " for every entry"
+	case "isValidName":
+		description = "should start with a letter and contain only letters, digits, '-', '.' and '_'"
+	case "isValidTaskName":
+		description = "should be scale_up_by_<n>, scale_down_by_<n> or scale_vertical_to_<launch template version>"
+	case "isValidAttribute":
+		description = "should contain only letters, digits, '_' and '-'"
+	default:
+		description = "fails the " + fieldErr.Tag() + " validation"
+	}
+	if fieldErr.Kind() == reflect.Slice || fieldErr.Kind() == reflect.Map {
+		return description
+	}
+	return fmt.Sprintf("%s, got %v", description, fieldErr.Value())
+}
+
+// Input:
+//
+//	name (string): Go name of a field, like MinNodesAllowed
+//
+// Description:
+//
+//	Converts the Go name of a field referred by a validation to the snake case used in the config file
+//
+// Return:
+//
+//	(string): Returns the snake case name, like min_nodes_allowed
+func snakeCase(name string) string {
+	var builder strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				builder.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
@@ -0,0 +1,36 @@
+package configcheck
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidationMessages(t *testing.T) {
+	_, err := config.ReadConfig("testdata/invalid_config.yaml")
+	assert.Equal(t, []string{
+		"cluster_details.jvm_factor: should be at most 0.5, got 0.7",
+		"cluster_details.key_management.kms_key_id: is required when source is aws_kms",
+		"task_details[0].rules[0].metric: should be one of CpuUtil, RamUtil, DiskUtil, HeapUtil, NumShards, ShardsPerGB, IndexingPressure, got CpuUsage",
+		"task_details[1].rules[0].occurrences_percent: is only allowed when stat is COUNT, got 95",
+	}, ValidationMessages(err))
+}
+
+func TestValidationMessagesOtherErrors(t *testing.T) {
+	assert.Nil(t, ValidationMessages(nil))
+	assert.Equal(t, []string{"open missing.yaml: no such file"}, ValidationMessages(errors.New("open missing.yaml: no such file")))
+}
+
+func TestConfigPath(t *testing.T) {
+	assert.Equal(t, "cluster_details.launch_template_id", configPath("ConfigStruct.ClusterDetails.LaunchTemplateId"))
+	assert.Equal(t, "cluster_details.cluster_name", configPath("ConfigStruct.ClusterDetails.ClusterStatic.ClusterName"))
+	assert.Equal(t, "cluster_details.node_groups[1].max_nodes_allowed", configPath("ConfigStruct.ClusterDetails.NodeGroups[1].MaxNodesAllowed"))
+	assert.Equal(t, "task_details[2].rules[0].scheduling_time", configPath("ConfigStruct.TaskDetails[2].Rules[0].scheduling_time"))
+}
+
+func TestSnakeCase(t *testing.T) {
+	assert.Equal(t, "min_nodes_allowed", snakeCase("MinNodesAllowed"))
+	assert.Equal(t, "stat", snakeCase("Stat"))
+}
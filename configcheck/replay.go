@@ -0,0 +1,207 @@
+package configcheck
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/maplelabs/opensearch-scaling-manager/cluster"
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+	"github.com/maplelabs/opensearch-scaling-manager/logger"
+)
+
+// A global logger variable used across the package for logging.
+var log logger.LOG
+
+// Input:
+//
+// Description:
+//
+//	Initialize the configcheck module.
+//
+// Return:
+func init() {
+	log.Init("logger")
+	log.Info.Println("Configcheck module initialized")
+}
+
+// This struct contains a period during which a task would have been recommended at every evaluation.
+type FiringPeriod struct {
+	// From indicates the time of the first evaluation recommending the task in milliseconds.
+	From int64 `json:"from"`
+	// To indicates the time of the last evaluation recommending the task in milliseconds.
+	To int64 `json:"to"`
+	// Evaluations indicates the number of evaluations recommending the task in the period.
+	Evaluations int `json:"evaluations"`
+}
+
+// This struct contains the result of replaying the stored metrics for a task.
+type TaskReplay struct {
+	// Path indicates the path of the task in the config file.
+	Path string `json:"path"`
+	// TaskName indicates the name of the task.
+	TaskName string `json:"task_name"`
+	// NodeGroup indicates the node group of the task.
+	NodeGroup string `json:"node_group,omitempty"`
+	// Periods indicates the periods during which the task would have been recommended.
+	Periods []FiringPeriod `json:"periods"`
+}
+
+// Input:
+//
+//	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//	configStruct (config.ConfigStruct): Validated config of the scaling manager
+//	hours (int): Number of hours, up to now, to replay
+//
+// Description:
+//
+//	Evaluates the metric based tasks at every recommendation polling interval of the last hours, on the
+//	node metrics stored at that time, the same way the recommendation engine does. The provisioning which
+//	would have followed a recommendation is not simulated, so a task keeps firing while its rules hold.
+//
+// Return:
+//
+//	([]TaskReplay, error): Returns the replay of every metric based task and error if any
+func ReplayTasks(ctx context.Context, configStruct config.ConfigStruct, hours int) ([]TaskReplay, error) {
+	pollingInterval := configStruct.UserConfig.RecommendationPollingInterval
+	to := time.Now().UnixMilli()
+	from := to - int64(hours)*time.Hour.Milliseconds()
+	var steps []int64
+	for step := from + int64(pollingInterval)*1000; step <= to; step += int64(pollingInterval) * 1000 {
+		steps = append(steps, step)
+	}
+
+	var replays []TaskReplay
+	for i, task := range configStruct.TaskDetails {
+		if task.Operator == "EVENT" {
+			continue
+		}
+		maxDecisionPeriod := 0
+		for _, rule := range task.Rules {
+			if rule.DecisionPeriod > maxDecisionPeriod {
+				maxDecisionPeriod = rule.DecisionPeriod
+			}
+		}
+		series := make(map[string][]cluster.MetricBucket)
+		for _, rule := range task.Rules {
+			if _, ok := series[rule.Metric]; ok {
+				continue
+			}
+			buckets, err := cluster.GetMetricBuckets(ctx, rule.Metric, from-int64(maxDecisionPeriod)*time.Minute.Milliseconds(), to, pollingInterval, task.NodeGroup)
+			if err != nil {
+				return nil, err
+			}
+			series[rule.Metric] = buckets
+		}
+		replays = append(replays, TaskReplay{
+			Path:      fmt.Sprintf("task_details[%d]", i),
+			TaskName:  task.TaskName,
+			NodeGroup: task.NodeGroup,
+			Periods:   replayTask(task, series, steps, pollingInterval),
+		})
+	}
+	return replays, nil
+}
+
+// Input:
+//
+//	task (config.Task): Metric based task to be replayed
+//	series (map[string][]cluster.MetricBucket): Statistics per polling interval keyed by metric
+//	steps ([]int64): Times of the evaluations in milliseconds, in order
+//	pollingInterval (int): Time in seconds which is the interval between each evaluation
+//
+// Description:
+//
+//	Evaluates the task at every step and merges the consecutive steps recommending the task into periods.
+//
+// Return:
+//
+//	([]FiringPeriod): Returns the periods during which the task would have been recommended
+func replayTask(task config.Task, series map[string][]cluster.MetricBucket, steps []int64, pollingInterval int) []FiringPeriod {
+	taskOperation := "scale_down"
+	if action, err := config.ParseTaskName(task.TaskName); err == nil {
+		taskOperation = action.RuleOperation()
+	}
+	var periods []FiringPeriod
+	firing := false
+	for _, step := range steps {
+		isRecommended := task.Operator == "AND"
+		for _, rule := range task.Rules {
+			isRecommendedRule := evaluateRule(rule, series[rule.Metric], step, taskOperation, pollingInterval)
+			if task.Operator == "OR" && isRecommendedRule || task.Operator == "AND" && !isRecommendedRule {
+				isRecommended = isRecommendedRule
+				break
+			}
+		}
+		if !isRecommended {
+			firing = false
+			continue
+		}
+		if firing {
+			periods[len(periods)-1].To = step
+			periods[len(periods)-1].Evaluations++
+		} else {
+			periods = append(periods, FiringPeriod{From: step, To: step, Evaluations: 1})
+		}
+		firing = true
+	}
+	return periods
+}
+
+// Input:
+//
+//	rule (config.Rule): Rule to be evaluated
+//	buckets ([]cluster.MetricBucket): Statistics of the metric of the rule per polling interval
+//	at (int64): Time of the evaluation in milliseconds
+//	taskOperation (string): scale_up or scale_down
+//	pollingInterval (int): Time in seconds which is the interval between each evaluation
+//
+// Description:
+//
+//	Evaluates the rule on the intervals starting within its decision period before the evaluation time:
+//	  * AVG: the average over all the documents is above the limit to scale up, below it to scale down
+//	  * COUNT: the percent of intervals whose average reached the limit to scale up, or stayed below it to
+//	    scale down, out of the intervals in the decision period is at least occurrences_percent
+//	  * TERM: every document reached the limit to scale up, no document reached it to scale down, for ShardsPerGB
+//	A rule without data points in its decision period is not recommended.
+//
+// Return:
+//
+//	(bool): Returns true if the rule would have been recommended
+func evaluateRule(rule config.Rule, buckets []cluster.MetricBucket, at int64, taskOperation string, pollingInterval int) bool {
+	start := at - int64(rule.DecisionPeriod)*time.Minute.Milliseconds()
+	var count, violated int
+	var sum float64
+	var min, max float32
+	for _, bucket := range buckets {
+		if bucket.Timestamp < start || bucket.Timestamp >= at {
+			continue
+		}
+		if count == 0 || bucket.Min < min {
+			min = bucket.Min
+		}
+		if count == 0 || bucket.Max > max {
+			max = bucket.Max
+		}
+		count += bucket.Count
+		sum += float64(bucket.Avg) * float64(bucket.Count)
+		if taskOperation == "scale_up" && bucket.Avg >= rule.Limit || taskOperation == "scale_down" && bucket.Avg < rule.Limit {
+			violated++
+		}
+	}
+	if count == 0 {
+		return false
+	}
+	switch rule.Stat {
+	case "AVG":
+		avg := float32(sum / float64(count))
+		return taskOperation == "scale_up" && avg > rule.Limit || taskOperation == "scale_down" && avg < rule.Limit
+	case "COUNT":
+		counts := (rule.DecisionPeriod * 60) / pollingInterval
+		return counts != 0 && (violated*100)/counts >= rule.Occurrences
+	case "TERM":
+		// The recommendation engine only evaluates TERM for ShardsPerGB
+		return rule.Metric == "ShardsPerGB" && (taskOperation == "scale_up" && min >= rule.Limit || taskOperation == "scale_down" && max < rule.Limit)
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package configcheck
+
+import (
+	"testing"
+
+	"github.com/maplelabs/opensearch-scaling-manager/cluster"
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// Buckets of 5 minutes with the given averages, the first one starting at 0
+func buckets(avgs ...float32) []cluster.MetricBucket {
+	var metricBuckets []cluster.MetricBucket
+	for i, avg := range avgs {
+		metricBuckets = append(metricBuckets, cluster.MetricBucket{Timestamp: int64(i) * 300000, Count: 5, Avg: avg, Min: avg - 1, Max: avg + 1})
+	}
+	return metricBuckets
+}
+
+func TestEvaluateRule(t *testing.T) {
+	series := buckets(50, 90, 90, 90)
+	at := int64(1200000)
+
+	avg := config.Rule{Metric: "CpuUtil", Limit: 75, Stat: "AVG", DecisionPeriod: 15}
+	assert.True(t, evaluateRule(avg, series, at, "scale_up", 300))
+	assert.False(t, evaluateRule(avg, series, at, "scale_down", 300))
+
+	count := config.Rule{Metric: "CpuUtil", Limit: 80, Stat: "COUNT", DecisionPeriod: 15, Occurrences: 100}
+	assert.True(t, evaluateRule(count, series, at, "scale_up", 300))
+	count.DecisionPeriod = 20
+	assert.False(t, evaluateRule(count, series, at, "scale_up", 300))
+
+	term := config.Rule{Metric: "ShardsPerGB", Limit: 60, Stat: "TERM", DecisionPeriod: 15}
+	assert.True(t, evaluateRule(term, series, at, "scale_up", 300))
+	term.Metric = "CpuUtil"
+	assert.False(t, evaluateRule(term, series, at, "scale_up", 300))
+
+	assert.False(t, evaluateRule(avg, nil, at, "scale_up", 300))
+}
+
+func TestReplayTask(t *testing.T) {
+	task := config.Task{TaskName: "scale_up_by_1", Operator: "AND", Rules: []config.Rule{
+		{Metric: "CpuUtil", Limit: 75, Stat: "AVG", DecisionPeriod: 5},
+		{Metric: "RamUtil", Limit: 75, Stat: "AVG", DecisionPeriod: 5},
+	}}
+	series := map[string][]cluster.MetricBucket{
+		"CpuUtil": buckets(50, 90, 90, 50, 90),
+		"RamUtil": buckets(90, 90, 90, 90, 90),
+	}
+	steps := []int64{300000, 600000, 900000, 1200000, 1500000}
+
+	periods := replayTask(task, series, steps, 300)
+	assert.Equal(t, []FiringPeriod{
+		{From: 600000, To: 900000, Evaluations: 2},
+		{From: 1500000, To: 1500000, Evaluations: 1},
+	}, periods)
+
+	task.Operator = "OR"
+	series["CpuUtil"] = buckets(10, 10, 10, 10, 10)
+	series["RamUtil"] = buckets(10, 10, 10, 10, 10)
+	assert.Empty(t, replayTask(task, series, steps, 300))
+
+	// The rules of a vertical scaling are satisfied above the limits, like the ones of a scale up
+	task.TaskName = "scale_vertical_to_4"
+	series["CpuUtil"] = buckets(90, 90, 10, 10, 10)
+	assert.Equal(t, []FiringPeriod{{From: 300000, To: 600000, Evaluations: 2}}, replayTask(task, series, steps, 300))
+}
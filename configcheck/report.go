@@ -0,0 +1,98 @@
+package configcheck
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// This struct contains the result of checking a config file.
+type Report struct {
+	// Errors indicates the validation errors of the config file.
+	Errors []string `json:"errors"`
+	// Issues indicates the conflicts found in the tasks.
+	Issues []Issue `json:"issues"`
+	// Replay indicates when the tasks would have been recommended, empty if the metrics were not replayed.
+	Replay []TaskReplay `json:"replay,omitempty"`
+}
+
+// Input:
+//
+// Caller:
+//
+//	Object of Report
+//
+// Description:
+//
+//	Checks if the config can be used by the scaling manager, it has no validation errors and no error issues
+//
+// Return:
+//
+//	(bool): Returns true if the config is valid
+func (r Report) IsValid() bool {
+	if len(r.Errors) > 0 {
+		return false
+	}
+	for _, issue := range r.Issues {
+		if issue.Severity == SeverityError {
+			return false
+		}
+	}
+	return true
+}
+
+// Input:
+//
+//	w (io.Writer): Writer to which the report is written
+//	location (*time.Location): Time zone in which the replay times are written
+//
+// Caller:
+//
+//	Object of Report
+//
+// Description:
+//
+//	Writes the report as text, one line per error and issue followed by the replay of every task
+//
+// Return:
+func (r Report) WriteText(w io.Writer, location *time.Location) {
+	for _, message := range r.Errors {
+		fmt.Fprintln(w, "error: "+message)
+	}
+	for _, issue := range r.Issues {
+		fmt.Fprintf(w, "%s: %s: %s\n", issue.Severity, issue.Path, issue.Message)
+	}
+	if r.IsValid() {
+		fmt.Fprintln(w, "The config is valid")
+	}
+	for _, replay := range r.Replay {
+		name := replay.TaskName
+		if replay.NodeGroup != "" {
+			name += " (" + replay.NodeGroup + ")"
+		}
+		if len(replay.Periods) == 0 {
+			fmt.Fprintf(w, "%s %s: not recommended\n", replay.Path, name)
+			continue
+		}
+		fmt.Fprintf(w, "%s %s: recommended in %d periods\n", replay.Path, name, len(replay.Periods))
+		for _, period := range replay.Periods {
+			fmt.Fprintf(w, "  %s - %s (%d evaluations)\n", formatTime(period.From, location), formatTime(period.To, location), period.Evaluations)
+		}
+	}
+}
+
+// Input:
+//
+//	millis (int64): Time in milliseconds
+//	location (*time.Location): Time zone in which the time is written
+//
+// Description:
+//
+//	Formats a time of the replay
+//
+// Return:
+//
+//	(string): Returns the formatted time
+func formatTime(millis int64, location *time.Location) string {
+	return time.UnixMilli(millis).In(location).Format("2006-01-02 15:04")
+}
@@ -2,170 +2,191 @@ package crypto
 
 import (
 	"context"
-	"crypto/aes"
-	"crypto/cipher"
-	"encoding/base32"
 	ansibleutils "github.com/maplelabs/opensearch-scaling-manager/ansible_scripts"
 	"github.com/maplelabs/opensearch-scaling-manager/config"
 	"github.com/maplelabs/opensearch-scaling-manager/logger"
 	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
 	utils "github.com/maplelabs/opensearch-scaling-manager/utilities"
-	mrand "math/rand"
 	"os"
 	"strings"
 	"time"
 )
 
 var log = new(logger.LOG)
-var EncryptionSecret string
-var seed = time.Now().Unix()
 var SecretFilepath = ".secret.txt"
 
+// Maximum time the playbook copying the config and secret files to the other nodes may take
+const broadcastTimeout = 10 * time.Minute
+
 // Initializing logger module
 func init() {
 	log.Init("logger")
 	log.Info.Println("Crypto module initiated")
-	mrand.Seed(seed)
-	configStruct, err := config.GetConfig()
-	if err != nil {
-		log.Error.Println("Error validating config file", err)
-		panic(err)
-	}
-	if _, err = os.Stat(SecretFilepath); err == nil {
-		EncryptionSecret = GetEncryptionSecret()
-		GetDecryptedOsCreds(&configStruct.ClusterDetails.OsCredentials)
-		GetDecryptedCloudCreds(&configStruct.ClusterDetails.CloudCredentials)
-	}
-
-	osutils.InitializeOsClient(configStruct.ClusterDetails.OsCredentials.OsAdminUsername, configStruct.ClusterDetails.OsCredentials.OsAdminPassword)
-	UpdateSecretAndEncryptCreds(true, configStruct)
-}
-
-// bytes is used when creating ciphers for the string
-var bytes = []byte{35, 46, 57, 24, 85, 35, 24, 74, 87, 35, 88, 98, 66, 32, 14, 05}
-
-// Generate a random string of length 16
-func GeneratePassword() string {
-	mrand.Seed(time.Now().UnixNano())
-	digits := "0123456789"
-	specials := "*@#$"
-	all := "ABCDEFGHIJKLMNOPQRSTUVWXYZ" +
-		"abcdefghijklmnopqrstuvwxyz" +
-		digits + specials
-	length := 16
-	buf := make([]byte, length)
-	buf[0] = digits[mrand.Intn(len(digits))]
-	buf[1] = specials[mrand.Intn(len(specials))]
-	for i := 2; i < length; i++ {
-		buf[i] = all[mrand.Intn(len(all))]
-	}
-	mrand.Shuffle(len(buf), func(i, j int) {
-		buf[i], buf[j] = buf[j], buf[i]
-	})
-	str := string(buf)
-	return str
 }
 
-func GenerateAndScrambleSecret() {
-	EncryptionSecret = GeneratePassword()
-	f, err := os.Create(SecretFilepath)
+// Input:
+//
+// Description:
+//
+//	Initializes the opensearch client like InitializeClient, then encrypts the credentials in the config file
+//	with a new secret, which is copied to the other nodes. It has to be called by the scaling manager service
+//	before any request to opensearch is made.
+//
+// Return:
+//
+//	(error): Returns error if the config is invalid or the client can't be initialized
+func Initialize() error {
+	configStruct, err := InitializeClient()
 	if err != nil {
-		log.Panic.Println("Error while creating secret file in master node: ", err)
-		panic(err)
+		return err
 	}
-	defer f.Close()
-	scrambled_secret := Encode([]byte(getScrambledOrOriginalSecret(EncryptionSecret, true)))
-	_, err = f.WriteString(scrambled_secret)
+	err = UpdateSecretAndEncryptCreds(true, configStruct)
 	if err != nil {
-		log.Panic.Println("Error while writing secret in the master node : ", err)
-		panic(err)
+		log.Error.Println("Unable to encrypt the creds: ", err)
+		return err
 	}
+	return nil
 }
 
-func GetEncryptionSecret() string {
-	data, err := os.ReadFile(SecretFilepath)
+// Input:
+//
+// Description:
+//
+//	Reads the config file, decrypts the credentials with the secret if it exists and initializes the opensearch
+//	client with them. The config file is left as it is, so that commands reading from opensearch can use it.
+//
+// Return:
+//
+//	(config.ConfigStruct, error): Returns the config with the decrypted credentials and error if any
+func InitializeClient() (config.ConfigStruct, error) {
+	configStruct, err := config.GetConfig()
 	if err != nil {
-		log.Panic.Println("Error reading the secret file")
-		panic(err)
+		log.Error.Println("Error validating config file", err)
+		return configStruct, err
 	}
-	decoded_data, decodeErr := Decode(string(data))
-	if decodeErr != nil {
-		log.Error.Println("Error decoding the data: ", decodeErr)
-		panic(decodeErr)
+	if _, err = os.Stat(SecretFilepath); err == nil {
+		err = loadSecretFile(configStruct.ClusterDetails.KeyManagement, configStruct.ClusterDetails.CloudCredentials.Region)
+		if err != nil {
+			log.Error.Println("Unable to read the key from the secret file: ", err)
+			return configStruct, err
+		}
+		GetDecryptedOsCreds(&configStruct.ClusterDetails.OsCredentials)
+		GetDecryptedCloudCreds(&configStruct.ClusterDetails.CloudCredentials)
+	}
+
+	err = osutils.InitializeOsClient(configStruct.ClusterDetails.OsConnection, configStruct.ClusterDetails.OsCredentials.OsAdminUsername, configStruct.ClusterDetails.OsCredentials.OsAdminPassword)
+	if err != nil {
+		log.Error.Println("Unable to initialize the opensearch client: ", err)
+		return configStruct, err
 	}
-	return getScrambledOrOriginalSecret(string(decoded_data), false)
+	return configStruct, nil
 }
 
+// The credentials read from the environment or a file are not encrypted, as they are not written to the config file
 func GetEncryptedOsCred(osCred *config.OsCredentials) error {
 	var err error
 
-	osCred.OsAdminUsername, err = GetEncryptedData(osCred.OsAdminUsername)
-	if err != nil {
-		return err
+	if !osCred.IsExternal("os_admin_username") {
+		osCred.OsAdminUsername, err = GetEncryptedData(osCred.OsAdminUsername)
+		if err != nil {
+			return err
+		}
 	}
 
-	osCred.OsAdminPassword, err = GetEncryptedData(osCred.OsAdminPassword)
-	if err != nil {
-		return err
+	if !osCred.IsExternal("os_admin_password") {
+		osCred.OsAdminPassword, err = GetEncryptedData(osCred.OsAdminPassword)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// The credentials read from the environment or a file are not encrypted, as they are not written to the config file
 func GetEncryptedCloudCred(cloudCred *config.CloudCredentials) error {
 	var err error
 
-	cloudCred.SecretKey, err = GetEncryptedData(cloudCred.SecretKey)
-	if err != nil {
-		return err
+	if !cloudCred.IsExternal("secret_key") {
+		cloudCred.SecretKey, err = GetEncryptedData(cloudCred.SecretKey)
+		if err != nil {
+			return err
+		}
 	}
 
-	cloudCred.AccessKey, err = GetEncryptedData(cloudCred.AccessKey)
-	if err != nil {
-		return err
+	if !cloudCred.IsExternal("access_key") {
+		cloudCred.AccessKey, err = GetEncryptedData(cloudCred.AccessKey)
+		if err != nil {
+			return err
+		}
 	}
 
-	cloudCred.RoleArn, err = GetEncryptedData(cloudCred.RoleArn)
-	if err != nil {
-		return err
+	if !cloudCred.IsExternal("role_arn") {
+		cloudCred.RoleArn, err = GetEncryptedData(cloudCred.RoleArn)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// The credentials read from the environment or a file are used as they are
 func GetDecryptedOsCreds(osCred *config.OsCredentials) {
 
-	os_admin_username := GetDecryptedData(osCred.OsAdminUsername)
-	if os_admin_username != "" {
-		osCred.OsAdminUsername = os_admin_username
+	if !osCred.IsExternal("os_admin_username") {
+		os_admin_username := GetDecryptedData(osCred.OsAdminUsername)
+		if os_admin_username != "" {
+			osCred.OsAdminUsername = os_admin_username
+		}
 	}
 
-	os_admin_password := GetDecryptedData(osCred.OsAdminPassword)
-	if os_admin_password != "" {
-		osCred.OsAdminPassword = os_admin_password
+	if !osCred.IsExternal("os_admin_password") {
+		os_admin_password := GetDecryptedData(osCred.OsAdminPassword)
+		if os_admin_password != "" {
+			osCred.OsAdminPassword = os_admin_password
+		}
 	}
 
 }
 
+// The credentials read from the environment or a file are used as they are
 func GetDecryptedCloudCreds(cloudCred *config.CloudCredentials) {
 
-	secret_key := GetDecryptedData(cloudCred.SecretKey)
-	if secret_key != "" {
-		cloudCred.SecretKey = secret_key
+	if !cloudCred.IsExternal("secret_key") {
+		secret_key := GetDecryptedData(cloudCred.SecretKey)
+		if secret_key != "" {
+			cloudCred.SecretKey = secret_key
+		}
 	}
 
-	access_key := GetDecryptedData(cloudCred.AccessKey)
-	if access_key != "" {
-		cloudCred.AccessKey = access_key
+	if !cloudCred.IsExternal("access_key") {
+		access_key := GetDecryptedData(cloudCred.AccessKey)
+		if access_key != "" {
+			cloudCred.AccessKey = access_key
+		}
 	}
 
-	role_arn := GetDecryptedData(cloudCred.RoleArn)
-	if role_arn != "" {
-		cloudCred.RoleArn = role_arn
+	if !cloudCred.IsExternal("role_arn") {
+		role_arn := GetDecryptedData(cloudCred.RoleArn)
+		if role_arn != "" {
+			cloudCred.RoleArn = role_arn
+		}
 	}
 
 }
 
+// Checks if any credential is read from the config file, the config file only has to be encrypted and
+// copied to the other nodes then
+func hasCredsInFile(config_struct config.ConfigStruct) bool {
+	osCred := config_struct.ClusterDetails.OsCredentials
+	cloudCred := config_struct.ClusterDetails.CloudCredentials
+	return !osCred.IsExternal("os_admin_username") || !osCred.IsExternal("os_admin_password") ||
+		(cloudCred.SecretKey != "" && !cloudCred.IsExternal("secret_key")) ||
+		(cloudCred.AccessKey != "" && !cloudCred.IsExternal("access_key")) ||
+		(cloudCred.RoleArn != "" && !cloudCred.IsExternal("role_arn"))
+}
+
 func UpdateEncryptedCred(initialRun bool, config_struct config.ConfigStruct) error {
 	copyCreds := config_struct.ClusterDetails.OsCredentials
 	OsCredErr := GetEncryptedOsCred(&config_struct.ClusterDetails.OsCredentials)
@@ -188,49 +209,71 @@ func UpdateEncryptedCred(initialRun bool, config_struct config.ConfigStruct) err
 
 	// initialize new os client connection with the updated creds
 	if !initialRun {
-		osutils.InitializeOsClient(copyCreds.OsAdminUsername, copyCreds.OsAdminPassword)
+		err = osutils.InitializeOsClient(config_struct.ClusterDetails.OsConnection, copyCreds.OsAdminUsername, copyCreds.OsAdminPassword)
+		if err != nil {
+			log.Error.Println("Unable to initialize the opensearch client with the updated creds: ", err)
+			return err
+		}
 	}
 	return nil
 }
 
 func DecryptCredsAndInitializeOs(config_struct config.ConfigStruct) {
 	GetDecryptedOsCreds(&config_struct.ClusterDetails.OsCredentials)
-	osutils.InitializeOsClient(config_struct.ClusterDetails.OsCredentials.OsAdminUsername, config_struct.ClusterDetails.OsCredentials.OsAdminPassword)
+	err := osutils.InitializeOsClient(config_struct.ClusterDetails.OsConnection, config_struct.ClusterDetails.OsCredentials.OsAdminUsername, config_struct.ClusterDetails.OsCredentials.OsAdminPassword)
+	if err != nil {
+		log.Error.Println("Unable to initialize the opensearch client with the updated creds: ", err)
+	}
 }
 
 func UpdateSecretAndEncryptCreds(initial_run bool, config_struct config.ConfigStruct) error {
+	if !hasCredsInFile(config_struct) {
+		// The config file is left as it is on every node when all the creds come from the environment or files
+		if initial_run {
+			return nil
+		}
+		log.Info.Println("The creds are not read from the config file, initializing the opensearch client with the updated creds")
+		return osutils.InitializeOsClient(config_struct.ClusterDetails.OsConnection, config_struct.ClusterDetails.OsCredentials.OsAdminUsername, config_struct.ClusterDetails.OsCredentials.OsAdminPassword)
+	}
 	if initial_run {
-		if utils.CheckIfMaster(context.Background(), "") {
-			GenerateAndScrambleSecret()
-			UpdateEncryptedCred(initial_run, config_struct)
-			//ansible logic to copy the secret and config
-			hostFileName := "broadcast_hosts"
-			utils.HostsWithCurrentNodes(hostFileName, config_struct.ClusterDetails)
-			err := ansibleutils.UpdateWithTags(hostFileName, config_struct.ClusterDetails, []string{"update_secret", "update_config"})
-			if err != nil {
-				log.Error.Println(err)
-				log.Error.Println("Unable to update config.yaml and .secret.txt on the other node")
-				panic(err)
-			}
+		isMaster, err := utils.CheckIfMaster(context.Background(), "")
+		if err != nil {
+			return err
+		}
+		if !isMaster {
+			return nil
 		}
 	} else {
 		GetDecryptedOsCreds(&config_struct.ClusterDetails.OsCredentials)
 		GetDecryptedCloudCreds(&config_struct.ClusterDetails.CloudCredentials)
-		GenerateAndScrambleSecret()
-		UpdateEncryptedCred(initial_run, config_struct)
-		//ansible logic to copy the secret and config
-		hostFileName := "broadcast_hosts"
-		utils.HostsWithCurrentNodes(hostFileName, config_struct.ClusterDetails)
-		err := ansibleutils.UpdateWithTags(hostFileName, config_struct.ClusterDetails, []string{"update_secret", "update_config"})
-		if err != nil {
-			log.Error.Println(err)
-			log.Error.Println("Unable to update config.yaml and .secret.txt on the other node")
-			panic(err)
-		}
-
+	}
+	if legacySecret != "" {
+		log.Info.Println("Encrypting the creds of the legacy secret file again with AES-GCM")
+	}
+	err := rotateDataKey(config_struct.ClusterDetails.KeyManagement, config_struct.ClusterDetails.CloudCredentials.Region)
+	if err != nil {
+		log.Error.Println("Unable to write a new key to the secret file: ", err)
+		return err
+	}
+	// The config is encrypted with the new secret even if the client could not be initialized with
+	// the updated creds, so the secret and config are still copied to the other nodes
+	credErr := UpdateEncryptedCred(initial_run, config_struct)
+	//ansible logic to copy the secret and config
+	inventory, err := ansibleutils.ClusterInventory(config_struct.ClusterDetails)
+	if err != nil {
+		log.Error.Println("Unable to write the inventory of the current nodes: ", err)
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), broadcastTimeout)
+	defer cancel()
+	_, err = ansibleutils.UpdateWithTags(ctx, inventory, config_struct.ClusterDetails, []string{"update_secret", "update_config"})
+	if err != nil {
+		log.Error.Println(err)
+		log.Error.Println("Unable to update config.yaml and .secret.txt on the other node")
+		panic(err)
 	}
 
-	return nil
+	return credErr
 }
 
 func OsCredsMismatch(currOsCred config.OsCredentials, prevOsCred config.OsCredentials) bool {
@@ -247,64 +290,15 @@ func CloudCredsMismatch(currCloudCred config.CloudCredentials, prevCloudCred con
 	return false
 }
 
-// Encode the given byte value
-func Encode(b []byte) string {
-	return base32.StdEncoding.EncodeToString(b)
-}
-
-// Encrypt method is to encrypt or hide any classified text
-func Encrypt(text, EncryptionSecret string) (string, error) {
-	block, err := aes.NewCipher([]byte(EncryptionSecret))
-	if err != nil {
-		return "", err
-	}
-	plainText := []byte(text)
-	cfb := cipher.NewCFBEncrypter(block, bytes)
-	cipherText := make([]byte, len(plainText))
-	cfb.XORKeyStream(cipherText, plainText)
-	return Encode(cipherText), nil
-}
-
-// Decode the given string
-func Decode(s string) ([]byte, error) {
-	data, err := base32.StdEncoding.DecodeString(s)
-	if err != nil {
-		if !strings.Contains(err.Error(), "illegal base32 data at input") {
-			log.Panic.Println("Error while decoding : ", err)
-			panic(err)
-		} else {
-			return data, err
-		}
-	}
-	return data, nil
-}
-
-// Decrypt method is to extract back the encrypted text
-func Decrypt(text, EncryptionSecret string) (string, error) {
-	block, err := aes.NewCipher([]byte(EncryptionSecret))
-	if err != nil {
-		log.Error.Println("Error while creating cipher during decryption : ", err)
-		return "", err
-	}
-	cipherText, err := Decode(text)
-	if err != nil {
-		return "", nil
-	}
-	cfb := cipher.NewCFBDecrypter(block, bytes)
-	plainText := make([]byte, len(cipherText))
-	cfb.XORKeyStream(plainText, cipherText)
-	return string(plainText), nil
-}
-
-// Creates an encrypted string : performs AES encryption using the defined secret
-// and return base32 encoded string. Also checks if the encrypted string is able
-// to be decrypted used the same secret.
+// Creates an encrypted string : performs AES-GCM encryption using the data key
+// and a random nonce. Also checks if the encrypted string is able to be decrypted
+// using the same key.
 func GetEncryptedData(toBeEncrypted string) (string, error) {
-	encText, err := Encrypt(toBeEncrypted, EncryptionSecret)
+	encText, err := encryptValue(dataKey, toBeEncrypted)
 	if err != nil {
 		return "", err
 	} else {
-		_, err := Decrypt(encText, EncryptionSecret)
+		_, err := decryptValue(dataKey, encText)
 		if err != nil {
 			log.Error.Println("Error decrypting your encrypted text: ", err)
 			return "", err
@@ -313,78 +307,20 @@ func GetEncryptedData(toBeEncrypted string) (string, error) {
 	return encText, nil
 }
 
-// Return the decrypted string of the given encrypted string
+// Return the decrypted string of the given encrypted string. The values of a legacy
+// secret file are decrypted with its secret, and plain text values are returned as
+// an empty string so that they are used as they are.
 func GetDecryptedData(encryptedString string) string {
-	decrypted_txt, err := Decrypt(encryptedString, EncryptionSecret)
+	var decrypted_txt string
+	var err error
+	if strings.HasPrefix(encryptedString, encryptedValuePrefix) {
+		decrypted_txt, err = decryptValue(dataKey, encryptedString)
+	} else if legacySecret != "" {
+		decrypted_txt, err = legacyDecrypt(encryptedString, legacySecret)
+	}
 	if err != nil {
 		log.Panic.Println("Error decrypting your encrypted text: ", err)
 		panic(err)
 	}
 	return decrypted_txt
 }
-
-// Converts a 16 len string to 4*4 matrix
-func stringToMatrix(str string) [4][4]string {
-	var matrix [4][4]string
-	for i := 0; i < 4; i++ {
-		for j := 0; j < 4; j++ {
-			matrix[i][j] = string(str[i*4+j])
-		}
-	}
-	return matrix
-}
-
-// Returns the transpose of the given matrix
-func transpose(matrix [4][4]string) [4][4]string {
-	var transposedMatrix [4][4]string
-	for i := 0; i < 4; i++ {
-		for j := 0; j < 4; j++ {
-			transposedMatrix[j][i] = matrix[i][j]
-		}
-	}
-	return transposedMatrix
-}
-
-// Returns the matrix with interchanged rows
-func reverse(matrix [4][4]string) [4][4]string {
-	for i, j := 0, len(matrix)-1; i < j; i, j = i+1, j-1 {
-		matrix[i], matrix[j] = matrix[j], matrix[i]
-	}
-	return matrix
-}
-
-// Returns the matrix with intergchanged diagonal values
-func reverse_diag(matrix [4][4]string) [4][4]string {
-	for i := 0; i < 4; i++ {
-		temp := matrix[i][i]
-		matrix[i][i] = matrix[i][4-i-1]
-		matrix[i][4-i-1] = temp
-	}
-	return matrix
-}
-
-// Input :
-// secret (string) : The string which needs to be scrambled or unscrambled
-// scrambled (boolean) : True for scramble, false for unscramble
-//
-// Description :
-// This function scrambles and unscrambles the given string by converting it
-// into matrix and interchanging the values in it.
-//
-// Output :
-// string : scrambled or unscrambled string as per the requirement
-func getScrambledOrOriginalSecret(secret string, scrambled bool) string {
-	var requiredArr []string
-	matrix := stringToMatrix(secret)
-	if scrambled {
-		matrix = reverse_diag(reverse(transpose(matrix)))
-	} else {
-		matrix = transpose(reverse(reverse_diag(matrix)))
-	}
-	for i := 0; i < 4; i++ {
-		for j := 0; j < 4; j++ {
-			requiredArr = append(requiredArr, matrix[i][j])
-		}
-	}
-	return strings.Join(requiredArr, "")
-}
@@ -0,0 +1,327 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+)
+
+// Names of the key sources
+const (
+	LocalKeySource        = "local"
+	AwsKmsKeySource       = "aws_kms"
+	VaultTransitKeySource = "vault_transit"
+)
+
+// Encryption context bound to the data keys wrapped by AWS KMS, which has to match when they are unwrapped
+var kmsEncryptionContext = map[string]*string{"service": aws.String("opensearch-scaling-manager")}
+
+// A key source wraps the data key encrypting the credentials before it is written to the secret file, and
+// unwraps it when the secret file is read.
+type KeySource interface {
+	// Name returns the name of the key source, which is recorded in the secret file.
+	Name() string
+	// WrapKey encrypts the data key.
+	WrapKey(dataKey []byte) ([]byte, error)
+	// UnwrapKey decrypts the data key.
+	UnwrapKey(wrappedKey []byte) ([]byte, error)
+}
+
+// The local key source keeps the data key as it is, the secret file is only readable by its owner.
+type localKeySource struct{}
+
+// The AWS KMS key source wraps the data key with a KMS key.
+type kmsKeySource struct {
+	client kmsiface.KMSAPI
+	keyId  string
+}
+
+// The Vault key source wraps the data key with a key of the transit secrets engine.
+type vaultKeySource struct {
+	client  *http.Client
+	address string
+	mount   string
+	key     string
+	token   string
+}
+
+// Input:
+//
+//	name (string): Name of the key source, the local key source if empty
+//	keyManagement (config.KeyManagement): Settings of the key sources
+//	cloudRegion (string): Region of the cloud credentials, used by AWS KMS if no region is set
+//
+// Description:
+//
+//	Creates the key source with the given name. The name is the one set in the config when the data key is
+//	wrapped and the one recorded in the secret file when it is unwrapped. AWS KMS uses the default credential
+//	chain of the node (environment, shared config, instance role), as the cloud credentials are encrypted
+//	with the data key.
+//
+// Return:
+//
+//	(KeySource, error): Returns the key source and error if it is unknown or misses its settings
+func NewKeySource(name string, keyManagement config.KeyManagement, cloudRegion string) (KeySource, error) {
+	switch name {
+	case "", LocalKeySource:
+		return localKeySource{}, nil
+	case AwsKmsKeySource:
+		if keyManagement.KmsKeyId == "" {
+			return nil, fmt.Errorf("key_management.kms_key_id is required for the %s key source", name)
+		}
+		region := keyManagement.KmsRegion
+		if region == "" {
+			region = cloudRegion
+		}
+		sess, err := session.NewSession(&aws.Config{Region: aws.String(region)})
+		if err != nil {
+			return nil, err
+		}
+		return kmsKeySource{client: kms.New(sess), keyId: keyManagement.KmsKeyId}, nil
+	case VaultTransitKeySource:
+		if keyManagement.VaultAddress == "" || keyManagement.VaultTransitKey == "" {
+			return nil, fmt.Errorf("key_management.vault_address and key_management.vault_transit_key are required for the %s key source", name)
+		}
+		mount := keyManagement.VaultMount
+		if mount == "" {
+			mount = "transit"
+		}
+		token := keyManagement.VaultToken
+		if token == "" {
+			token = os.Getenv("VAULT_TOKEN")
+		}
+		return vaultKeySource{
+			client:  &http.Client{Timeout: 30 * time.Second},
+			address: strings.TrimRight(keyManagement.VaultAddress, "/"),
+			mount:   strings.Trim(mount, "/"),
+			key:     keyManagement.VaultTransitKey,
+			token:   token,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown key source %s, expected %s, %s or %s", name, LocalKeySource, AwsKmsKeySource, VaultTransitKeySource)
+	}
+}
+
+// Input:
+//
+// Description:
+//
+//	Names the local key source
+//
+// Return:
+//
+//	(string): Returns local
+func (localKeySource) Name() string {
+	return LocalKeySource
+}
+
+// Input:
+//
+//	dataKey ([]byte): Data key to be wrapped
+//
+// Description:
+//
+//	Keeps the data key as it is, the secret file holding it is only readable by its owner
+//
+// Return:
+//
+//	([]byte, error): Returns the data key
+func (localKeySource) WrapKey(dataKey []byte) ([]byte, error) {
+	return dataKey, nil
+}
+
+// Input:
+//
+//	wrappedKey ([]byte): Data key read from the secret file
+//
+// Description:
+//
+//	Returns the data key read from the secret file as it is
+//
+// Return:
+//
+//	([]byte, error): Returns the data key
+func (localKeySource) UnwrapKey(wrappedKey []byte) ([]byte, error) {
+	return wrappedKey, nil
+}
+
+// Input:
+//
+// Description:
+//
+//	Names the AWS KMS key source
+//
+// Return:
+//
+//	(string): Returns aws_kms
+func (k kmsKeySource) Name() string {
+	return AwsKmsKeySource
+}
+
+// Input:
+//
+//	dataKey ([]byte): Data key to be wrapped
+//
+// Description:
+//
+//	Encrypts the data key with the KMS key, bound to the encryption context of the scaling manager
+//
+// Return:
+//
+//	([]byte, error): Returns the encrypted data key and error if KMS fails
+func (k kmsKeySource) WrapKey(dataKey []byte) ([]byte, error) {
+	output, err := k.client.Encrypt(&kms.EncryptInput{
+		KeyId:             aws.String(k.keyId),
+		Plaintext:         dataKey,
+		EncryptionContext: kmsEncryptionContext,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to wrap the data key with the KMS key %s: %w", k.keyId, err)
+	}
+	return output.CiphertextBlob, nil
+}
+
+// Input:
+//
+//	wrappedKey ([]byte): Data key encrypted by KMS
+//
+// Description:
+//
+//	Decrypts the data key with the KMS key
+//
+// Return:
+//
+//	([]byte, error): Returns the data key and error if KMS fails
+func (k kmsKeySource) UnwrapKey(wrappedKey []byte) ([]byte, error) {
+	output, err := k.client.Decrypt(&kms.DecryptInput{
+		KeyId:             aws.String(k.keyId),
+		CiphertextBlob:    wrappedKey,
+		EncryptionContext: kmsEncryptionContext,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to unwrap the data key with the KMS key %s: %w", k.keyId, err)
+	}
+	return output.Plaintext, nil
+}
+
+// Input:
+//
+// Description:
+//
+//	Names the Vault transit key source
+//
+// Return:
+//
+//	(string): Returns vault_transit
+func (v vaultKeySource) Name() string {
+	return VaultTransitKeySource
+}
+
+// Input:
+//
+//	dataKey ([]byte): Data key to be wrapped
+//
+// Description:
+//
+//	Encrypts the data key with the transit key, Vault returns a ciphertext like vault:v1:<base64>
+//
+// Return:
+//
+//	([]byte, error): Returns the ciphertext and error if Vault fails
+func (v vaultKeySource) WrapKey(dataKey []byte) ([]byte, error) {
+	var response struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	err := v.transit("encrypt", map[string]string{"plaintext": base64.StdEncoding.EncodeToString(dataKey)}, &response)
+	if err != nil {
+		return nil, err
+	}
+	if response.Data.Ciphertext == "" {
+		return nil, fmt.Errorf("vault returned no ciphertext for the transit key %s", v.key)
+	}
+	return []byte(response.Data.Ciphertext), nil
+}
+
+// Input:
+//
+//	wrappedKey ([]byte): Ciphertext returned by Vault
+//
+// Description:
+//
+//	Decrypts the data key with the transit key
+//
+// Return:
+//
+//	([]byte, error): Returns the data key and error if Vault fails
+func (v vaultKeySource) UnwrapKey(wrappedKey []byte) ([]byte, error) {
+	var response struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	err := v.transit("decrypt", map[string]string{"ciphertext": string(wrappedKey)}, &response)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(response.Data.Plaintext)
+}
+
+// Input:
+//
+//	operation (string): Transit operation, encrypt or decrypt
+//	body (map[string]string): Body of the request
+//	response (interface{}): Value the response is decoded into
+//
+// Description:
+//
+//	Calls an operation of the transit secrets engine with the transit key
+//
+// Return:
+//
+//	(error): Returns error if the request fails or Vault returns an error
+func (v vaultKeySource) transit(operation string, body map[string]string, response interface{}) error {
+	requestBody, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/v1/%s/%s/%s", v.address, v.mount, operation, url.PathEscape(v.key))
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, endpoint, bytes.NewReader(requestBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", v.token)
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to %s the data key with the vault transit key %s: %w", operation, v.key, err)
+	}
+	defer resp.Body.Close()
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var vaultErr struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.Unmarshal(responseBody, &vaultErr)
+		return fmt.Errorf("unable to %s the data key with the vault transit key %s: %s %s", operation, v.key, resp.Status, strings.Join(vaultErr.Errors, "; "))
+	}
+	return json.Unmarshal(responseBody, response)
+}
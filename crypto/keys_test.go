@@ -0,0 +1,139 @@
+package crypto
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// An in memory KMS which wraps the keys by reversing them behind the key id
+type fakeKms struct {
+	kmsiface.KMSAPI
+	keyId string
+}
+
+func (f fakeKms) Encrypt(input *kms.EncryptInput) (*kms.EncryptOutput, error) {
+	if aws.StringValue(input.KeyId) != f.keyId {
+		return nil, errors.New("NotFoundException: key not found")
+	}
+	blob := append([]byte(f.keyId+":"), reversed(input.Plaintext)...)
+	return &kms.EncryptOutput{CiphertextBlob: blob, KeyId: input.KeyId}, nil
+}
+
+func (f fakeKms) Decrypt(input *kms.DecryptInput) (*kms.DecryptOutput, error) {
+	if aws.StringValue(input.EncryptionContext["service"]) != "opensearch-scaling-manager" {
+		return nil, errors.New("InvalidCiphertextException: encryption context mismatch")
+	}
+	blob := string(input.CiphertextBlob)
+	if !strings.HasPrefix(blob, f.keyId+":") {
+		return nil, errors.New("IncorrectKeyException: wrong key")
+	}
+	return &kms.DecryptOutput{Plaintext: reversed([]byte(strings.TrimPrefix(blob, f.keyId+":")))}, nil
+}
+
+func reversed(data []byte) []byte {
+	result := make([]byte, len(data))
+	for i := range data {
+		result[len(data)-1-i] = data[i]
+	}
+	return result
+}
+
+// A Vault transit engine mounted at transit with the key scaling-manager, accepting the token root
+func fakeVault(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		var body map[string]string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		switch r.URL.Path {
+		case "/v1/transit/encrypt/scaling-manager":
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"ciphertext": "vault:v1:" + body["plaintext"]}})
+		case "/v1/transit/decrypt/scaling-manager":
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"plaintext": strings.TrimPrefix(body["ciphertext"], "vault:v1:")}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":["no handler for route"]}`))
+		}
+	}))
+}
+
+func TestNewKeySource(t *testing.T) {
+	source, err := NewKeySource("", config.KeyManagement{}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, LocalKeySource, source.Name())
+
+	_, err = NewKeySource(AwsKmsKeySource, config.KeyManagement{}, "us-west-2")
+	assert.EqualError(t, err, "key_management.kms_key_id is required for the aws_kms key source")
+	source, err = NewKeySource(AwsKmsKeySource, config.KeyManagement{KmsKeyId: "alias/scaling-manager"}, "us-west-2")
+	assert.NoError(t, err)
+	assert.Equal(t, AwsKmsKeySource, source.Name())
+
+	_, err = NewKeySource(VaultTransitKeySource, config.KeyManagement{VaultAddress: "http://127.0.0.1:8200"}, "")
+	assert.EqualError(t, err, "key_management.vault_address and key_management.vault_transit_key are required for the vault_transit key source")
+	t.Setenv("VAULT_TOKEN", "from-env")
+	source, err = NewKeySource(VaultTransitKeySource, config.KeyManagement{VaultAddress: "http://127.0.0.1:8200/", VaultTransitKey: "key"}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, vaultKeySource{client: source.(vaultKeySource).client, address: "http://127.0.0.1:8200", mount: "transit", key: "key", token: "from-env"}, source)
+
+	_, err = NewKeySource("gcp_kms", config.KeyManagement{}, "")
+	assert.EqualError(t, err, "unknown key source gcp_kms, expected local, aws_kms or vault_transit")
+}
+
+func TestKmsKeySource(t *testing.T) {
+	source := kmsKeySource{client: fakeKms{keyId: "alias/scaling-manager"}, keyId: "alias/scaling-manager"}
+	key := []byte("0123456789abcdef0123456789abcdef")
+	wrappedKey, err := source.WrapKey(key)
+	assert.NoError(t, err)
+	assert.NotEqual(t, key, wrappedKey)
+	unwrappedKey, err := source.UnwrapKey(wrappedKey)
+	assert.NoError(t, err)
+	assert.Equal(t, key, unwrappedKey)
+
+	otherSource := kmsKeySource{client: fakeKms{keyId: "alias/other"}, keyId: "alias/other"}
+	_, err = otherSource.UnwrapKey(wrappedKey)
+	assert.EqualError(t, err, "unable to unwrap the data key with the KMS key alias/other: IncorrectKeyException: wrong key")
+	_, err = kmsKeySource{client: fakeKms{keyId: "alias/other"}, keyId: "alias/scaling-manager"}.WrapKey(key)
+	assert.Error(t, err)
+}
+
+func TestVaultKeySource(t *testing.T) {
+	server := fakeVault(t)
+	defer server.Close()
+	useTempSecretFile(t)
+	keyManagement := config.KeyManagement{Source: VaultTransitKeySource, VaultAddress: server.URL, VaultTransitKey: "scaling-manager", VaultToken: "root"}
+
+	err := rotateDataKey(keyManagement, "")
+	assert.NoError(t, err)
+	key := dataKey
+	var record keyRecord
+	assert.NoError(t, json.Unmarshal(secretFileContent, &record))
+	assert.Equal(t, VaultTransitKeySource, record.Source)
+	wrappedKey, _ := base64.StdEncoding.DecodeString(record.Key)
+	assert.True(t, strings.HasPrefix(string(wrappedKey), "vault:v1:"))
+
+	// The key source recorded in the secret file unwraps the key, even if the config changed meanwhile
+	dataKey = nil
+	assert.NoError(t, loadSecretFile(keyManagement, ""))
+	assert.Equal(t, key, dataKey)
+
+	keyManagement.VaultToken = "expired"
+	err = loadSecretFile(keyManagement, "")
+	assert.EqualError(t, err, "unable to decrypt the data key with the vault transit key scaling-manager: 403 Forbidden permission denied")
+	keyManagement.VaultToken, keyManagement.VaultTransitKey = "root", "missing"
+	err = rotateDataKey(keyManagement, "")
+	assert.EqualError(t, err, "unable to encrypt the data key with the vault transit key missing: 404 Not Found no handler for route")
+}
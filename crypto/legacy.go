@@ -0,0 +1,129 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base32"
+	"fmt"
+	"strings"
+)
+
+// IV of the legacy AES-CFB encryption. The values encrypted with it are only decrypted, so that the master
+// encrypts them again with AES-GCM.
+var legacyIv = []byte{35, 46, 57, 24, 85, 35, 24, 74, 87, 35, 88, 98, 66, 32, 14, 05}
+
+// Input:
+//
+//	content ([]byte): Content of a legacy secret file
+//
+// Description:
+//
+//	Reads the 16 characters secret of a legacy secret file, which is base32 encoded and scrambled
+//
+// Return:
+//
+//	(string, error): Returns the legacy secret and error if the content is not a legacy secret
+func readLegacySecret(content []byte) (string, error) {
+	decoded, err := base32.StdEncoding.DecodeString(strings.TrimSpace(string(content)))
+	if err != nil {
+		return "", fmt.Errorf("the secret file is neither a key record nor a legacy secret: %w", err)
+	}
+	if len(decoded) != 16 {
+		return "", fmt.Errorf("the legacy secret should have 16 characters, got %d", len(decoded))
+	}
+	return getScrambledOrOriginalSecret(string(decoded), false), nil
+}
+
+// Input:
+//
+//	text (string): Value encrypted with the legacy AES-CFB encryption and base32 encoded
+//	secret (string): Legacy secret
+//
+// Description:
+//
+//	Decrypts a value encrypted by the legacy encryption. Values which are not base32 encoded are plain text
+//	values and decrypted to an empty string, so that they are used as they are.
+//
+// Return:
+//
+//	(string, error): Returns the decrypted value and error if the secret is not a valid AES key
+func legacyDecrypt(text string, secret string) (string, error) {
+	block, err := aes.NewCipher([]byte(secret))
+	if err != nil {
+		return "", err
+	}
+	cipherText, err := base32.StdEncoding.DecodeString(text)
+	if err != nil {
+		return "", nil
+	}
+	cfb := cipher.NewCFBDecrypter(block, legacyIv)
+	plainText := make([]byte, len(cipherText))
+	cfb.XORKeyStream(plainText, cipherText)
+	return string(plainText), nil
+}
+
+// Converts a 16 len string to 4*4 matrix
+func stringToMatrix(str string) [4][4]string {
+	var matrix [4][4]string
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			matrix[i][j] = string(str[i*4+j])
+		}
+	}
+	return matrix
+}
+
+// Returns the transpose of the given matrix
+func transpose(matrix [4][4]string) [4][4]string {
+	var transposedMatrix [4][4]string
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			transposedMatrix[j][i] = matrix[i][j]
+		}
+	}
+	return transposedMatrix
+}
+
+// Returns the matrix with interchanged rows
+func reverse(matrix [4][4]string) [4][4]string {
+	for i, j := 0, len(matrix)-1; i < j; i, j = i+1, j-1 {
+		matrix[i], matrix[j] = matrix[j], matrix[i]
+	}
+	return matrix
+}
+
+// Returns the matrix with intergchanged diagonal values
+func reverse_diag(matrix [4][4]string) [4][4]string {
+	for i := 0; i < 4; i++ {
+		temp := matrix[i][i]
+		matrix[i][i] = matrix[i][4-i-1]
+		matrix[i][4-i-1] = temp
+	}
+	return matrix
+}
+
+// Input :
+// secret (string) : The string which needs to be scrambled or unscrambled
+// scrambled (boolean) : True for scramble, false for unscramble
+//
+// Description :
+// This function scrambles and unscrambles the given string by converting it
+// into matrix and interchanging the values in it.
+//
+// Output :
+// string : scrambled or unscrambled string as per the requirement
+func getScrambledOrOriginalSecret(secret string, scrambled bool) string {
+	var requiredArr []string
+	matrix := stringToMatrix(secret)
+	if scrambled {
+		matrix = reverse_diag(reverse(transpose(matrix)))
+	} else {
+		matrix = transpose(reverse(reverse_diag(matrix)))
+	}
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			requiredArr = append(requiredArr, matrix[i][j])
+		}
+	}
+	return strings.Join(requiredArr, "")
+}
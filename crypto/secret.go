@@ -0,0 +1,267 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+)
+
+// Version of the key record written to the secret file, the legacy secret files hold a scrambled secret
+const keyRecordVersion = 2
+
+// Prefix of the values encrypted with AES-GCM, the values without it are legacy or plain text values
+const encryptedValuePrefix = "enc:v2:"
+
+// Length of the AES-256 data key
+const dataKeyLength = 32
+
+// This struct contains the record of the data key written to the secret file.
+type keyRecord struct {
+	// Version indicates the version of the record.
+	Version int `json:"version"`
+	// Source indicates the name of the key source which wrapped the data key.
+	Source string `json:"source"`
+	// Key indicates the wrapped data key, base64 encoded.
+	Key string `json:"key"`
+}
+
+// A global variable holding the data key encrypting the credentials with AES-GCM
+var dataKey []byte
+
+// A global variable holding the secret of a legacy secret file, set until the credentials are encrypted again
+var legacySecret string
+
+// A global variable holding the content of the secret file the key was read from, to detect its changes
+var secretFileContent []byte
+
+// Input:
+//
+//	keyManagement (config.KeyManagement): Settings of the key sources
+//	cloudRegion (string): Region of the cloud credentials
+//
+// Description:
+//
+//	Reads the data key from the secret file, unwrapping it with the key source recorded in the file. The local
+//	secret file has to be readable only by its owner. A legacy secret file is read for its secret, which is only
+//	used to decrypt the values until the master encrypts them again.
+//
+// Return:
+//
+//	(error): Returns error if the secret file can't be read or the data key can't be unwrapped
+func loadSecretFile(keyManagement config.KeyManagement, cloudRegion string) error {
+	content, err := os.ReadFile(SecretFilepath)
+	if err != nil {
+		return err
+	}
+	var record keyRecord
+	if json.Unmarshal(content, &record) != nil {
+		secret, err := readLegacySecret(content)
+		if err != nil {
+			return err
+		}
+		log.Warn.Println("The secret file has the legacy format, the creds are encrypted again with AES-GCM when the master starts")
+		dataKey, legacySecret, secretFileContent = nil, secret, content
+		return nil
+	}
+	if record.Version != keyRecordVersion {
+		return fmt.Errorf("the secret file has the version %d, expected %d", record.Version, keyRecordVersion)
+	}
+	source, err := NewKeySource(record.Source, keyManagement, cloudRegion)
+	if err != nil {
+		return err
+	}
+	if source.Name() == LocalKeySource {
+		err = checkSecretFilePermissions()
+		if err != nil {
+			return err
+		}
+	}
+	wrappedKey, err := base64.StdEncoding.DecodeString(record.Key)
+	if err != nil {
+		return fmt.Errorf("the key of the secret file is not base64 encoded: %w", err)
+	}
+	key, err := source.UnwrapKey(wrappedKey)
+	if err != nil {
+		return err
+	}
+	if len(key) != dataKeyLength {
+		return fmt.Errorf("the data key should have %d bytes, got %d", dataKeyLength, len(key))
+	}
+	dataKey, legacySecret, secretFileContent = key, "", content
+	return nil
+}
+
+// Input:
+//
+//	keyManagement (config.KeyManagement): Settings of the key sources
+//	cloudRegion (string): Region of the cloud credentials
+//
+// Description:
+//
+//	Generates a new data key from the random source of the operating system, wraps it with the key source of
+//	the config and writes it to the secret file, readable only by its owner
+//
+// Return:
+//
+//	(error): Returns error if the key can't be generated, wrapped or written
+func rotateDataKey(keyManagement config.KeyManagement, cloudRegion string) error {
+	source, err := NewKeySource(keyManagement.Source, keyManagement, cloudRegion)
+	if err != nil {
+		return err
+	}
+	key := make([]byte, dataKeyLength)
+	_, err = rand.Read(key)
+	if err != nil {
+		return err
+	}
+	wrappedKey, err := source.WrapKey(key)
+	if err != nil {
+		return err
+	}
+	content, err := json.Marshal(keyRecord{Version: keyRecordVersion, Source: source.Name(), Key: base64.StdEncoding.EncodeToString(wrappedKey)})
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(SecretFilepath, content, 0600)
+	if err != nil {
+		return err
+	}
+	// WriteFile keeps the permissions of an existing file, like a legacy secret file
+	err = os.Chmod(SecretFilepath, 0600)
+	if err != nil {
+		return err
+	}
+	dataKey, legacySecret, secretFileContent = key, "", content
+	return nil
+}
+
+// Input:
+//
+// Description:
+//
+//	Checks that the secret file holding the data key is readable and writable only by its owner
+//
+// Return:
+//
+//	(error): Returns error if the group or others have any permission on the secret file
+func checkSecretFilePermissions() error {
+	fileInfo, err := os.Stat(SecretFilepath)
+	if err != nil {
+		return err
+	}
+	if fileInfo.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("the secret file %s has the permissions %04o, it should only be accessible by its owner (0600)", SecretFilepath, fileInfo.Mode().Perm())
+	}
+	return nil
+}
+
+// Input:
+//
+//	configStruct (config.ConfigStruct): Config in use
+//
+// Description:
+//
+//	Reads the data key again if the secret file changed, like when the master copied a new one to this node
+//
+// Return:
+//
+//	(bool, error): Returns true if the key changed and error if the new secret file can't be read
+func ReloadKey(configStruct config.ConfigStruct) (bool, error) {
+	content, err := os.ReadFile(SecretFilepath)
+	if err != nil {
+		return false, err
+	}
+	if bytes.Equal(content, secretFileContent) {
+		return false, nil
+	}
+	err = loadSecretFile(configStruct.ClusterDetails.KeyManagement, configStruct.ClusterDetails.CloudCredentials.Region)
+	return err == nil, err
+}
+
+// Input:
+//
+//	key ([]byte): AES-256 data key
+//	plainText (string): Value to be encrypted
+//
+// Description:
+//
+//	Encrypts the value with AES-GCM and a random nonce, which is stored before the cipher text
+//
+// Return:
+//
+//	(string, error): Returns enc:v2: followed by the base64 encoded nonce and cipher text, and error if any
+func encryptValue(key []byte, plainText string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plainText), nil)
+	return encryptedValuePrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Input:
+//
+//	key ([]byte): AES-256 data key
+//	value (string): Value encrypted by encryptValue
+//
+// Description:
+//
+//	Decrypts and authenticates the value, a value encrypted with another key or changed fails
+//
+// Return:
+//
+//	(string, error): Returns the decrypted value and error if it can't be decrypted
+func decryptValue(key []byte, value string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedValuePrefix))
+	if err != nil {
+		return "", fmt.Errorf("the encrypted value is not base64 encoded: %w", err)
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("the encrypted value is too short")
+	}
+	plainText, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("the encrypted value can't be authenticated with the data key")
+	}
+	return string(plainText), nil
+}
+
+// Input:
+//
+//	key ([]byte): AES-256 data key
+//
+// Description:
+//
+//	Creates the AES-GCM cipher of the key
+//
+// Return:
+//
+//	(cipher.AEAD, error): Returns the cipher and error if the key is missing or invalid
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) == 0 {
+		return nil, errors.New("no data key is loaded from the secret file")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
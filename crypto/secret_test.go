@@ -0,0 +1,147 @@
+package crypto
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// Points the secret file to a temporary directory and resets the loaded key
+func useTempSecretFile(t *testing.T) {
+	previousPath := SecretFilepath
+	SecretFilepath = filepath.Join(t.TempDir(), ".secret.txt")
+	dataKey, legacySecret, secretFileContent = nil, "", nil
+	t.Cleanup(func() {
+		SecretFilepath = previousPath
+		dataKey, legacySecret, secretFileContent = nil, "", nil
+	})
+}
+
+func TestEncryptValue(t *testing.T) {
+	key := make([]byte, dataKeyLength)
+	encrypted, err := encryptValue(key, "s3cr3t-Passw0rd")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encrypted, encryptedValuePrefix))
+	decrypted, err := decryptValue(key, encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cr3t-Passw0rd", decrypted)
+
+	// Every value has its own nonce
+	encryptedAgain, err := encryptValue(key, "s3cr3t-Passw0rd")
+	assert.NoError(t, err)
+	assert.NotEqual(t, encrypted, encryptedAgain)
+
+	otherKey := make([]byte, dataKeyLength)
+	otherKey[0] = 1
+	_, err = decryptValue(otherKey, encrypted)
+	assert.Error(t, err)
+
+	// A changed value fails the authentication
+	tampered := []byte(encrypted)
+	tampered[len(tampered)-3] ^= 1
+	_, err = decryptValue(key, string(tampered))
+	assert.Error(t, err)
+
+	_, err = decryptValue(key, encryptedValuePrefix+"AAAA")
+	assert.Error(t, err)
+	_, err = encryptValue(nil, "admin")
+	assert.Error(t, err)
+}
+
+func TestRotateAndLoadDataKey(t *testing.T) {
+	useTempSecretFile(t)
+	err := rotateDataKey(config.KeyManagement{}, "")
+	assert.NoError(t, err)
+	key := dataKey
+	assert.Len(t, key, dataKeyLength)
+
+	fileInfo, err := os.Stat(SecretFilepath)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fileInfo.Mode().Perm())
+	var record keyRecord
+	content, _ := os.ReadFile(SecretFilepath)
+	assert.NoError(t, json.Unmarshal(content, &record))
+	assert.Equal(t, keyRecord{Version: keyRecordVersion, Source: LocalKeySource, Key: record.Key}, record)
+
+	encrypted, err := GetEncryptedData("admin")
+	assert.NoError(t, err)
+	dataKey = nil
+	err = loadSecretFile(config.KeyManagement{}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, key, dataKey)
+	assert.Equal(t, "admin", GetDecryptedData(encrypted))
+	// Plain text values are used as they are
+	assert.Equal(t, "", GetDecryptedData("admin"))
+
+	err = rotateDataKey(config.KeyManagement{}, "")
+	assert.NoError(t, err)
+	assert.NotEqual(t, key, dataKey)
+	assert.Panics(t, func() { GetDecryptedData(encrypted) })
+}
+
+func TestLoadDataKeyWithOpenPermissions(t *testing.T) {
+	useTempSecretFile(t)
+	assert.NoError(t, rotateDataKey(config.KeyManagement{}, ""))
+	assert.NoError(t, os.Chmod(SecretFilepath, 0644))
+	err := loadSecretFile(config.KeyManagement{}, "")
+	assert.EqualError(t, err, fmt.Sprintf("the secret file %s has the permissions 0644, it should only be accessible by its owner (0600)", SecretFilepath))
+
+	// A new key fixes the permissions of the existing file
+	assert.NoError(t, rotateDataKey(config.KeyManagement{}, ""))
+	assert.NoError(t, loadSecretFile(config.KeyManagement{}, ""))
+}
+
+func TestLegacySecretMigration(t *testing.T) {
+	useTempSecretFile(t)
+	content, err := os.ReadFile("testdata/legacy_secret.txt")
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(SecretFilepath, content, 0644))
+
+	err = loadSecretFile(config.KeyManagement{}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "Ab3*cdEfGh1@ijKl", legacySecret)
+	assert.Nil(t, dataKey)
+	// Values encrypted by the legacy AES-CFB encryption with the secret
+	assert.Equal(t, "admin", GetDecryptedData("NG4ZUKUI"))
+	assert.Equal(t, "s3cr3t-Passw0rd", GetDecryptedData("PPXJIMOVMWPB5DG2SL7N3XOS"))
+	assert.Equal(t, "", GetDecryptedData("plain-text"))
+
+	// The master encrypts the values again with a new data key
+	assert.NoError(t, rotateDataKey(config.KeyManagement{}, ""))
+	assert.Equal(t, "", legacySecret)
+	encrypted, err := GetEncryptedData("admin")
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", GetDecryptedData(encrypted))
+	assert.Equal(t, "", GetDecryptedData("NG4ZUKUI"))
+
+	_, err = readLegacySecret([]byte("not a secret"))
+	assert.Error(t, err)
+}
+
+func TestReloadKey(t *testing.T) {
+	useTempSecretFile(t)
+	assert.NoError(t, rotateDataKey(config.KeyManagement{}, ""))
+	configStruct := config.ConfigStruct{}
+
+	changed, err := ReloadKey(configStruct)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	// The master copies a new secret file
+	key := dataKey
+	content := secretFileContent
+	assert.NoError(t, rotateDataKey(config.KeyManagement{}, ""))
+	newKey := dataKey
+	dataKey, secretFileContent = key, content
+
+	changed, err = ReloadKey(configStruct)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, newKey, dataKey)
+}
@@ -0,0 +1,286 @@
+package fetchmetrics
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
+	utils "github.com/maplelabs/opensearch-scaling-manager/utilities"
+)
+
+// Description: NodeGap is indexed by the master for a node which reported metrics earlier but is missing from the cluster
+type NodeGap struct {
+	NodeId        string
+	NodeName      string
+	HostIp        string
+	LastSeen      int64
+	Timestamp     int64
+	StatTag       string
+	_documentType string
+}
+
+// Description: seenNode holds the details of a node the last time it was present in the node stats response
+type seenNode struct {
+	NodeName string
+	HostIp   string
+	LastSeen int64
+}
+
+// Nodes which were present in the cluster, keyed by node id. Only maintained on the master node.
+var seenNodes = make(map[string]seenNode)
+
+// Set once seenNodes has been loaded from the documents indexed by the previous master
+var seenNodesLoaded bool
+
+// Nodes missing for longer than this are considered as removed from the cluster and no gap is reported for them
+const gapReportWindow = 6 * time.Hour
+
+// Input:
+//
+//	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//
+// Description:
+//
+//	Fetches the node stats of all the nodes in a single call and indexes a document per node.
+//	CPU and memory utilization are taken from the opensearch os stats as the system can't be read from the master,
+//	and no document is indexed for a node without them. Nodes which were reporting earlier and are missing from the
+//	node stats response now are indexed as a NodeGap document.
+//
+// Return:
+func IndexAllNodeStats(ctx context.Context) {
+	if !seenNodesLoaded {
+		loadSeenNodes(ctx)
+		seenNodesLoaded = true
+	}
+
+	nodes := []string{"_all"}
+	metrics := []string{"jvm", "os", "fs", "indices", "indexing_pressure"}
+	nodesStats, err := osutils.FetchNodesStats(ctx, nodes, metrics)
+	if err != nil {
+		log.Error.Println("Node stat fetch error: ", err)
+		return
+	}
+
+	shardsPerNode := getShardsPerNode(ctx)
+
+	masterNodeId, err := utils.GetMasterNodeId(ctx)
+	if err != nil {
+		log.Error.Println("Unable to fetch the master node: ", err)
+	}
+
+	currentNodes := make(map[string]bool)
+	indexedNodes := 0
+	for nodeId, nodeInfo := range nodesStats.Nodes {
+		// The node is in the cluster even if its document can't be indexed, so no gap is reported for it
+		currentNodes[nodeId] = true
+		seenNodes[nodeId] = seenNode{NodeName: nodeInfo.Name, HostIp: nodeInfo.Host, LastSeen: nodeInfo.Timestamp}
+
+		nodeMetrics := new(NodeMetrics)
+		nodeMetrics.NumShards = shardsPerNode[nodeInfo.Name]
+		err = populateNodeMetrics(nodeMetrics, nodeId, nodeInfo, nodeId == masterNodeId)
+		if err != nil {
+			log.Error.Println("Unable to read the node stats of node ", nodeInfo.Name, ": ", err)
+			continue
+		}
+		cpuUtil, ramUtil, osStatsErr := getOsStatsUtil(nodeInfo)
+		if osStatsErr != nil {
+			log.Error.Println("Unable to read CPU and memory utilization of node ", nodeMetrics.NodeName, ", skipping its document: ", osStatsErr)
+			continue
+		}
+		nodeMetrics.CpuUtil = cpuUtil
+		nodeMetrics.RamUtil = ramUtil
+
+		nodeMetricsJson, jsonErr := json.MarshalIndent(nodeMetrics, "", "\t")
+		if jsonErr != nil {
+			log.Error.Println("Error converting struct to Json: ", jsonErr)
+			continue
+		}
+		resp, err := osutils.IndexMetrics(ctx, osutils.NodeStatsIndex, nodeMetricsJson)
+		err = osutils.DecodeResponse(resp, err, nil)
+		if err != nil {
+			log.Error.Println("Error indexing document of node ", nodeMetrics.NodeName, ": ", err)
+			continue
+		}
+		indexedNodes++
+	}
+	log.Info.Println("Node documents of ", indexedNodes, " nodes indexed successfully")
+
+	indexNodeGaps(ctx, currentNodes)
+}
+
+// Input:
+//
+//	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//
+// Description:
+//
+//	Fetches the number of shards allocated to each node.
+//
+// Return:
+//
+//	(map[string]int): Returns the number of shards keyed by node name
+func getShardsPerNode(ctx context.Context) map[string]int {
+	shardsPerNode := make(map[string]int)
+	allocations, err := osutils.FetchCatAllocation(ctx, []string{})
+	if err != nil {
+		log.Error.Println("Cat allocation fetch error: ", err)
+		return shardsPerNode
+	}
+	for _, allocation := range allocations {
+		numShards, err := allocation.NumShards()
+		if err != nil {
+			continue
+		}
+		shardsPerNode[allocation.Node] = numShards
+	}
+	return shardsPerNode
+}
+
+// Input:
+//
+//	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//	currentNodes (map[string]bool): Ids of the nodes present in the current node stats response
+//
+// Description:
+//
+//	Indexes a NodeGap document for every node seen within the gapReportWindow which is missing now.
+//	Nodes missing for longer than the window are forgotten.
+//
+// Return:
+func indexNodeGaps(ctx context.Context, currentNodes map[string]bool) {
+	now := time.Now()
+	for nodeId, node := range seenNodes {
+		if currentNodes[nodeId] {
+			continue
+		}
+		if now.Sub(time.UnixMilli(node.LastSeen)) > gapReportWindow {
+			log.Info.Println("Node ", node.NodeName, " is missing for more than ", gapReportWindow, ", no longer reporting it as a gap")
+			delete(seenNodes, nodeId)
+			continue
+		}
+		log.Warn.Println("Node ", node.NodeName, " (", node.HostIp, ") is missing from the cluster, last seen at ", time.UnixMilli(node.LastSeen))
+		nodeGap := NodeGap{
+			NodeId:        nodeId,
+			NodeName:      node.NodeName,
+			HostIp:        node.HostIp,
+			LastSeen:      node.LastSeen,
+			Timestamp:     now.UnixMilli(),
+			StatTag:       "NodeGap",
+			_documentType: "NodeGap",
+		}
+		nodeGapJson, jsonErr := json.Marshal(nodeGap)
+		if jsonErr != nil {
+			log.Error.Println("Error converting struct to Json: ", jsonErr)
+			continue
+		}
+		resp, err := osutils.IndexMetrics(ctx, osutils.NodeStatsIndex, nodeGapJson)
+		err = osutils.DecodeResponse(resp, err, nil)
+		if err != nil {
+			log.Error.Println("Error indexing gap document of node ", node.NodeName, ": ", err)
+			continue
+		}
+	}
+}
+
+// Input:
+//
+// Description:
+//
+//	Generates the query to fetch the latest node document of every node which reported within the gapReportWindow
+//
+// Return:
+//
+//	(string): Returns the query string that can be given as an OS query api parameter.
+func getSeenNodesQuery() string {
+	return `{
+          "size": 0,
+          "query": {
+            "bool": {
+              "filter": {
+                "range": {
+                  "Timestamp": {
+                    "gte": "now-` + strconv.Itoa(int(gapReportWindow.Minutes())) + `m"
+                  }
+                }
+              },
+              "must": [
+                {
+                  "term": {
+                    "StatTag": "NodeStatistics"
+                  }
+                }
+              ]
+            }
+          },
+          "aggs": {
+            "nodes": {
+              "terms": {
+                "field": "NodeId",
+                "size": 1000
+              },
+              "aggs": {
+                "latest": {
+                  "top_hits": {
+                    "size": 1,
+                    "sort": {
+                      "Timestamp": "desc"
+                    },
+                    "_source": ["NodeName", "HostIp", "Timestamp"]
+                  }
+                }
+              }
+            }
+          }
+        }`
+}
+
+// Input:
+//
+//	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//
+// Description:
+//
+//	Loads the nodes which reported metrics recently from the index, so that gaps are also detected
+//	for nodes which went missing before the current node became the master.
+//
+// Return:
+func loadSeenNodes(ctx context.Context) {
+	var seenNodesResult struct {
+		Aggregations struct {
+			Nodes struct {
+				Buckets []struct {
+					Key    string `json:"key"`
+					Latest struct {
+						Hits struct {
+							Hits []struct {
+								Source struct {
+									NodeName  string
+									HostIp    string
+									Timestamp int64
+								} `json:"_source"`
+							} `json:"hits"`
+						} `json:"hits"`
+					} `json:"latest"`
+				} `json:"buckets"`
+			} `json:"nodes"`
+		} `json:"aggregations"`
+	}
+	resp, err := osutils.SearchQuery(ctx, osutils.NodeStatsIndex, []byte(getSeenNodesQuery()))
+	err = osutils.DecodeResponse(resp, err, &seenNodesResult)
+	if err != nil {
+		log.Error.Println("Unable to fetch the nodes which reported earlier: ", err)
+		return
+	}
+
+	for _, bucket := range seenNodesResult.Aggregations.Nodes.Buckets {
+		hits := bucket.Latest.Hits.Hits
+		if bucket.Key == "" || len(hits) == 0 {
+			continue
+		}
+		source := hits[0].Source
+		seenNodes[bucket.Key] = seenNode{NodeName: source.NodeName, HostIp: source.HostIp, LastSeen: source.Timestamp}
+	}
+	log.Info.Println("Loaded ", len(seenNodes), " nodes which reported metrics earlier")
+}
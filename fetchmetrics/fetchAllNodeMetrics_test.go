@@ -0,0 +1,159 @@
+package fetchmetrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
+	opensearch "github.com/opensearch-project/opensearch-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// This struct contains the opensearch cluster served to the tests and the documents indexed into it.
+type fakeCluster struct {
+	seenNodes  string
+	nodesStats string
+	// failNodeIndex fails the indexing of the NodeStatistics documents
+	failNodeIndex bool
+	documents     []map[string]interface{}
+}
+
+// Starts a server serving the cluster and points the client of the osutils package to it
+func (c *fakeCluster) serve(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		// The client checks the product with a GET on the root first
+		case r.URL.Path == "/":
+			w.Write([]byte(`{"version": {"number": "2.4.0"}}`))
+		case strings.HasSuffix(r.URL.Path, "/_search"):
+			w.Write([]byte(c.seenNodes))
+		case strings.HasPrefix(r.URL.Path, "/_nodes"):
+			w.Write([]byte(c.nodesStats))
+		case strings.HasPrefix(r.URL.Path, "/_cat/allocation"):
+			w.Write([]byte(`[{"shards": "4", "node": "node-1", "ip": "10.0.0.1"}]`))
+		case strings.HasPrefix(r.URL.Path, "/_cluster/state"):
+			w.Write([]byte(`{"master_node": "n1"}`))
+		case strings.HasPrefix(r.URL.Path, "/"+osutils.NodeStatsIndex+"/_doc/"):
+			body, _ := io.ReadAll(r.Body)
+			var document map[string]interface{}
+			assert.Nil(t, json.Unmarshal(body, &document))
+			if c.failNodeIndex && document["StatTag"] == "NodeStatistics" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{}`))
+				return
+			}
+			c.documents = append(c.documents, document)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	client, err := osutils.NewClient(opensearch.Config{Addresses: []string{server.URL}, DisableRetry: true})
+	assert.Nil(t, err)
+	previous := osutils.SetClient(client)
+	t.Cleanup(func() {
+		osutils.SetClient(previous)
+		server.Close()
+		seenNodes = make(map[string]seenNode)
+		seenNodesLoaded = false
+	})
+}
+
+// Returns the NodeName of the documents with the stat tag
+func (c *fakeCluster) indexedNodes(statTag string) []string {
+	var nodeNames []string
+	for _, document := range c.documents {
+		if document["StatTag"] == statTag {
+			nodeNames = append(nodeNames, document["NodeName"].(string))
+		}
+	}
+	return nodeNames
+}
+
+func TestIndexAllNodeStats(t *testing.T) {
+	now := time.Now()
+	cluster := &fakeCluster{
+		// node-3 reported to the previous master and left the cluster since
+		seenNodes: fmt.Sprintf(`{"aggregations": {"nodes": {"buckets": [
+			{"key": "n1", "latest": {"hits": {"hits": [{"_source": {"NodeName": "node-1", "HostIp": "10.0.0.1", "Timestamp": %d}}]}}},
+			{"key": "n3", "latest": {"hits": {"hits": [{"_source": {"NodeName": "node-3", "HostIp": "10.0.0.3", "Timestamp": %d}}]}}},
+			{"key": "n4", "latest": {"hits": {"hits": []}}}
+		]}}}`, now.Add(-time.Minute).UnixMilli(), now.Add(-10*time.Minute).UnixMilli()),
+		// The jvm stats of node-2 are missing, so its document can't be populated
+		nodesStats: fmt.Sprintf(`{"nodes": {
+			"n1": {"name": "node-1", "host": "10.0.0.1", "timestamp": %d, "roles": ["data"],
+				"os": {"cpu": {"percent": 40}, "mem": {"used_percent": 60}},
+				"jvm": {"mem": {"heap_used_percent": 50, "heap_committed_in_bytes": 1073741824}},
+				"fs": {"total": {"total_in_bytes": 100, "available_in_bytes": 25}}},
+			"n2": {"name": "node-2", "host": "10.0.0.2", "timestamp": %d, "roles": ["data"],
+				"os": {"cpu": {"percent": 40}, "mem": {"used_percent": 60}},
+				"fs": {"total": {"total_in_bytes": 100, "available_in_bytes": 25}}}
+		}}`, now.UnixMilli(), now.UnixMilli()),
+	}
+	cluster.serve(t)
+
+	IndexAllNodeStats(context.Background())
+	assert.Equal(t, []string{"node-1"}, cluster.indexedNodes("NodeStatistics"))
+	// The node missing from the response is reported, the node whose document failed is not
+	assert.Equal(t, []string{"node-3"}, cluster.indexedNodes("NodeGap"))
+	assert.Len(t, cluster.documents, 2)
+	node := cluster.documents[0]
+	if node["StatTag"] != "NodeStatistics" {
+		node = cluster.documents[1]
+	}
+	assert.Equal(t, float64(40), node["CpuUtil"])
+	assert.Equal(t, float64(60), node["RamUtil"])
+	assert.Equal(t, float64(75), node["DiskUtil"])
+	assert.Equal(t, float64(4), node["NumShards"])
+	assert.Equal(t, true, node["IsMaster"])
+	assert.Contains(t, seenNodes, "n2")
+	assert.NotContains(t, seenNodes, "n4")
+}
+
+func TestIndexAllNodeStatsIndexingFailure(t *testing.T) {
+	cluster := &fakeCluster{
+		seenNodes: `{"aggregations": {"nodes": {"buckets": []}}}`,
+		nodesStats: fmt.Sprintf(`{"nodes": {
+			"n1": {"name": "node-1", "host": "10.0.0.1", "timestamp": %d, "roles": ["data"],
+				"os": {"cpu": {"percent": 40}, "mem": {"used_percent": 60}},
+				"jvm": {"mem": {"heap_used_percent": 50, "heap_committed_in_bytes": 1073741824}},
+				"fs": {"total": {"total_in_bytes": 100, "available_in_bytes": 25}}}
+		}}`, time.Now().UnixMilli()),
+	}
+	cluster.serve(t)
+	IndexAllNodeStats(context.Background())
+	assert.Equal(t, []string{"node-1"}, cluster.indexedNodes("NodeStatistics"))
+
+	// A node still in the cluster whose document can't be indexed is not reported as a gap
+	cluster.failNodeIndex = true
+	IndexAllNodeStats(context.Background())
+	cluster.failNodeIndex = false
+	IndexAllNodeStats(context.Background())
+	assert.Empty(t, cluster.indexedNodes("NodeGap"))
+	assert.Equal(t, []string{"node-1", "node-1"}, cluster.indexedNodes("NodeStatistics"))
+}
+
+func TestIndexNodeGaps(t *testing.T) {
+	cluster := &fakeCluster{}
+	cluster.serve(t)
+	now := time.Now()
+	seenNodes["n1"] = seenNode{NodeName: "node-1", HostIp: "10.0.0.1", LastSeen: now.UnixMilli()}
+	seenNodes["n2"] = seenNode{NodeName: "node-2", HostIp: "10.0.0.2", LastSeen: now.Add(-time.Hour).UnixMilli()}
+	seenNodes["n3"] = seenNode{NodeName: "node-3", HostIp: "10.0.0.3", LastSeen: now.Add(-gapReportWindow - time.Minute).UnixMilli()}
+
+	indexNodeGaps(context.Background(), map[string]bool{"n1": true})
+	assert.Equal(t, []string{"node-2"}, cluster.indexedNodes("NodeGap"))
+	assert.Equal(t, "10.0.0.2", cluster.documents[0]["HostIp"])
+	assert.Equal(t, float64(seenNodes["n2"].LastSeen), cluster.documents[0]["LastSeen"])
+	// The node missing for longer than the window is forgotten
+	assert.NotContains(t, seenNodes, "n3")
+}
@@ -27,60 +27,30 @@ type ClusterMetrics struct {
 //
 // Return:
 //
-//	(ClusterMetrics): Returns cluster metrics struct
-func FetchClusterHealthMetrics(ctx context.Context) ClusterMetrics {
+//	(ClusterMetrics, error): Returns cluster metrics struct and error if any
+func FetchClusterHealthMetrics(ctx context.Context) (ClusterMetrics, error) {
+	clusterMetrics := new(ClusterMetrics)
 
-	//Create an interface to capture the response from cluster health and cluster stats API
-	var clusterStatsInterface map[string]interface{}
-	var clusterHealthInterface map[string]interface{}
-
-	clusterStats := new(ClusterMetrics)
-
-	//Create a cluster stats request and fetch the response
-	resp, err := osutils.GetClusterStats(ctx)
+	//Fetch the cluster stats
+	clusterStats, err := osutils.FetchClusterStats(ctx)
 	if err != nil {
-		log.Error.Println("cluster Stats fetch ERROR:", err)
+		return *clusterMetrics, err
 	}
-	defer resp.Body.Close()
-
-	//decode and dump the cluster stats response into interface
-	decodeErr := json.NewDecoder(resp.Body).Decode(&clusterStatsInterface)
-	if decodeErr != nil {
-		log.Error.Println("decode Error: ", decodeErr)
-	}
-
-	//Parse the interface and populate required fields in cluster stats
-	clusterStats.NumActiveDataNodes = int(clusterStatsInterface["nodes"].(map[string]interface{})["count"].(map[string]interface{})["data"].(float64))
-	clusterStats.NumMasterNodes = int(clusterStatsInterface["nodes"].(map[string]interface{})["count"].(map[string]interface{})["master"].(float64))
-	clusterStats.Timestamp = int64(clusterStatsInterface["timestamp"].(float64))
-	clusterStats.TotalShards = int(clusterStatsInterface["indices"].(map[string]interface{})["shards"].(map[string]interface{})["total"].(float64))
 
-	//create a cluster health request and fetch cluster health
+	//Fetch the cluster health
 	waitForShards := false
-	clusterHealthRequest, err := osutils.GetClusterHealth(ctx, &waitForShards)
+	clusterHealth, err := osutils.FetchClusterHealth(ctx, &waitForShards)
 	if err != nil {
-		log.Error.Println("cluster Health fetch ERROR:", err)
+		return *clusterMetrics, err
 	}
-	defer clusterHealthRequest.Body.Close()
 
-	//Decode the response and dump the response into the cluster health interface
-	decodeErr2 := json.NewDecoder(clusterHealthRequest.Body).Decode(&clusterHealthInterface)
-	if decodeErr2 != nil {
-		log.Error.Println("decode Error: ", decodeErr)
-	}
-
-	//Parse the interface and populate required fields in cluster stats
-	clusterStats.NumNodes = int(clusterHealthInterface["number_of_nodes"].(float64))
-	clusterStats.ClusterStatus = clusterStatsInterface["status"].(string)
-	clusterStats.NumActiveShards = int(clusterHealthInterface["active_shards"].(float64))
-	clusterStats.NumActivePrimaryShards = int(clusterHealthInterface["active_primary_shards"].(float64))
-	clusterStats.NumInitializingShards = int(clusterHealthInterface["initializing_shards"].(float64))
-	clusterStats.NumUnassignedShards = int(clusterHealthInterface["unassigned_shards"].(float64))
-	clusterStats.NumRelocatingShards = int(clusterHealthInterface["relocating_shards"].(float64))
-	clusterStats.StatTag = "ClusterStatistics"
-	clusterStats._documentType = "ClusterStatistics"
-	clusterStats.ClusterName = clusterHealthInterface["cluster_name"].(string)
-	return *clusterStats
+	//Populate required fields in cluster metrics
+	cluster.PopulateClusterDynamic(&clusterMetrics.ClusterDynamic, clusterStats, clusterHealth)
+	clusterMetrics.Timestamp = clusterStats.Timestamp
+	clusterMetrics.StatTag = "ClusterStatistics"
+	clusterMetrics._documentType = "ClusterStatistics"
+	clusterMetrics.ClusterName = clusterHealth.ClusterName
+	return *clusterMetrics, nil
 }
 
 // Input:
@@ -93,23 +63,26 @@ func FetchClusterHealthMetrics(ctx context.Context) ClusterMetrics {
 //
 // Return:
 func IndexClusterHealth(ctx context.Context) {
-	var clusterHealth = ClusterMetrics{}
-
 	//fetch the cluster stats
-	clusterHealth = FetchClusterHealthMetrics(ctx)
+	clusterHealth, err := FetchClusterHealthMetrics(ctx)
+	if err != nil {
+		log.Error.Println("Unable to fetch the cluster stats: ", err)
+		return
+	}
 
 	//Convert the cluster stats struct into Json
 	clusterHealthJson, jsonErr := json.MarshalIndent(clusterHealth, "", "\t")
 	if jsonErr != nil {
 		log.Error.Println("Error converting struct to json: ", jsonErr)
+		return
 	}
 
 	//Check and index the Json document into opensearch
-	resp, err := osutils.IndexMetrics(ctx, clusterHealthJson)
+	resp, err := osutils.IndexMetrics(ctx, osutils.ClusterStatsIndex, clusterHealthJson)
+	err = osutils.DecodeResponse(resp, err, nil)
 	if err != nil {
-		log.Panic.Println("Error indexing cluster document: ", err)
-		panic(err)
+		log.Error.Println("Error indexing cluster document: ", err)
+		return
 	}
-	defer resp.Body.Close()
 	log.Info.Println("Cluster document indexed successfully")
 }
@@ -2,6 +2,7 @@ package fetchmetrics
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/maplelabs/opensearch-scaling-manager/logger"
@@ -18,27 +19,110 @@ func init() {
 	log.Info.Println("FetchMetrics module initiated")
 }
 
+// This struct contains the settings of the metrics collection which can be changed while it runs.
+type fetchSettings struct {
+	pollingInterval int
+	purgeAfter      int
+	collectionMode  string
+}
+
+// A channel passing the settings changed in the config file to the running metrics collection
+var settingsUpdates = make(chan fetchSettings, 1)
+
 // Input:
 //
-//	pollingInterval(int): Interval (minutes) at which metrics are fetched and indexed
+//	pollingInterval(int): Interval (seconds) at which metrics are fetched and indexed
+//	purgeAfter(int): Number of hours after which the documents are purged
+//	collectionMode(string): "node" if every node indexes its own metrics, "master" if the master indexes metrics of all the nodes
 //
 // Descriptions:
 //
-//		Fetch metrics will index node level and cluster level(if current node is master)
-//		parameters to opensearch index and deletes documents that are older than
-//	    72 hours
+//	Fetch metrics will index node level and cluster level(if current node is master)
+//	parameters to opensearch index. The master also purges documents that are older than
+//	purgeAfter hours. The settings passed with UpdateSettings replace the given ones while it runs.
 //
 // Return:
-func FetchMetrics(pollingInterval int, purgeAfter int) {
+func FetchMetrics(pollingInterval int, purgeAfter int, collectionMode string) {
+	settings := fetchSettings{pollingInterval, purgeAfter, collectionMode}
 	ticker := time.NewTicker(time.Duration(pollingInterval) * time.Second)
-	for ; true; <-ticker.C {
+	for ; true; settings = waitForTick(ticker, settings) {
 		//check if current node is the master node and update the cluster stats if it is master
-		if utils.CheckIfMaster(ctx, "") {
+		isMaster, err := utils.CheckIfMaster(ctx, "")
+		if err != nil {
+			log.Error.Println("Unable to check if the node is master, skipping the metrics collection: ", err)
+			continue
+		}
+		if isMaster {
 			IndexClusterHealth(ctx)
+			//Purge documents from opensearch index that are older than purgeAfter hours
+			PurgeOldDocs(ctx, settings.purgeAfter)
+		} else {
+			// The purge has to be set up again if this node becomes master later
+			appliedPurgeAfter = 0
+		}
+		if settings.collectionMode == "master" {
+			//Index the node stats of all the nodes from the master
+			if isMaster {
+				IndexAllNodeStats(ctx)
+			} else {
+				// Gaps have to be detected again from the index if this node becomes master later
+				seenNodesLoaded = false
+			}
+		} else {
+			//Index the the node stats
+			IndexNodeStats(ctx)
+		}
+	}
+}
+
+// Input:
+//
+//	pollingInterval(int): Interval (seconds) at which metrics are fetched and indexed
+//	purgeAfter(int): Number of hours after which the documents are purged
+//	collectionMode(string): "node" if every node indexes its own metrics, "master" if the master indexes metrics of all the nodes
+//
+// Descriptions:
+//
+//	Passes the settings changed in the config file to the running metrics collection, which applies them from
+//	its next collection. Only the latest settings are kept if the collection has not picked up the previous ones.
+//
+// Return:
+func UpdateSettings(pollingInterval int, purgeAfter int, collectionMode string) {
+	select {
+	case <-settingsUpdates:
+	default:
+	}
+	settingsUpdates <- fetchSettings{pollingInterval, purgeAfter, collectionMode}
+}
+
+// Input:
+//
+//	ticker(*time.Ticker): Ticker of the metrics collection
+//	settings(fetchSettings): Settings in use
+//
+// Descriptions:
+//
+//	Waits for the next tick of the metrics collection, applying the settings changed meanwhile. The ticker is
+//	reset when the polling interval changes.
+//
+// Return:
+//
+//	(fetchSettings): Returns the settings to be used for the next collection
+func waitForTick(ticker *time.Ticker, settings fetchSettings) fetchSettings {
+	for {
+		select {
+		case <-ticker.C:
+			return settings
+		case updated := <-settingsUpdates:
+			if updated.pollingInterval != settings.pollingInterval {
+				log.Info.Println(fmt.Sprintf("Metrics are now fetched every %d seconds", updated.pollingInterval))
+				ticker.Reset(time.Duration(updated.pollingInterval) * time.Second)
+			}
+			if updated.collectionMode != settings.collectionMode {
+				// Gaps have to be detected again from the index as the nodes were not tracked meanwhile
+				seenNodesLoaded = false
+			}
+			settings = updated
 		}
-		//Index the the node stats
-		IndexNodeStats(ctx)
-		//Purge documents from elasticsearch index that are older than 72 hours
-		// DeleteOldDocs(ctx, purgeAfter)
 	}
 }
@@ -0,0 +1,38 @@
+package fetchmetrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitForTickAppliesSettings(t *testing.T) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	UpdateSettings(300, 48, "node")
+	UpdateSettings(1, 24, "master")
+
+	start := time.Now()
+	settings := waitForTick(ticker, fetchSettings{3600, 72, "node"})
+	assert.Equal(t, fetchSettings{1, 24, "master"}, settings)
+	assert.Less(t, time.Since(start), 10*time.Second)
+}
+
+func TestSelectUtil(t *testing.T) {
+	systemErr := errors.New("no cgroup")
+	osErr := errors.New("no os stats")
+
+	util, err := selectUtil("CPU", 42, nil, 30, osErr)
+	assert.Nil(t, err)
+	assert.Equal(t, float32(42), util)
+
+	util, err = selectUtil("CPU", 0, systemErr, 30, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, float32(30), util)
+
+	// No utilization is made up when neither source can be read
+	_, err = selectUtil("CPU", 0, systemErr, 0, osErr)
+	assert.EqualError(t, err, "unable to read CPU utilization from the system (no cgroup) or from opensearch os stats (no os stats)")
+}
@@ -3,12 +3,11 @@ package fetchmetrics
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/maplelabs/opensearch-scaling-manager/cluster"
 	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
 	utils "github.com/maplelabs/opensearch-scaling-manager/utilities"
-	"os/exec"
-	"strconv"
-	"strings"
 )
 
 // Description: NodeMetrics struct holds the node level metrics that are to be populated and indexed to elasticsearch
@@ -21,83 +20,50 @@ type NodeMetrics struct {
 
 // Input:
 //
-//	m(map[string]interface): Holds the node stats response
-//	nodeId(string): Unique id which is used to access the response of corresponding node.
+//	nodeInfo (osutils.NodeStats): Holds the node stats response of a single node
 //
 // Description:
 //
-//	The function calculates and returns disk utilization.
-//	The disk utilization is fetched from the node stats response from elasticsearch, there is no difference in terms
-//	of output when we fetch from linux or elasticsearch. And to fetch from the linux we need to make a call to elasticsearch
-//	to find where the binary of es is installed, when we make this call we get the disk utilization along with the mount path
-//	hence the overhead of calculating from linux is minimized by making use of response from elasticsearch.
+//	Reads the CPU and memory utilization from the "os" section of the node stats response.
+//	These are used when the utilization can't be read from the system directly.
 //
 // Return:
 //
-//	(float32): Returns the disk utilization of the node
-func getDiskUtil(m map[string]interface{}, nodeId string) float32 {
-	//Parse the node stats interface for required info
-	list := m["nodes"].(map[string]interface{})[nodeId].(map[string]interface{})["fs"].(map[string]interface{})["data"].([]interface{})
-	listJson, err := json.MarshalIndent(list[0], "", " ")
-	if err != nil {
-		log.Error.Println("Cannot marshall: ", err)
+//	(float32, float32, error): Returns the CPU utilization, memory utilization and error if the section is missing
+func getOsStatsUtil(nodeInfo osutils.NodeStats) (float32, float32, error) {
+	if nodeInfo.Os == nil {
+		return 0, 0, errors.New("os stats not present in node stats response")
 	}
-	var listInterface map[string]interface{}
-	err1 := json.Unmarshal(listJson, &listInterface)
-	if err1 != nil {
-		log.Error.Println("Unmarshal Error: ", err1)
+	if nodeInfo.Os.Cpu == nil || nodeInfo.Os.Mem == nil {
+		return 0, 0, errors.New("cpu or mem stats not present in node stats response")
 	}
-	// disk utilization = ((total space - available space) / total space) *100
-	return float32(((listInterface["total_in_bytes"].(float64) - listInterface["available_in_bytes"].(float64)) / listInterface["total_in_bytes"].(float64)) * 100)
+	return nodeInfo.Os.Cpu.Percent, nodeInfo.Os.Mem.UsedPercent, nil
 }
 
 // Input:
-// Description:
 //
-//	The functions fetchs CPU utilization directly from system through linux commands
+//	name (string): Name of the utilization, used in the messages
+//	systemUtil (float32): Utilization read from the system
+//	systemErr (error): Error reading the utilization from the system
+//	osUtil (float32): Utilization read from the opensearch os stats
+//	osErr (error): Error reading the opensearch os stats
 //
-// Output:
-//
-//	(float32): Returns CPU utilization of system
-func getCpuUtil() float32 {
-	//Executing the top command to fetch the CPU utilization
-	cmd := exec.Command("bash", "-c", "top -bn2 | grep '%Cpu' | tail -1 | awk '{print 100-$8}'")
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		log.Error.Println("error: ", out)
-	}
-
-	//Converting the result to float
-	cpuFloat, err := strconv.ParseFloat(strings.TrimSuffix(string(out), "\n"), 8)
-	if err != nil {
-		log.Error.Println("Unable to convert to float: ", err)
-	}
-	return float32(cpuFloat)
-}
-
-// Input:
 // Description:
 //
-//	The functions fetchs Memory utilization directly from system through linux commands
+//	Selects the utilization read from the system, or the one of the opensearch os stats when the system can't be read.
 //
 // Return:
 //
-//	(float32): Returns the memory utilization of the system.
-func getRamUtil() float32 {
-	//Executing the top command to fetch the memory utilization
-	cmd := exec.Command("bash", "-c", "top -bn2 | grep -E '[KiB|MiB|GiB] Mem' | tail -1 | awk '{print ($8/$4)*100}'")
-	memout, err := cmd.CombinedOutput()
-	if err != nil {
-		log.Error.Println(err)
+//	(float32, error): Returns the utilization and error if it can't be read from either
+func selectUtil(name string, systemUtil float32, systemErr error, osUtil float32, osErr error) (float32, error) {
+	if systemErr == nil {
+		return systemUtil, nil
 	}
-
-	//Converting the result to float
-	memFloat, err := strconv.ParseFloat(strings.TrimSuffix(string(memout), "\n"), 8)
-	if err != nil {
-		log.Error.Println("Unable to convert to float: ", err)
+	log.Warn.Println("Unable to read "+name+" utilization from the system, using opensearch os stats: ", systemErr)
+	if osErr != nil {
+		return 0, fmt.Errorf("unable to read %s utilization from the system (%v) or from opensearch os stats (%v)", name, systemErr, osErr)
 	}
-
-	return float32(memFloat)
+	return osUtil, nil
 }
 
 // Input:
@@ -115,71 +81,118 @@ func IndexNodeStats(ctx context.Context) {
 
 	//creating a node stats requests with filter to reduce the response to requirement
 	nodes := []string{"_local"}
-	metrics := []string{"jvm", "os", "fs", "indices"}
-	nodeStatResp, err := osutils.GetNodeStats(ctx, nodes, metrics)
+	metrics := []string{"jvm", "os", "fs", "indices", "indexing_pressure"}
+	//Fetching the node stats of the current node
+	nodesStats, err := osutils.FetchNodesStats(ctx, nodes, metrics)
 	if err != nil {
 		log.Error.Println("Node stat fetch error: ", err)
+		return
 	}
-	defer nodeStatResp.Body.Close()
-
-	//A map to dump the values from node stats response
-	var nodeStatsInterface map[string]interface{}
+	nodeId := utils.ParseNodeId(nodesStats.Nodes)
+	nodeInfo := nodesStats.Nodes[nodeId]
 
-	//Decoding the response into the the interface
-	decodeErr := json.NewDecoder(nodeStatResp.Body).Decode(&nodeStatsInterface)
-	if decodeErr != nil {
-		log.Error.Println("decode Error: ", decodeErr)
+	allocations, err := osutils.FetchCatAllocation(ctx, nodes)
+	if err != nil {
+		log.Error.Println("Cat allocation fetch error: ", err)
+		return
 	}
-
-	catAllocationResp, catErr := osutils.CatAllocation(ctx, nodes)
-	if catErr != nil {
-		log.Error.Println("Cat allocation fetch error: ", catErr)
+	for _, allocation := range allocations {
+		if allocation.Node == nodeInfo.Name {
+			nodeMetrics.NumShards, err = allocation.NumShards()
+			if err != nil {
+				log.Error.Println("Unexpected number of shards in cat allocation: ", err)
+				return
+			}
+		}
 	}
-	defer catAllocationResp.Body.Close()
 
-	var catAllocationInterface interface{}
-
-	decodeErr = json.NewDecoder(catAllocationResp.Body).Decode(&catAllocationInterface)
-	if decodeErr != nil {
-		log.Error.Println("decode Error: ", decodeErr)
+	//populating the node stats structure
+	isMaster, err := utils.CheckIfMaster(ctx, nodeId)
+	if err != nil {
+		log.Error.Println("Unable to check if the node is master: ", err)
+		return
+	}
+	err = populateNodeMetrics(nodeMetrics, nodeId, nodeInfo, isMaster)
+	if err != nil {
+		log.Error.Println("Unable to read the node stats: ", err)
+		return
 	}
 
-	nodeMetrics.NumShards = int(catAllocationInterface.(float64))
-
-	//parsing the interface and populating the node stats structure
-	nodeId := utils.ParseNodeId(nodeStatsInterface["nodes"].(map[string]interface{}))
-	nodeInfo := nodeStatsInterface["nodes"].(map[string]interface{})[nodeId].(map[string]interface{})
-	nodeMetrics.NodeId = nodeId
-	nodeMetrics.NodeName = nodeInfo["name"].(string)
-	nodeMetrics.Timestamp = int64(nodeInfo["timestamp"].(float64))
-	nodeMetrics.HostIp = nodeInfo["host"].(string)
-	nodeMetrics.IsMaster = utils.CheckIfMaster(ctx, nodeId)
-	for _, role := range nodeInfo["roles"].([]interface{}) {
-		if role == "data" {
-			nodeMetrics.IsData = true
-		}
+	// A document without a utilization would be read as 0% by the rules, so none is indexed
+	osCpuUtil, osRamUtil, osStatsErr := getOsStatsUtil(nodeInfo)
+	cpuUtil, cpuErr := getCpuUtil()
+	nodeMetrics.CpuUtil, err = selectUtil("CPU", cpuUtil, cpuErr, osCpuUtil, osStatsErr)
+	if err != nil {
+		log.Error.Println("Skipping the node document: ", err)
+		return
+	}
+	ramUtil, ramErr := getRamUtil()
+	nodeMetrics.RamUtil, err = selectUtil("memory", ramUtil, ramErr, osRamUtil, osStatsErr)
+	if err != nil {
+		log.Error.Println("Skipping the node document: ", err)
+		return
 	}
-	nodeMetrics.CpuUtil = getCpuUtil()
-	nodeMetrics.RamUtil = getRamUtil()
-	nodeMetrics.HeapUtil = float32(nodeInfo["jvm"].(map[string]interface{})["mem"].(map[string]interface{})["heap_used_percent"].(float64))
-	heapInBytes := nodeInfo["jvm"].(map[string]interface{})["mem"].(map[string]interface{})["heap_committed_in_bytes"].(float64)
-	heapInGB := heapInBytes / (1 << 30)
-	nodeMetrics.ShardsPerGB = float64(nodeMetrics.NumShards) / heapInGB
-	nodeMetrics.DiskUtil = getDiskUtil(nodeStatsInterface, nodeId)
-	nodeMetrics.StatTag = "NodeStatistics"
-	nodeMetrics._documentType = "NodeStatistics"
 
 	//marshall the node metrics, to index into the elasticsearch
 	nodeMetricsJson, jsonErr := json.MarshalIndent(nodeMetrics, "", "\t")
 	if jsonErr != nil {
 		log.Error.Println("Error converting struct to Json: ", jsonErr)
+		return
 	}
 
-	resp, err := osutils.IndexMetrics(ctx, nodeMetricsJson)
+	resp, err := osutils.IndexMetrics(ctx, osutils.NodeStatsIndex, nodeMetricsJson)
+	err = osutils.DecodeResponse(resp, err, nil)
 	if err != nil {
-		log.Panic.Println("Error indexing document: ", err)
-		panic(err)
+		log.Error.Println("Error indexing document: ", err)
+		return
 	}
-	defer resp.Body.Close()
 	log.Info.Println("Node document indexed successfully")
 }
+
+// Input:
+//
+//	nodeMetrics (*NodeMetrics): The node metrics struct to be populated, NumShards is expected to be set by the caller
+//	nodeId (string): Unique id of the node
+//	nodeInfo (osutils.NodeStats): Holds the node stats response of the node
+//	isMaster (bool): True if the node is the elected master
+//
+// Description:
+//
+//	Populates the node identity, heap, shards, disk and indexing pressure metrics from the node stats response.
+//	CPU and memory utilization are left to the caller as they can be read from the system or from opensearch.
+//	The disk utilization is fetched from the node stats response from opensearch, there is no difference in terms
+//	of output when we fetch from linux or opensearch, and opensearch already reports it for the data path.
+//
+// Return:
+//
+//	(error): Returns error if the jvm or fs stats are missing
+func populateNodeMetrics(nodeMetrics *NodeMetrics, nodeId string, nodeInfo osutils.NodeStats, isMaster bool) error {
+	if nodeInfo.Jvm == nil {
+		return errors.New("jvm stats not present in node stats response")
+	}
+	diskUtil, err := nodeInfo.DiskUtil()
+	if err != nil {
+		return err
+	}
+	nodeMetrics.NodeId = nodeId
+	nodeMetrics.NodeName = nodeInfo.Name
+	nodeMetrics.Timestamp = nodeInfo.Timestamp
+	nodeMetrics.HostIp = nodeInfo.Host
+	nodeMetrics.IsMaster = isMaster
+	nodeMetrics.IsData = nodeInfo.HasRole("data")
+	nodeMetrics.NodeGroup = nodeInfo.NodeGroup()
+	nodeMetrics.Attributes = nodeInfo.Attributes
+	// Indexing pressure stats are only reported by the nodes holding data, it is left as 0 otherwise
+	if indexingPressure, err := nodeInfo.IndexingPressureUtil(); err == nil {
+		nodeMetrics.IndexingPressure = indexingPressure
+	}
+	nodeMetrics.HeapUtil = nodeInfo.Jvm.Mem.HeapUsedPercent
+	heapInGB := float64(nodeInfo.Jvm.Mem.HeapCommittedInBytes) / (1 << 30)
+	if heapInGB > 0 {
+		nodeMetrics.ShardsPerGB = float64(nodeMetrics.NumShards) / heapInGB
+	}
+	nodeMetrics.DiskUtil = diskUtil
+	nodeMetrics.StatTag = "NodeStatistics"
+	nodeMetrics._documentType = "NodeStatistics"
+	return nil
+}
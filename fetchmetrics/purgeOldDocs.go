@@ -2,20 +2,78 @@ package fetchmetrics
 
 import (
 	"context"
-	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
+	"errors"
 	"strconv"
+	"time"
+
+	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
 )
 
+// Minimum time between two delete by query requests when ISM is not available
+const deleteOldDocsInterval = time.Hour
+
+// Purge duration with which the ISM policy was last applied, 0 if not applied yet
+var appliedPurgeAfter int
+
+// Time at which the old documents were last deleted by query
+var lastDeleteOldDocs time.Time
+
 // Input:
 //
 //	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//	purgeAfter (int): Number of hours after which the documents are purged
 //
 // Description:
 //
-//	Deletes documents that older than 72 hours
+//	Purges the documents older than purgeAfter hours. Called on the master node only.
+//	If the ISM plugin is installed the policy rolling over and deleting the indices is applied,
+//	once at startup and whenever purgeAfter changes. Otherwise the old documents are deleted by query
+//	at most once every deleteOldDocsInterval.
 //
 // Return:
-func DeleteOldDocs(ctx context.Context, purgeAfter int) {
+func PurgeOldDocs(ctx context.Context, purgeAfter int) {
+	if purgeAfter == appliedPurgeAfter {
+		return
+	}
+
+	ismAvailable, err := osutils.IsmAvailable(ctx)
+	if err != nil {
+		log.Error.Println("Unable to check if ISM plugin is installed: ", err)
+	}
+	if ismAvailable {
+		err = osutils.ApplyIsmPolicy(ctx, purgeAfter)
+		if err != nil {
+			log.Error.Println("Unable to apply the ISM policy: ", err)
+			return
+		}
+		appliedPurgeAfter = purgeAfter
+		return
+	}
+
+	if time.Since(lastDeleteOldDocs) < deleteOldDocsInterval {
+		return
+	}
+	err = DeleteOldDocs(ctx, purgeAfter)
+	if err != nil {
+		log.Error.Println("Unable to delete old documents: ", err)
+		return
+	}
+	lastDeleteOldDocs = time.Now()
+}
+
+// Input:
+//
+//	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//	purgeAfter (int): Number of hours after which the documents are purged
+//
+// Description:
+//
+//	Deletes documents that are older than purgeAfter hours
+//
+// Return:
+//
+//	(error): Returns error if any
+func DeleteOldDocs(ctx context.Context, purgeAfter int) error {
 	var jsonQuery = []byte(`{
 				  "query": {
 				    "bool": {
@@ -31,9 +89,12 @@ func DeleteOldDocs(ctx context.Context, purgeAfter int) {
 				}`)
 	deleteResp, err := osutils.DeleteWithQuery(ctx, jsonQuery)
 	if err != nil {
-		log.Panic.Println("Unable to execute request: ", err)
-		panic(err)
+		return err
 	}
 	defer deleteResp.Body.Close()
+	if deleteResp.IsError() {
+		return errors.New(deleteResp.String())
+	}
 	log.Info.Println("Document deleted: ", deleteResp)
+	return nil
 }
@@ -0,0 +1,478 @@
+package fetchmetrics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Root of the proc and cgroup filesystems. These are variables so that tests can point them to fixture files.
+var (
+	procFsPath   = "/proc"
+	cgroupFsPath = "/sys/fs/cgroup"
+)
+
+// Interval between the two samples which are used to calculate the CPU utilization
+var cpuSampleInterval = 1 * time.Second
+
+// The memory limit reported by cgroup v1 when there is no limit set on the group (PAGE_COUNTER_MAX rounded to page size)
+const cgroupV1NoLimit uint64 = 9223372036854771712
+
+// Description: cpuTimes holds the aggregated busy and total time of the CPUs, in the units of the source it was read from.
+type cpuTimes struct {
+	busy  uint64
+	total uint64
+}
+
+// Description: cgroupCpu holds the CPU usage (nanoseconds) of the cgroup and the number of CPUs it is allowed to use.
+type cgroupCpu struct {
+	usageNs uint64
+	limit   float64
+}
+
+// Input:
+//
+//	prev (cpuTimes): The earlier sample of the CPU times
+//	curr (cpuTimes): The later sample of the CPU times
+//
+// Description:
+//
+//	Calculates the CPU utilization in percentage between the two samples.
+//
+// Return:
+//
+//	(float32, error): Returns the CPU utilization and error if the samples can't be compared
+func cpuUtilBetween(prev cpuTimes, curr cpuTimes) (float32, error) {
+	if curr.total <= prev.total || curr.busy < prev.busy {
+		return 0, errors.New("CPU times did not advance between the samples")
+	}
+	return float32(curr.busy-prev.busy) / float32(curr.total-prev.total) * 100, nil
+}
+
+// Input:
+//
+//	statPath (string): Path of the stat file, usually /proc/stat
+//
+// Description:
+//
+//	Reads the aggregated "cpu" line of the stat file. Idle and iowait are accounted as idle time,
+//	guest times are already part of user and nice so they are not added to the total.
+//
+// Return:
+//
+//	(cpuTimes, error): Returns the busy and total jiffies and error if any
+func readProcStat(statPath string) (cpuTimes, error) {
+	var times cpuTimes
+	f, err := os.Open(statPath)
+	if err != nil {
+		return times, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] != "cpu" {
+			continue
+		}
+		// cpu user nice system idle iowait irq softirq steal guest guest_nice
+		if len(fields) < 5 {
+			return times, fmt.Errorf("unexpected cpu line in %s: %q", statPath, scanner.Text())
+		}
+		for i, field := range fields[1:] {
+			if i >= 8 {
+				break
+			}
+			value, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return times, fmt.Errorf("unable to parse %s: %w", statPath, err)
+			}
+			times.total += value
+			// idle (index 3) and iowait (index 4) are not busy time
+			if i != 3 && i != 4 {
+				times.busy += value
+			}
+		}
+		return times, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return times, err
+	}
+	return times, fmt.Errorf("no cpu line found in %s", statPath)
+}
+
+// Input:
+//
+//	meminfoPath (string): Path of the meminfo file, usually /proc/meminfo
+//
+// Description:
+//
+//	Reads the meminfo file into a map of field name to value in bytes.
+//
+// Return:
+//
+//	(map[string]uint64, error): Returns the meminfo fields and error if any
+func readMeminfo(meminfoPath string) (map[string]uint64, error) {
+	f, err := os.Open(meminfoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	meminfo := make(map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// MemTotal:        6147400 kB
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %w", meminfoPath, err)
+		}
+		if len(fields) == 3 && fields[2] == "kB" {
+			value *= 1024
+		}
+		meminfo[strings.TrimSuffix(fields[0], ":")] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return meminfo, nil
+}
+
+// Input:
+//
+//	meminfo (map[string]uint64): Fields of the meminfo file in bytes
+//
+// Description:
+//
+//	Calculates the memory utilization of the host. Memory which can be reclaimed (MemAvailable)
+//	is not counted as used. Kernels older than 3.14 do not report MemAvailable, free memory
+//	along with buffers and page cache is used instead.
+//
+// Return:
+//
+//	(float32, error): Returns the memory utilization in percentage and error if any
+func memUtilFromMeminfo(meminfo map[string]uint64) (float32, error) {
+	total, ok := meminfo["MemTotal"]
+	if !ok || total == 0 {
+		return 0, errors.New("MemTotal not found in meminfo")
+	}
+	available, ok := meminfo["MemAvailable"]
+	if !ok {
+		available = meminfo["MemFree"] + meminfo["Buffers"] + meminfo["Cached"]
+	}
+	if available > total {
+		available = total
+	}
+	return float32(total-available) / float32(total) * 100, nil
+}
+
+// Input:
+//
+//	fileName (string): Path of the file containing a single value
+//
+// Description:
+//
+//	Reads a cgroup file which contains a single line value.
+//
+// Return:
+//
+//	(string, error): Returns the trimmed content of the file and error if any
+func readCgroupValue(fileName string) (string, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// Input:
+//
+//	fileName (string): Path of a cgroup file with "key value" lines (memory.stat, cpu.stat)
+//
+// Description:
+//
+//	Reads a flat keyed cgroup file into a map.
+//
+// Return:
+//
+//	(map[string]uint64, error): Returns the parsed keys and values and error if any
+func readCgroupKeyValues(fileName string) (map[string]uint64, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %w", fileName, err)
+		}
+		values[fields[0]] = value
+	}
+	return values, scanner.Err()
+}
+
+// Input:
+//
+//	cgroupPath (string): Mount point of the cgroup filesystem
+//
+// Description:
+//
+//	Checks if the cgroup filesystem is the unified hierarchy (cgroup v2).
+//
+// Return:
+//
+//	(bool): Returns true for cgroup v2
+func isCgroupV2(cgroupPath string) bool {
+	_, err := os.Stat(filepath.Join(cgroupPath, "cgroup.controllers"))
+	return err == nil
+}
+
+// Input:
+//
+//	cgroupPath (string): Mount point of the cgroup filesystem
+//	hostMemTotal (uint64): Total memory of the host in bytes
+//
+// Description:
+//
+//	Reads the memory limit and working set of the cgroup the process is running in.
+//	The working set excludes the inactive file cache as it can be reclaimed under pressure.
+//
+// Return:
+//
+//	(uint64, uint64, bool, error): Returns the working set and limit in bytes, true if a limit
+//	lower than the host memory is set and error if any
+func readCgroupMemory(cgroupPath string, hostMemTotal uint64) (uint64, uint64, bool, error) {
+	var limitFile, usageFile, statFile, inactiveKey string
+	if isCgroupV2(cgroupPath) {
+		limitFile = filepath.Join(cgroupPath, "memory.max")
+		usageFile = filepath.Join(cgroupPath, "memory.current")
+		statFile = filepath.Join(cgroupPath, "memory.stat")
+		inactiveKey = "inactive_file"
+	} else {
+		limitFile = filepath.Join(cgroupPath, "memory", "memory.limit_in_bytes")
+		usageFile = filepath.Join(cgroupPath, "memory", "memory.usage_in_bytes")
+		statFile = filepath.Join(cgroupPath, "memory", "memory.stat")
+		inactiveKey = "total_inactive_file"
+	}
+
+	limitStr, err := readCgroupValue(limitFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, 0, false, nil
+		}
+		return 0, 0, false, err
+	}
+	if limitStr == "max" {
+		return 0, 0, false, nil
+	}
+	limit, err := strconv.ParseUint(limitStr, 10, 64)
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("unable to parse %s: %w", limitFile, err)
+	}
+	if limit >= cgroupV1NoLimit || (hostMemTotal > 0 && limit >= hostMemTotal) {
+		return 0, 0, false, nil
+	}
+
+	usageStr, err := readCgroupValue(usageFile)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	usage, err := strconv.ParseUint(usageStr, 10, 64)
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("unable to parse %s: %w", usageFile, err)
+	}
+
+	stat, err := readCgroupKeyValues(statFile)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, 0, false, err
+	}
+	if inactive, ok := stat[inactiveKey]; ok && inactive < usage {
+		usage -= inactive
+	}
+	return usage, limit, true, nil
+}
+
+// Input:
+//
+//	cgroupPath (string): Mount point of the cgroup filesystem
+//
+// Description:
+//
+//	Reads the CPU quota and the consumed CPU time of the cgroup the process is running in.
+//
+// Return:
+//
+//	(cgroupCpu, bool, error): Returns the usage and CPU limit, true if a CPU quota is set and error if any
+func readCgroupCpu(cgroupPath string) (cgroupCpu, bool, error) {
+	var cpu cgroupCpu
+	if isCgroupV2(cgroupPath) {
+		// cpu.max contains "$MAX $PERIOD", where $MAX is "max" if there is no quota
+		maxStr, err := readCgroupValue(filepath.Join(cgroupPath, "cpu.max"))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return cpu, false, nil
+			}
+			return cpu, false, err
+		}
+		fields := strings.Fields(maxStr)
+		if len(fields) != 2 || fields[0] == "max" {
+			return cpu, false, nil
+		}
+		quota, qErr := strconv.ParseFloat(fields[0], 64)
+		period, pErr := strconv.ParseFloat(fields[1], 64)
+		if qErr != nil || pErr != nil || period <= 0 {
+			return cpu, false, fmt.Errorf("unable to parse cpu.max: %q", maxStr)
+		}
+		cpu.limit = quota / period
+
+		stat, err := readCgroupKeyValues(filepath.Join(cgroupPath, "cpu.stat"))
+		if err != nil {
+			return cpu, false, err
+		}
+		usageUsec, ok := stat["usage_usec"]
+		if !ok {
+			return cpu, false, errors.New("usage_usec not found in cpu.stat")
+		}
+		cpu.usageNs = usageUsec * 1000
+		return cpu, true, nil
+	}
+
+	quotaStr, err := readCgroupValue(filepath.Join(cgroupPath, "cpu", "cpu.cfs_quota_us"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cpu, false, nil
+		}
+		return cpu, false, err
+	}
+	quota, err := strconv.ParseFloat(quotaStr, 64)
+	if err != nil {
+		return cpu, false, fmt.Errorf("unable to parse cpu.cfs_quota_us: %w", err)
+	}
+	// A quota of -1 means there is no limit
+	if quota <= 0 {
+		return cpu, false, nil
+	}
+	periodStr, err := readCgroupValue(filepath.Join(cgroupPath, "cpu", "cpu.cfs_period_us"))
+	if err != nil {
+		return cpu, false, err
+	}
+	period, err := strconv.ParseFloat(periodStr, 64)
+	if err != nil || period <= 0 {
+		return cpu, false, fmt.Errorf("unable to parse cpu.cfs_period_us: %q", periodStr)
+	}
+	cpu.limit = quota / period
+
+	usageStr, err := readCgroupValue(filepath.Join(cgroupPath, "cpuacct", "cpuacct.usage"))
+	if err != nil {
+		return cpu, false, err
+	}
+	cpu.usageNs, err = strconv.ParseUint(usageStr, 10, 64)
+	if err != nil {
+		return cpu, false, fmt.Errorf("unable to parse cpuacct.usage: %w", err)
+	}
+	return cpu, true, nil
+}
+
+// Input:
+//
+//	prev (cgroupCpu): The earlier sample of the cgroup CPU usage
+//	curr (cgroupCpu): The later sample of the cgroup CPU usage
+//	elapsed (time.Duration): Wall clock time between the two samples
+//
+// Description:
+//
+//	Calculates the CPU utilization of the cgroup relative to its CPU quota.
+//
+// Return:
+//
+//	(float32, error): Returns the CPU utilization in percentage and error if any
+func cgroupCpuUtilBetween(prev cgroupCpu, curr cgroupCpu, elapsed time.Duration) (float32, error) {
+	if elapsed <= 0 || curr.limit <= 0 || curr.usageNs < prev.usageNs {
+		return 0, errors.New("cgroup CPU usage can't be calculated between the samples")
+	}
+	util := float64(curr.usageNs-prev.usageNs) / (float64(elapsed.Nanoseconds()) * curr.limit) * 100
+	if util > 100 {
+		util = 100
+	}
+	return float32(util), nil
+}
+
+// Input:
+// Description:
+//
+//	The functions fetches CPU utilization of the system by sampling /proc/stat twice.
+//	If the process runs in a cgroup with a CPU quota, the utilization is relative to the quota.
+//
+// Output:
+//
+//	(float32, error): Returns CPU utilization of system and error if any
+func getCpuUtil() (float32, error) {
+	cgroupPrev, limited, err := readCgroupCpu(cgroupFsPath)
+	if err != nil {
+		log.Warn.Println("Unable to read cgroup CPU limits, using /proc/stat: ", err)
+		limited = false
+	}
+	if limited {
+		start := time.Now()
+		time.Sleep(cpuSampleInterval)
+		cgroupCurr, _, err := readCgroupCpu(cgroupFsPath)
+		if err != nil {
+			return 0, err
+		}
+		return cgroupCpuUtilBetween(cgroupPrev, cgroupCurr, time.Since(start))
+	}
+
+	statPath := filepath.Join(procFsPath, "stat")
+	prev, err := readProcStat(statPath)
+	if err != nil {
+		return 0, err
+	}
+	time.Sleep(cpuSampleInterval)
+	curr, err := readProcStat(statPath)
+	if err != nil {
+		return 0, err
+	}
+	return cpuUtilBetween(prev, curr)
+}
+
+// Input:
+// Description:
+//
+//	The functions fetches memory utilization of the system from /proc/meminfo.
+//	If the process runs in a cgroup with a memory limit, the utilization is relative to the limit.
+//
+// Return:
+//
+//	(float32, error): Returns the memory utilization of the system and error if any
+func getRamUtil() (float32, error) {
+	meminfo, err := readMeminfo(filepath.Join(procFsPath, "meminfo"))
+	if err != nil {
+		return 0, err
+	}
+
+	usage, limit, limited, err := readCgroupMemory(cgroupFsPath, meminfo["MemTotal"])
+	if err != nil {
+		log.Warn.Println("Unable to read cgroup memory limits, using /proc/meminfo: ", err)
+	} else if limited {
+		return float32(usage) / float32(limit) * 100, nil
+	}
+
+	return memUtilFromMeminfo(meminfo)
+}
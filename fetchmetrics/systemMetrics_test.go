@@ -0,0 +1,210 @@
+package fetchmetrics
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadProcStat(t *testing.T) {
+	times, err := readProcStat("testdata/proc/stat")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(95000), times.total)
+	assert.Equal(t, uint64(14000), times.busy)
+}
+
+func TestReadProcStatMalformed(t *testing.T) {
+	_, err := readProcStat("testdata/proc/stat_malformed")
+	assert.NotNil(t, err)
+
+	_, err = readProcStat("testdata/proc/missing")
+	assert.NotNil(t, err)
+}
+
+func TestCpuUtilBetween(t *testing.T) {
+	prev, err := readProcStat("testdata/proc/stat")
+	assert.Nil(t, err)
+	curr, err := readProcStat("testdata/proc/stat_next")
+	assert.Nil(t, err)
+
+	util, err := cpuUtilBetween(prev, curr)
+	assert.Nil(t, err)
+	assert.InDelta(t, 47.368, util, 0.01)
+
+	_, err = cpuUtilBetween(curr, curr)
+	assert.NotNil(t, err)
+}
+
+func TestGetCpuUtilWithoutProgress(t *testing.T) {
+	defer func(proc, cgroup string, interval time.Duration) {
+		procFsPath, cgroupFsPath, cpuSampleInterval = proc, cgroup, interval
+	}(procFsPath, cgroupFsPath, cpuSampleInterval)
+	procFsPath = "testdata/proc"
+	cgroupFsPath = "testdata/cgroupv2_unlimited"
+	cpuSampleInterval = 0
+
+	// The fixture does not change between the samples, this must be reported instead of returning 0
+	_, err := getCpuUtil()
+	assert.NotNil(t, err)
+}
+
+func TestMemUtilFromMeminfo(t *testing.T) {
+	meminfo, err := readMeminfo("testdata/proc/meminfo")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(8000000*1024), meminfo["MemTotal"])
+	assert.Equal(t, uint64(0), meminfo["HugePages_Total"])
+
+	util, err := memUtilFromMeminfo(meminfo)
+	assert.Nil(t, err)
+	assert.InDelta(t, 75, util, 0.01)
+
+	meminfo, err = readMeminfo("testdata/proc/meminfo_no_available")
+	assert.Nil(t, err)
+	util, err = memUtilFromMeminfo(meminfo)
+	assert.Nil(t, err)
+	assert.InDelta(t, 75, util, 0.01)
+
+	_, err = memUtilFromMeminfo(map[string]uint64{})
+	assert.NotNil(t, err)
+}
+
+func TestReadCgroupMemory(t *testing.T) {
+	hostMemTotal := uint64(8000000 * 1024)
+
+	usage, limit, limited, err := readCgroupMemory("testdata/cgroupv1", hostMemTotal)
+	assert.Nil(t, err)
+	assert.True(t, limited)
+	assert.Equal(t, uint64(4294967296), limit)
+	assert.Equal(t, uint64(2147483648), usage)
+
+	usage, limit, limited, err = readCgroupMemory("testdata/cgroupv2", hostMemTotal)
+	assert.Nil(t, err)
+	assert.True(t, limited)
+	assert.Equal(t, uint64(2147483648), limit)
+	assert.Equal(t, uint64(1073741824), usage)
+
+	_, _, limited, err = readCgroupMemory("testdata/cgroupv1_unlimited", hostMemTotal)
+	assert.Nil(t, err)
+	assert.False(t, limited)
+
+	_, _, limited, err = readCgroupMemory("testdata/cgroupv2_unlimited", hostMemTotal)
+	assert.Nil(t, err)
+	assert.False(t, limited)
+
+	// A limit larger than the host memory is not a real limit
+	_, _, limited, err = readCgroupMemory("testdata/cgroupv2", 1024)
+	assert.Nil(t, err)
+	assert.False(t, limited)
+}
+
+func TestReadCgroupCpu(t *testing.T) {
+	cpu, limited, err := readCgroupCpu("testdata/cgroupv1")
+	assert.Nil(t, err)
+	assert.True(t, limited)
+	assert.Equal(t, 2.0, cpu.limit)
+	assert.Equal(t, uint64(123456789000), cpu.usageNs)
+
+	cpu, limited, err = readCgroupCpu("testdata/cgroupv2")
+	assert.Nil(t, err)
+	assert.True(t, limited)
+	assert.Equal(t, 1.5, cpu.limit)
+	assert.Equal(t, uint64(5000000000), cpu.usageNs)
+
+	_, limited, err = readCgroupCpu("testdata/cgroupv1_unlimited")
+	assert.Nil(t, err)
+	assert.False(t, limited)
+
+	_, limited, err = readCgroupCpu("testdata/cgroupv2_unlimited")
+	assert.Nil(t, err)
+	assert.False(t, limited)
+}
+
+func TestCgroupCpuUtilBetween(t *testing.T) {
+	prev := cgroupCpu{usageNs: 5000000000, limit: 1.5}
+	curr := cgroupCpu{usageNs: 5750000000, limit: 1.5}
+
+	util, err := cgroupCpuUtilBetween(prev, curr, time.Second)
+	assert.Nil(t, err)
+	assert.InDelta(t, 50, util, 0.01)
+
+	_, err = cgroupCpuUtilBetween(prev, curr, 0)
+	assert.NotNil(t, err)
+}
+
+func TestGetRamUtil(t *testing.T) {
+	defer func(proc, cgroup string) {
+		procFsPath, cgroupFsPath = proc, cgroup
+	}(procFsPath, cgroupFsPath)
+	procFsPath = "testdata/proc"
+
+	cgroupFsPath = "testdata/cgroupv2"
+	util, err := getRamUtil()
+	assert.Nil(t, err)
+	assert.InDelta(t, 50, util, 0.01)
+
+	cgroupFsPath = "testdata/cgroupv2_unlimited"
+	util, err = getRamUtil()
+	assert.Nil(t, err)
+	assert.InDelta(t, 75, util, 0.01)
+}
+
+func TestGetOsStatsUtil(t *testing.T) {
+	var nodeInfo osutils.NodeStats
+	assert.Nil(t, json.Unmarshal([]byte(`{"os": {"cpu": {"percent": 12}, "mem": {"used_percent": 64}}}`), &nodeInfo))
+	cpuUtil, ramUtil, err := getOsStatsUtil(nodeInfo)
+	assert.Nil(t, err)
+	assert.Equal(t, float32(12), cpuUtil)
+	assert.Equal(t, float32(64), ramUtil)
+
+	_, _, err = getOsStatsUtil(osutils.NodeStats{})
+	assert.NotNil(t, err)
+
+	var cpuOnlyNodeInfo osutils.NodeStats
+	assert.Nil(t, json.Unmarshal([]byte(`{"os": {"cpu": {"percent": 12}}}`), &cpuOnlyNodeInfo))
+	_, _, err = getOsStatsUtil(cpuOnlyNodeInfo)
+	assert.NotNil(t, err)
+}
+
+func TestPopulateNodeMetrics(t *testing.T) {
+	var nodeInfo osutils.NodeStats
+	assert.Nil(t, json.Unmarshal([]byte(`{
+		"name": "node-1",
+		"host": "10.0.0.1",
+		"timestamp": 1668500512345,
+		"roles": ["cluster_manager", "data", "ingest"],
+		"attributes": {"node_group": "data-hot", "temp": "hot"},
+		"jvm": {"mem": {"heap_used_percent": 47, "heap_committed_in_bytes": 2147483648}},
+		"fs": {"data": [{"total_in_bytes": 200, "available_in_bytes": 150}]},
+		"indexing_pressure": {"memory": {"current": {"all_in_bytes": 40}, "limit_in_bytes": 100}}
+	}`), &nodeInfo))
+	nodeMetrics := &NodeMetrics{}
+	nodeMetrics.NumShards = 10
+	assert.Nil(t, populateNodeMetrics(nodeMetrics, "node-id-1", nodeInfo, true))
+	assert.Equal(t, "node-id-1", nodeMetrics.NodeId)
+	assert.Equal(t, "node-1", nodeMetrics.NodeName)
+	assert.Equal(t, "10.0.0.1", nodeMetrics.HostIp)
+	assert.Equal(t, int64(1668500512345), nodeMetrics.Timestamp)
+	assert.True(t, nodeMetrics.IsMaster)
+	assert.True(t, nodeMetrics.IsData)
+	assert.Equal(t, "data-hot", nodeMetrics.NodeGroup)
+	assert.Equal(t, map[string]string{"node_group": "data-hot", "temp": "hot"}, nodeMetrics.Attributes)
+	assert.Equal(t, float32(40), nodeMetrics.IndexingPressure)
+	assert.Equal(t, float32(47), nodeMetrics.HeapUtil)
+	assert.Equal(t, float64(5), nodeMetrics.ShardsPerGB)
+	assert.Equal(t, float32(25), nodeMetrics.DiskUtil)
+	assert.Equal(t, "NodeStatistics", nodeMetrics.StatTag)
+
+	// A node without indexing pressure stats is still indexed
+	nodeInfo.IndexingPressure = nil
+	nodeMetrics = &NodeMetrics{}
+	assert.Nil(t, populateNodeMetrics(nodeMetrics, "node-id-1", nodeInfo, true))
+	assert.Equal(t, float32(0), nodeMetrics.IndexingPressure)
+
+	// A node without jvm or fs stats is not indexed
+	nodeInfo.Fs = nil
+	assert.NotNil(t, populateNodeMetrics(&NodeMetrics{}, "node-id-1", nodeInfo, true))
+	assert.NotNil(t, populateNodeMetrics(&NodeMetrics{}, "node-id-2", osutils.NodeStats{Name: "node-2"}, false))
+}
@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 
 	"github.com/knadh/koanf"
@@ -40,6 +41,27 @@ type LOG struct {
 
 var k = koanf.New(".")
 
+// findConfigFile looks for the file in the current directory and then in its parents, so that the
+// config at the root of the module is found when the tests of a package run from the package directory.
+// The name is returned as it is if no directory has the file.
+func findConfigFile(name string) string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return name
+	}
+	for {
+		configFile := filepath.Join(dir, name)
+		if _, err := os.Stat(configFile); err == nil {
+			return configFile
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return name
+		}
+		dir = parent
+	}
+}
+
 // Init initilise logging
 func (l *LOG) Init(module string) {
 	l.module = module
@@ -49,7 +71,7 @@ func (l *LOG) Init(module string) {
 	if workingDir != "" {
 		configFile = workingDir + "/log_config.json"
 	} else {
-		configFile = "log_config.json"
+		configFile = findConfigFile("log_config.json")
 	}
 
 	var (
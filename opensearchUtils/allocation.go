@@ -0,0 +1,385 @@
+package osutils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	osapi "github.com/opensearch-project/opensearch-go/opensearchapi"
+)
+
+// Custom node attribute (node.attr.temp) holding the data tier, hot or warm, of a node.
+const TierAttribute string = "temp"
+
+// Prefix of the index settings filtering the nodes to which the shards of an index are allocated
+const allocationSettingPrefix string = "index.routing.allocation."
+
+// Cluster setting enabling the allocation of the shards, limited to the primaries while a node is restarted
+const ClusterAllocationEnableSetting string = "cluster.routing.allocation.enable"
+
+// Cluster setting excluding the nodes by name from the allocation, which moves their shards to the other nodes
+const ClusterAllocationExcludeNameSetting string = "cluster.routing.allocation.exclude._name"
+
+// This struct contains the fields read from the _plugins/_ism/policies response, limited to the allocation actions.
+type ismPoliciesResponse struct {
+	Policies []struct {
+		Id     string `json:"_id"`
+		Policy struct {
+			States []struct {
+				Name    string `json:"name"`
+				Actions []struct {
+					Allocation *struct {
+						Require map[string]string `json:"require"`
+						Include map[string]string `json:"include"`
+					} `json:"allocation"`
+				} `json:"actions"`
+			} `json:"states"`
+		} `json:"policy"`
+	} `json:"policies"`
+}
+
+// Input:
+//
+//	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//
+// Description:
+//
+//	Fetches the allocation filtering settings (index.routing.allocation.*) of all the indices
+//
+// Return:
+//
+//	(map[string]map[string]string, error): Returns the flat allocation settings keyed by index and error if any
+func FetchIndexAllocationSettings(ctx context.Context) (map[string]map[string]string, error) {
+	status, body, err := performRequest(ctx, http.MethodGet, "/_all/_settings/"+allocationSettingPrefix+"*?flat_settings=true", "")
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("get index settings failed with status %d: %s", status, body)
+	}
+	var settingsResp map[string]struct {
+		Settings map[string]string `json:"settings"`
+	}
+	err = json.Unmarshal(body, &settingsResp)
+	if err != nil {
+		return nil, err
+	}
+	indicesSettings := make(map[string]map[string]string)
+	for index, indexSettings := range settingsResp {
+		indicesSettings[index] = indexSettings.Settings
+	}
+	return indicesSettings, nil
+}
+
+// Input:
+//
+//	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//	index (string): Name of the index to be updated
+//	settings (map[string]interface{}): Flat settings to be updated, a nil value resets the setting
+//
+// Description:
+//
+//	Updates the dynamic settings of the index
+//
+// Return:
+//
+//	(error): Returns error if any
+func UpdateIndexSettings(ctx context.Context, index string, settings map[string]interface{}) error {
+	body, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	resp, err := osapi.IndicesPutSettingsRequest{
+		Index: []string{index},
+		Body:  bytes.NewReader(body),
+	}.Do(ctx, osClient)
+	return DecodeResponse(resp, err, nil)
+}
+
+// Input:
+//
+//	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//	enable (string): Shards which can be allocated, like primaries, empty to reset the setting to all
+//
+// Description:
+//
+//	Updates the persistent cluster.routing.allocation.enable setting, which keeps the replicas of a restarted
+//	node from being allocated to the other nodes while it is down
+//
+// Return:
+//
+//	(error): Returns error if any
+func SetShardAllocation(ctx context.Context, enable string) error {
+	var value interface{}
+	if enable != "" {
+		value = enable
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"persistent": map[string]interface{}{ClusterAllocationEnableSetting: value},
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := osapi.ClusterPutSettingsRequest{Body: bytes.NewReader(body)}.Do(ctx, osClient)
+	return DecodeResponse(resp, err, nil)
+}
+
+// Input:
+//
+//	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//
+// Description:
+//
+//	Flushes all the indices, so that the shards of a restarted node recover from their files instead of the
+//	translog
+//
+// Return:
+//
+//	(error): Returns error if any
+func FlushIndices(ctx context.Context) error {
+	resp, err := osapi.IndicesFlushRequest{}.Do(ctx, osClient)
+	return DecodeResponse(resp, err, nil)
+}
+
+// Input:
+//
+//	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//	nodeName (string): Name of the master eligible node
+//
+// Description:
+//
+//	Excludes the node from the voting configuration, so that another master is elected if the node is the
+//	elected master and the node can be removed without the cluster losing its quorum
+//
+// Return:
+//
+//	(error): Returns error if any
+func ExcludeFromVoting(ctx context.Context, nodeName string) error {
+	resp, err := osapi.ClusterPostVotingConfigExclusionsRequest{NodeNames: nodeName}.Do(ctx, osClient)
+	return DecodeResponse(resp, err, nil)
+}
+
+// Input:
+//
+//	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//
+// Description:
+//
+//	Clears the exclusions of the voting configuration once the excluded nodes left the cluster
+//
+// Return:
+//
+//	(error): Returns error if any
+func ClearVotingExclusions(ctx context.Context) error {
+	resp, err := osapi.ClusterDeleteVotingConfigExclusionsRequest{}.Do(ctx, osClient)
+	return DecodeResponse(resp, err, nil)
+}
+
+// Input:
+//
+//	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//	nodeName (string): Name of the node
+//	excluded (bool): True to exclude the node from the allocation, false to remove the exclusion
+//
+// Description:
+//
+//	Adds the node to or removes it from the persistent cluster.routing.allocation.exclude._name setting, keeping
+//	the other nodes excluded. Excluding a node moves its shards to the other nodes without stopping it.
+//
+// Return:
+//
+//	(error): Returns error if any
+func SetAllocationExclusion(ctx context.Context, nodeName string, excluded bool) error {
+	var settings struct {
+		Persistent map[string]interface{} `json:"persistent"`
+	}
+	flatSettings := true
+	resp, err := osapi.ClusterGetSettingsRequest{FlatSettings: &flatSettings}.Do(ctx, osClient)
+	err = DecodeResponse(resp, err, &settings)
+	if err != nil {
+		return err
+	}
+	current, _ := settings.Persistent[ClusterAllocationExcludeNameSetting].(string)
+	if listContains(current, nodeName) == excluded {
+		return nil
+	}
+	var value interface{}
+	if excluded {
+		value = strings.Trim(current+","+nodeName, ",")
+	} else if remaining := listRemove(current, nodeName); remaining != "" {
+		value = remaining
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"persistent": map[string]interface{}{ClusterAllocationExcludeNameSetting: value},
+	})
+	if err != nil {
+		return err
+	}
+	resp, err = osapi.ClusterPutSettingsRequest{Body: bytes.NewReader(body)}.Do(ctx, osClient)
+	return DecodeResponse(resp, err, nil)
+}
+
+// Input:
+//
+//	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//	attribute (string): Node attribute of the allocation filter
+//	value (string): Value of the node attribute
+//
+// Description:
+//
+//	Fetches the ISM policies having an allocation action which requires or includes the nodes with the attribute
+//	value, like the policies moving indices to the warm tier. No policies are returned if ISM is not installed.
+//
+// Return:
+//
+//	([]string, error): Returns the ids of the policies and error if any
+func FetchIsmPoliciesAllocatingTo(ctx context.Context, attribute string, value string) ([]string, error) {
+	status, body, err := performRequest(ctx, http.MethodGet, "/_plugins/_ism/policies?size=1000", "")
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNotFound {
+		return nil, nil
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("get ISM policies failed with status %d: %s", status, body)
+	}
+	return ismPoliciesAllocatingTo(body, attribute, value)
+}
+
+// Input:
+//
+//	body ([]byte): Response of the _plugins/_ism/policies api
+//	attribute (string): Node attribute of the allocation filter
+//	value (string): Value of the node attribute
+//
+// Description:
+//
+//	Parses the policies having an allocation action which requires or includes the nodes with the attribute value
+//
+// Return:
+//
+//	([]string, error): Returns the ids of the policies and error if any
+func ismPoliciesAllocatingTo(body []byte, attribute string, value string) ([]string, error) {
+	var policiesResp ismPoliciesResponse
+	err := json.Unmarshal(body, &policiesResp)
+	if err != nil {
+		return nil, err
+	}
+	var policyIds []string
+	for _, policy := range policiesResp.Policies {
+	states:
+		for _, state := range policy.Policy.States {
+			for _, action := range state.Actions {
+				if action.Allocation == nil {
+					continue
+				}
+				if listContains(action.Allocation.Require[attribute], value) || listContains(action.Allocation.Include[attribute], value) {
+					policyIds = append(policyIds, policy.Id)
+					break states
+				}
+			}
+		}
+	}
+	return policyIds, nil
+}
+
+// Input:
+//
+//	indicesSettings (map[string]map[string]string): Flat allocation settings keyed by index
+//	node (NodeStats): Node to be removed from the cluster
+//	tier (string): Data tier of the node, empty if the node is not part of a tier
+//	lastOfTier (bool): True if no other node of the tier is left after removing the node
+//
+// Description:
+//
+//	Plans the allocation settings to be updated so that the shards can be moved off the node before it is removed.
+//	Indices required or included on the node by _name, _host or _ip are moved to the tier of the node instead, or
+//	left unfiltered if the node has no tier. When the last node of a tier is removed, the indices required or
+//	included on the tier are left unfiltered, as they could not be allocated otherwise. Exclude filters are kept.
+//
+// Return:
+//
+//	(map[string]map[string]interface{}): Returns the settings to be updated keyed by index, nil values reset a setting
+func PlanAllocationMigration(indicesSettings map[string]map[string]string, node NodeStats, tier string, lastOfTier bool) map[string]map[string]interface{} {
+	nodeValues := map[string]string{"_name": node.Name, "_host": node.Host, "_ip": node.Host}
+	migrations := make(map[string]map[string]interface{})
+	for index, settings := range indicesSettings {
+		indexMigration := make(map[string]interface{})
+		for key, value := range settings {
+			// Filters are set as index.routing.allocation.<require|include|exclude>.<attribute>
+			filter := strings.SplitN(strings.TrimPrefix(key, allocationSettingPrefix), ".", 2)
+			if !strings.HasPrefix(key, allocationSettingPrefix) || len(filter) != 2 || filter[0] == "exclude" {
+				continue
+			}
+			var removeValue string
+			if nodeValue, ok := nodeValues[filter[1]]; ok {
+				removeValue = nodeValue
+			} else if filter[1] == TierAttribute && lastOfTier {
+				removeValue = tier
+			}
+			if removeValue == "" || !listContains(value, removeValue) {
+				continue
+			}
+			remaining := listRemove(value, removeValue)
+			if remaining != "" {
+				indexMigration[key] = remaining
+				continue
+			}
+			indexMigration[key] = nil
+			if filter[1] != TierAttribute && tier != "" && !lastOfTier {
+				indexMigration[allocationSettingPrefix+filter[0]+"."+TierAttribute] = tier
+			}
+		}
+		if len(indexMigration) > 0 {
+			migrations[index] = indexMigration
+		}
+	}
+	return migrations
+}
+
+// Input:
+//
+//	list (string): Comma separated list of values
+//	value (string): Value to be checked
+//
+// Description:
+//
+//	Checks if the comma separated list has the value
+//
+// Return:
+//
+//	(bool): Returns true if the value is present in the list
+func listContains(list string, value string) bool {
+	for _, item := range strings.Split(list, ",") {
+		if strings.TrimSpace(item) == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Input:
+//
+//	list (string): Comma separated list of values
+//	value (string): Value to be removed
+//
+// Description:
+//
+//	Removes the value from the comma separated list
+//
+// Return:
+//
+//	(string): Returns the remaining list
+func listRemove(list string, value string) string {
+	var remaining []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != value && item != "" {
+			remaining = append(remaining, item)
+		}
+	}
+	return strings.Join(remaining, ",")
+}
@@ -0,0 +1,135 @@
+package osutils
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	opensearch "github.com/opensearch-project/opensearch-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsmPoliciesAllocatingTo(t *testing.T) {
+	body, err := os.ReadFile("testdata/ism_policies.json")
+	assert.Nil(t, err)
+
+	policyIds, err := ismPoliciesAllocatingTo(body, TierAttribute, "warm")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"hot-warm-delete"}, policyIds)
+
+	policyIds, err = ismPoliciesAllocatingTo(body, TierAttribute, "hot")
+	assert.Nil(t, err)
+	assert.Empty(t, policyIds)
+}
+
+func TestPlanAllocationMigration(t *testing.T) {
+	node := NodeStats{Name: "node-10-81-1-227", Host: "10.81.1.227"}
+	indicesSettings := map[string]map[string]string{
+		"logs-000001": {"index.routing.allocation.require._name": "node-10-81-1-227"},
+		"logs-000002": {"index.routing.allocation.include._ip": "10.81.1.226,10.81.1.227"},
+		"logs-000003": {"index.routing.allocation.require.temp": "warm"},
+		"logs-000004": {"index.routing.allocation.exclude._name": "node-10-81-1-227"},
+		"logs-000005": {"index.routing.allocation.total_shards_per_node": "2"},
+		"logs-000006": {},
+	}
+
+	// The indices pinned to the node are moved to its tier
+	migrations := PlanAllocationMigration(indicesSettings, node, "warm", false)
+	assert.Equal(t, map[string]map[string]interface{}{
+		"logs-000001": {
+			"index.routing.allocation.require._name": nil,
+			"index.routing.allocation.require.temp":  "warm",
+		},
+		"logs-000002": {"index.routing.allocation.include._ip": "10.81.1.226"},
+	}, migrations)
+
+	// The last node of the tier also releases the indices required on the tier
+	migrations = PlanAllocationMigration(indicesSettings, node, "warm", true)
+	assert.Equal(t, map[string]map[string]interface{}{
+		"logs-000001": {"index.routing.allocation.require._name": nil},
+		"logs-000002": {"index.routing.allocation.include._ip": "10.81.1.226"},
+		"logs-000003": {"index.routing.allocation.require.temp": nil},
+	}, migrations)
+
+	// A node without a tier is only unpinned
+	migrations = PlanAllocationMigration(indicesSettings, node, "", false)
+	assert.Equal(t, map[string]interface{}{"index.routing.allocation.require._name": nil}, migrations["logs-000001"])
+	assert.NotContains(t, migrations, "logs-000003")
+}
+
+func TestSetShardAllocation(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The product check of the client is not recorded
+		if r.URL.Path == "/" {
+			w.Write([]byte(`{"version": {"number": "2.4.0"}}`))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.RequestURI()+" "+string(body))
+		w.Write([]byte(`{"acknowledged": true}`))
+	}))
+	defer server.Close()
+	client, err := NewClient(opensearch.Config{Addresses: []string{server.URL}, DisableRetry: true})
+	assert.Nil(t, err)
+	previousClient := osClient
+	osClient = client
+	defer func() { osClient = previousClient }()
+
+	assert.Nil(t, SetShardAllocation(context.Background(), "primaries"))
+	assert.Nil(t, FlushIndices(context.Background()))
+	assert.Nil(t, SetShardAllocation(context.Background(), ""))
+	assert.Nil(t, ExcludeFromVoting(context.Background(), "node-1"))
+	assert.Nil(t, ClearVotingExclusions(context.Background()))
+	assert.Equal(t, []string{
+		`PUT /_cluster/settings {"persistent":{"cluster.routing.allocation.enable":"primaries"}}`,
+		"POST /_flush ",
+		`PUT /_cluster/settings {"persistent":{"cluster.routing.allocation.enable":null}}`,
+		"POST /_cluster/voting_config_exclusions?node_names=node-1 ",
+		"DELETE /_cluster/voting_config_exclusions ",
+	}, requests)
+}
+
+func TestSetAllocationExclusion(t *testing.T) {
+	var requests []string
+	excluded := "node-2"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The product check of the client is not recorded
+		if r.URL.Path == "/" {
+			w.Write([]byte(`{"version": {"number": "2.4.0"}}`))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.RequestURI()+" "+string(body))
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"persistent": {"cluster.routing.allocation.exclude._name": "` + excluded + `"}, "transient": {}}`))
+			return
+		}
+		w.Write([]byte(`{"acknowledged": true}`))
+	}))
+	defer server.Close()
+	client, err := NewClient(opensearch.Config{Addresses: []string{server.URL}, DisableRetry: true})
+	assert.Nil(t, err)
+	previousClient := osClient
+	osClient = client
+	defer func() { osClient = previousClient }()
+
+	assert.Nil(t, SetAllocationExclusion(context.Background(), "node-1", true))
+	// The setting is not updated if the node is already excluded
+	assert.Nil(t, SetAllocationExclusion(context.Background(), "node-2", true))
+	assert.Nil(t, SetAllocationExclusion(context.Background(), "node-2", false))
+	excluded = "node-1,node-2"
+	assert.Nil(t, SetAllocationExclusion(context.Background(), "node-2", false))
+	assert.Equal(t, []string{
+		"GET /_cluster/settings?flat_settings=true ",
+		`PUT /_cluster/settings {"persistent":{"cluster.routing.allocation.exclude._name":"node-2,node-1"}}`,
+		"GET /_cluster/settings?flat_settings=true ",
+		"GET /_cluster/settings?flat_settings=true ",
+		`PUT /_cluster/settings {"persistent":{"cluster.routing.allocation.exclude._name":null}}`,
+		"GET /_cluster/settings?flat_settings=true ",
+		`PUT /_cluster/settings {"persistent":{"cluster.routing.allocation.exclude._name":"node-1"}}`,
+	}, requests)
+}
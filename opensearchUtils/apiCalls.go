@@ -3,6 +3,8 @@ package osutils
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"strings"
 	"time"
 
@@ -12,24 +14,48 @@ import (
 // Input:
 //
 //	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//	index (string): The index (or write alias) to which the document is indexed
 //	jsonDoc (byte): The request body in form of bytes
 //
 // Description:
 //
-//	Calls the osapi IndexRequest and returns the response
+//	Calls the osapi IndexRequest and returns the response. The document is indexed with a generated ID, so that
+//	a request retried by the client overwrites the document instead of indexing it twice.
 //
 // Return:
 //
 //	(*osapi.Response, error): Returns the api response and error if any
-func IndexMetrics(ctx context.Context, jsonDoc []byte) (*osapi.Response, error) {
+func IndexMetrics(ctx context.Context, index string, jsonDoc []byte) (*osapi.Response, error) {
+	docId, err := newDocumentId()
+	if err != nil {
+		return nil, err
+	}
 	return osapi.IndexRequest{
-		Index:        IndexName,
+		Index:        index,
 		DocumentType: "_doc",
+		DocumentID:   docId,
 		Body:         bytes.NewReader(jsonDoc),
 		Refresh:      "wait_for",
 	}.Do(ctx, osClient)
 }
 
+// Input:
+//
+// Description:
+//
+//	Generates a random ID for a new document
+//
+// Return:
+//
+//	(string, error): Returns the document ID and error if any
+func newDocumentId() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
 // Input:
 //
 //	ctx (context.Context): Request-scoped data that transits processes and APIs.
@@ -121,8 +147,29 @@ func CatAllocation(ctx context.Context, nodes []string) (*osapi.Response, error)
 
 // Input:
 //
-//	jsonQuery ([]byte): The json query in bytes that needs to be queried from the index
+//	nodes ([]string): List of nodes for which the allocation needs to be fetched
+//	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//
+// Description:
+//
+//	Calls the osapi CatAllocationRequest in json format with the shards, node and ip columns and returns the response
+//
+// Return:
+//
+//	(*osapi.Response, error): Returns the api response and error if any
+func CatAllocationJson(ctx context.Context, nodes []string) (*osapi.Response, error) {
+	return osapi.CatAllocationRequest{
+		NodeID: nodes,
+		Format: "json",
+		H:      []string{"shards", "node", "ip"},
+	}.Do(ctx, osClient)
+}
+
+// Input:
+//
 //	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//	index (string): The index (or alias) that needs to be queried
+//	jsonQuery ([]byte): The json query in bytes that needs to be queried from the index
 //
 // Description:
 //
@@ -131,9 +178,9 @@ func CatAllocation(ctx context.Context, nodes []string) (*osapi.Response, error)
 // Return:
 //
 //	(*osapi.Response, error): Returns the api response and error if any
-func SearchQuery(ctx context.Context, jsonQuery []byte) (*osapi.Response, error) {
+func SearchQuery(ctx context.Context, index string, jsonQuery []byte) (*osapi.Response, error) {
 	return osapi.SearchRequest{
-		Index: []string{IndexName},
+		Index: []string{index},
 		Body:  bytes.NewReader(jsonQuery),
 	}.Do(ctx, osClient)
 }
@@ -145,14 +192,14 @@ func SearchQuery(ctx context.Context, jsonQuery []byte) (*osapi.Response, error)
 //
 // Description:
 //
-//	Calls the osapi GetRequest and returns the response
+//	Calls the osapi GetRequest on the state index and returns the response
 //
 // Return:
 //
 //	(*osapi.Response, error): Returns the api response and error if any
 func SearchDoc(ctx context.Context, docId string) (*osapi.Response, error) {
 	return osapi.GetRequest{
-		Index:      IndexName,
+		Index:      StateIndexName,
 		DocumentID: docId,
 	}.Do(ctx, osClient)
 }
@@ -165,14 +212,14 @@ func SearchDoc(ctx context.Context, docId string) (*osapi.Response, error) {
 //
 // Description:
 //
-//	Calls the osapi IndexRequest along with document ID to update and returns the response
+//	Calls the osapi IndexRequest on the state index along with document ID to update and returns the response
 //
 // Return:
 //
 //	(*osapi.Response, error): Returns the api response and error if any
 func UpdateDoc(ctx context.Context, docId string, content string) (*osapi.Response, error) {
 	return osapi.IndexRequest{
-		Index:      IndexName,
+		Index:      StateIndexName,
 		DocumentID: docId,
 		Body:       strings.NewReader(content),
 		Refresh:    "wait_for",
@@ -186,15 +233,17 @@ func UpdateDoc(ctx context.Context, docId string, content string) (*osapi.Respon
 //
 // Description:
 //
-//	Calls the osapi DeleteByQueryRequest and returns the response
+//	Calls the osapi DeleteByQueryRequest on all the statistics indices and returns the response. Version conflicts
+//	with documents being indexed at the same time are skipped instead of aborting the request.
 //
 // Return:
 //
 //	(*osapi.Response, error): Returns the api response and error if any
 func DeleteWithQuery(ctx context.Context, jsonQuery []byte) (*osapi.Response, error) {
 	return osapi.DeleteByQueryRequest{
-		Index: []string{IndexName},
-		Body:  bytes.NewReader(jsonQuery),
+		Index:     []string{IndexPattern},
+		Body:      bytes.NewReader(jsonQuery),
+		Conflicts: "proceed",
 	}.Do(ctx, osClient)
 }
 
@@ -0,0 +1,278 @@
+package osutils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	opensearch "github.com/opensearch-project/opensearch-go"
+	osapi "github.com/opensearch-project/opensearch-go/opensearchapi"
+)
+
+// Number of times a request is retried on connection errors and the retryStatuses
+const maxRetries = 3
+
+// Response statuses on which a request is retried
+var retryStatuses = []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+// Backoff before the first retry, doubled on every retry up to maxRetryBackoff
+const (
+	initialRetryBackoff = 200 * time.Millisecond
+	maxRetryBackoff     = 5 * time.Second
+)
+
+// Number of consecutive failed requests after which the circuit breaker opens
+const breakerFailureThreshold = 5
+
+// Time for which the circuit breaker stays open before letting a trial request through
+const breakerCooldown = 30 * time.Second
+
+// ErrCircuitOpen is returned without calling opensearch while the circuit breaker is open
+var ErrCircuitOpen = errors.New("opensearch is unavailable, circuit breaker is open")
+
+// ResponseError is returned for a response with an error status
+type ResponseError struct {
+	StatusCode int
+	Body       string
+}
+
+// Input:
+//
+// Description:
+//
+//	Formats the status and body of the error response
+//
+// Return:
+//
+//	(string): Returns the error message
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("opensearch responded with status %d: %s", e.StatusCode, e.Body)
+}
+
+// Input:
+//
+//	err (error): Error returned by a request
+//
+// Description:
+//
+//	Checks if the error is an opensearch response with status 404
+//
+// Return:
+//
+//	(bool): Returns true if the requested resource was not found
+func IsNotFound(err error) bool {
+	var respErr *ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound
+}
+
+// Client wraps the opensearch client and stops sending requests while opensearch is failing.
+// It implements opensearchapi.Transport, so the opensearchapi requests are performed through it.
+type Client struct {
+	client  *opensearch.Client
+	breaker *circuitBreaker
+}
+
+// Input:
+//
+//	config (opensearch.Config): Configuration of the opensearch client
+//
+// Description:
+//
+//	Creates the client. Requests failing with a connection error or a retryStatuses status are retried
+//	with an exponential backoff, unless the config sets its own RetryBackoff, and the circuit breaker counts the
+//	requests still failing after the retries.
+//	Every request is retried, so the documents are indexed with an ID for the retries to be idempotent.
+//
+// Return:
+//
+//	(*Client, error): Returns the client and error if any
+func NewClient(config opensearch.Config) (*Client, error) {
+	config.MaxRetries = maxRetries
+	config.RetryOnStatus = retryStatuses
+	if config.RetryBackoff == nil {
+		config.RetryBackoff = retryBackoff
+	}
+	client, err := opensearch.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		client:  client,
+		breaker: newCircuitBreaker(breakerFailureThreshold, breakerCooldown),
+	}, nil
+}
+
+// Input:
+//
+//	attempt (int): The retry attempt, starting from 1
+//
+// Description:
+//
+//	Returns the time to wait before the retry, doubling with every attempt and with a random jitter of up to 50%
+//	so that the nodes do not retry in lockstep
+//
+// Return:
+//
+//	(time.Duration): Returns the backoff
+func retryBackoff(attempt int) time.Duration {
+	backoff := maxRetryBackoff
+	if attempt < 16 {
+		backoff = initialRetryBackoff << (attempt - 1)
+	}
+	if backoff > maxRetryBackoff {
+		backoff = maxRetryBackoff
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// Input:
+//
+//	req (*http.Request): The request to be performed
+//
+// Description:
+//
+//	Performs the request unless the circuit breaker is open. Connection errors and server side errors
+//	are counted as failures, any other response closes the circuit breaker again.
+//
+// Return:
+//
+//	(*http.Response, error): Returns the response and error if any
+func (c *Client) Perform(req *http.Request) (*http.Response, error) {
+	if !c.breaker.allow() {
+		return nil, ErrCircuitOpen
+	}
+	resp, err := c.client.Perform(req)
+	if err != nil || resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+		if c.breaker.failure() {
+			log.Error.Println("Opensearch requests keep failing, pausing requests for ", breakerCooldown)
+		}
+	} else {
+		if c.breaker.success() {
+			log.Info.Println("Opensearch requests are succeeding again")
+		}
+	}
+	return resp, err
+}
+
+// Input:
+//
+//	resp (*osapi.Response): Response of an opensearchapi request
+//	err (error): Error of the opensearchapi request
+//	v (interface{}): Pointer to decode the response body into, the body is discarded if nil
+//
+// Description:
+//
+//	Converts the result of an opensearchapi request into an error. Responses with an error status are
+//	returned as *ResponseError, otherwise the body is decoded into v. The body is always closed.
+//
+// Return:
+//
+//	(error): Returns error if any
+func DecodeResponse(resp *osapi.Response, err error, v interface{}) error {
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.IsError() {
+		body, _ := io.ReadAll(resp.Body)
+		return &ResponseError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	if v == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// circuitBreaker stops requests after consecutive failures. Once the cooldown has passed a single trial
+// request is let through, its result decides if the breaker closes or stays open for another cooldown.
+type circuitBreaker struct {
+	mutex            sync.Mutex
+	failureThreshold int
+	cooldown         time.Duration
+	failures         int
+	openedAt         time.Time
+	trialInFlight    bool
+	now              func() time.Time
+}
+
+// Input:
+//
+//	failureThreshold (int): Number of consecutive failures after which the breaker opens
+//	cooldown (time.Duration): Time for which the breaker stays open
+//
+// Description:
+//
+//	Creates a closed circuit breaker
+//
+// Return:
+//
+//	(*circuitBreaker): Returns the circuit breaker
+func newCircuitBreaker(failureThreshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{failureThreshold: failureThreshold, cooldown: cooldown, now: time.Now}
+}
+
+// Input:
+//
+// Description:
+//
+//	Checks if a request can be sent. While open, only a single trial request is allowed after the cooldown.
+//
+// Return:
+//
+//	(bool): Returns true if the request can be sent
+func (b *circuitBreaker) allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.failures < b.failureThreshold {
+		return true
+	}
+	if b.trialInFlight || b.now().Sub(b.openedAt) < b.cooldown {
+		return false
+	}
+	b.trialInFlight = true
+	return true
+}
+
+// Input:
+//
+// Description:
+//
+//	Records a failed request, opening the breaker once the failureThreshold is reached
+//
+// Return:
+//
+//	(bool): Returns true if the breaker opened with this failure
+func (b *circuitBreaker) failure() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	wasOpen := b.failures >= b.failureThreshold
+	b.failures++
+	b.trialInFlight = false
+	if b.failures >= b.failureThreshold {
+		b.openedAt = b.now()
+	}
+	return !wasOpen && b.failures >= b.failureThreshold
+}
+
+// Input:
+//
+// Description:
+//
+//	Records a successful request, closing the breaker
+//
+// Return:
+//
+//	(bool): Returns true if the breaker was open
+func (b *circuitBreaker) success() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	wasOpen := b.failures >= b.failureThreshold
+	b.failures = 0
+	b.trialInFlight = false
+	return wasOpen
+}
@@ -0,0 +1,145 @@
+package osutils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	opensearch "github.com/opensearch-project/opensearch-go"
+	osapi "github.com/opensearch-project/opensearch-go/opensearchapi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	for attempt := 1; attempt <= 20; attempt++ {
+		backoff := retryBackoff(attempt)
+		expected := maxRetryBackoff
+		if attempt < 16 && initialRetryBackoff<<(attempt-1) < maxRetryBackoff {
+			expected = initialRetryBackoff << (attempt - 1)
+		}
+		assert.GreaterOrEqual(t, backoff, expected/2)
+		assert.LessOrEqual(t, backoff, expected)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Unix(0, 0)
+	breaker := newCircuitBreaker(3, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	// Stays closed below the threshold and a success resets the failures
+	assert.False(t, breaker.failure())
+	assert.False(t, breaker.failure())
+	assert.False(t, breaker.success())
+	assert.False(t, breaker.failure())
+	assert.False(t, breaker.failure())
+	assert.True(t, breaker.allow())
+
+	// Opens on reaching the threshold
+	assert.True(t, breaker.failure())
+	assert.False(t, breaker.allow())
+
+	// Lets a single trial request through after the cooldown
+	now = now.Add(time.Minute)
+	assert.True(t, breaker.allow())
+	assert.False(t, breaker.allow())
+
+	// A failed trial keeps it open for another cooldown
+	assert.False(t, breaker.failure())
+	assert.False(t, breaker.allow())
+	now = now.Add(time.Minute)
+	assert.True(t, breaker.allow())
+
+	// A successful trial closes it
+	assert.True(t, breaker.success())
+	assert.True(t, breaker.allow())
+	assert.True(t, breaker.allow())
+}
+
+func TestClientPerform(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(status)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(opensearch.Config{Addresses: []string{server.URL}, DisableRetry: true})
+	assert.Nil(t, err)
+
+	for i := 0; i < breakerFailureThreshold; i++ {
+		resp, err := osapi.PingRequest{}.Do(context.Background(), client)
+		assert.NotNil(t, DecodeResponse(resp, err, nil))
+	}
+	assert.Equal(t, breakerFailureThreshold, requests)
+
+	// The breaker is open, so the request is not sent
+	_, err = osapi.PingRequest{}.Do(context.Background(), client)
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, breakerFailureThreshold, requests)
+}
+
+func TestDecodeResponse(t *testing.T) {
+	resp := &osapi.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"acknowledged": true}`))}
+	var body map[string]interface{}
+	assert.Nil(t, DecodeResponse(resp, nil, &body))
+	assert.Equal(t, true, body["acknowledged"])
+
+	resp = &osapi.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader(`{"found": false}`))}
+	err := DecodeResponse(resp, nil, &body)
+	assert.True(t, IsNotFound(err))
+	assert.Contains(t, err.Error(), `{"found": false}`)
+
+	resp = &osapi.Response{StatusCode: http.StatusInternalServerError, Body: io.NopCloser(strings.NewReader(`{}`))}
+	err = DecodeResponse(resp, nil, nil)
+	assert.NotNil(t, err)
+	assert.False(t, IsNotFound(err))
+
+	requestErr := errors.New("connection refused")
+	assert.Equal(t, requestErr, DecodeResponse(nil, requestErr, nil))
+}
+
+func TestIndexMetricsRetry(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The client checks the product with a GET on the root first
+		if r.URL.Path == "/" {
+			w.Write([]byte(`{"version": {"number": "2.4.0"}}`))
+			return
+		}
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		if len(paths) == 1 {
+			w.WriteHeader(http.StatusGatewayTimeout)
+		} else {
+			w.WriteHeader(http.StatusCreated)
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	previousClient := osClient
+	defer func() { osClient = previousClient }()
+	var backoffs []int
+	var err error
+	osClient, err = NewClient(opensearch.Config{Addresses: []string{server.URL}, RetryBackoff: func(attempt int) time.Duration {
+		backoffs = append(backoffs, attempt)
+		return 0
+	}})
+	assert.Nil(t, err)
+
+	// The retry overwrites the document indexed by the first attempt
+	resp, err := IndexMetrics(context.Background(), NodeStatsIndex, []byte(`{"StatTag": "NodeStatistics"}`))
+	assert.Nil(t, DecodeResponse(resp, err, nil))
+	assert.Len(t, paths, 2)
+	// The backoff of the config is kept
+	assert.Equal(t, []int{1}, backoffs)
+	assert.Equal(t, paths[0], paths[1])
+	assert.Regexp(t, "^PUT /"+NodeStatsIndex+"/_doc/[0-9a-f]{32}$", paths[0])
+}
@@ -0,0 +1,207 @@
+package osutils
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/opensearch-project/opensearch-go/signer"
+)
+
+// Endpoint used when no endpoint is configured
+const defaultEndpoint string = "http://localhost:9200"
+
+// Supported methods of authenticating to the opensearch cluster
+const (
+	AuthBasic    string = "basic"
+	AuthAwsSigV4 string = "aws_sigv4"
+	AuthNone     string = "none"
+)
+
+// This struct contains the details of connecting to the opensearch cluster.
+type ConnectionConfig struct {
+	// Endpoints indicates the list of opensearch urls the requests are distributed to. Defaults to http://localhost:9200.
+	Endpoints []string `yaml:"endpoints,omitempty" validate:"omitempty,dive,url" json:"endpoints,omitempty"`
+	// AuthMethod indicates how the requests are authenticated. These can be:
+	//      basic: The os_credentials are sent as basic auth (default)
+	//      aws_sigv4: The requests are signed with AWS Signature Version 4, for Amazon OpenSearch Service
+	//      none: The requests are not authenticated
+	AuthMethod string `yaml:"auth_method,omitempty" validate:"omitempty,oneof=basic aws_sigv4 none" json:"auth_method,omitempty"`
+	// Tls indicates the TLS settings for https endpoints.
+	Tls TlsConfig `yaml:"tls,omitempty" json:"tls,omitempty"`
+	// AwsSigV4 indicates the signing settings used with the aws_sigv4 auth method.
+	AwsSigV4 AwsSigV4Config `yaml:"aws_sigv4,omitempty" json:"aws_sigv4,omitempty"`
+}
+
+// This struct contains the TLS settings for connecting to the opensearch cluster.
+type TlsConfig struct {
+	// CaFile indicates the path of the PEM bundle of the CAs to verify the server certificate with. The system CAs are used if empty.
+	CaFile string `yaml:"ca_file,omitempty" json:"ca_file,omitempty"`
+	// CertFile indicates the path of the PEM client certificate, for clusters requiring client certificate authentication.
+	CertFile string `yaml:"cert_file,omitempty" validate:"required_with=KeyFile" json:"cert_file,omitempty"`
+	// KeyFile indicates the path of the PEM private key of the client certificate.
+	KeyFile string `yaml:"key_file,omitempty" validate:"required_with=CertFile" json:"key_file,omitempty"`
+	// InsecureSkipVerify disables the verification of the server certificate. Meant for lab setups only.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify,omitempty" json:"insecure_skip_verify,omitempty"`
+}
+
+// This struct contains the settings for signing the requests with AWS Signature Version 4.
+type AwsSigV4Config struct {
+	// Region indicates the AWS region of the domain.
+	Region string `yaml:"region,omitempty" json:"region,omitempty"`
+	// Service indicates the service name used in the signature, "es" (default) for managed domains and "aoss" for serverless collections.
+	Service string `yaml:"service,omitempty" validate:"omitempty,oneof=es aoss" json:"service,omitempty"`
+	// RoleArn indicates the role assumed for signing. The default AWS credential chain is used if empty.
+	RoleArn string `yaml:"role_arn,omitempty" json:"role_arn,omitempty"`
+}
+
+// Input:
+//
+// Description:
+//
+//	Returns the endpoints to connect to, defaulting to the local node
+//
+// Return:
+//
+//	([]string): Returns the list of endpoints
+func (c ConnectionConfig) endpoints() []string {
+	if len(c.Endpoints) == 0 {
+		return []string{defaultEndpoint}
+	}
+	return c.Endpoints
+}
+
+// Input:
+//
+// Description:
+//
+//	Builds the TLS client configuration from the CA bundle, client certificate and verification settings
+//
+// Return:
+//
+//	(*tls.Config, error): Returns the TLS configuration and error if any
+func (t TlsConfig) clientConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+	if t.InsecureSkipVerify {
+		log.Warn.Println("TLS certificate verification of the opensearch endpoints is disabled")
+	}
+
+	if t.CaFile != "" {
+		caCert, err := os.ReadFile(t.CaFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the CA file: %w", err)
+		}
+		caPool := x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no certificate found in the CA file %s", t.CaFile)
+		}
+		tlsConfig.RootCAs = caPool
+	}
+
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load the client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// Input:
+//
+// Description:
+//
+//	Builds the HTTP transport used by the opensearch client. Connections are kept alive and reused across requests.
+//
+// Return:
+//
+//	(*http.Transport, error): Returns the transport and error if any
+func (c ConnectionConfig) transport() (*http.Transport, error) {
+	tlsConfig, err := c.Tls.clientConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		TLSClientConfig:       tlsConfig,
+		MaxIdleConnsPerHost:   10,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 60 * time.Second,
+	}, nil
+}
+
+// awsSigV4Signer signs the requests with AWS Signature Version 4
+type awsSigV4Signer struct {
+	signer  *v4.Signer
+	region  string
+	service string
+}
+
+// Input:
+//
+// Description:
+//
+//	Creates the signer for the aws_sigv4 auth method. The credentials are taken from the default AWS
+//	credential chain (environment, shared config, instance role), assuming RoleArn if given.
+//
+// Return:
+//
+//	(signer.Signer, error): Returns the signer and error if any
+func (a AwsSigV4Config) newSigner() (signer.Signer, error) {
+	if a.Region == "" {
+		return nil, errors.New("aws_sigv4.region is required for the aws_sigv4 auth method")
+	}
+	service := a.Service
+	if service == "" {
+		service = "es"
+	}
+	sess, err := session.NewSession(&aws.Config{Region: aws.String(a.Region)})
+	if err != nil {
+		return nil, err
+	}
+	creds := sess.Config.Credentials
+	if a.RoleArn != "" {
+		creds = stscreds.NewCredentials(sess, a.RoleArn)
+	}
+	return &awsSigV4Signer{signer: v4.NewSigner(creds), region: a.Region, service: service}, nil
+}
+
+// Input:
+//
+//	req (*http.Request): The request to be signed
+//
+// Description:
+//
+//	Signs the request, the body is read and restored so that it can still be sent
+//
+// Return:
+//
+//	(error): Returns error if any
+func (s *awsSigV4Signer) SignRequest(req *http.Request) error {
+	var body io.ReadSeeker
+	if req.Body != nil {
+		content, err := io.ReadAll(req.Body)
+		if err != nil {
+			return err
+		}
+		req.Body.Close()
+		body = bytes.NewReader(content)
+	}
+	_, err := s.signer.Sign(req, body, s.service, s.region, time.Now().UTC())
+	return err
+}
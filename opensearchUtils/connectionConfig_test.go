@@ -0,0 +1,64 @@
+package osutils
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndpoints(t *testing.T) {
+	assert.Equal(t, []string{defaultEndpoint}, ConnectionConfig{}.endpoints())
+
+	endpoints := []string{"https://10.0.0.1:9200", "https://10.0.0.2:9200"}
+	assert.Equal(t, endpoints, ConnectionConfig{Endpoints: endpoints}.endpoints())
+}
+
+func TestTlsClientConfig(t *testing.T) {
+	tlsConfig, err := TlsConfig{}.clientConfig()
+	assert.Nil(t, err)
+	assert.Nil(t, tlsConfig.RootCAs)
+	assert.False(t, tlsConfig.InsecureSkipVerify)
+
+	tlsConfig, err = TlsConfig{InsecureSkipVerify: true}.clientConfig()
+	assert.Nil(t, err)
+	assert.True(t, tlsConfig.InsecureSkipVerify)
+
+	_, err = TlsConfig{CaFile: "testdata/missing.pem"}.clientConfig()
+	assert.NotNil(t, err)
+
+	_, err = TlsConfig{CaFile: "testdata/not_a_cert.pem"}.clientConfig()
+	assert.NotNil(t, err)
+
+	_, err = TlsConfig{CertFile: "testdata/not_a_cert.pem", KeyFile: "testdata/not_a_cert.pem"}.clientConfig()
+	assert.NotNil(t, err)
+}
+
+func TestNewSignerRequiresRegion(t *testing.T) {
+	_, err := AwsSigV4Config{}.newSigner()
+	assert.NotNil(t, err)
+}
+
+func TestSignRequest(t *testing.T) {
+	s := &awsSigV4Signer{
+		signer:  v4.NewSigner(credentials.NewStaticCredentials("access", "secret", "")),
+		region:  "us-west-2",
+		service: "es",
+	}
+	req, err := http.NewRequest(http.MethodPost, "https://search.example.com/_search", strings.NewReader(`{"size": 0}`))
+	assert.Nil(t, err)
+
+	err = s.SignRequest(req)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(req.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/"))
+	assert.Contains(t, req.Header.Get("Authorization"), "/us-west-2/es/aws4_request")
+
+	// The body must still be readable after signing
+	body, err := io.ReadAll(req.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"size": 0}`, string(body))
+}
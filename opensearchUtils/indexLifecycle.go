@@ -0,0 +1,270 @@
+package osutils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	osapi "github.com/opensearch-project/opensearch-go/opensearchapi"
+)
+
+// Name of the Index State Management policy managing the backing indices
+const IsmPolicyId string = "monitor-stats-policy"
+
+// Name of the plugin providing Index State Management
+const ismPluginName string = "opensearch-index-management"
+
+// Size at which the write index is rolled over irrespective of its age
+const rolloverMinSize string = "5gb"
+
+// Input:
+//
+//	ctx (context.Context)
+//	index (string): Name of the index to be created
+//	body (string): Settings, mappings and aliases of the index
+//
+// Description:
+//
+//	Creates the index. An index which already exists is not treated as an error.
+//
+// Return:
+//
+//	(error): Returns error if any
+func createIndex(ctx context.Context, index string, body string) error {
+	resp, err := osapi.IndicesCreateRequest{
+		Index: index,
+		Body:  strings.NewReader(body),
+	}.Do(ctx, osClient)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.IsError() {
+		respBody := resp.String()
+		if strings.Contains(respBody, "resource_already_exists_exception") {
+			return nil
+		}
+		return errors.New(respBody)
+	}
+	log.Info.Println("Created index: ", index)
+	return nil
+}
+
+// Input:
+//
+//	ctx (context.Context)
+//	method (string): HTTP method of the request
+//	path (string): Path of the api
+//	body (string): Request body, empty if the request has no body
+//
+// Description:
+//
+//	Performs a request on an api not covered by the opensearchapi package, like the ISM plugin apis
+//
+// Return:
+//
+//	(int, []byte, error): Returns the status code, response body and error if any
+func performRequest(ctx context.Context, method string, path string, body string) (int, []byte, error) {
+	var reqBody io.Reader
+	if body != "" {
+		reqBody = strings.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, path, reqBody)
+	if err != nil {
+		return 0, nil, err
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := osClient.Perform(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, err
+	}
+	return resp.StatusCode, respBody, nil
+}
+
+// Input:
+//
+//	ctx (context.Context)
+//
+// Description:
+//
+//	Checks if the Index State Management plugin is installed on the cluster
+//
+// Return:
+//
+//	(bool, error): Returns true if the plugin is installed and error if any
+func IsmAvailable(ctx context.Context) (bool, error) {
+	status, body, err := performRequest(ctx, http.MethodGet, "/_cat/plugins?format=json", "")
+	if err != nil {
+		return false, err
+	}
+	if status != http.StatusOK {
+		return false, fmt.Errorf("cat plugins failed with status %d: %s", status, body)
+	}
+	var plugins []map[string]interface{}
+	err = json.Unmarshal(body, &plugins)
+	if err != nil {
+		return false, err
+	}
+	for _, plugin := range plugins {
+		if plugin["component"] == ismPluginName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Input:
+//
+//	purgeAfter (int): Number of hours after which the documents are purged
+//
+// Description:
+//
+//	Returns the age in hours at which the write index is rolled over. The index is rolled over
+//	four times within the purge duration so that at most a quarter of it is retained longer than required.
+//
+// Return:
+//
+//	(int): Returns the rollover age in hours
+func rolloverHours(purgeAfter int) int {
+	hours := purgeAfter / 4
+	if hours < 1 {
+		return 1
+	}
+	return hours
+}
+
+// Input:
+//
+//	purgeAfter (int): Number of hours after which the documents are purged
+//
+// Description:
+//
+//	Generates the ISM policy which rolls over the write index and deletes the backing indices once all
+//	their documents are older than purgeAfter hours. The age of a rolled over index is counted from its
+//	creation, so the delete transition waits for the rollover duration on top of purgeAfter.
+//
+// Return:
+//
+//	(string): Returns the policy that can be given as the body of the ISM policy api
+func getIsmPolicy(purgeAfter int) string {
+	rollover := rolloverHours(purgeAfter)
+	return `{
+	  "policy": {
+	    "description": "Rollover and purge of the ` + IndexPattern + ` indices, documents are retained for ` + strconv.Itoa(purgeAfter) + ` hours",
+	    "default_state": "hot",
+	    "states": [
+	      {
+	        "name": "hot",
+	        "actions": [
+	          {
+	            "rollover": {
+	              "min_index_age": "` + strconv.Itoa(rollover) + `h",
+	              "min_size": "` + rolloverMinSize + `"
+	            }
+	          }
+	        ],
+	        "transitions": [
+	          {
+	            "state_name": "delete",
+	            "conditions": {
+	              "min_index_age": "` + strconv.Itoa(purgeAfter+rollover) + `h"
+	            }
+	          }
+	        ]
+	      },
+	      {
+	        "name": "delete",
+	        "actions": [
+	          {
+	            "delete": {}
+	          }
+	        ],
+	        "transitions": []
+	      }
+	    ],
+	    "ism_template": [
+	      {
+	        "index_patterns": ["` + IndexPattern + `"],
+	        "priority": 100
+	      }
+	    ]
+	  }
+	}`
+}
+
+// Input:
+//
+//	ctx (context.Context)
+//	purgeAfter (int): Number of hours after which the documents are purged
+//
+// Description:
+//
+//	Creates or updates the ISM policy for the backing indices. New backing indices pick up the policy
+//	through its ism_template, existing ones are attached to the policy or switched to the updated version.
+//
+// Return:
+//
+//	(error): Returns error if any
+func ApplyIsmPolicy(ctx context.Context, purgeAfter int) error {
+	policyPath := "/_plugins/_ism/policies/" + IsmPolicyId
+	status, body, err := performRequest(ctx, http.MethodGet, policyPath, "")
+	if err != nil {
+		return err
+	}
+
+	putPath := policyPath
+	switch status {
+	case http.StatusOK:
+		var existing map[string]interface{}
+		err = json.Unmarshal(body, &existing)
+		if err != nil {
+			return err
+		}
+		seqNo, _ := existing["_seq_no"].(float64)
+		primaryTerm, _ := existing["_primary_term"].(float64)
+		putPath += "?if_seq_no=" + strconv.Itoa(int(seqNo)) + "&if_primary_term=" + strconv.Itoa(int(primaryTerm))
+	case http.StatusNotFound:
+	default:
+		return fmt.Errorf("get ISM policy failed with status %d: %s", status, body)
+	}
+
+	status, body, err = performRequest(ctx, http.MethodPut, putPath, getIsmPolicy(purgeAfter))
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK && status != http.StatusCreated {
+		return fmt.Errorf("put ISM policy failed with status %d: %s", status, body)
+	}
+
+	// Attach the indices created before the policy existed, managed indices are skipped by the add api
+	status, body, err = performRequest(ctx, http.MethodPost, "/_plugins/_ism/add/"+IndexPattern, `{"policy_id": "`+IsmPolicyId+`"}`)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("add ISM policy failed with status %d: %s", status, body)
+	}
+
+	// Managed indices keep running the policy version they were attached with until changed
+	status, body, err = performRequest(ctx, http.MethodPost, "/_plugins/_ism/change_policy/"+IndexPattern, `{"policy_id": "`+IsmPolicyId+`"}`)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("change ISM policy failed with status %d: %s", status, body)
+	}
+	log.Info.Println("ISM policy ", IsmPolicyId, " applied, documents are purged after ", purgeAfter, " hours")
+	return nil
+}
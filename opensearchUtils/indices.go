@@ -0,0 +1,483 @@
+package osutils
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	osapi "github.com/opensearch-project/opensearch-go/opensearchapi"
+)
+
+//go:embed mappings/*.json
+var mappingsFs embed.FS
+
+// Single index holding all the documents in older versions, an alias over rollover indices in some of them
+const legacyIndexName string = "monitor-stats"
+
+// Index template applied to the backing indices of legacyIndexName
+const legacyTemplateName string = legacyIndexName + "-template"
+
+// Description: managedIndex describes an index (or rollover alias) created and maintained by the application
+type managedIndex struct {
+	// Name of the index, or the write alias for rollover indices
+	name string
+	// File under mappings/ holding the mappings. The version of the mappings is kept in _meta.version.
+	mappingFile string
+	// If true name is a write alias over rollover indices created from an index template.
+	// Otherwise name is an alias over a single index named after the mappings version.
+	rollover bool
+	// StatTag of the documents moved to this index from legacyIndexName
+	statTags []string
+}
+
+// Indices maintained by the application
+var managedIndices = []managedIndex{
+	{name: NodeStatsIndex, mappingFile: "node.json", rollover: true, statTags: []string{"NodeStatistics", "NodeGap"}},
+	{name: ClusterStatsIndex, mappingFile: "cluster.json", rollover: true, statTags: []string{"ClusterStatistics"}},
+	{name: ProvisionStatsIndex, mappingFile: "provision.json", rollover: true, statTags: []string{"ProvisionStats"}},
+	{name: StateIndexName, mappingFile: "state.json", rollover: false, statTags: []string{"State"}},
+}
+
+// Input:
+//
+// Description:
+//
+//	Reads the mappings of the index from the embedded mapping file
+//
+// Return:
+//
+//	(map[string]interface{}, error): Returns the mappings and error if any
+func (m managedIndex) mappings() (map[string]interface{}, error) {
+	content, err := mappingsFs.ReadFile("mappings/" + m.mappingFile)
+	if err != nil {
+		return nil, err
+	}
+	var file map[string]map[string]interface{}
+	err = json.Unmarshal(content, &file)
+	if err != nil {
+		return nil, err
+	}
+	return file["mappings"], nil
+}
+
+// Input:
+//
+//	mappings (map[string]interface{}): Mappings of an index
+//
+// Description:
+//
+//	Reads the version of the mappings from _meta.version. Mappings without a version are treated as version 0.
+//
+// Return:
+//
+//	(int): Returns the version of the mappings
+func mappingsVersion(mappings map[string]interface{}) int {
+	meta, ok := mappings["_meta"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	version, _ := meta["version"].(float64)
+	return int(version)
+}
+
+// Input:
+//
+//	ctx (context.Context)
+//	index (managedIndex): Index to be set up
+//
+// Description:
+//
+//	Creates the index with the current mappings if it does not exist. For rollover indices the index template is
+//	put first and the first backing index is created with the write alias. An existing index whose mappings are
+//	older than the current version is upgraded.
+//
+// Return:
+//
+//	(error): Returns error if any
+func setupIndex(ctx context.Context, index managedIndex) error {
+	mappings, err := index.mappings()
+	if err != nil {
+		return err
+	}
+	if index.rollover {
+		err = putIndexTemplate(ctx, index, mappings)
+		if err != nil {
+			return err
+		}
+	}
+
+	exist, err := osapi.IndicesExistsRequest{
+		Index: []string{index.name},
+	}.Do(ctx, osClient)
+	if err != nil {
+		return err
+	}
+	exist.Body.Close()
+	if exist.StatusCode == http.StatusOK {
+		return upgradeMappings(ctx, index, mappings)
+	}
+
+	if index.rollover {
+		return createIndex(ctx, index.name+"-000001", `{"aliases": {"`+index.name+`": {"is_write_index": true}}}`)
+	}
+	return createVersionedIndex(ctx, index, mappings, true)
+}
+
+// Input:
+//
+//	ctx (context.Context)
+//	index (managedIndex): Index which is not rolled over
+//	mappings (map[string]interface{}): Current mappings of the index
+//	withAlias (bool): If true the alias is added to the created index
+//
+// Description:
+//
+//	Creates the concrete index for the current mappings version, named <name>-v<version>
+//
+// Return:
+//
+//	(error): Returns error if any
+func createVersionedIndex(ctx context.Context, index managedIndex, mappings map[string]interface{}, withAlias bool) error {
+	indexBody := map[string]interface{}{"mappings": mappings}
+	if withAlias {
+		indexBody["aliases"] = map[string]interface{}{index.name: map[string]interface{}{}}
+	}
+	body, err := json.Marshal(indexBody)
+	if err != nil {
+		return err
+	}
+	return createIndex(ctx, versionedIndexName(index, mappings), string(body))
+}
+
+// Input:
+//
+//	index (managedIndex): Index which is not rolled over
+//	mappings (map[string]interface{}): Mappings of the index
+//
+// Description:
+//
+//	Returns the name of the concrete index holding the given version of the mappings
+//
+// Return:
+//
+//	(string): Returns the concrete index name
+func versionedIndexName(index managedIndex, mappings map[string]interface{}) string {
+	return index.name + "-v" + strconv.Itoa(mappingsVersion(mappings))
+}
+
+// Input:
+//
+//	ctx (context.Context)
+//	index (managedIndex): Rollover index for which the template is put
+//	mappings (map[string]interface{}): Current mappings of the index
+//
+// Description:
+//
+//	Puts the index template applied to the backing indices of the alias. The template carries the mappings
+//	and the rollover alias setting used by the ISM rollover action.
+//
+// Return:
+//
+//	(error): Returns error if any
+func putIndexTemplate(ctx context.Context, index managedIndex, mappings map[string]interface{}) error {
+	template := map[string]interface{}{
+		"index_patterns": []string{index.name + "-*"},
+		"priority":       100,
+		"template": map[string]interface{}{
+			"settings": map[string]interface{}{
+				"plugins.index_state_management.rollover_alias": index.name,
+			},
+			"mappings": mappings,
+		},
+	}
+	body, err := json.Marshal(template)
+	if err != nil {
+		return err
+	}
+	resp, err := osapi.IndicesPutIndexTemplateRequest{
+		Name: index.name + "-template",
+		Body: bytes.NewReader(body),
+	}.Do(ctx, osClient)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.IsError() {
+		return errors.New(resp.String())
+	}
+	return nil
+}
+
+// Input:
+//
+//	ctx (context.Context)
+//	index (managedIndex): Existing index to be upgraded
+//	mappings (map[string]interface{}): Current mappings of the index
+//
+// Description:
+//
+//	Compares the mappings version of the index (the write index for rollover indices) with the current version.
+//	Older mappings are updated in place, which works for added fields. If the update is rejected, like for a
+//	changed field type, a rollover index is rolled over so that the new write index is created from the template,
+//	any other index is reindexed to a new index for the current version.
+//
+// Return:
+//
+//	(error): Returns error if any
+func upgradeMappings(ctx context.Context, index managedIndex, mappings map[string]interface{}) error {
+	resp, err := osapi.IndicesGetMappingRequest{
+		Index: []string{index.name},
+	}.Do(ctx, osClient)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.IsError() {
+		return errors.New(resp.String())
+	}
+	var indexMappings map[string]map[string]map[string]interface{}
+	err = json.NewDecoder(resp.Body).Decode(&indexMappings)
+	if err != nil {
+		return err
+	}
+
+	// Backing indices are numbered in the order of creation, the last one is the write index
+	var indexNames []string
+	for indexName := range indexMappings {
+		indexNames = append(indexNames, indexName)
+	}
+	if len(indexNames) == 0 {
+		return fmt.Errorf("no index found for %s", index.name)
+	}
+	sort.Strings(indexNames)
+	currentIndex := indexNames[len(indexNames)-1]
+	existingVersion := mappingsVersion(indexMappings[currentIndex]["mappings"])
+	version := mappingsVersion(mappings)
+	if existingVersion >= version {
+		return nil
+	}
+
+	log.Info.Println("Upgrading mappings of ", index.name, " from version ", existingVersion, " to ", version)
+	body, err := json.Marshal(mappings)
+	if err != nil {
+		return err
+	}
+	putResp, err := osapi.IndicesPutMappingRequest{
+		Index: []string{index.name},
+		Body:  bytes.NewReader(body),
+	}.Do(ctx, osClient)
+	if err != nil {
+		return err
+	}
+	defer putResp.Body.Close()
+	if !putResp.IsError() {
+		return nil
+	}
+	if !index.rollover {
+		log.Warn.Println("Mappings of ", index.name, " can't be updated in place, reindexing to a new index: ", putResp.String())
+		return recreateIndex(ctx, index, mappings, indexNames)
+	}
+
+	log.Warn.Println("Mappings of ", index.name, " can't be updated in place, rolling over to a new index: ", putResp.String())
+	rolloverResp, err := osapi.IndicesRolloverRequest{
+		Alias: index.name,
+	}.Do(ctx, osClient)
+	if err != nil {
+		return err
+	}
+	defer rolloverResp.Body.Close()
+	if rolloverResp.IsError() {
+		return errors.New(rolloverResp.String())
+	}
+	return nil
+}
+
+// Input:
+//
+//	ctx (context.Context)
+//	index (managedIndex): Index which is not rolled over
+//	mappings (map[string]interface{}): Current mappings of the index
+//	oldIndices ([]string): Concrete indices currently behind the index name
+//
+// Description:
+//
+//	Creates the index for the current mappings version, copies the documents to it and then replaces the
+//	old indices by the alias in a single request, so readers never see the index missing.
+//
+// Return:
+//
+//	(error): Returns error if any
+func recreateIndex(ctx context.Context, index managedIndex, mappings map[string]interface{}, oldIndices []string) error {
+	err := createVersionedIndex(ctx, index, mappings, false)
+	if err != nil {
+		return err
+	}
+	newIndex := versionedIndexName(index, mappings)
+	err = reindex(ctx, index.name, `{"match_all": {}}`, newIndex)
+	if err != nil {
+		return err
+	}
+
+	var actions []map[string]interface{}
+	for _, oldIndex := range oldIndices {
+		actions = append(actions, map[string]interface{}{"remove_index": map[string]interface{}{"index": oldIndex}})
+	}
+	actions = append(actions, map[string]interface{}{"add": map[string]interface{}{"index": newIndex, "alias": index.name}})
+	body, err := json.Marshal(map[string]interface{}{"actions": actions})
+	if err != nil {
+		return err
+	}
+	resp, err := osapi.IndicesUpdateAliasesRequest{
+		Body: bytes.NewReader(body),
+	}.Do(ctx, osClient)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.IsError() {
+		return errors.New(resp.String())
+	}
+	log.Info.Println("Moved ", index.name, " to ", newIndex)
+	return nil
+}
+
+// Input:
+//
+//	ctx (context.Context)
+//
+// Description:
+//
+//	Deletes the index template of the legacy index, if present
+//
+// Return:
+//
+//	(error): Returns error if any
+func deleteLegacyTemplate(ctx context.Context) error {
+	resp, err := osapi.IndicesDeleteIndexTemplateRequest{
+		Name: legacyTemplateName,
+	}.Do(ctx, osClient)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.IsError() && resp.StatusCode != http.StatusNotFound {
+		return errors.New(resp.String())
+	}
+	return nil
+}
+
+// Input:
+//
+//	ctx (context.Context)
+//
+// Description:
+//
+//	Migrates the documents of the legacy monitor-stats index (or alias) to the dedicated index of their StatTag
+//	and deletes the legacy indices. Documents already present in the destination are skipped, so an
+//	interrupted migration can be run again.
+//
+// Return:
+//
+//	(error): Returns error if any
+func migrateLegacyIndex(ctx context.Context) error {
+	settingsResp, err := osapi.IndicesGetSettingsRequest{
+		Index: []string{legacyIndexName},
+	}.Do(ctx, osClient)
+	if err != nil {
+		return err
+	}
+	defer settingsResp.Body.Close()
+	if settingsResp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if settingsResp.IsError() {
+		return errors.New(settingsResp.String())
+	}
+	// The settings response is keyed by the concrete indices, which also resolves the alias
+	var legacySettings map[string]interface{}
+	err = json.NewDecoder(settingsResp.Body).Decode(&legacySettings)
+	if err != nil {
+		return err
+	}
+
+	log.Info.Println("Migrating the documents of ", legacyIndexName, " to the dedicated indices")
+	for _, index := range managedIndices {
+		statTags, err := json.Marshal(index.statTags)
+		if err != nil {
+			return err
+		}
+		err = reindex(ctx, legacyIndexName, `{"terms": {"StatTag.keyword": `+string(statTags)+`}}`, index.name)
+		if err != nil {
+			return err
+		}
+	}
+
+	var legacyIndices []string
+	for legacyIndex := range legacySettings {
+		legacyIndices = append(legacyIndices, legacyIndex)
+	}
+	deleteResp, err := osapi.IndicesDeleteRequest{
+		Index: legacyIndices,
+	}.Do(ctx, osClient)
+	if err != nil {
+		return err
+	}
+	defer deleteResp.Body.Close()
+	if deleteResp.IsError() {
+		return errors.New(deleteResp.String())
+	}
+	log.Info.Println("Migration completed, deleted legacy indices: ", strings.Join(legacyIndices, ", "))
+	return nil
+}
+
+// Input:
+//
+//	ctx (context.Context)
+//	sourceIndex (string): Index from which the documents are copied
+//	query (string): Query selecting the documents to be copied
+//	destIndex (string): Index, or alias with a write index, to which the documents are copied
+//
+// Description:
+//
+//	Copies the documents matching the query from the source to the destination index.
+//	Documents already present in the destination are skipped.
+//
+// Return:
+//
+//	(error): Returns error if any
+func reindex(ctx context.Context, sourceIndex string, query string, destIndex string) error {
+	body := `{
+	  "conflicts": "proceed",
+	  "source": {"index": "` + sourceIndex + `", "query": ` + query + `},
+	  "dest": {"index": "` + destIndex + `", "op_type": "create"}
+	}`
+	waitForCompletion := true
+	resp, err := osapi.ReindexRequest{
+		Body:              strings.NewReader(body),
+		WaitForCompletion: &waitForCompletion,
+	}.Do(ctx, osClient)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.IsError() {
+		return errors.New(resp.String())
+	}
+	var reindexResp map[string]interface{}
+	err = json.NewDecoder(resp.Body).Decode(&reindexResp)
+	if err != nil {
+		return err
+	}
+	if failures, ok := reindexResp["failures"].([]interface{}); ok && len(failures) > 0 {
+		return fmt.Errorf("reindex to %s failed: %v", destIndex, failures[0])
+	}
+	log.Info.Println("Reindexed ", reindexResp["created"], " documents to ", destIndex)
+	return nil
+}
@@ -0,0 +1,32 @@
+package osutils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManagedIndexMappings(t *testing.T) {
+	for _, index := range managedIndices {
+		mappings, err := index.mappings()
+		assert.Nil(t, err, index.name)
+		assert.GreaterOrEqual(t, mappingsVersion(mappings), 1, index.name)
+
+		properties, ok := mappings["properties"].(map[string]interface{})
+		assert.True(t, ok, index.name)
+		assert.Contains(t, properties, "Timestamp", index.name)
+		assert.Contains(t, properties, "StatTag", index.name)
+	}
+}
+
+func TestMappingsVersion(t *testing.T) {
+	assert.Equal(t, 0, mappingsVersion(map[string]interface{}{}))
+	assert.Equal(t, 0, mappingsVersion(map[string]interface{}{"_meta": map[string]interface{}{}}))
+	assert.Equal(t, 3, mappingsVersion(map[string]interface{}{"_meta": map[string]interface{}{"version": float64(3)}}))
+}
+
+func TestVersionedIndexName(t *testing.T) {
+	index := managedIndex{name: StateIndexName}
+	mappings := map[string]interface{}{"_meta": map[string]interface{}{"version": float64(2)}}
+	assert.Equal(t, "scaling-manager-state-v2", versionedIndexName(index, mappings))
+}
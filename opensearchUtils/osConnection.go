@@ -1,31 +1,35 @@
 package osutils
 
 import (
-	"bytes"
 	"context"
-	_ "embed"
+	"fmt"
 	"github.com/maplelabs/opensearch-scaling-manager/logger"
-	"net/http"
-	"os"
 
 	opensearch "github.com/opensearch-project/opensearch-go"
 	osapi "github.com/opensearch-project/opensearch-go/opensearchapi"
 )
 
-//go:embed mappings.json
-
-var mappingsFile []byte
-
-// Index used by the application
+// Indices used by the application. The statistics indices are write aliases over rollover indices,
+// searches on an alias cover all of its backing indices.
 const (
-	IndexName string = "monitor-stats"
+	// NodeStatsIndex holds the NodeStatistics and NodeGap documents
+	NodeStatsIndex string = "monitor-stats-node"
+	// ClusterStatsIndex holds the ClusterStatistics documents
+	ClusterStatsIndex string = "monitor-stats-cluster"
+	// ProvisionStatsIndex holds the ProvisionStats and OrphanCleanup documents
+	ProvisionStatsIndex string = "monitor-stats-provision"
+	// StateIndexName is the index holding the provisioning state document. It is not rolled over
+	// as the state document is read and updated by its document ID.
+	StateIndexName string = "scaling-manager-state"
+	// IndexPattern matches the backing indices of all the statistics aliases
+	IndexPattern string = "monitor-stats-*"
 )
 
 // A global logger variable used across the package for logging.
 var log = new(logger.LOG)
 
 // // A global opensearch Client used across the package for Opensearch operations.
-var osClient *opensearch.Client
+var osClient *Client
 
 // Input:
 //
@@ -41,46 +45,70 @@ func init() {
 
 // Input:
 //
-//	username (string): Username for OS cluster
-//	password (string): Password for OS cluster
+//	connection (ConnectionConfig): Endpoints, TLS and auth method of the OS cluster
+//	username (string): Username for OS cluster, used with the basic auth method
+//	password (string): Password for OS cluster, used with the basic auth method
 //
 // Description:
 //
-//	Initialize the Opensearch client
+//	Initialize the Opensearch client, checks the connection and sets up the indices
 //
 // Return:
-func InitializeOsClient(username string, password string) {
+//
+//	(error): Returns error if any
+func InitializeOsClient(connection ConnectionConfig, username string, password string) error {
 	var err error
 
-	osClient, err = opensearch.NewClient(opensearch.Config{
-		Addresses: []string{"http://localhost:9200"},
-		Username:  username,
-		Password:  password,
-		Transport: &http.Transport{
-			DisableKeepAlives: true,
-		},
-		MaxRetries: 5,
-	})
+	osConfig := opensearch.Config{
+		Addresses: connection.endpoints(),
+	}
+	osConfig.Transport, err = connection.transport()
 	if err != nil {
-		log.Fatal.Println(err)
-		os.Exit(1)
+		return fmt.Errorf("invalid TLS configuration: %w", err)
+	}
+	switch connection.AuthMethod {
+	case AuthAwsSigV4:
+		osConfig.Signer, err = connection.AwsSigV4.newSigner()
+		if err != nil {
+			return fmt.Errorf("unable to create the AWS SigV4 signer: %w", err)
+		}
+	case AuthNone:
+	default:
+		osConfig.Username = username
+		osConfig.Password = password
 	}
 
-	res, err := osClient.Ping()
+	osClient, err = NewClient(osConfig)
 	if err != nil {
-		log.Fatal.Println(err)
-		os.Exit(1)
+		return err
 	}
 
-	if res.IsError() {
-		log.Fatal.Println("Unable to ping OpenSearch! Error: ", res.Status())
-		os.Exit(1)
+	ctx := context.Background()
+	pingResp, err := osapi.PingRequest{}.Do(ctx, osClient)
+	err = DecodeResponse(pingResp, err, nil)
+	if err != nil {
+		return fmt.Errorf("unable to ping OpenSearch: %w", err)
 	}
+	log.Info.Println("OpenSearch connection successful")
 
-	log.Info.Println("OpenSearch connection successful: ", res)
-
-	CheckIfIndexExists(context.Background())
+	return CheckIfIndexExists(ctx)
+}
 
+// Input:
+//
+//	client (*Client): Client the requests are performed with
+//
+// Description:
+//
+//	Replaces the client used across the package, like with a client of a cluster which was already set up
+//
+// Return:
+//
+//	(*Client): Returns the client which was replaced
+func SetClient(client *Client) *Client {
+	previous := osClient
+	osClient = client
+	return previous
 }
 
 // Input:
@@ -89,34 +117,29 @@ func InitializeOsClient(username string, password string) {
 //
 // Description:
 //
-//	The function checks if index exists, if it exists it does nothing and returns. If it does not exists, it creates the index and returns
+//	The function makes sure the indices used by the application exist with the current version of their mappings.
+//	The documents of the single monitor-stats index used by older versions are migrated to the dedicated indices.
 //
-// Output:
-func CheckIfIndexExists(ctx context.Context) {
-
-	var indexName = []string{IndexName}
-
-	//Create a index exists request to fetch if index is already present or not
-	exist, err := osapi.IndicesExistsRequest{
-		Index: indexName,
-	}.Do(ctx, osClient)
+// Return:
+//
+//	(error): Returns error if any
+func CheckIfIndexExists(ctx context.Context) error {
+	// The legacy template overlaps with the templates of the dedicated indices and has to go first
+	err := deleteLegacyTemplate(ctx)
 	if err != nil {
-		log.Panic.Println("Check index exists request error: ", err)
-		panic(err)
+		return fmt.Errorf("legacy index template delete request error: %w", err)
 	}
-	//If status code == 200 then index exists, print index exists, return
-	if exist.StatusCode == 200 {
-		return
+
+	for _, index := range managedIndices {
+		err = setupIndex(ctx, index)
+		if err != nil {
+			return fmt.Errorf("index setup error for %s: %w", index.name, err)
+		}
 	}
-	//If status code is not 200 then index does not exist, so crete a new Index via index create request API,
-	// pass mappings and index name.
-	indexCreateRequest, err := osapi.IndicesCreateRequest{
-		Index: IndexName,
-		Body:  bytes.NewReader(mappingsFile),
-	}.Do(ctx, osClient)
+
+	err = migrateLegacyIndex(ctx)
 	if err != nil {
-		log.Panic.Println("Index create request error: ", err)
-		panic(err)
+		return fmt.Errorf("legacy index migration error: %w", err)
 	}
-	log.Info.Println("Created!: ", indexCreateRequest)
+	return nil
 }
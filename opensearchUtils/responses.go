@@ -0,0 +1,391 @@
+package osutils
+
+import (
+	"context"
+	"errors"
+	"strconv"
+)
+
+// Custom node attribute (node.attr.node_group) holding the name of the node group a node was provisioned in.
+const NodeGroupAttribute string = "node_group"
+
+// This struct contains the fields read from the _cluster/stats response.
+type ClusterStatsResponse struct {
+	// ClusterName indicates the name of the cluster.
+	ClusterName string `json:"cluster_name"`
+	// ClusterUuid indicates the unique id of the cluster.
+	ClusterUuid string `json:"cluster_uuid"`
+	// Timestamp indicates the time in milliseconds at which the stats were collected.
+	Timestamp int64 `json:"timestamp"`
+	// Status indicates the health status of the cluster (green, yellow or red).
+	Status string `json:"status"`
+	// Indices indicates the index level stats of the cluster.
+	Indices ClusterIndicesStats `json:"indices"`
+	// Nodes indicates the node level stats of the cluster.
+	Nodes ClusterNodesStats `json:"nodes"`
+}
+
+// This struct contains the index level stats of the _cluster/stats response.
+type ClusterIndicesStats struct {
+	// Count indicates the number of indices.
+	Count int `json:"count"`
+	// Store indicates the size of all the shards of the indices.
+	Store struct {
+		SizeInBytes int64 `json:"size_in_bytes"`
+	} `json:"store"`
+	// Shards indicates the shard counts. It is empty when the cluster has no indices.
+	Shards struct {
+		Total     int `json:"total"`
+		Primaries int `json:"primaries"`
+	} `json:"shards"`
+}
+
+// This struct contains the node level stats of the _cluster/stats response.
+type ClusterNodesStats struct {
+	// Count indicates the number of nodes per role.
+	Count ClusterNodesCount `json:"count"`
+	// Jvm indicates the heap memory summed over all the nodes.
+	Jvm struct {
+		Mem struct {
+			HeapMaxInBytes int64 `json:"heap_max_in_bytes"`
+		} `json:"mem"`
+	} `json:"jvm"`
+	// Fs indicates the disk space of the data paths summed over all the nodes.
+	Fs struct {
+		TotalInBytes     int64 `json:"total_in_bytes"`
+		AvailableInBytes int64 `json:"available_in_bytes"`
+	} `json:"fs"`
+}
+
+// This struct contains the number of nodes per role. OpenSearch 2.x reports the master eligible nodes
+// as cluster_manager and keeps master for compatibility.
+type ClusterNodesCount struct {
+	Total          int `json:"total"`
+	Data           int `json:"data"`
+	Ingest         int `json:"ingest"`
+	Master         int `json:"master"`
+	ClusterManager int `json:"cluster_manager"`
+}
+
+// Input:
+//
+// Description:
+//
+//	Returns the number of master eligible nodes, reported as master or cluster_manager depending on the version
+//
+// Return:
+//
+//	(int): Returns the number of master eligible nodes
+func (c ClusterNodesCount) MasterEligible() int {
+	if c.ClusterManager > c.Master {
+		return c.ClusterManager
+	}
+	return c.Master
+}
+
+// This struct contains the fields read from the _cluster/health response.
+type ClusterHealthResponse struct {
+	ClusterName         string `json:"cluster_name"`
+	Status              string `json:"status"`
+	TimedOut            bool   `json:"timed_out"`
+	NumberOfNodes       int    `json:"number_of_nodes"`
+	NumberOfDataNodes   int    `json:"number_of_data_nodes"`
+	ActivePrimaryShards int    `json:"active_primary_shards"`
+	ActiveShards        int    `json:"active_shards"`
+	RelocatingShards    int    `json:"relocating_shards"`
+	InitializingShards  int    `json:"initializing_shards"`
+	UnassignedShards    int    `json:"unassigned_shards"`
+}
+
+// This struct contains the fields read from the _cluster/state response.
+type ClusterStateResponse struct {
+	ClusterName string `json:"cluster_name"`
+	ClusterUuid string `json:"cluster_uuid"`
+	// MasterNode indicates the id of the elected master node. It is empty while no master is elected.
+	MasterNode string `json:"master_node"`
+}
+
+// This struct contains the fields read from the _nodes/stats response.
+type NodesStatsResponse struct {
+	ClusterName string `json:"cluster_name"`
+	// Nodes indicates the stats of each node keyed by node id.
+	Nodes map[string]NodeStats `json:"nodes"`
+}
+
+// This struct contains the stats of a single node. The sections which were not requested in the
+// metrics of the _nodes/stats request are nil.
+type NodeStats struct {
+	Name             string            `json:"name"`
+	Host             string            `json:"host"`
+	Ip               string            `json:"ip"`
+	TransportAddress string            `json:"transport_address"`
+	Timestamp        int64             `json:"timestamp"`
+	Roles            []string          `json:"roles"`
+	Attributes       map[string]string `json:"attributes"`
+	Os               *NodeOsStats      `json:"os"`
+	Jvm              *NodeJvmStats     `json:"jvm"`
+	Fs               *NodeFsStats      `json:"fs"`
+	// IndexingPressure indicates the memory used by the indexing requests on the node.
+	IndexingPressure *NodeIndexingPressureStats `json:"indexing_pressure"`
+}
+
+// This struct contains the os section of the node stats.
+type NodeOsStats struct {
+	Cpu *struct {
+		Percent float32 `json:"percent"`
+	} `json:"cpu"`
+	Mem *struct {
+		TotalInBytes int64   `json:"total_in_bytes"`
+		UsedInBytes  int64   `json:"used_in_bytes"`
+		UsedPercent  float32 `json:"used_percent"`
+	} `json:"mem"`
+}
+
+// This struct contains the jvm section of the node stats.
+type NodeJvmStats struct {
+	Mem struct {
+		HeapUsedInBytes      int64   `json:"heap_used_in_bytes"`
+		HeapUsedPercent      float32 `json:"heap_used_percent"`
+		HeapCommittedInBytes int64   `json:"heap_committed_in_bytes"`
+		HeapMaxInBytes       int64   `json:"heap_max_in_bytes"`
+	} `json:"mem"`
+}
+
+// This struct contains the indexing_pressure section of the node stats. Indexing requests are rejected once
+// the memory used by the ongoing requests reaches the limit.
+type NodeIndexingPressureStats struct {
+	Memory struct {
+		Current struct {
+			AllInBytes int64 `json:"all_in_bytes"`
+		} `json:"current"`
+		LimitInBytes int64 `json:"limit_in_bytes"`
+	} `json:"memory"`
+}
+
+// This struct contains the fs section of the node stats.
+type NodeFsStats struct {
+	// Total indicates the sum over all the data paths.
+	Total NodeFsData `json:"total"`
+	// Data indicates the stats of each data path.
+	Data []NodeFsData `json:"data"`
+}
+
+// This struct contains the disk space of a data path.
+type NodeFsData struct {
+	Path             string `json:"path"`
+	Mount            string `json:"mount"`
+	TotalInBytes     int64  `json:"total_in_bytes"`
+	FreeInBytes      int64  `json:"free_in_bytes"`
+	AvailableInBytes int64  `json:"available_in_bytes"`
+}
+
+// This struct contains a row of the _cat/allocation response in json format. The cat apis return
+// the numbers as strings, and the shards of unassigned shards are reported under the node UNASSIGNED.
+type CatAllocationRecord struct {
+	Shards string `json:"shards"`
+	Node   string `json:"node"`
+	Ip     string `json:"ip"`
+}
+
+// Input:
+//
+//	role (string): The role to be checked
+//
+// Description:
+//
+//	Checks if the node has the role
+//
+// Return:
+//
+//	(bool): Returns true if the node has the role
+func (n NodeStats) HasRole(role string) bool {
+	for _, nodeRole := range n.Roles {
+		if nodeRole == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Input:
+//
+// Description:
+//
+//	Checks if the node is master eligible, reported as master or cluster_manager role depending on the version
+//
+// Return:
+//
+//	(bool): Returns true if the node is master eligible
+func (n NodeStats) IsMasterEligible() bool {
+	return n.HasRole("master") || n.HasRole("cluster_manager")
+}
+
+// Input:
+//
+// Description:
+//
+//	Returns the node group of the node from the node_group attribute. Nodes which were not provisioned in a
+//	node group have no such attribute.
+//
+// Return:
+//
+//	(string): Returns the node group name, empty if the attribute is not set
+func (n NodeStats) NodeGroup() string {
+	return n.Attributes[NodeGroupAttribute]
+}
+
+// Input:
+//
+// Description:
+//
+//	Calculates the disk utilization of the node. The first data path is used as the data is written there,
+//	falling back to the total over all the paths if the data paths are not reported.
+//
+// Return:
+//
+//	(float32, error): Returns the disk utilization in percent and error if the fs stats are missing
+func (n NodeStats) DiskUtil() (float32, error) {
+	if n.Fs == nil {
+		return 0, errors.New("fs stats not present in node stats response")
+	}
+	disk := n.Fs.Total
+	if len(n.Fs.Data) > 0 {
+		disk = n.Fs.Data[0]
+	}
+	if disk.TotalInBytes == 0 {
+		return 0, errors.New("total disk space not present in node stats response")
+	}
+	// disk utilization = ((total space - available space) / total space) *100
+	return float32(float64(disk.TotalInBytes-disk.AvailableInBytes) / float64(disk.TotalInBytes) * 100), nil
+}
+
+// Input:
+//
+// Description:
+//
+//	Calculates the indexing pressure of the node, the memory used by the ongoing indexing requests relative to
+//	the memory limit at which the requests are rejected.
+//
+// Return:
+//
+//	(float32, error): Returns the indexing pressure in percent and error if the indexing_pressure stats are missing
+func (n NodeStats) IndexingPressureUtil() (float32, error) {
+	if n.IndexingPressure == nil {
+		return 0, errors.New("indexing_pressure stats not present in node stats response")
+	}
+	memory := n.IndexingPressure.Memory
+	if memory.LimitInBytes == 0 {
+		return 0, errors.New("indexing pressure limit not present in node stats response")
+	}
+	return float32(float64(memory.Current.AllInBytes) / float64(memory.LimitInBytes) * 100), nil
+}
+
+// Input:
+//
+// Description:
+//
+//	Reads the number of shards of the row
+//
+// Return:
+//
+//	(int, error): Returns the number of shards and error if it is not a number
+func (c CatAllocationRecord) NumShards() (int, error) {
+	return strconv.Atoi(c.Shards)
+}
+
+// Input:
+//
+//	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//
+// Description:
+//
+//	Fetches and decodes the _cluster/stats response
+//
+// Return:
+//
+//	(ClusterStatsResponse, error): Returns the cluster stats and error if any
+func FetchClusterStats(ctx context.Context) (ClusterStatsResponse, error) {
+	var clusterStats ClusterStatsResponse
+	resp, err := GetClusterStats(ctx)
+	err = DecodeResponse(resp, err, &clusterStats)
+	return clusterStats, err
+}
+
+// Input:
+//
+//	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//	waitForShards (*bool): If true, waits until there are no initializing and relocating shards
+//
+// Description:
+//
+//	Fetches and decodes the _cluster/health response
+//
+// Return:
+//
+//	(ClusterHealthResponse, error): Returns the cluster health and error if any
+func FetchClusterHealth(ctx context.Context, waitForShards *bool) (ClusterHealthResponse, error) {
+	var clusterHealth ClusterHealthResponse
+	resp, err := GetClusterHealth(ctx, waitForShards)
+	err = DecodeResponse(resp, err, &clusterHealth)
+	return clusterHealth, err
+}
+
+// Input:
+//
+//	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//
+// Description:
+//
+//	Fetches and decodes the _cluster/state response
+//
+// Return:
+//
+//	(ClusterStateResponse, error): Returns the cluster state and error if any
+func FetchClusterState(ctx context.Context) (ClusterStateResponse, error) {
+	var clusterState ClusterStateResponse
+	resp, err := GetClusterState(ctx)
+	err = DecodeResponse(resp, err, &clusterState)
+	return clusterState, err
+}
+
+// Input:
+//
+//	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//	nodes ([]string): The list of nodes for which the stats needs to be fetched
+//	metrics ([]string): The list of metrics that needs to be fetched, all the metrics are fetched if empty
+//
+// Description:
+//
+//	Fetches and decodes the _nodes/stats response
+//
+// Return:
+//
+//	(NodesStatsResponse, error): Returns the node stats and error if any
+func FetchNodesStats(ctx context.Context, nodes []string, metrics []string) (NodesStatsResponse, error) {
+	var nodesStats NodesStatsResponse
+	resp, err := GetNodeStats(ctx, nodes, metrics)
+	err = DecodeResponse(resp, err, &nodesStats)
+	if err == nil && len(nodesStats.Nodes) == 0 {
+		err = errors.New("no nodes present in the node stats response")
+	}
+	return nodesStats, err
+}
+
+// Input:
+//
+//	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//	nodes ([]string): List of nodes for which the allocation needs to be fetched, all the nodes if empty
+//
+// Description:
+//
+//	Fetches and decodes the _cat/allocation response
+//
+// Return:
+//
+//	([]CatAllocationRecord, error): Returns a row per node and error if any
+func FetchCatAllocation(ctx context.Context, nodes []string) ([]CatAllocationRecord, error) {
+	var allocations []CatAllocationRecord
+	resp, err := CatAllocationJson(ctx, nodes)
+	err = DecodeResponse(resp, err, &allocations)
+	return allocations, err
+}
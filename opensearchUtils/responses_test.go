@@ -0,0 +1,165 @@
+package osutils
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Versions of the recorded responses in testdata
+var fixtureVersions = []string{"opensearch-1.3.6", "opensearch-2.4.0"}
+
+func decodeFixture(t *testing.T, version string, name string, v interface{}) {
+	content, err := os.ReadFile(filepath.Join("testdata", version, name))
+	assert.Nil(t, err)
+	assert.Nil(t, json.Unmarshal(content, v))
+}
+
+func TestDecodeClusterStats(t *testing.T) {
+	for _, version := range fixtureVersions {
+		var clusterStats ClusterStatsResponse
+		decodeFixture(t, version, "cluster_stats.json", &clusterStats)
+		assert.Equal(t, "os-cluster", clusterStats.ClusterName, version)
+		assert.NotEmpty(t, clusterStats.ClusterUuid, version)
+		assert.Equal(t, int64(1668500512345), clusterStats.Timestamp, version)
+		assert.Equal(t, 3, clusterStats.Nodes.Count.Data, version)
+		assert.Equal(t, 3, clusterStats.Nodes.Count.MasterEligible(), version)
+		assert.Equal(t, 12, clusterStats.Indices.Shards.Primaries, version)
+		assert.Equal(t, int64(98765432), clusterStats.Indices.Store.SizeInBytes, version)
+		assert.Equal(t, int64(3221225472), clusterStats.Nodes.Jvm.Mem.HeapMaxInBytes, version)
+		assert.Equal(t, int64(322122547200), clusterStats.Nodes.Fs.TotalInBytes, version)
+		assert.Equal(t, int64(209379655680), clusterStats.Nodes.Fs.AvailableInBytes, version)
+	}
+
+	var clusterStats ClusterStatsResponse
+	decodeFixture(t, "opensearch-1.3.6", "cluster_stats.json", &clusterStats)
+	assert.Equal(t, "green", clusterStats.Status)
+	assert.Equal(t, 24, clusterStats.Indices.Shards.Total)
+
+	decodeFixture(t, "opensearch-2.4.0", "cluster_stats.json", &clusterStats)
+	assert.Equal(t, "yellow", clusterStats.Status)
+	assert.Equal(t, 22, clusterStats.Indices.Shards.Total)
+	assert.Equal(t, 3, clusterStats.Nodes.Count.ClusterManager)
+
+	// A cluster without indices reports no shard counts
+	var emptyClusterStats ClusterStatsResponse
+	assert.Nil(t, json.Unmarshal([]byte(`{"indices": {"count": 0, "shards": {}}}`), &emptyClusterStats))
+	assert.Equal(t, 0, emptyClusterStats.Indices.Shards.Total)
+}
+
+func TestDecodeClusterHealth(t *testing.T) {
+	var clusterHealth ClusterHealthResponse
+	decodeFixture(t, "opensearch-1.3.6", "cluster_health.json", &clusterHealth)
+	assert.Equal(t, ClusterHealthResponse{
+		ClusterName:         "os-cluster",
+		Status:              "green",
+		NumberOfNodes:       3,
+		NumberOfDataNodes:   3,
+		ActivePrimaryShards: 12,
+		ActiveShards:        24,
+	}, clusterHealth)
+
+	decodeFixture(t, "opensearch-2.4.0", "cluster_health.json", &clusterHealth)
+	assert.Equal(t, ClusterHealthResponse{
+		ClusterName:         "os-cluster",
+		Status:              "yellow",
+		NumberOfNodes:       3,
+		NumberOfDataNodes:   3,
+		ActivePrimaryShards: 12,
+		ActiveShards:        22,
+		UnassignedShards:    2,
+	}, clusterHealth)
+}
+
+func TestDecodeClusterState(t *testing.T) {
+	var clusterState ClusterStateResponse
+	decodeFixture(t, "opensearch-1.3.6", "cluster_state.json", &clusterState)
+	assert.Equal(t, "fT3qXk7aRdWm1pNc0YzL4g", clusterState.MasterNode)
+	assert.Equal(t, "Qx3rZ8nOSXyW1v0GxqPk9A", clusterState.ClusterUuid)
+
+	decodeFixture(t, "opensearch-2.4.0", "cluster_state.json", &clusterState)
+	assert.Equal(t, "Lk2Zc9WqS5mO3eVb7Rt1xw", clusterState.MasterNode)
+	assert.Equal(t, "Z1m8cVqkTn2Jx4sLw0Ry7Q", clusterState.ClusterUuid)
+}
+
+func TestDecodeNodesStats(t *testing.T) {
+	for _, version := range fixtureVersions {
+		var nodesStats NodesStatsResponse
+		decodeFixture(t, version, "nodes_stats.json", &nodesStats)
+		assert.Len(t, nodesStats.Nodes, 3, version)
+
+		node := nodesStats.Nodes["fT3qXk7aRdWm1pNc0YzL4g"]
+		assert.Equal(t, "node-10-81-1-225", node.Name, version)
+		assert.Equal(t, "10.81.1.225", node.Host, version)
+		assert.Equal(t, int64(1668500512345), node.Timestamp, version)
+		assert.Equal(t, "true", node.Attributes["shard_indexing_pressure_enabled"], version)
+		assert.True(t, node.HasRole("data"), version)
+		assert.True(t, node.IsMasterEligible(), version)
+		assert.Equal(t, "", node.NodeGroup(), version)
+		assert.False(t, node.HasRole("ml"), version)
+		assert.Equal(t, float32(12), node.Os.Cpu.Percent, version)
+		assert.Equal(t, float32(64), node.Os.Mem.UsedPercent, version)
+		assert.Equal(t, float32(47), node.Jvm.Mem.HeapUsedPercent, version)
+		assert.Equal(t, int64(1073741824), node.Jvm.Mem.HeapCommittedInBytes, version)
+
+		diskUtil, err := node.DiskUtil()
+		assert.Nil(t, err, version)
+		assert.InDelta(t, 25, diskUtil, 0.01, version)
+
+		indexingPressure, err := node.IndexingPressureUtil()
+		assert.Nil(t, err, version)
+		assert.InDelta(t, 25, indexingPressure, 0.01, version)
+	}
+
+	var nodesStats NodesStatsResponse
+	decodeFixture(t, "opensearch-1.3.6", "nodes_stats.json", &nodesStats)
+	assert.True(t, nodesStats.Nodes["fT3qXk7aRdWm1pNc0YzL4g"].HasRole("master"))
+
+	decodeFixture(t, "opensearch-2.4.0", "nodes_stats.json", &nodesStats)
+	assert.True(t, nodesStats.Nodes["fT3qXk7aRdWm1pNc0YzL4g"].HasRole("cluster_manager"))
+	assert.Equal(t, "data-warm", nodesStats.Nodes["p9HwT4bYQ2eJ6sKd8Ux3vA"].NodeGroup())
+	assert.Equal(t, "warm", nodesStats.Nodes["p9HwT4bYQ2eJ6sKd8Ux3vA"].Attributes["temp"])
+}
+
+func TestNodeStatsMissingSections(t *testing.T) {
+	var node NodeStats
+	assert.Nil(t, json.Unmarshal([]byte(`{"name": "node-1", "roles": ["data"]}`), &node))
+	assert.Nil(t, node.Os)
+	assert.Nil(t, node.Jvm)
+	assert.False(t, node.IsMasterEligible())
+	_, err := node.DiskUtil()
+	assert.NotNil(t, err)
+	_, err = node.IndexingPressureUtil()
+	assert.NotNil(t, err)
+
+	// Falls back to the total when the data paths are not reported
+	assert.Nil(t, json.Unmarshal([]byte(`{"fs": {"total": {"total_in_bytes": 100, "available_in_bytes": 40}}}`), &node))
+	diskUtil, err := node.DiskUtil()
+	assert.Nil(t, err)
+	assert.InDelta(t, 60, diskUtil, 0.01)
+
+	var emptyFsNode NodeStats
+	assert.Nil(t, json.Unmarshal([]byte(`{"fs": {"total": {}, "data": []}}`), &emptyFsNode))
+	_, err = emptyFsNode.DiskUtil()
+	assert.NotNil(t, err)
+}
+
+func TestDecodeCatAllocation(t *testing.T) {
+	var allocations []CatAllocationRecord
+	decodeFixture(t, "opensearch-1.3.6", "cat_allocation.json", &allocations)
+	assert.Len(t, allocations, 3)
+	numShards, err := allocations[0].NumShards()
+	assert.Nil(t, err)
+	assert.Equal(t, 8, numShards)
+
+	decodeFixture(t, "opensearch-2.4.0", "cat_allocation.json", &allocations)
+	assert.Len(t, allocations, 4)
+	assert.Equal(t, "UNASSIGNED", allocations[3].Node)
+	assert.Equal(t, "", allocations[3].Ip)
+	numShards, err = allocations[3].NumShards()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, numShards)
+}
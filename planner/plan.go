@@ -0,0 +1,352 @@
+// This package builds the capacity plan of the cluster. It projects how long the cluster can grow at the
+// current rate before the disk or shards per GB limits of the scale up rules are hit, and how many nodes
+// keep the cluster below the limits over the planning horizon.
+package planner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+
+	"github.com/maplelabs/opensearch-scaling-manager/cluster"
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+	"github.com/maplelabs/opensearch-scaling-manager/logger"
+)
+
+// A global logger variable used across the package for logging.
+var log logger.LOG
+
+// Metrics which are projected by the plan, limited by the scale up rules on the same metric.
+var plannedMetrics = []string{"DiskUtil", "ShardsPerGB"}
+
+// Input:
+//
+// Description:
+//
+//	Initialize the planner module.
+//
+// Return:
+func init() {
+	log.Init("logger")
+	log.Info.Println("Planner module initialized")
+}
+
+// This struct contains the projection of a metric.
+type MetricPlan struct {
+	// Metric indicates the name of the metric.
+	Metric string `json:"metric"`
+	// Limit indicates the lowest limit of the scale up rules on the metric.
+	Limit float32 `json:"limit"`
+	// Current indicates the current value of the metric for the cluster.
+	Current float64 `json:"current"`
+	// GrowthPerDay indicates the growth of the daily average of the metric per day, nil without enough history.
+	GrowthPerDay *float64 `json:"growth_per_day"`
+	// DaysUntilLimit indicates the days until the limit is hit at the current growth, nil if it is not growing.
+	DaysUntilLimit *float64 `json:"days_until_limit"`
+	// RequiredDataNodes indicates the number of data nodes keeping the metric below the limit at the end of the horizon.
+	RequiredDataNodes int `json:"required_data_nodes"`
+}
+
+// This struct contains the capacity plan of the cluster.
+type Plan struct {
+	// NumNodes indicates the current number of nodes.
+	NumNodes int `json:"num_nodes"`
+	// NumDataNodes indicates the current number of data nodes.
+	NumDataNodes int `json:"num_data_nodes"`
+	// TotalShards indicates the current number of shards.
+	TotalShards int `json:"total_shards"`
+	// DiskUsedInBytes indicates the disk space currently used on the data paths.
+	DiskUsedInBytes int64 `json:"disk_used_in_bytes"`
+	// DiskTotalInBytes indicates the disk space of the data paths.
+	DiskTotalInBytes int64 `json:"disk_total_in_bytes"`
+	// LookbackDays indicates the number of days of stored metrics used to find the growth.
+	LookbackDays int `json:"lookback_days"`
+	// HorizonDays indicates the number of days for which the node count is planned.
+	HorizonDays int `json:"horizon_days"`
+	// Metrics indicates the projection of every metric limited by a scale up rule.
+	Metrics []MetricPlan `json:"metrics"`
+	// RecommendedNodes indicates the number of nodes recommended for the horizon.
+	RecommendedNodes int `json:"recommended_nodes"`
+	// Remarks indicates the caveats of the recommendation.
+	Remarks []string `json:"remarks,omitempty"`
+}
+
+// Input:
+//
+//	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//	configStruct (config.ConfigStruct): Config of the scaling manager
+//	lookbackDays (int): Number of days of stored metrics used to find the growth
+//	horizonDays (int): Number of days for which the node count is planned
+//
+// Description:
+//
+//	Reads the current shard count and disk usage of the cluster and the daily averages of the planned metrics
+//	from the stored node metrics, and builds the capacity plan with the limits of the scale up rules.
+//
+// Return:
+//
+//	(Plan, error): Returns the capacity plan and error if any
+func GetPlan(ctx context.Context, configStruct config.ConfigStruct, lookbackDays int, horizonDays int) (Plan, error) {
+	current, _, err := cluster.GetClusterCurrent(false)
+	if err != nil {
+		return Plan{}, err
+	}
+	limits := GetRuleLimits(configStruct.TaskDetails)
+	history := make(map[string][]cluster.DailyAvg)
+	for metric := range limits {
+		history[metric], err = cluster.GetDailyAvg(ctx, metric, lookbackDays, "")
+		if err != nil {
+			return Plan{}, err
+		}
+	}
+	plan := BuildPlan(current, history, limits, horizonDays, configStruct.ClusterDetails)
+	plan.LookbackDays = lookbackDays
+	return plan, nil
+}
+
+// Input:
+//
+//	tasks ([]config.Task): Tasks of the config
+//
+// Description:
+//
+//	Finds the lowest limit of the metric based scale up and scale vertical rules for every planned metric, as
+//	the lowest limit is the first one to trigger a scale up.
+//
+// Return:
+//
+//	(map[string]float32): Returns the limits keyed by metric, metrics without scale up rules are left out
+func GetRuleLimits(tasks []config.Task) map[string]float32 {
+	limits := make(map[string]float32)
+	for _, task := range tasks {
+		if task.Operator == "EVENT" {
+			continue
+		}
+		action, err := config.ParseTaskName(task.TaskName)
+		if err != nil || action.RuleOperation() != "scale_up" {
+			continue
+		}
+		for _, rule := range task.Rules {
+			if !isPlannedMetric(rule.Metric) {
+				continue
+			}
+			if limit, ok := limits[rule.Metric]; !ok || rule.Limit < limit {
+				limits[rule.Metric] = rule.Limit
+			}
+		}
+	}
+	return limits
+}
+
+// Input:
+//
+//	current (cluster.ClusterDynamic): Current statistics of the cluster
+//	history (map[string][]cluster.DailyAvg): Daily averages of the planned metrics keyed by metric
+//	limits (map[string]float32): Lowest scale up limits keyed by metric
+//	horizonDays (int): Number of days for which the node count is planned
+//	clusterCfg (config.ClusterDetails): Cluster Level config details
+//
+// Description:
+//
+//	Projects every metric with a limit at its growth over the history. The current values are spread over more
+//	or fewer data nodes linearly, so the data nodes required for a metric are the current data nodes scaled by
+//	the ratio of the projected value at the end of the horizon to the limit. The recommended node count adds
+//	the most data nodes required by a metric to the nodes which are not data nodes, within the node limits.
+//
+// Return:
+//
+//	(Plan): Returns the capacity plan
+func BuildPlan(current cluster.ClusterDynamic, history map[string][]cluster.DailyAvg, limits map[string]float32, horizonDays int, clusterCfg config.ClusterDetails) Plan {
+	plan := Plan{
+		NumNodes:         current.NumNodes,
+		NumDataNodes:     current.NumActiveDataNodes,
+		TotalShards:      current.TotalShards,
+		DiskUsedInBytes:  current.DiskTotalInBytes - current.DiskAvailableInBytes,
+		DiskTotalInBytes: current.DiskTotalInBytes,
+		HorizonDays:      horizonDays,
+	}
+	requiredDataNodes := 0
+	for _, metric := range plannedMetrics {
+		limit, ok := limits[metric]
+		if !ok {
+			plan.Remarks = append(plan.Remarks, "No scale up rule on "+metric+", it is not planned")
+			continue
+		}
+		value, ok := currentValue(metric, current)
+		if !ok {
+			plan.Remarks = append(plan.Remarks, "Current "+metric+" is not reported by the cluster, it is not planned")
+			continue
+		}
+		metricPlan := MetricPlan{Metric: metric, Limit: limit, Current: value}
+		projected := value
+		if value >= float64(limit) {
+			days := 0.0
+			metricPlan.DaysUntilLimit = &days
+		}
+		if growth, ok := growthPerDay(history[metric]); ok {
+			metricPlan.GrowthPerDay = &growth
+			if value < float64(limit) && growth > 0 {
+				days := (float64(limit) - value) / growth
+				metricPlan.DaysUntilLimit = &days
+			}
+			projected += math.Max(growth, 0) * float64(horizonDays)
+		} else {
+			plan.Remarks = append(plan.Remarks, "Not enough stored metrics to find the growth of "+metric+", it is planned at the current value")
+		}
+		metricPlan.RequiredDataNodes = int(math.Max(1, math.Ceil(float64(current.NumActiveDataNodes)*projected/float64(limit))))
+		if metricPlan.RequiredDataNodes > requiredDataNodes {
+			requiredDataNodes = metricPlan.RequiredDataNodes
+		}
+		plan.Metrics = append(plan.Metrics, metricPlan)
+	}
+	// The data nodes are kept as they are when no metric is planned
+	if len(plan.Metrics) == 0 {
+		requiredDataNodes = current.NumActiveDataNodes
+	}
+
+	plan.RecommendedNodes = current.NumNodes - current.NumActiveDataNodes + requiredDataNodes
+	if plan.RecommendedNodes > clusterCfg.MaxNodesAllowed {
+		plan.Remarks = append(plan.Remarks, fmt.Sprintf("%d nodes are required, above max_nodes_allowed", plan.RecommendedNodes))
+		plan.RecommendedNodes = clusterCfg.MaxNodesAllowed
+	}
+	if plan.RecommendedNodes < clusterCfg.MinNodesAllowed {
+		plan.RecommendedNodes = clusterCfg.MinNodesAllowed
+	}
+	return plan
+}
+
+// Input:
+//
+//	metric (string): Name of the planned metric
+//	current (cluster.ClusterDynamic): Current statistics of the cluster
+//
+// Description:
+//
+//	Calculates the current value of the metric for the cluster, in the same unit as the node metrics.
+//
+// Return:
+//
+//	(float64, bool): Returns the value and false if the cluster statistics needed are not reported
+func currentValue(metric string, current cluster.ClusterDynamic) (float64, bool) {
+	switch metric {
+	case "DiskUtil":
+		if current.DiskTotalInBytes == 0 {
+			return 0, false
+		}
+		return float64(current.DiskTotalInBytes-current.DiskAvailableInBytes) / float64(current.DiskTotalInBytes) * 100, true
+	case "ShardsPerGB":
+		if current.HeapMaxInBytes == 0 {
+			return 0, false
+		}
+		return float64(current.TotalShards) / (float64(current.HeapMaxInBytes) / (1 << 30)), true
+	}
+	return 0, false
+}
+
+// Input:
+//
+//	dailyAvgs ([]cluster.DailyAvg): Daily averages of a metric
+//
+// Description:
+//
+//	Fits a line through the daily averages with least squares, the slope is the growth per day.
+//
+// Return:
+//
+//	(float64, bool): Returns the growth per day and false if there are less than two days of metrics
+func growthPerDay(dailyAvgs []cluster.DailyAvg) (float64, bool) {
+	if len(dailyAvgs) < 2 {
+		return 0, false
+	}
+	const dayInMillis = 24 * 60 * 60 * 1000
+	var sumX, sumY, sumXY, sumXX float64
+	for _, dailyAvg := range dailyAvgs {
+		x := float64(dailyAvg.Timestamp-dailyAvgs[0].Timestamp) / dayInMillis
+		y := float64(dailyAvg.Avg)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	n := float64(len(dailyAvgs))
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0, false
+	}
+	return (n*sumXY - sumX*sumY) / denominator, true
+}
+
+// Input:
+//
+//	metric (string): Name of the metric
+//
+// Description:
+//
+//	Checks if the metric is projected by the plan
+//
+// Return:
+//
+//	(bool): Returns true if the metric is planned
+func isPlannedMetric(metric string) bool {
+	for _, plannedMetric := range plannedMetrics {
+		if metric == plannedMetric {
+			return true
+		}
+	}
+	return false
+}
+
+// Input:
+//
+//	w (io.Writer): Writer to which the table is written
+//
+// Caller:
+//
+//	Object of Plan
+//
+// Description:
+//
+//	Writes the capacity plan as a table
+//
+// Return:
+//
+//	(error): Returns error if any
+func (p Plan) WriteTable(w io.Writer) error {
+	fmt.Fprintf(w, "Nodes: %d (%d data)  Shards: %d  Disk: %.1f GB used of %.1f GB\n\n", p.NumNodes, p.NumDataNodes,
+		p.TotalShards, float64(p.DiskUsedInBytes)/(1<<30), float64(p.DiskTotalInBytes)/(1<<30))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "METRIC\tLIMIT\tCURRENT\tGROWTH/DAY\tDAYS UNTIL LIMIT\tDATA NODES IN %d DAYS\n", p.HorizonDays)
+	for _, metric := range p.Metrics {
+		fmt.Fprintf(tw, "%s\t%.2f\t%.2f\t%s\t%s\t%d\n", metric.Metric, metric.Limit, metric.Current,
+			formatOptional(metric.GrowthPerDay, "%.2f"), formatOptional(metric.DaysUntilLimit, "%.1f"), metric.RequiredDataNodes)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "\nRecommended nodes for the next %d days: %d\n", p.HorizonDays, p.RecommendedNodes)
+	for _, remark := range p.Remarks {
+		fmt.Fprintln(w, "  - "+remark)
+	}
+	return nil
+}
+
+// Input:
+//
+//	value (*float64): Value to be formatted, nil if unknown
+//	format (string): Format of the value
+//
+// Description:
+//
+//	Formats an optional value of the table, unknown values are shown as -
+//
+// Return:
+//
+//	(string): Returns the formatted value
+func formatOptional(value *float64, format string) string {
+	if value == nil {
+		return "-"
+	}
+	return fmt.Sprintf(format, *value)
+}
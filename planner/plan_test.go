@@ -0,0 +1,119 @@
+package planner
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/maplelabs/opensearch-scaling-manager/cluster"
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+	"github.com/stretchr/testify/assert"
+)
+
+const dayInMillis = 24 * 60 * 60 * 1000
+
+func TestGetRuleLimits(t *testing.T) {
+	tasks := []config.Task{
+		{TaskName: "scale_up_by_1", Operator: "OR", Rules: []config.Rule{
+			{Metric: "CpuUtil", Limit: 80},
+			{Metric: "DiskUtil", Limit: 75},
+			{Metric: "ShardsPerGB", Limit: 25},
+		}},
+		{TaskName: "scale_up_by_2", Operator: "AND", Rules: []config.Rule{{Metric: "DiskUtil", Limit: 70}}},
+		{TaskName: "scale_down_by_1", Operator: "AND", Rules: []config.Rule{{Metric: "DiskUtil", Limit: 50}}},
+		{TaskName: "scale_up_by_1", Operator: "EVENT", Rules: []config.Rule{{SchedulingTime: "0 0 * * 1"}}},
+		// The rules of a vertical scale fire above their limits like the scale up rules
+		{TaskName: "scale_vertical_to_3", Operator: "OR", Rules: []config.Rule{{Metric: "ShardsPerGB", Limit: 20}}},
+	}
+	assert.Equal(t, map[string]float32{"DiskUtil": 70, "ShardsPerGB": 20}, GetRuleLimits(tasks))
+	assert.Empty(t, GetRuleLimits(nil))
+}
+
+func TestGrowthPerDay(t *testing.T) {
+	growth, ok := growthPerDay([]cluster.DailyAvg{
+		{Timestamp: 0, Avg: 40},
+		{Timestamp: dayInMillis, Avg: 42},
+		{Timestamp: 3 * dayInMillis, Avg: 46},
+	})
+	assert.True(t, ok)
+	assert.InDelta(t, 2, growth, 0.0001)
+
+	_, ok = growthPerDay([]cluster.DailyAvg{{Timestamp: 0, Avg: 40}})
+	assert.False(t, ok)
+}
+
+func TestBuildPlan(t *testing.T) {
+	current := cluster.ClusterDynamic{
+		NumNodes:             5,
+		NumActiveDataNodes:   3,
+		TotalShards:          60,
+		DiskTotalInBytes:     300 << 30,
+		DiskAvailableInBytes: 150 << 30,
+		HeapMaxInBytes:       6 << 30,
+	}
+	history := map[string][]cluster.DailyAvg{
+		"DiskUtil":    {{Timestamp: 0, Avg: 46}, {Timestamp: dayInMillis, Avg: 48}, {Timestamp: 2 * dayInMillis, Avg: 50}},
+		"ShardsPerGB": {{Timestamp: 0, Avg: 10}, {Timestamp: dayInMillis, Avg: 10}},
+	}
+	limits := map[string]float32{"DiskUtil": 75, "ShardsPerGB": 25}
+	clusterCfg := config.ClusterDetails{ClusterStatic: cluster.ClusterStatic{MaxNodesAllowed: 10, MinNodesAllowed: 3}}
+
+	plan := BuildPlan(current, history, limits, 30, clusterCfg)
+	assert.Equal(t, int64(150<<30), plan.DiskUsedInBytes)
+	assert.Len(t, plan.Metrics, 2)
+	// Disk is at 50% growing 2% a day, it reaches 110% in 30 days on 3 data nodes
+	disk := plan.Metrics[0]
+	assert.Equal(t, "DiskUtil", disk.Metric)
+	assert.InDelta(t, 50, disk.Current, 0.0001)
+	assert.InDelta(t, 2, *disk.GrowthPerDay, 0.0001)
+	assert.InDelta(t, 12.5, *disk.DaysUntilLimit, 0.0001)
+	assert.Equal(t, 5, disk.RequiredDataNodes)
+	// Shards per GB is not growing, the limit is never hit
+	shards := plan.Metrics[1]
+	assert.InDelta(t, 10, shards.Current, 0.0001)
+	assert.Nil(t, shards.DaysUntilLimit)
+	assert.Equal(t, 2, shards.RequiredDataNodes)
+	assert.Equal(t, 7, plan.RecommendedNodes)
+	assert.Empty(t, plan.Remarks)
+
+	// The recommendation is capped by max_nodes_allowed
+	clusterCfg.MaxNodesAllowed = 6
+	plan = BuildPlan(current, history, limits, 30, clusterCfg)
+	assert.Equal(t, 6, plan.RecommendedNodes)
+	assert.Len(t, plan.Remarks, 1)
+
+	// Without history the metrics are planned at the current value, without rules they are not planned
+	plan = BuildPlan(current, nil, map[string]float32{"DiskUtil": 40}, 30, clusterCfg)
+	assert.Len(t, plan.Metrics, 1)
+	assert.Nil(t, plan.Metrics[0].GrowthPerDay)
+	assert.Equal(t, 0.0, *plan.Metrics[0].DaysUntilLimit)
+	assert.Equal(t, 4, plan.Metrics[0].RequiredDataNodes)
+	assert.Equal(t, 6, plan.RecommendedNodes)
+	assert.Len(t, plan.Remarks, 2)
+}
+
+func TestWriteTable(t *testing.T) {
+	growth, days := 2.0, 12.5
+	plan := Plan{
+		NumNodes:         5,
+		NumDataNodes:     3,
+		TotalShards:      60,
+		DiskUsedInBytes:  150 << 30,
+		DiskTotalInBytes: 300 << 30,
+		HorizonDays:      30,
+		Metrics: []MetricPlan{
+			{Metric: "DiskUtil", Limit: 75, Current: 50, GrowthPerDay: &growth, DaysUntilLimit: &days, RequiredDataNodes: 5},
+			{Metric: "ShardsPerGB", Limit: 25, Current: 10, RequiredDataNodes: 2},
+		},
+		RecommendedNodes: 7,
+	}
+	var buf bytes.Buffer
+	assert.Nil(t, plan.WriteTable(&buf))
+	assert.Equal(t, `Nodes: 5 (3 data)  Shards: 60  Disk: 150.0 GB used of 300.0 GB
+
+METRIC       LIMIT  CURRENT  GROWTH/DAY  DAYS UNTIL LIMIT  DATA NODES IN 30 DAYS
+DiskUtil     75.00  50.00    2.00        12.5              5
+ShardsPerGB  25.00  10.00    -           -                 2
+
+Recommended nodes for the next 30 days: 7
+`, buf.String())
+}
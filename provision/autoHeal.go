@@ -0,0 +1,579 @@
+package provision
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+	"github.com/maplelabs/opensearch-scaling-manager/crypto"
+	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
+	utils "github.com/maplelabs/opensearch-scaling-manager/utilities"
+)
+
+// Rule triggered recorded for the replacements of the unhealthy nodes in the state and the ProvisionStats documents
+const autoHealRule = "auto_heal"
+
+// Reasons recorded as the rules responsible for the replacement of a node
+const (
+	nodeLeftCluster = "node_left_cluster"
+	metricsMissing  = "metrics_missing"
+)
+
+// Time range of the node metrics searched for the nodes which reported earlier
+const reportedNodesLookback = 24 * time.Hour
+
+// This struct contains the latest metrics a node reported
+type reportedNode struct {
+	NodeName  string
+	HostIp    string
+	NodeGroup string
+	// LastReported indicates the time in epoch milliseconds of the latest metrics of the node.
+	LastReported int64
+}
+
+// This struct contains a node which is replaced by the auto heal
+type unhealthyNode struct {
+	reportedNode
+	// Reason indicates if the node left the cluster or stopped reporting metrics.
+	Reason string
+}
+
+// This struct contains the part of the search response of getReportedNodesQuery which is used
+type reportedNodesResponse struct {
+	Aggregations struct {
+		Nodes struct {
+			Buckets []struct {
+				Latest struct {
+					Hits struct {
+						Hits []struct {
+							Source struct {
+								NodeName  string `json:"NodeName"`
+								HostIp    string `json:"HostIp"`
+								NodeGroup string `json:"NodeGroup"`
+								Timestamp int64  `json:"Timestamp"`
+							} `json:"_source"`
+						} `json:"hits"`
+					} `json:"hits"`
+				} `json:"latest"`
+			} `json:"buckets"`
+		} `json:"nodes"`
+	} `json:"aggregations"`
+}
+
+// Input:
+//
+// Description:
+//
+//	Generates the query to fetch the latest node document of every host which reported within the lookback.
+//	The nodes are identified by their ip, as a node replacing another one gets a new instance.
+//
+// Return:
+//
+//	(string): Returns the query string that can be given as an OS query api parameter.
+func getReportedNodesQuery() string {
+	return `{
+          "size": 0,
+          "query": {
+            "bool": {
+              "filter": {
+                "range": {
+                  "Timestamp": {
+                    "gte": "now-` + strconv.Itoa(int(reportedNodesLookback.Minutes())) + `m"
+                  }
+                }
+              },
+              "must": [
+                {
+                  "term": {
+                    "StatTag": "NodeStatistics"
+                  }
+                }
+              ]
+            }
+          },
+          "aggs": {
+            "nodes": {
+              "terms": {
+                "field": "HostIp",
+                "size": 1000
+              },
+              "aggs": {
+                "latest": {
+                  "top_hits": {
+                    "size": 1,
+                    "sort": {
+                      "Timestamp": "desc"
+                    },
+                    "_source": ["NodeName", "HostIp", "NodeGroup", "Timestamp"]
+                  }
+                }
+              }
+            }
+          }
+        }`
+}
+
+// Input:
+//
+// Description:
+//
+//	Generates the query counting the nodes replaced by the auto heal within the last 24 hours
+//
+// Return:
+//
+//	(string): Returns the query string that can be given as an OS query api parameter.
+func getReplacementsQuery() string {
+	return `{
+          "size": 0,
+          "track_total_hits": true,
+          "query": {
+            "bool": {
+              "filter": [
+                {
+                  "term": {
+                    "RuleTriggered": "` + autoHealRule + `"
+                  }
+                },
+                {
+                  "range": {
+                    "Timestamp": {
+                      "gte": "now-24h"
+                    }
+                  }
+                }
+              ]
+            }
+          }
+        }`
+}
+
+// Input:
+//
+//	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//
+// Description:
+//
+//	Fetches the latest metrics of the nodes which reported within the lookback
+//
+// Return:
+//
+//	(map[string]reportedNode, error): Returns the reported nodes by ip and error if any
+func fetchReportedNodes(ctx context.Context) (map[string]reportedNode, error) {
+	var response reportedNodesResponse
+	resp, err := osutils.SearchQuery(ctx, osutils.NodeStatsIndex, []byte(getReportedNodesQuery()))
+	err = osutils.DecodeResponse(resp, err, &response)
+	if err != nil {
+		return nil, err
+	}
+	reportedNodes := make(map[string]reportedNode)
+	for _, bucket := range response.Aggregations.Nodes.Buckets {
+		if len(bucket.Latest.Hits.Hits) == 0 {
+			continue
+		}
+		source := bucket.Latest.Hits.Hits[0].Source
+		reportedNodes[source.HostIp] = reportedNode{NodeName: source.NodeName, HostIp: source.HostIp, NodeGroup: source.NodeGroup, LastReported: source.Timestamp}
+	}
+	return reportedNodes, nil
+}
+
+// Input:
+//
+//	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//
+// Description:
+//
+//	Counts the ProvisionStats documents of the auto heal within the last 24 hours, failed replacements included
+//
+// Return:
+//
+//	(int, error): Returns the number of replacements and error if any
+func countReplacements(ctx context.Context) (int, error) {
+	var response struct {
+		Hits struct {
+			Total struct {
+				Value int `json:"value"`
+			} `json:"total"`
+		} `json:"hits"`
+	}
+	resp, err := osutils.SearchQuery(ctx, osutils.ProvisionStatsIndex, []byte(getReplacementsQuery()))
+	err = osutils.DecodeResponse(resp, err, &response)
+	if err != nil {
+		return 0, err
+	}
+	return response.Hits.Total.Value, nil
+}
+
+// Input:
+//
+//	reportedNodes (map[string]reportedNode): Latest metrics of the nodes by ip
+//	nodes (map[string]osutils.NodeStats): Nodes of the cluster keyed by node id
+//	masterNodeId (string): Node id of the elected master
+//	autoHeal (config.AutoHeal): Detection windows of the auto heal
+//	now (time.Time): Time the metrics are compared to
+//
+// Description:
+//
+//	Selects the nodes which reported metrics earlier and either left the cluster for longer than the node
+//	missing window, or are still in the cluster but did not report metrics for longer than the metrics missing
+//	window. The elected master is never selected. The nodes of the cluster are not selected for missing metrics
+//	if less than half of them reported recently, as the metrics collection itself is failing then.
+//
+// Return:
+//
+//	([]unhealthyNode): Returns the unhealthy nodes, the one which reported last the earliest first
+func selectUnhealthyNodes(reportedNodes map[string]reportedNode, nodes map[string]osutils.NodeStats, masterNodeId string, autoHeal config.AutoHeal, now time.Time) []unhealthyNode {
+	nodeIds := make(map[string]string)
+	freshNodes := 0
+	for nodeId, node := range nodes {
+		nodeIds[node.Host] = nodeId
+		if reported, ok := reportedNodes[node.Host]; ok && now.Sub(time.UnixMilli(reported.LastReported)) <= autoHeal.MetricsMissingWindow() {
+			freshNodes++
+		}
+	}
+	metricsReliable := freshNodes*2 >= len(nodes)
+
+	var unhealthyNodes []unhealthyNode
+	for ip, reported := range reportedNodes {
+		missingFor := now.Sub(time.UnixMilli(reported.LastReported))
+		nodeId, inCluster := nodeIds[ip]
+		if !inCluster && missingFor > autoHeal.NodeMissingWindow() {
+			unhealthyNodes = append(unhealthyNodes, unhealthyNode{reportedNode: reported, Reason: nodeLeftCluster})
+		} else if inCluster && nodeId != masterNodeId && metricsReliable && missingFor > autoHeal.MetricsMissingWindow() {
+			unhealthyNodes = append(unhealthyNodes, unhealthyNode{reportedNode: reported, Reason: metricsMissing})
+		}
+	}
+	sort.Slice(unhealthyNodes, func(i, j int) bool {
+		return unhealthyNodes[i].LastReported < unhealthyNodes[j].LastReported
+	})
+	return unhealthyNodes
+}
+
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Cluster Level config details
+//	usrCfg (config.UserConfig): User defined config for application behavior
+//	t (*time.Time): Time used by the simulator
+//
+// Description:
+//
+//	AutoHeal detects the nodes which left the cluster or stopped reporting metrics to the monitor-stats indices
+//	and replaces the one which reported last the earliest. Only the nodes whose instance is still running are
+//	replaced, so that the nodes removed by a scale down are not, and only if the scaling manager can terminate
+//	their instance, so that no instance is left running once its node was replaced. No node is replaced once
+//	the replacements within the last 24 hours reached max_replacements_per_day.
+//
+// Return:
+//
+//	(bool): Returns true if a node was replaced, whether the replacement succeeded or not
+func AutoHeal(clusterCfg config.ClusterDetails, usrCfg config.UserConfig, t *time.Time) bool {
+	if !clusterCfg.AutoHeal.Enabled || usrCfg.MonitorWithSimulator {
+		return false
+	}
+	if !provisionLock.TryLock() {
+		log.Debug.Println("Provision is in progress, skipping the auto heal")
+		return false
+	}
+	defer provisionLock.Unlock()
+	ctx := context.Background()
+	reportedNodes, err := fetchReportedNodes(ctx)
+	if err != nil {
+		log.Error.Println("Unable to fetch the nodes which reported metrics, skipping the auto heal: ", err)
+		return false
+	}
+	nodes, err := utils.GetNodes()
+	if err != nil {
+		log.Error.Println("Unable to fetch the nodes of the cluster, skipping the auto heal: ", err)
+		return false
+	}
+	masterNodeId, err := utils.GetMasterNodeId(ctx)
+	if err != nil {
+		log.Error.Println("Unable to fetch the master node, skipping the auto heal: ", err)
+		return false
+	}
+	unhealthyNodes := selectUnhealthyNodes(reportedNodes, nodes, masterNodeId, clusterCfg.AutoHeal, time.Now())
+	if len(unhealthyNodes) == 0 {
+		return false
+	}
+
+	if !usrCfg.MonitorWithLogs {
+		cloudCredentials := clusterCfg.CloudCredentials
+		crypto.GetDecryptedCloudCreds(&cloudCredentials)
+		var privateIps []string
+		for _, node := range unhealthyNodes {
+			privateIps = append(privateIps, node.HostIp)
+		}
+		instanceIds, err := RunningInstanceIds(privateIps, cloudCredentials)
+		if err != nil {
+			log.Error.Println("Unable to fetch the instances of the unhealthy nodes, skipping the auto heal: ", err)
+			return false
+		}
+		managedIds, err := managedNodeInstanceIds(privateIps, cloudCredentials)
+		if err != nil {
+			log.Error.Println("Unable to fetch the instances of the unhealthy nodes, skipping the auto heal: ", err)
+			return false
+		}
+		var runningNodes []unhealthyNode
+		for _, node := range unhealthyNodes {
+			if _, ok := instanceIds[node.HostIp]; !ok {
+				continue
+			}
+			if _, ok := managedIds[node.HostIp]; !ok {
+				log.Warn.Println(fmt.Sprintf("Node %s (%s) is unhealthy: %s, but its instance was not launched by the scaling manager, it is not replaced", node.NodeName, node.HostIp, node.Reason))
+				continue
+			}
+			runningNodes = append(runningNodes, node)
+		}
+		unhealthyNodes = runningNodes
+		if len(unhealthyNodes) == 0 {
+			return false
+		}
+	}
+	for _, node := range unhealthyNodes {
+		log.Warn.Println(fmt.Sprintf("Node %s (%s) is unhealthy: %s, last metrics at %s", node.NodeName, node.HostIp, node.Reason, time.UnixMilli(node.LastReported).Format(time.RFC3339)))
+	}
+
+	replacements, err := countReplacements(ctx)
+	if err != nil {
+		log.Error.Println("Unable to count the nodes replaced today, skipping the auto heal: ", err)
+		return false
+	}
+	if replacements >= clusterCfg.AutoHeal.ReplacementsPerDay() {
+		log.Warn.Println(fmt.Sprintf("%d nodes were already replaced within the last 24 hours, the unhealthy nodes are not replaced", replacements))
+		return false
+	}
+	node := unhealthyNodes[0]
+	if _, err := clusterCfg.GetNodeGroup(node.NodeGroup); err != nil {
+		log.Error.Println("Unable to replace the node ", node.NodeName, ": ", err)
+		return false
+	}
+	triggerReplacement(clusterCfg, usrCfg, t, autoHealRule, node)
+	return true
+}
+
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Cluster Level config details
+//	usrCfg (config.UserConfig): User defined config for application behavior
+//	t (*time.Time): Time used by the simulator
+//	rule (string): Rule triggered recorded for the replacement, auto_heal or spot_interruption
+//	node (unhealthyNode): Node to be replaced
+//
+// Description:
+//
+//	Sets the state of the replacement of the node and replaces it.
+//
+// Return:
+func triggerReplacement(clusterCfg config.ClusterDetails, usrCfg config.UserConfig, t *time.Time, rule string, node unhealthyNode) {
+	err := state.GetCurrentState()
+	if err != nil {
+		log.Error.Println("Unable to read the provisioning state, skipping the provision: ", err)
+		return
+	}
+	state.PreviousState = state.CurrentState
+	state.CurrentState = "provisioning_autoheal"
+	state.NumNodes = 1
+	state.RemainingNodes = 1
+	state.RuleTriggered = rule
+	state.RulesResponsible = node.Reason
+	state.NodeGroup = node.NodeGroup
+	state.NodeName = node.NodeName
+	state.NodeIp = node.HostIp
+	err = state.UpdateState()
+	if err != nil {
+		log.Error.Println("Unable to update the provisioning state, skipping the provision: ", err)
+		return
+	}
+	isReplaced, err := ReplaceNode(clusterCfg, usrCfg, t)
+	completeProvision("autoheal", isReplaced, err)
+}
+
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Cluster Level config details
+//	usrCfg (config.UserConfig): User defined config for application behavior
+//	t (*time.Time): Time used by the simulator
+//
+// Description:
+//
+//	ReplaceNode replaces the unhealthy or interrupted node of the state through the scale out steps. A new node is
+//	spinned from the launch template of the node group and configured, and once it joined the cluster the node is
+//	drained if it is still in the cluster. Its instance is then terminated if it is still running, and the cluster
+//	has to become green. The allocation and voting exclusions of an interrupted node are cleared once it is gone.
+//	An unhealthy node whose running instance the scaling manager can't terminate is not replaced, while the
+//	running instance of an interrupted node which was not launched by the scaling manager is left to EC2.
+//
+// Return:
+//
+//	(bool, error): Returns true if the node was replaced and error if any
+func ReplaceNode(clusterCfg config.ClusterDetails, usrCfg config.UserConfig, t *time.Time) (bool, error) {
+	if err := state.GetCurrentState(); err != nil {
+		return false, &interruptedError{err}
+	}
+	crypto.GetDecryptedCloudCreds(&clusterCfg.CloudCredentials)
+	crypto.GetDecryptedOsCreds(&clusterCfg.OsCredentials)
+	nodeGroup, err := clusterCfg.GetNodeGroup(state.NodeGroup)
+	if err != nil {
+		return false, err
+	}
+	monitorWithLogs := usrCfg.MonitorWithLogs
+	pollingInterval := time.Duration(usrCfg.RecommendationPollingInterval) * time.Second
+	for {
+		switch state.CurrentState {
+		case "provisioning_autoheal":
+			log.Info.Println(fmt.Sprintf("Replacing the node %s (%s): %s", state.NodeName, state.NodeIp, state.RulesResponsible))
+			if !monitorWithLogs && state.RuleTriggered == autoHealRule {
+				isRunning, isManaged, err := nodeInstance(state.NodeIp, clusterCfg.CloudCredentials)
+				if err != nil {
+					return false, err
+				}
+				if isRunning && !isManaged {
+					return false, errors.New("the instance of the node " + state.NodeName + " was not launched by the scaling manager and can't be terminated, the node is not replaced")
+				}
+			}
+			state.ProvisionStartTime = time.Now().UnixMilli()
+			if err := setProvisionState("start_autoheal_process"); err != nil {
+				return false, err
+			}
+		// Spin the node replacing the unhealthy node
+		case "start_autoheal_process":
+			if monitorWithLogs {
+				log.Info.Println("Spin a new vm")
+				time.Sleep(pollingInterval)
+			} else {
+				state.NewNodeIp, state.InstanceId, err = spinNewNode(clusterCfg, nodeGroup, nodeGroup.LaunchTemplateVersion, state.NodeIp)
+				if err != nil {
+					return false, err
+				}
+				log.Info.Println("Spinned a new node: ", state.NewNodeIp)
+			}
+			if err := setProvisionState("autoheal_triggered_spin_vm"); err != nil {
+				return false, err
+			}
+		// Configure opensearch on the new node
+		case "autoheal_triggered_spin_vm":
+			if monitorWithLogs {
+				log.Info.Println("Configure opensearch on the new node")
+				time.Sleep(pollingInterval)
+			} else {
+				err := setUpNewNode(clusterCfg, nodeGroup, state.NewNodeIp, state.InstanceId)
+				if err != nil {
+					return false, err
+				}
+			}
+			if err := setProvisionState("autoheal_new_node_configured"); err != nil {
+				return false, err
+			}
+		// Wait for the new node to join the cluster
+		case "autoheal_new_node_configured":
+			if !monitorWithLogs {
+				err := waitForNewNodeToJoin(clusterCfg, state.NewNodeIp)
+				if err != nil {
+					return false, err
+				}
+				startScalingManagerOnNewNode(clusterCfg, nodeGroup, state.NewNodeIp)
+			}
+			if err := setProvisionState("autoheal_new_node_joined"); err != nil {
+				return false, err
+			}
+		// Move the shards of the unhealthy node to the other nodes if it is still in the cluster
+		case "autoheal_new_node_joined":
+			if monitorWithLogs {
+				log.Info.Println("Drain the node through ansible")
+				time.Sleep(pollingInterval)
+			} else {
+				nodes, err := utils.GetNodes()
+				if err != nil {
+					return false, &interruptedError{err}
+				}
+				inCluster := false
+				for _, node := range nodes {
+					if node.Host == state.NodeIp {
+						inCluster = true
+					}
+				}
+				if inCluster {
+					if nodeGroup.Tier != "" {
+						if err := migrateIndexAllocation(context.Background(), nodes, state.NodeName, nodeGroup.Tier); err != nil {
+							return false, err
+						}
+					}
+					err = removeNodeFromCluster(clusterCfg, nodes, nodeGroup, state.NodeName, state.NodeIp)
+					if err != nil {
+						return false, err
+					}
+				} else {
+					log.Info.Println("The node ", state.NodeName, " is not in the cluster, skipping its drain")
+				}
+			}
+			if err := setProvisionState("autoheal_node_drained"); err != nil {
+				return false, err
+			}
+		// Terminate the node and wait for the cluster to be green
+		case "autoheal_node_drained":
+			if !monitorWithLogs {
+				isRunning, isManaged, err := nodeInstance(state.NodeIp, clusterCfg.CloudCredentials)
+				if err != nil {
+					return false, err
+				}
+				// An interrupted spot instance is already terminated by EC2
+				if isRunning && isManaged {
+					log.Info.Println("Terminating the instance of the node ", state.NodeName)
+					err = terminateNodeInstance(state.NodeIp, clusterCfg.CloudCredentials)
+					if err != nil {
+						return false, err
+					}
+				} else if isRunning {
+					log.Warn.Println("The instance of the node ", state.NodeName, " was not launched by the scaling manager, it is left to EC2 to interrupt")
+				}
+				if state.RuleTriggered == spotInterruptionRule {
+					if err := osutils.SetAllocationExclusion(context.Background(), state.NodeName, false); err != nil {
+						log.Warn.Println("Unable to clear the allocation exclusion of the interrupted node: ", err)
+					}
+					if err := osutils.ClearVotingExclusions(context.Background()); err != nil {
+						log.Warn.Println("Unable to clear the voting exclusions: ", err)
+					}
+				}
+				waitForGreenCluster(pollingInterval)
+			}
+			log.Info.Println(fmt.Sprintf("Node %s replaced by %s", state.NodeName, state.NewNodeIp))
+			state.RemainingNodes = 0
+			if err := setProvisionState("provisioned_autoheal_successfully"); err != nil {
+				return false, err
+			}
+		case "provisioned_autoheal_successfully":
+			return true, nil
+		default:
+			return false, errors.New("unknown auto heal state " + state.CurrentState)
+		}
+	}
+}
+
+// Input:
+//
+//	privateIp (string): Private ip of the node
+//	cred (config.CloudCredentials): Cloud credentials required to connect to AWS account
+//
+// Description:
+//
+//	Checks if the instance of the node is still running and if the scaling manager can terminate it
+//
+// Return:
+//
+//	(bool, bool, error): Returns true if the instance is running, true if it can be terminated and error if any
+func nodeInstance(privateIp string, cred config.CloudCredentials) (bool, bool, error) {
+	instanceIds, err := RunningInstanceIds([]string{privateIp}, cred)
+	if err != nil {
+		return false, false, err
+	}
+	managedIds, err := managedNodeInstanceIds([]string{privateIp}, cred)
+	if err != nil {
+		return false, false, err
+	}
+	_, isRunning := instanceIds[privateIp]
+	_, isManaged := managedIds[privateIp]
+	return isRunning, isManaged, nil
+}
@@ -0,0 +1,49 @@
+package provision
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectUnhealthyNodes(t *testing.T) {
+	now := time.Now()
+	reportedAgo := func(name, ip string, ago time.Duration) reportedNode {
+		return reportedNode{NodeName: name, HostIp: ip, LastReported: now.Add(-ago).UnixMilli()}
+	}
+	nodes := map[string]osutils.NodeStats{
+		"m1": {Name: "node-1", Host: "10.0.0.1"},
+		"d2": {Name: "node-2", Host: "10.0.0.2"},
+		"d3": {Name: "node-3", Host: "10.0.0.3"},
+		"d4": {Name: "node-4", Host: "10.0.0.4"},
+	}
+	reportedNodes := map[string]reportedNode{
+		"10.0.0.1": reportedAgo("node-1", "10.0.0.1", 20*time.Minute),
+		"10.0.0.2": reportedAgo("node-2", "10.0.0.2", 20*time.Minute),
+		"10.0.0.3": reportedAgo("node-3", "10.0.0.3", time.Minute),
+		"10.0.0.4": reportedAgo("node-4", "10.0.0.4", time.Minute),
+		"10.0.0.5": reportedAgo("node-5", "10.0.0.5", 30*time.Minute),
+		"10.0.0.6": reportedAgo("node-6", "10.0.0.6", 5*time.Minute),
+	}
+
+	// The elected master is not replaced and node-6 did not leave for long enough
+	unhealthyNodes := selectUnhealthyNodes(reportedNodes, nodes, "m1", config.AutoHeal{Enabled: true}, now)
+	assert.Equal(t, []unhealthyNode{
+		{reportedNode: reportedNodes["10.0.0.5"], Reason: nodeLeftCluster},
+		{reportedNode: reportedNodes["10.0.0.2"], Reason: metricsMissing},
+	}, unhealthyNodes)
+
+	unhealthyNodes = selectUnhealthyNodes(reportedNodes, nodes, "m1", config.AutoHeal{Enabled: true, NodeMissingMinutes: 4, MetricsMissingMinutes: 25}, now)
+	assert.Equal(t, []unhealthyNode{
+		{reportedNode: reportedNodes["10.0.0.5"], Reason: nodeLeftCluster},
+		{reportedNode: reportedNodes["10.0.0.6"], Reason: nodeLeftCluster},
+	}, unhealthyNodes)
+
+	// The missing metrics are not trusted when most of the nodes stopped reporting
+	reportedNodes["10.0.0.3"] = reportedAgo("node-3", "10.0.0.3", 20*time.Minute)
+	unhealthyNodes = selectUnhealthyNodes(reportedNodes, nodes, "m1", config.AutoHeal{Enabled: true}, now)
+	assert.Equal(t, []unhealthyNode{{reportedNode: reportedNodes["10.0.0.5"], Reason: nodeLeftCluster}}, unhealthyNodes)
+}
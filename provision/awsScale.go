@@ -1,29 +1,89 @@
 package provision
 
 import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/maplelabs/opensearch-scaling-manager/config"
 )
 
+// Tags added by EC2 to the instances launched from a launch template
+const (
+	launchTemplateIdTag      = "aws:ec2launchtemplate:id"
+	launchTemplateVersionTag = "aws:ec2launchtemplate:version"
+)
+
+// Tags set on the instances launched by the scaling manager. Only the instances with the manager tag are
+// terminated by the scaling manager.
+const (
+	managerTag      = "opensearch-scaling-manager:managed"
+	clusterUuidTag  = "opensearch-scaling-manager:cluster-uuid"
+	launchTimeTag   = "opensearch-scaling-manager:launch-time"
+	launchReasonTag = "opensearch-scaling-manager:launch-reason"
+	// lifecycleTag indicates the lifecycle of the instance, spot or on-demand
+	lifecycleTag = "opensearch-scaling-manager:lifecycle"
+)
+
+// States of the instances which are not terminated yet
+var liveInstanceStates = []string{"pending", "running", "stopping", "stopped"}
+
+// This struct contains an instance launched by the scaling manager for the cluster
+type ManagedInstance struct {
+	InstanceId string
+	PrivateIp  string
+	State      string
+	// LaunchTime and LaunchReason indicate when and why the scaling manager launched the instance, from its tags.
+	LaunchTime   string
+	LaunchReason string
+}
+
+// Lifecycles of the instances
+const (
+	spotLifecycle     = "spot"
+	onDemandLifecycle = "on-demand"
+)
+
+// Status codes of the spot instance requests whose instance received an interruption notice
+var interruptionStatusCodes = []string{"marked-for-termination", "marked-for-stop", "marked-for-hibernation"}
+
+// Error codes of RunInstances when no spot instance can be launched, after which an on-demand instance may be
+// launched instead
+var spotCapacityErrorCodes = map[string]bool{
+	"InsufficientInstanceCapacity": true,
+	"SpotMaxPriceTooLow":           true,
+	"MaxSpotInstanceCountExceeded": true,
+}
+
+// Path of the SSM parameters holding the secrets of the nodes bootstrapping from their user data, followed by the
+// UUID of the cluster
+const bootstrapSecretPathPrefix = "/opensearch-scaling-manager/"
+
+// Maximum number of parameters deleted by a DeleteParameters request
+const maxDeletedParameters = 10
+
 // Input:
-//	launchTemplateId (string): Launch Template ID using which a new ec2 instance will be spinned up
-//	launchTemplateVersion (string): Template version of the launch template specified
+//
 //	cred (config.CloudCredentials): Cloud credentials to connect to AWS
 //
 // Description:
 //
-//	Spins a new ec2 instance on AWS using the launchTemplate specified.
-//	Returns the ip address of the created ec2 instance for further configuration of Opensearch
+//	Creates an AWS session with the static credentials, or the credentials of the role if role_arn is set
 //
 // Return:
 //
-//	(string, string, error): Returns the private ip address, instance ID of the spinned node and error if any
-func SpinNewVm(launchTemplateId string, launchTemplateVersion string, cred config.CloudCredentials) (string, string, error) {
+//	(*session.Session, *aws.Config): Returns the session and the config of the clients
+func newAwsSession(cred config.CloudCredentials) (*session.Session, *aws.Config) {
 	sess := session.Must(session.NewSession())
 	var creds *credentials.Credentials
 	if cred.RoleArn != "" {
@@ -31,7 +91,136 @@ func SpinNewVm(launchTemplateId string, launchTemplateVersion string, cred confi
 	} else {
 		creds = credentials.NewStaticCredentials(cred.AccessKey, cred.SecretKey, "")
 	}
-	svc := ec2.New(sess, &aws.Config{Region: aws.String(cred.Region), Credentials: creds})
+	return sess, &aws.Config{Region: aws.String(cred.Region), Credentials: creds}
+}
+
+// Input:
+//
+//	cred (config.CloudCredentials): Cloud credentials to connect to AWS
+//
+// Description:
+//
+//	Creates an EC2 client with the static credentials, or the credentials of the role if role_arn is set
+//
+// Return:
+//
+//	(*ec2.EC2): Returns the EC2 client
+func newEc2Client(cred config.CloudCredentials) *ec2.EC2 {
+	return ec2.New(newAwsSession(cred))
+}
+
+// Input:
+//
+//	clusterUuid (string): UUID of the opensearch cluster
+//
+// Description:
+//
+//	Returns the SSM parameter path under which the secrets of the nodes of the cluster bootstrapping from their
+//	user data are stored
+//
+// Return:
+//
+//	(string): Returns the parameter path
+func bootstrapSecretPath(clusterUuid string) string {
+	return bootstrapSecretPathPrefix + clusterUuid + "/bootstrap"
+}
+
+// Input:
+//
+//	secrets (map[string]string): Secrets of the new node by parameter name
+//	cred (config.CloudCredentials): Cloud credentials required to connect to AWS account
+//
+// Description:
+//
+//	Stores the secrets of a new node as SecureString parameters in the SSM Parameter Store, from which the node
+//	fetches them when it boots
+//
+// Return:
+//
+//	(error): Returns error if a secret can't be stored
+func PutBootstrapSecrets(secrets map[string]string, cred config.CloudCredentials) error {
+	ssmClient := ssm.New(newAwsSession(cred))
+	var names []string
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		_, err := ssmClient.PutParameter(&ssm.PutParameterInput{
+			Name:  aws.String(name),
+			Value: aws.String(secrets[name]),
+			Type:  aws.String(ssm.ParameterTypeSecureString),
+			// The parameters above 4 KB are stored in the advanced tier
+			Tier: aws.String(ssm.ParameterTierIntelligentTiering),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to store the secret %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Input:
+//
+//	clusterUuid (string): UUID of the opensearch cluster
+//	cred (config.CloudCredentials): Cloud credentials required to connect to AWS account
+//
+// Description:
+//
+//	Deletes the secrets stored for the nodes of the cluster bootstrapping from their user data
+//
+// Return:
+//
+//	(error): Returns error if the secrets can't be listed or deleted
+func DeleteBootstrapSecrets(clusterUuid string, cred config.CloudCredentials) error {
+	ssmClient := ssm.New(newAwsSession(cred))
+	var names []*string
+	err := ssmClient.GetParametersByPathPages(&ssm.GetParametersByPathInput{
+		Path:      aws.String(bootstrapSecretPath(clusterUuid)),
+		Recursive: aws.Bool(true),
+	}, func(page *ssm.GetParametersByPathOutput, lastPage bool) bool {
+		for _, parameter := range page.Parameters {
+			names = append(names, parameter.Name)
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	for start := 0; start < len(names); start += maxDeletedParameters {
+		end := start + maxDeletedParameters
+		if end > len(names) {
+			end = len(names)
+		}
+		_, err := ssmClient.DeleteParameters(&ssm.DeleteParametersInput{Names: names[start:end]})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Input:
+//	launchTemplateId (string): Launch Template ID using which a new ec2 instance will be spinned up
+//	launchTemplateVersion (string): Template version of the launch template specified
+//	userData ([]byte): User data of the instance, replacing the user data of the launch template if not empty
+//	spot (*config.Spot): Spot options of the node group if a spot instance is launched, nil for an on-demand instance
+//	clusterUuid (string): UUID of the cluster the instance joins
+//	reason (string): Why the instance is launched, like scale_up or auto_heal
+//	cred (config.CloudCredentials): Cloud credentials to connect to AWS
+//
+// Description:
+//
+//	Spins a new ec2 instance on AWS using the launchTemplate specified.
+//	Returns the ip address of the created ec2 instance for further configuration of Opensearch
+//	The instance is tagged as launched by the scaling manager for the cluster, with its launch time, the reason
+//	and its lifecycle, so that the spot instances of a node group can be counted.
+//
+// Return:
+//
+//	(string, string, error): Returns the private ip address, instance ID of the spinned node and error if any
+func SpinNewVm(launchTemplateId string, launchTemplateVersion string, userData []byte, spot *config.Spot, clusterUuid string, reason string, cred config.CloudCredentials) (string, string, error) {
+	svc := newEc2Client(cred)
 
 	launchTemplate := &ec2.LaunchTemplateSpecification{
 		LaunchTemplateId: &launchTemplateId,
@@ -39,12 +228,32 @@ func SpinNewVm(launchTemplateId string, launchTemplateVersion string, cred confi
 	}
 
 	// Specify the details of the instance that you want to create.
-	runResult, err := svc.RunInstances(&ec2.RunInstancesInput{
+	runInput := &ec2.RunInstancesInput{
 		// An Amazon Linux AMI ID for t2.micro instances in the us-west-2 region
 		LaunchTemplate: launchTemplate,
 		MinCount:       aws.Int64(1),
 		MaxCount:       aws.Int64(1),
-	})
+	}
+	if len(userData) > 0 {
+		runInput.UserData = aws.String(base64.StdEncoding.EncodeToString(userData))
+	}
+	lifecycle := onDemandLifecycle
+	if spot != nil {
+		lifecycle = spotLifecycle
+		spotOptions := &ec2.SpotMarketOptions{
+			SpotInstanceType:             aws.String(ec2.SpotInstanceTypeOneTime),
+			InstanceInterruptionBehavior: aws.String(ec2.InstanceInterruptionBehaviorTerminate),
+		}
+		if spot.MaxPrice != "" {
+			spotOptions.MaxPrice = aws.String(spot.MaxPrice)
+		}
+		runInput.InstanceMarketOptions = &ec2.InstanceMarketOptionsRequest{
+			MarketType:  aws.String(ec2.MarketTypeSpot),
+			SpotOptions: spotOptions,
+		}
+	}
+	runInput.TagSpecifications = launchTagSpecifications(clusterUuid, reason, lifecycle, time.Now())
+	runResult, err := svc.RunInstances(runInput)
 
 	log.Info.Println("Creating new instance *************")
 
@@ -75,14 +284,7 @@ func SpinNewVm(launchTemplateId string, launchTemplateVersion string, cred confi
 //
 //	(error): Returns error if any while checking for the status
 func InstanceStatusCheck(instanceId string, cred config.CloudCredentials) error {
-	sess := session.Must(session.NewSession())
-	var creds *credentials.Credentials
-	if cred.RoleArn != "" {
-		creds = stscreds.NewCredentials(sess, cred.RoleArn)
-	} else {
-		creds = credentials.NewStaticCredentials(cred.AccessKey, cred.SecretKey, "")
-	}
-	svc := ec2.New(sess, &aws.Config{Region: aws.String(cred.Region), Credentials: creds})
+	svc := newEc2Client(cred)
 
 	allInstances := true
 
@@ -102,37 +304,32 @@ func InstanceStatusCheck(instanceId string, cred config.CloudCredentials) error
 // Input:
 //
 //	privateIp (string): private ip address of the instance that needs to be terminated
+//	clusterUuid (string): UUID of the opensearch cluster the instance was launched for
 //      cred (config.CloudCredentials): Cloud credentials required to connect to AWS account
 //
 // Description:
 //
 //	Uses the private ip address passed as input to identify the instance id.
 //	Terminates the ec2 instance.
+//	Only a live instance with the manager tag and the UUID of the cluster is terminated, so that an unrelated
+//	instance which reused the ip of a node, or an instance of another cluster managed from the same account, is
+//	never terminated.
 //
 // Return:
 //
 //	(error): Returns error if any while terminating the instance
-func TerminateInstance(privateIp string, cred config.CloudCredentials) error {
-	sess := session.Must(session.NewSession())
-	var creds *credentials.Credentials
-
-	if cred.RoleArn != "" {
-		creds = stscreds.NewCredentials(sess, cred.RoleArn)
-	} else {
-		creds = credentials.NewStaticCredentials(cred.AccessKey, cred.SecretKey, "")
-	}
-
-	svc := ec2.New(sess, &aws.Config{Region: aws.String(cred.Region), Credentials: creds})
+func TerminateInstance(privateIp string, clusterUuid string, cred config.CloudCredentials) error {
+	svc := newEc2Client(cred)
 
 	describeInput := &ec2.DescribeInstancesInput{
-		Filters: []*ec2.Filter{
+		Filters: append([]*ec2.Filter{
 			{
 				Name: aws.String("private-ip-address"),
 				Values: []*string{
 					aws.String(privateIp),
 				},
 			},
-		},
+		}, managedInstanceFilters(clusterUuid)...),
 	}
 
 	describeResult, descErr := svc.DescribeInstances(describeInput)
@@ -142,7 +339,18 @@ func TerminateInstance(privateIp string, cred config.CloudCredentials) error {
 		return descErr
 	}
 
-	instanceId := *describeResult.Reservations[0].Instances[0].InstanceId
+	var instanceIds []string
+	for _, reservation := range describeResult.Reservations {
+		for _, instance := range reservation.Instances {
+			if isClusterInstance(instance, clusterUuid) {
+				instanceIds = append(instanceIds, aws.StringValue(instance.InstanceId))
+			}
+		}
+	}
+	if len(instanceIds) != 1 {
+		return fmt.Errorf("expected one instance launched by the scaling manager with the private ip %s, found %d", privateIp, len(instanceIds))
+	}
+	instanceId := instanceIds[0]
 
 	log.Info.Println("Terminating instance with ID: ", instanceId)
 
@@ -166,3 +374,471 @@ func TerminateInstance(privateIp string, cred config.CloudCredentials) error {
 	log.Info.Println(result)
 	return nil
 }
+
+// Input:
+//
+//	launchTemplateId (string): Launch Template ID of the node group
+//	launchTemplateVersion (string): Version of the launch template, a number, $Latest or $Default
+//	cred (config.CloudCredentials): Cloud credentials required to connect to AWS account
+//
+// Description:
+//
+//	Resolves $Latest and $Default to the number of the version they currently refer to, so that all the nodes
+//	replaced by a vertical scaling are launched from the same version even if the launch template changes
+//
+// Return:
+//
+//	(string, error): Returns the number of the version and error if the launch template can't be described
+func LaunchTemplateVersionNumber(launchTemplateId string, launchTemplateVersion string, cred config.CloudCredentials) (string, error) {
+	if _, err := strconv.Atoi(launchTemplateVersion); err == nil {
+		return launchTemplateVersion, nil
+	}
+	svc := newEc2Client(cred)
+	describeResult, err := svc.DescribeLaunchTemplates(&ec2.DescribeLaunchTemplatesInput{
+		LaunchTemplateIds: []*string{aws.String(launchTemplateId)},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(describeResult.LaunchTemplates) == 0 {
+		return "", errors.New("launch template " + launchTemplateId + " not found")
+	}
+	launchTemplate := describeResult.LaunchTemplates[0]
+	switch launchTemplateVersion {
+	case "$Latest":
+		return strconv.FormatInt(aws.Int64Value(launchTemplate.LatestVersionNumber), 10), nil
+	case "$Default":
+		return strconv.FormatInt(aws.Int64Value(launchTemplate.DefaultVersionNumber), 10), nil
+	}
+	return "", errors.New("invalid launch template version " + launchTemplateVersion)
+}
+
+// Input:
+//
+//	launchTemplateId (string): Launch Template ID of the node group
+//	privateIps ([]string): Private ip addresses of the instances
+//	cred (config.CloudCredentials): Cloud credentials required to connect to AWS account
+//
+// Description:
+//
+//	Reads the version of the launch template the running instances were launched from, from the tags EC2 adds
+//	to the instances launched from a launch template
+//
+// Return:
+//
+//	(map[string]string, error): Returns the versions by private ip, without the instances launched from another
+//	launch template or without one, and error if any
+func InstanceLaunchTemplateVersions(launchTemplateId string, privateIps []string, cred config.CloudCredentials) (map[string]string, error) {
+	versions := make(map[string]string)
+	if len(privateIps) == 0 {
+		return versions, nil
+	}
+	svc := newEc2Client(cred)
+	describeInput := &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{Name: aws.String("private-ip-address"), Values: aws.StringSlice(privateIps)},
+			{Name: aws.String("instance-state-name"), Values: aws.StringSlice([]string{"pending", "running"})},
+		},
+	}
+	err := svc.DescribeInstancesPages(describeInput, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				tags := make(map[string]string)
+				for _, tag := range instance.Tags {
+					tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+				}
+				if tags[launchTemplateIdTag] == launchTemplateId {
+					versions[aws.StringValue(instance.PrivateIpAddress)] = tags[launchTemplateVersionTag]
+				}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// Input:
+//
+//	privateIps ([]string): Private ip addresses of the instances
+//	cred (config.CloudCredentials): Cloud credentials required to connect to AWS account
+//
+// Description:
+//
+//	Finds the instances which are pending or running, so that the nodes whose instances were terminated, like the
+//	ones removed by a scale down, are not replaced
+//
+// Return:
+//
+//	(map[string]string, error): Returns the instance IDs by private ip and error if any
+func RunningInstanceIds(privateIps []string, cred config.CloudCredentials) (map[string]string, error) {
+	instanceIds := make(map[string]string)
+	if len(privateIps) == 0 {
+		return instanceIds, nil
+	}
+	svc := newEc2Client(cred)
+	describeInput := &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{Name: aws.String("private-ip-address"), Values: aws.StringSlice(privateIps)},
+			{Name: aws.String("instance-state-name"), Values: aws.StringSlice([]string{"pending", "running"})},
+		},
+	}
+	err := svc.DescribeInstancesPages(describeInput, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				instanceIds[aws.StringValue(instance.PrivateIpAddress)] = aws.StringValue(instance.InstanceId)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return instanceIds, nil
+}
+
+// Input:
+//
+//	privateIps ([]string): Private ip addresses of the nodes
+//	clusterUuid (string): UUID of the opensearch cluster
+//	cred (config.CloudCredentials): Cloud credentials required to connect to AWS account
+//
+// Description:
+//
+//	Finds the instances of the nodes which TerminateInstance can terminate: the live instances with the manager
+//	tag and the UUID of the cluster. An ip shared by more than one such instance is left out, as TerminateInstance refuses it. The nodes
+//	which are left out must not be drained or replaced, as their instance would keep running once they left
+//	the cluster.
+//
+// Return:
+//
+//	(map[string]string, error): Returns the instance IDs by private ip and error if any
+func ManagedInstanceIds(privateIps []string, clusterUuid string, cred config.CloudCredentials) (map[string]string, error) {
+	if len(privateIps) == 0 {
+		return make(map[string]string), nil
+	}
+	svc := newEc2Client(cred)
+	describeInput := &ec2.DescribeInstancesInput{
+		Filters: append([]*ec2.Filter{
+			{Name: aws.String("private-ip-address"), Values: aws.StringSlice(privateIps)},
+		}, managedInstanceFilters(clusterUuid)...),
+	}
+	var instances []*ec2.Instance
+	err := svc.DescribeInstancesPages(describeInput, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, reservation := range page.Reservations {
+			instances = append(instances, reservation.Instances...)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return instanceIdsByIp(instances, clusterUuid), nil
+}
+
+// Input:
+//
+//	instances ([]*ec2.Instance): Described instances
+//	clusterUuid (string): UUID of the opensearch cluster
+//
+// Description:
+//
+//	Maps the private ips to the instance IDs of the cluster, leaving out the ips shared by more than one instance
+//
+// Return:
+//
+//	(map[string]string): Returns the instance IDs by private ip
+func instanceIdsByIp(instances []*ec2.Instance, clusterUuid string) map[string]string {
+	instanceIds := make(map[string]string)
+	shared := make(map[string]bool)
+	for _, instance := range instances {
+		if !isClusterInstance(instance, clusterUuid) {
+			continue
+		}
+		privateIp := aws.StringValue(instance.PrivateIpAddress)
+		if _, ok := instanceIds[privateIp]; ok {
+			shared[privateIp] = true
+		}
+		instanceIds[privateIp] = aws.StringValue(instance.InstanceId)
+	}
+	for privateIp := range shared {
+		delete(instanceIds, privateIp)
+	}
+	return instanceIds
+}
+
+// Input:
+//
+//	privateIps ([]string): Private ip addresses of the instances
+//	cred (config.CloudCredentials): Cloud credentials required to connect to AWS account
+//
+// Description:
+//
+//	Finds the lifecycle, spot or on-demand, of the running instances from their lifecycle tag. The instances
+//	launched without the tag are spot instances if EC2 reports them as such.
+//
+// Return:
+//
+//	(map[string]string, error): Returns the lifecycles by private ip and error if any
+func InstanceLifecycles(privateIps []string, cred config.CloudCredentials) (map[string]string, error) {
+	lifecycles := make(map[string]string)
+	if len(privateIps) == 0 {
+		return lifecycles, nil
+	}
+	svc := newEc2Client(cred)
+	describeInput := &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			{Name: aws.String("private-ip-address"), Values: aws.StringSlice(privateIps)},
+			{Name: aws.String("instance-state-name"), Values: aws.StringSlice([]string{"pending", "running"})},
+		},
+	}
+	err := svc.DescribeInstancesPages(describeInput, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				lifecycle := onDemandLifecycle
+				if aws.StringValue(instance.InstanceLifecycle) == ec2.InstanceLifecycleTypeSpot {
+					lifecycle = spotLifecycle
+				}
+				for _, tag := range instance.Tags {
+					if aws.StringValue(tag.Key) == lifecycleTag {
+						lifecycle = aws.StringValue(tag.Value)
+					}
+				}
+				lifecycles[aws.StringValue(instance.PrivateIpAddress)] = lifecycle
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lifecycles, nil
+}
+
+// Input:
+//
+//	cred (config.CloudCredentials): Cloud credentials required to connect to AWS account
+//
+// Description:
+//
+//	Lists the spot instances which received an interruption notice, from the status of their spot instance
+//	requests. The notice is given two minutes before the instance is interrupted.
+//
+// Return:
+//
+//	([]SpotInterruption, error): Returns the interrupted instances and error if any
+func MarkedForInterruption(cred config.CloudCredentials) ([]SpotInterruption, error) {
+	svc := newEc2Client(cred)
+	requests, err := svc.DescribeSpotInstanceRequests(&ec2.DescribeSpotInstanceRequestsInput{
+		Filters: []*ec2.Filter{
+			{Name: aws.String("status-code"), Values: aws.StringSlice(interruptionStatusCodes)},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	noticeTimes := make(map[string]time.Time)
+	var instanceIds []string
+	for _, request := range requests.SpotInstanceRequests {
+		if request.InstanceId == nil {
+			continue
+		}
+		instanceIds = append(instanceIds, aws.StringValue(request.InstanceId))
+		if request.Status != nil {
+			noticeTimes[aws.StringValue(request.InstanceId)] = aws.TimeValue(request.Status.UpdateTime)
+		}
+	}
+	if len(instanceIds) == 0 {
+		return nil, nil
+	}
+	var interruptions []SpotInterruption
+	err = svc.DescribeInstancesPages(&ec2.DescribeInstancesInput{InstanceIds: aws.StringSlice(instanceIds)}, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				instanceId := aws.StringValue(instance.InstanceId)
+				interruptions = append(interruptions, SpotInterruption{
+					InstanceId: instanceId,
+					PrivateIp:  aws.StringValue(instance.PrivateIpAddress),
+					NoticeTime: noticeTimes[instanceId],
+				})
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return interruptions, nil
+}
+
+// Input:
+//
+//	err (error): Error of RunInstances
+//
+// Description:
+//
+//	Checks if a spot instance could not be launched for lack of spot capacity or because of the max price
+//
+// Return:
+//
+//	(bool): Returns true if an on-demand instance may be launched instead
+func isSpotCapacityError(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		return spotCapacityErrorCodes[aerr.Code()]
+	}
+	return false
+}
+
+// Input:
+//
+//	clusterUuid (string): UUID of the cluster the instance joins
+//	reason (string): Why the instance is launched
+//	lifecycle (string): Lifecycle of the instance, spot or on-demand
+//	launchTime (time.Time): Time the instance is launched
+//
+// Description:
+//
+//	Builds the tags of an instance launched by the scaling manager
+//
+// Return:
+//
+//	([]*ec2.TagSpecification): Returns the tag specifications of the RunInstances request
+func launchTagSpecifications(clusterUuid string, reason string, lifecycle string, launchTime time.Time) []*ec2.TagSpecification {
+	tags := []*ec2.Tag{
+		{Key: aws.String(managerTag), Value: aws.String("true")},
+		{Key: aws.String(clusterUuidTag), Value: aws.String(clusterUuid)},
+		{Key: aws.String(launchTimeTag), Value: aws.String(launchTime.UTC().Format(time.RFC3339))},
+		{Key: aws.String(launchReasonTag), Value: aws.String(reason)},
+		{Key: aws.String(lifecycleTag), Value: aws.String(lifecycle)},
+	}
+	return []*ec2.TagSpecification{{ResourceType: aws.String(ec2.ResourceTypeInstance), Tags: tags}}
+}
+
+// Input:
+//
+//	clusterUuid (string): UUID of the cluster
+//	cred (config.CloudCredentials): Cloud credentials required to connect to AWS account
+//
+// Description:
+//
+//	Lists the live instances the scaling manager launched for the cluster, from their tags
+//
+// Return:
+//
+//	([]ManagedInstance, error): Returns the instances and error if any
+func ManagedInstances(clusterUuid string, cred config.CloudCredentials) ([]ManagedInstance, error) {
+	svc := newEc2Client(cred)
+	describeInput := &ec2.DescribeInstancesInput{
+		Filters: managedInstanceFilters(clusterUuid),
+	}
+	var instances []ManagedInstance
+	err := svc.DescribeInstancesPages(describeInput, func(page *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				managedInstance := ManagedInstance{
+					InstanceId: aws.StringValue(instance.InstanceId),
+					PrivateIp:  aws.StringValue(instance.PrivateIpAddress),
+				}
+				if instance.State != nil {
+					managedInstance.State = aws.StringValue(instance.State.Name)
+				}
+				for _, tag := range instance.Tags {
+					switch aws.StringValue(tag.Key) {
+					case launchTimeTag:
+						managedInstance.LaunchTime = aws.StringValue(tag.Value)
+					case launchReasonTag:
+						managedInstance.LaunchReason = aws.StringValue(tag.Value)
+					}
+				}
+				instances = append(instances, managedInstance)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return instances, nil
+}
+
+// Input:
+//
+//	instanceId (string): Instance ID of the instance that needs to be terminated
+//	clusterUuid (string): UUID of the opensearch cluster the instance was launched for
+//	cred (config.CloudCredentials): Cloud credentials required to connect to AWS account
+//
+// Description:
+//
+//	Terminates the instance if it is live and has the manager tag and the UUID of the cluster
+//
+// Return:
+//
+//	(error): Returns error if the instance is not a live instance launched by the scaling manager for the cluster or
+//	can't be terminated
+func TerminateManagedInstance(instanceId string, clusterUuid string, cred config.CloudCredentials) error {
+	svc := newEc2Client(cred)
+	describeResult, err := svc.DescribeInstances(&ec2.DescribeInstancesInput{
+		InstanceIds: aws.StringSlice([]string{instanceId}),
+		Filters:     managedInstanceFilters(clusterUuid),
+	})
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, reservation := range describeResult.Reservations {
+		for _, instance := range reservation.Instances {
+			found = found || isClusterInstance(instance, clusterUuid)
+		}
+	}
+	if !found {
+		return errors.New("instance " + instanceId + " is not a live instance launched by the scaling manager for the cluster")
+	}
+	log.Info.Println("Terminating instance with ID: ", instanceId)
+	_, err = svc.TerminateInstances(&ec2.TerminateInstancesInput{InstanceIds: aws.StringSlice([]string{instanceId})})
+	return err
+}
+
+// Input:
+//
+//	clusterUuid (string): UUID of the opensearch cluster
+//
+// Description:
+//
+//	Filters the live instances launched by the scaling manager for the cluster
+//
+// Return:
+//
+//	([]*ec2.Filter): Returns the filters of the DescribeInstances request
+func managedInstanceFilters(clusterUuid string) []*ec2.Filter {
+	return []*ec2.Filter{
+		{Name: aws.String("tag:" + managerTag), Values: aws.StringSlice([]string{"true"})},
+		{Name: aws.String("tag:" + clusterUuidTag), Values: aws.StringSlice([]string{clusterUuid})},
+		{Name: aws.String("instance-state-name"), Values: aws.StringSlice(liveInstanceStates)},
+	}
+}
+
+// Input:
+//
+//	instance (*ec2.Instance): Described instance
+//	clusterUuid (string): UUID of the opensearch cluster
+//
+// Description:
+//
+//	Checks the instance has the manager tag and the UUID of the cluster, so that the instances of another cluster
+//	managed from the same account are never terminated or replaced
+//
+// Return:
+//
+//	(bool): Returns true if the instance was launched by the scaling manager for the cluster
+func isClusterInstance(instance *ec2.Instance, clusterUuid string) bool {
+	if clusterUuid == "" {
+		return false
+	}
+	tags := make(map[string]string)
+	for _, tag := range instance.Tags {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	return tags[managerTag] == "true" && tags[clusterUuidTag] == clusterUuid
+}
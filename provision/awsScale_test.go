@@ -0,0 +1,55 @@
+package provision
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLaunchTagSpecifications(t *testing.T) {
+	launchTime := time.Date(2024, 3, 1, 10, 30, 0, 0, time.FixedZone("IST", 19800))
+	specifications := launchTagSpecifications("uuid-1", "auto_heal", spotLifecycle, launchTime)
+	assert.Len(t, specifications, 1)
+	assert.Equal(t, "instance", aws.StringValue(specifications[0].ResourceType))
+	tags := make(map[string]string)
+	for _, tag := range specifications[0].Tags {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	assert.Equal(t, map[string]string{
+		"opensearch-scaling-manager:managed":       "true",
+		"opensearch-scaling-manager:cluster-uuid":  "uuid-1",
+		"opensearch-scaling-manager:launch-time":   "2024-03-01T05:00:00Z",
+		"opensearch-scaling-manager:launch-reason": "auto_heal",
+		"opensearch-scaling-manager:lifecycle":     "spot",
+	}, tags)
+}
+
+func TestInstanceIdsByIp(t *testing.T) {
+	instances := []*ec2.Instance{
+		{InstanceId: aws.String("i-1"), PrivateIpAddress: aws.String("10.0.0.1"), Tags: clusterTags("uuid-1")},
+		{InstanceId: aws.String("i-2"), PrivateIpAddress: aws.String("10.0.0.2"), Tags: clusterTags("uuid-1")},
+		{InstanceId: aws.String("i-3"), PrivateIpAddress: aws.String("10.0.0.2"), Tags: clusterTags("uuid-1")},
+		{InstanceId: aws.String("i-4"), PrivateIpAddress: aws.String("10.0.0.4"), Tags: clusterTags("uuid-2")},
+	}
+	// An ip shared by two managed instances is left out, as TerminateInstance refuses it
+	assert.Equal(t, map[string]string{"10.0.0.1": "i-1"}, instanceIdsByIp(instances, "uuid-1"))
+	// The instances of another cluster managed from the same account are left out
+	assert.Equal(t, map[string]string{"10.0.0.4": "i-4"}, instanceIdsByIp(instances, "uuid-2"))
+	assert.Empty(t, instanceIdsByIp(nil, "uuid-1"))
+}
+
+func TestIsClusterInstance(t *testing.T) {
+	assert.True(t, isClusterInstance(&ec2.Instance{Tags: clusterTags("uuid-1")}, "uuid-1"))
+	assert.False(t, isClusterInstance(&ec2.Instance{Tags: clusterTags("uuid-2")}, "uuid-1"))
+	assert.False(t, isClusterInstance(&ec2.Instance{Tags: clusterTags("")}, ""))
+	// An instance of the cluster without the manager tag was not launched by the scaling manager
+	assert.False(t, isClusterInstance(&ec2.Instance{Tags: clusterTags("uuid-1")[1:]}, "uuid-1"))
+	assert.False(t, isClusterInstance(&ec2.Instance{}, "uuid-1"))
+}
+
+func clusterTags(clusterUuid string) []*ec2.Tag {
+	return launchTagSpecifications(clusterUuid, "scale_up", onDemandLifecycle, time.Now())[0].Tags
+}
@@ -0,0 +1,100 @@
+package provision
+
+import (
+	"sort"
+
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+	"github.com/maplelabs/opensearch-scaling-manager/crypto"
+	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
+	utils "github.com/maplelabs/opensearch-scaling-manager/utilities"
+)
+
+// This struct contains the instances launched by the scaling manager compared with the nodes of the cluster
+type Inventory struct {
+	// ClusterUuid indicates the UUID of the cluster the instances were launched for.
+	ClusterUuid string
+	// Orphans indicates the live instances launched for the cluster which are not nodes of the cluster.
+	Orphans []ManagedInstance
+	// NodesWithoutInstance indicates the nodes of the cluster which have no running instance with their ip.
+	NodesWithoutInstance []osutils.NodeStats
+}
+
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Cluster Level config details
+//
+// Description:
+//
+//	ReconcileInventory lists the instances the scaling manager launched for the cluster which are not nodes of
+//	the cluster, like the instances left behind by a failed provision, and the nodes of the cluster without a
+//	running instance. The instance launched by the provision in progress is not an orphan.
+//
+// Return:
+//
+//	(Inventory, error): Returns the orphans and the nodes without an instance and error if any
+func ReconcileInventory(clusterCfg config.ClusterDetails) (Inventory, error) {
+	crypto.GetDecryptedCloudCreds(&clusterCfg.CloudCredentials)
+	clusterUuid, err := utils.GetClusterId()
+	if err != nil {
+		return Inventory{}, err
+	}
+	nodes, err := utils.GetNodes()
+	if err != nil {
+		return Inventory{}, err
+	}
+	instances, err := ManagedInstances(clusterUuid, clusterCfg.CloudCredentials)
+	if err != nil {
+		return Inventory{}, err
+	}
+	var privateIps []string
+	for _, node := range nodes {
+		privateIps = append(privateIps, node.Host)
+	}
+	instanceIds, err := RunningInstanceIds(privateIps, clusterCfg.CloudCredentials)
+	if err != nil {
+		return Inventory{}, err
+	}
+	if err := state.GetCurrentState(); err != nil {
+		return Inventory{}, err
+	}
+	inventory := reconcileInventory(instances, nodes, instanceIds, state.InstanceId)
+	inventory.ClusterUuid = clusterUuid
+	return inventory, nil
+}
+
+// Input:
+//
+//	instances ([]ManagedInstance): Live instances launched by the scaling manager for the cluster
+//	nodes (map[string]osutils.NodeStats): Nodes of the cluster keyed by node id
+//	instanceIds (map[string]string): Instance IDs of the running instances by private ip of the nodes
+//	provisioningInstanceId (string): Instance ID of the node launched by the provision in progress, if any
+//
+// Description:
+//
+//	Compares the instances launched by the scaling manager with the nodes of the cluster
+//
+// Return:
+//
+//	(Inventory): Returns the orphans by instance ID and the nodes without an instance by name
+func reconcileInventory(instances []ManagedInstance, nodes map[string]osutils.NodeStats, instanceIds map[string]string, provisioningInstanceId string) Inventory {
+	var inventory Inventory
+	nodeIps := make(map[string]bool)
+	for _, node := range nodes {
+		nodeIps[node.Host] = true
+		if _, ok := instanceIds[node.Host]; !ok {
+			inventory.NodesWithoutInstance = append(inventory.NodesWithoutInstance, node)
+		}
+	}
+	for _, instance := range instances {
+		if !nodeIps[instance.PrivateIp] && instance.InstanceId != provisioningInstanceId {
+			inventory.Orphans = append(inventory.Orphans, instance)
+		}
+	}
+	sort.Slice(inventory.Orphans, func(i, j int) bool {
+		return inventory.Orphans[i].InstanceId < inventory.Orphans[j].InstanceId
+	})
+	sort.Slice(inventory.NodesWithoutInstance, func(i, j int) bool {
+		return inventory.NodesWithoutInstance[i].Name < inventory.NodesWithoutInstance[j].Name
+	})
+	return inventory
+}
@@ -0,0 +1,32 @@
+package provision
+
+import (
+	"testing"
+
+	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconcileInventory(t *testing.T) {
+	nodes := map[string]osutils.NodeStats{
+		"n1": {Name: "node-1", Host: "10.0.0.1"},
+		"n2": {Name: "node-2", Host: "10.0.0.2"},
+		"n3": {Name: "node-3", Host: "10.0.0.3"},
+	}
+	instances := []ManagedInstance{
+		{InstanceId: "i-5", PrivateIp: "10.0.0.5", State: "running", LaunchReason: "scale_up"},
+		{InstanceId: "i-2", PrivateIp: "10.0.0.2", State: "running", LaunchReason: "scale_up"},
+		{InstanceId: "i-4", PrivateIp: "10.0.0.4", State: "stopped", LaunchReason: "auto_heal"},
+		{InstanceId: "i-6", PrivateIp: "10.0.0.6", State: "pending", LaunchReason: "scale_vertical"},
+	}
+	instanceIds := map[string]string{"10.0.0.1": "i-1", "10.0.0.2": "i-2"}
+
+	// The instance of the provision in progress has not joined the cluster yet
+	inventory := reconcileInventory(instances, nodes, instanceIds, "i-6")
+	assert.Equal(t, []ManagedInstance{instances[2], instances[0]}, inventory.Orphans)
+	assert.Equal(t, []osutils.NodeStats{nodes["n3"]}, inventory.NodesWithoutInstance)
+
+	inventory = reconcileInventory(nil, nodes, map[string]string{"10.0.0.1": "i-1", "10.0.0.2": "i-2", "10.0.0.3": "i-3"}, "")
+	assert.Empty(t, inventory.Orphans)
+	assert.Empty(t, inventory.NodesWithoutInstance)
+}
@@ -0,0 +1,167 @@
+package provision
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+	"github.com/maplelabs/opensearch-scaling-manager/crypto"
+	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
+)
+
+// Actions recorded in the OrphanCleanup documents
+const (
+	orphanTerminated      = "terminated"
+	orphanReported        = "reported"
+	orphanTerminateFailed = "terminate_failed"
+)
+
+// This struct contains an orphaned instance tracked by the clean up
+type trackedOrphan struct {
+	// AbsentSince indicates when the instance was first found absent from the cluster by this node.
+	AbsentSince time.Time
+	// Reported indicates if the instance was already reported in dry run.
+	Reported bool
+}
+
+// Orphaned instances found by this node by instance ID, the grace period starts over on a new master
+var trackedOrphans = make(map[string]*trackedOrphan)
+
+// Input:
+//
+//	orphans ([]ManagedInstance): Instances launched by the scaling manager which are not nodes of the cluster
+//	tracked (map[string]*trackedOrphan): Orphaned instances found earlier by instance ID
+//	now (time.Time): Time of the reconciliation
+//	gracePeriod (time.Duration): How long an instance has to be absent from the cluster before it is terminated
+//
+// Description:
+//
+//	Tracks since when every instance is absent from the cluster. The instances which are no longer orphans, like
+//	a new node which joined the cluster, are not tracked anymore.
+//
+// Return:
+//
+//	([]ManagedInstance): Returns the orphans absent from the cluster for longer than the grace period
+func expiredOrphans(orphans []ManagedInstance, tracked map[string]*trackedOrphan, now time.Time, gracePeriod time.Duration) []ManagedInstance {
+	orphanIds := make(map[string]bool)
+	var expired []ManagedInstance
+	for _, orphan := range orphans {
+		orphanIds[orphan.InstanceId] = true
+		if _, ok := tracked[orphan.InstanceId]; !ok {
+			tracked[orphan.InstanceId] = &trackedOrphan{AbsentSince: now}
+		}
+		if now.Sub(tracked[orphan.InstanceId].AbsentSince) >= gracePeriod {
+			expired = append(expired, orphan)
+		}
+	}
+	for instanceId := range tracked {
+		if !orphanIds[instanceId] {
+			delete(tracked, instanceId)
+		}
+	}
+	return expired
+}
+
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Cluster Level config details
+//	usrCfg (config.UserConfig): User defined config for application behavior
+//
+// Description:
+//
+//	CleanUpOrphans reconciles the instances launched by the scaling manager with the nodes of the cluster and
+//	terminates the instances absent from the cluster for longer than the grace period, or only reports them in
+//	dry run. Nothing is done while a provision runs, as its new node has not joined the cluster yet. Every action
+//	is recorded in an OrphanCleanup document. It is called on the elected master.
+//
+// Return:
+func CleanUpOrphans(clusterCfg config.ClusterDetails, usrCfg config.UserConfig) {
+	cleanup := clusterCfg.OrphanCleanup
+	if !cleanup.Enabled || usrCfg.MonitorWithSimulator {
+		return
+	}
+	if !provisionLock.TryLock() {
+		log.Debug.Println("Provision is in progress, skipping the orphan clean up")
+		return
+	}
+	defer provisionLock.Unlock()
+	if err := state.GetCurrentState(); err != nil {
+		log.Error.Println("Unable to read the provisioning state, skipping the orphan clean up: ", err)
+		return
+	}
+	if state.CurrentState != "normal" {
+		log.Debug.Println("Provision is in progress, skipping the orphan clean up")
+		return
+	}
+	inventory, err := ReconcileInventory(clusterCfg)
+	if err != nil {
+		log.Error.Println("Unable to reconcile the instances with the nodes of the cluster: ", err)
+		return
+	}
+	cloudCredentials := clusterCfg.CloudCredentials
+	crypto.GetDecryptedCloudCreds(&cloudCredentials)
+	for _, orphan := range expiredOrphans(inventory.Orphans, trackedOrphans, time.Now(), cleanup.GracePeriod()) {
+		tracked := trackedOrphans[orphan.InstanceId]
+		if cleanup.DryRun || usrCfg.MonitorWithLogs {
+			if !tracked.Reported {
+				log.Warn.Println(fmt.Sprintf("The instance %s (%s) launched for %s is absent from the cluster since %s, it is not terminated in dry run", orphan.InstanceId, orphan.PrivateIp, orphan.LaunchReason, tracked.AbsentSince.Format(time.RFC3339)))
+				recordOrphanAction(orphan, orphanReported, tracked.AbsentSince, nil)
+				tracked.Reported = true
+			}
+			continue
+		}
+		log.Warn.Println(fmt.Sprintf("Terminating the instance %s (%s) launched for %s, absent from the cluster since %s", orphan.InstanceId, orphan.PrivateIp, orphan.LaunchReason, tracked.AbsentSince.Format(time.RFC3339)))
+		err := TerminateManagedInstance(orphan.InstanceId, inventory.ClusterUuid, cloudCredentials)
+		if err != nil {
+			log.Error.Println("Unable to terminate the orphaned instance ", orphan.InstanceId, ": ", err)
+			recordOrphanAction(orphan, orphanTerminateFailed, tracked.AbsentSince, err)
+			continue
+		}
+		recordOrphanAction(orphan, orphanTerminated, tracked.AbsentSince, nil)
+		delete(trackedOrphans, orphan.InstanceId)
+	}
+}
+
+// Input:
+//
+//	orphan (ManagedInstance): Orphaned instance
+//	action (string): Action taken on the instance, terminated, reported or terminate_failed
+//	absentSince (time.Time): Time the instance was first found absent from the cluster
+//	err (error): Error of the termination if any
+//
+// Description:
+//
+//	Adds a document to Opensearch recording the action taken on the orphaned instance
+//
+// Return:
+func recordOrphanAction(orphan ManagedInstance, action string, absentSince time.Time, err error) {
+	orphanCleanup := make(map[string]interface{})
+	orphanCleanup["InstanceId"] = orphan.InstanceId
+	orphanCleanup["PrivateIp"] = orphan.PrivateIp
+	orphanCleanup["InstanceState"] = orphan.State
+	orphanCleanup["LaunchReason"] = orphan.LaunchReason
+	if orphan.LaunchTime != "" {
+		orphanCleanup["LaunchTime"] = orphan.LaunchTime
+	}
+	orphanCleanup["AbsentSince"] = absentSince.UnixMilli()
+	orphanCleanup["Action"] = action
+	if err != nil {
+		orphanCleanup["FailureReason"] = err.Error()
+	}
+	orphanCleanup["StatTag"] = "OrphanCleanup"
+	orphanCleanup["_documentType"] = "OrphanCleanup"
+	orphanCleanup["Timestamp"] = time.Now().UnixMilli()
+
+	doc, err := json.Marshal(orphanCleanup)
+	if err != nil {
+		log.Error.Println("json.Marshal ERROR: ", err)
+		return
+	}
+	indexResponse, err := osutils.IndexMetrics(context.Background(), osutils.ProvisionStatsIndex, doc)
+	err = osutils.DecodeResponse(indexResponse, err, nil)
+	if err != nil {
+		log.Error.Println("Failed to insert the orphan clean up document: ", err)
+	}
+}
@@ -0,0 +1,29 @@
+package provision
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpiredOrphans(t *testing.T) {
+	start := time.Now()
+	gracePeriod := 30 * time.Minute
+	tracked := make(map[string]*trackedOrphan)
+	orphan1 := ManagedInstance{InstanceId: "i-1", PrivateIp: "10.0.0.1"}
+	orphan2 := ManagedInstance{InstanceId: "i-2", PrivateIp: "10.0.0.2"}
+
+	assert.Empty(t, expiredOrphans([]ManagedInstance{orphan1}, tracked, start, gracePeriod))
+	assert.Equal(t, start, tracked["i-1"].AbsentSince)
+
+	assert.Empty(t, expiredOrphans([]ManagedInstance{orphan1, orphan2}, tracked, start.Add(20*time.Minute), gracePeriod))
+	assert.Equal(t, []ManagedInstance{orphan1}, expiredOrphans([]ManagedInstance{orphan1, orphan2}, tracked, start.Add(30*time.Minute), gracePeriod))
+
+	// An instance which joined the cluster is not tracked anymore and starts over if it leaves again
+	assert.Equal(t, []ManagedInstance{orphan1}, expiredOrphans([]ManagedInstance{orphan1}, tracked, start.Add(40*time.Minute), gracePeriod))
+	assert.NotContains(t, tracked, "i-2")
+	assert.Empty(t, expiredOrphans([]ManagedInstance{orphan2}, tracked, start.Add(60*time.Minute), gracePeriod))
+	assert.Equal(t, start.Add(60*time.Minute), tracked["i-2"].AbsentSince)
+	assert.NotContains(t, tracked, "i-1")
+}
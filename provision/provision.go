@@ -3,7 +3,6 @@
 package provision
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -17,8 +16,9 @@ import (
 	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
 	utils "github.com/maplelabs/opensearch-scaling-manager/utilities"
 	"net/http"
-	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/maplelabs/opensearch-scaling-manager/logger"
@@ -40,6 +40,133 @@ func init() {
 	log.Info.Println("Provisioner module initiated")
 }
 
+// interruptedError is returned when a provision step could not read or persist the state in opensearch.
+// The state is left at the last persisted step so that the provision can be resumed.
+type interruptedError struct {
+	err error
+}
+
+// Input:
+//
+// Description:
+//
+//	Returns the message of the underlying error
+//
+// Return:
+//
+//	(string): Returns the error message
+func (e *interruptedError) Error() string {
+	return "provisioning interrupted: " + e.err.Error()
+}
+
+// Input:
+//
+// Description:
+//
+//	Returns the underlying error
+//
+// Return:
+//
+//	(error): Returns the underlying error
+func (e *interruptedError) Unwrap() error {
+	return e.err
+}
+
+// Input:
+//
+//	nextState (string): State reached by the provision
+//
+// Description:
+//
+//	Persists the step of the provision in the state, so that a new master resumes it from there
+//
+// Return:
+//
+//	(error): Returns an interrupted error if the state can't be updated
+func setProvisionState(nextState string) error {
+	state.PreviousState = state.CurrentState
+	state.CurrentState = nextState
+	if err := state.UpdateState(); err != nil {
+		return &interruptedError{err}
+	}
+	return nil
+}
+
+// A global variable which is set when the last provision was interrupted and has to be resumed.
+var interrupted atomic.Bool
+
+// Held while this node provisions or changes the provisioning state. The recommendations, the event based tasks,
+// the resumption of the provisions, the auto heal, the replacement of the interrupted spot instances and the
+// orphan clean up run in different goroutines, and each of them only starts if it gets the lock, so that one
+// provision runs at a time.
+var provisionLock sync.Mutex
+
+// Input:
+//
+// Description:
+//
+//	Reports if the last provision was interrupted as opensearch was unavailable and has to be resumed.
+//
+// Return:
+//
+//	(bool): Returns true if the provision has to be resumed
+func Interrupted() bool {
+	return interrupted.Load()
+}
+
+// Maximum time a playbook run by a provision may take
+const playbookTimeout = 30 * time.Minute
+
+// A global variable holding the summaries of the playbooks run by the current provision, added to its
+// ProvisionStats document
+var playbookSummaries []ansibleutils.PlaybookSummary
+
+// Input:
+//
+// Description:
+//
+//	Creates the context of a playbook run by the provision, which stops the playbook after playbookTimeout. The
+//	progress of the playbook is recorded in the state and its summary is added to the ProvisionStats document.
+//
+// Return:
+//
+//	(context.Context, context.CancelFunc): Returns the context and the function releasing it
+func playbookContext() (context.Context, context.CancelFunc) {
+	ctx := ansibleutils.WithPlaybookHooks(context.Background(), ansibleutils.PlaybookHooks{
+		Progress: trackPlaybookProgress,
+		Done: func(result *ansibleutils.PlaybookResult) {
+			playbookSummaries = append(playbookSummaries, result.Summary())
+		},
+	})
+	return context.WithTimeout(ctx, playbookTimeout)
+}
+
+// Input:
+//
+//	progress (ansibleutils.TaskProgress): Task which started or failed
+//
+// Description:
+//
+//	Records the task which is running, or the host it failed on with the error, in the state
+//
+// Return:
+func trackPlaybookProgress(progress ansibleutils.TaskProgress) {
+	switch progress.Event {
+	case ansibleutils.ProgressTaskStart:
+		state.CurrentTask = progress.Task
+	case ansibleutils.ProgressFailed, ansibleutils.ProgressUnreachable:
+		state.FailedHost = progress.Host
+		state.FailureMessage = progress.Message
+		log.Warn.Println(fmt.Sprintf("Task %q is %s on %s: %s", progress.Task, progress.Event, progress.Host, progress.Message))
+	default:
+		return
+	}
+	err := state.UpdateState()
+	if err != nil {
+		log.Warn.Println("Unable to record the progress of the playbook in the state: ", err)
+	}
+}
+
 // Input:
 //
 //	clusterCfg (config.ClusterDetails): Cluster Level config details
@@ -47,10 +174,11 @@ func init() {
 //	numNodes (int): Number of nodes to be scaled up/down
 //	operation (string): scaleup or scaledown operation
 //	RulesResponsible (string): A string that contains the rules responsible for the decision of operation being performed
+//	nodeGroup (string): The node group to be scaled, empty if node groups are not configured
 //
 // Description:
 //
-//	TriggerProvision will call scale in/out the cluster based on the operation.
+//	TriggerProvision will call scale in/out the cluster based on the operation. The caller holds the provision lock.
 //	ToDo:
 //	        Think about the scenario where event based scaling needs to be performed.
 //	        Morning need to scale up and evening need to scale down.
@@ -58,8 +186,12 @@ func init() {
 //	        May be we can keep a concept of minimum number of nodes as a configuration input.
 //
 // Return:
-func TriggerProvision(clusterCfg config.ClusterDetails, usrCfg config.UserConfig, numNodes int, t *time.Time, operation, RulesResponsible string) {
-	state.GetCurrentState()
+func TriggerProvision(clusterCfg config.ClusterDetails, usrCfg config.UserConfig, numNodes int, t *time.Time, operation, RulesResponsible, nodeGroup string) {
+	err := state.GetCurrentState()
+	if err != nil {
+		log.Error.Println("Unable to read the provisioning state, skipping the provision: ", err)
+		return
+	}
 	if operation == "scale_up" {
 		state.PreviousState = state.CurrentState
 		state.CurrentState = "provisioning_scaleup"
@@ -67,22 +199,14 @@ func TriggerProvision(clusterCfg config.ClusterDetails, usrCfg config.UserConfig
 		state.RemainingNodes = numNodes
 		state.RuleTriggered = "scale_up"
 		state.RulesResponsible = RulesResponsible
-		state.UpdateState()
-		isScaledUp, err := ScaleOut(clusterCfg, usrCfg, t)
-		if isScaledUp {
-			log.Info.Println("Scaleup successful")
-			PushToOs("Success", err)
-		} else {
-			log.Error.Println(err)
-			state.GetCurrentState()
-			// Add a retry mechanism
-			state.PreviousState = state.CurrentState
-			state.CurrentState = "provisioning_scaleup_failed"
-			state.UpdateState()
-			PushToOs("Failed", err)
+		state.NodeGroup = nodeGroup
+		err = state.UpdateState()
+		if err != nil {
+			log.Error.Println("Unable to update the provisioning state, skipping the provision: ", err)
+			return
 		}
-		// Set the state back to normal to continue further
-		SetStateBackToNormal()
+		isScaledUp, err := ScaleOut(clusterCfg, usrCfg, t)
+		completeProvision("scaleup", isScaledUp, err)
 	} else if operation == "scale_down" {
 		state.PreviousState = state.CurrentState
 		state.CurrentState = "provisioning_scaledown"
@@ -90,22 +214,111 @@ func TriggerProvision(clusterCfg config.ClusterDetails, usrCfg config.UserConfig
 		state.RemainingNodes = numNodes
 		state.RuleTriggered = "scale_down"
 		state.RulesResponsible = RulesResponsible
-		state.UpdateState()
+		state.NodeGroup = nodeGroup
+		err = state.UpdateState()
+		if err != nil {
+			log.Error.Println("Unable to update the provisioning state, skipping the provision: ", err)
+			return
+		}
 		isScaledDown, err := ScaleIn(clusterCfg, usrCfg, t)
-		if isScaledDown {
-			log.Info.Println("Scaledown successful")
-			PushToOs("Success", err)
-		} else {
-			log.Error.Println(err)
-			state.GetCurrentState()
-			// Add a retry mechanism
-			state.PreviousState = state.CurrentState
-			state.CurrentState = "provisioning_scaledown_failed"
-			state.UpdateState()
-			PushToOs("Failed", err)
+		completeProvision("scaledown", isScaledDown, err)
+	}
+}
+
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Cluster Level config details
+//	usrCfg (config.UserConfig): User defined config for applicatio behavior
+//
+// Description:
+//
+//	ResumeProvision continues the provision from the step persisted in the state. It is called when the master
+//	changed during a provision, for instance when the master was replaced by a vertical scaling, when the last
+//	provision was interrupted or when a rolling restart was requested.
+//
+// Return:
+func ResumeProvision(clusterCfg config.ClusterDetails, usrCfg config.UserConfig, t *time.Time) {
+	if !provisionLock.TryLock() {
+		log.Debug.Println("A provision is running on this node, it is not resumed")
+		return
+	}
+	defer provisionLock.Unlock()
+	err := state.GetCurrentState()
+	if err != nil {
+		log.Error.Println("Unable to read the provisioning state, resuming in the next cycle: ", err)
+		return
+	}
+	// The provision was already completed, only the state could not be set back to normal
+	if strings.HasSuffix(state.CurrentState, "_failed") || strings.HasSuffix(state.CurrentState, "_successfully") {
+		err = SetStateBackToNormal()
+		interrupted.Store(err != nil)
+		return
+	}
+	if strings.Contains(state.CurrentState, "scaleup") {
+		log.Debug.Println("Calling scaleOut")
+		isScaledUp, err := ScaleOut(clusterCfg, usrCfg, t)
+		completeProvision("scaleup", isScaledUp, err)
+	} else if strings.Contains(state.CurrentState, "scaledown") {
+		log.Debug.Println("Calling scaleIn")
+		isScaledDown, err := ScaleIn(clusterCfg, usrCfg, t)
+		completeProvision("scaledown", isScaledDown, err)
+	} else if strings.Contains(state.CurrentState, "rollingrestart") {
+		log.Debug.Println("Calling rollingRestart")
+		isRestarted, err := RollingRestart(clusterCfg, usrCfg)
+		completeProvision("rollingrestart", isRestarted, err)
+	} else if strings.Contains(state.CurrentState, "verticalscale") {
+		log.Debug.Println("Calling scaleVertical")
+		isScaled, err := ScaleVertical(clusterCfg, usrCfg, t)
+		completeProvision("verticalscale", isScaled, err)
+	} else if strings.Contains(state.CurrentState, "autoheal") {
+		log.Debug.Println("Calling replaceNode")
+		isReplaced, err := ReplaceNode(clusterCfg, usrCfg, t)
+		completeProvision("autoheal", isReplaced, err)
+	}
+}
+
+// Input:
+//
+//	operation (string): scaleup or scaledown operation
+//	isProvisioned (bool): True if the provision completed successfully
+//	err (error): Error if any during provisioning
+//
+// Description:
+//
+//	Records the result of the provision and sets the state back to normal. If the provision was interrupted
+//	the state is left as it is, so that the provision is resumed instead of being marked failed.
+//
+// Return:
+func completeProvision(operation string, isProvisioned bool, err error) {
+	var interruptErr *interruptedError
+	if errors.As(err, &interruptErr) {
+		log.Error.Println(err)
+		log.Warn.Println("The ", operation, " will be resumed once opensearch is available")
+		interrupted.Store(true)
+		return
+	}
+	interrupted.Store(false)
+	if isProvisioned {
+		log.Info.Println(operation, " successful")
+		PushToOs("Success", err)
+	} else {
+		log.Error.Println(err)
+		getErr := state.GetCurrentState()
+		if getErr != nil {
+			log.Error.Println("Unable to read the provisioning state: ", getErr)
+		}
+		// Add a retry mechanism
+		state.PreviousState = state.CurrentState
+		state.CurrentState = "provisioning_" + operation + "_failed"
+		updateErr := state.UpdateState()
+		if updateErr != nil {
+			log.Error.Println("Unable to mark the provisioning state failed: ", updateErr)
 		}
-		// Set the state back to normal to continue further
-		SetStateBackToNormal()
+		PushToOs("Failed", err)
+	}
+	// Set the state back to normal to continue further
+	if SetStateBackToNormal() != nil {
+		interrupted.Store(true)
 	}
 }
 
@@ -117,8 +330,8 @@ func TriggerProvision(clusterCfg config.ClusterDetails, usrCfg config.UserConfig
 // Description:
 //
 //	ScaleOut will scale out the cluster with the number of nodes.
-//	This function will invoke commands to create a VM based on cloud type.
-//	Then it will configure the opensearch on newly created nodes.
+//	This function will invoke commands to create a VM based on cloud type, using the launch template of the node group.
+//	Then it will configure the opensearch on newly created nodes with the roles and attributes of the node group.
 //
 // Return:
 //
@@ -126,7 +339,13 @@ func TriggerProvision(clusterCfg config.ClusterDetails, usrCfg config.UserConfig
 func ScaleOut(clusterCfg config.ClusterDetails, usrCfg config.UserConfig, t *time.Time) (bool, error) {
 	// Read the current state of scaleup process and proceed with next step
 	// If no stage was already set. The function returns an empty string. Then, start the scaleup process
-	state.GetCurrentState()
+	if err := state.GetCurrentState(); err != nil {
+		return false, &interruptedError{err}
+	}
+	nodeGroup, err := clusterCfg.GetNodeGroup(state.NodeGroup)
+	if err != nil {
+		return false, err
+	}
 	crypto.GetDecryptedCloudCreds(&clusterCfg.CloudCredentials)
 	crypto.GetDecryptedOsCreds(&clusterCfg.OsCredentials)
 	var newNodeIp, newInstanceId string
@@ -143,7 +362,9 @@ func ScaleOut(clusterCfg config.ClusterDetails, usrCfg config.UserConfig, t *tim
 		state.PreviousState = state.CurrentState
 		state.CurrentState = "start_scaleup_process"
 		state.ProvisionStartTime = time.Now().UnixMilli()
-		state.UpdateState()
+		if err := state.UpdateState(); err != nil {
+			return false, &interruptedError{err}
+		}
 		fallthrough
 		// Spin new VMs based on number of nodes and cloud type
 	case "start_scaleup_process":
@@ -160,7 +381,7 @@ func ScaleOut(clusterCfg config.ClusterDetails, usrCfg config.UserConfig, t *tim
 			}
 		} else {
 			var err error
-			newNodeIp, newInstanceId, err = SpinNewVm(clusterCfg.LaunchTemplateId, clusterCfg.LaunchTemplateVersion, clusterCfg.CloudCredentials)
+			newNodeIp, newInstanceId, err = spinNewNode(clusterCfg, nodeGroup, nodeGroup.LaunchTemplateVersion, "")
 			if err != nil {
 				return false, err
 			}
@@ -170,12 +391,16 @@ func ScaleOut(clusterCfg config.ClusterDetails, usrCfg config.UserConfig, t *tim
 		state.InstanceId = newInstanceId
 		state.PreviousState = state.CurrentState
 		state.CurrentState = "scaleup_triggered_spin_vm"
-		state.UpdateState()
+		if err := state.UpdateState(); err != nil {
+			return false, &interruptedError{err}
+		}
 		fallthrough
 	// Add the newly added VM to the list of VMs
 	// Configure OS on newly created VM
 	case "scaleup_triggered_spin_vm":
-		state.GetCurrentState()
+		if err := state.GetCurrentState(); err != nil {
+			return false, &interruptedError{err}
+		}
 		newNodeIp = state.NodeIp
 		newInstanceId = state.InstanceId
 		if monitorWithLogs {
@@ -194,136 +419,380 @@ func ScaleOut(clusterCfg config.ClusterDetails, usrCfg config.UserConfig, t *tim
 				fakeSleep(t)
 			}
 		} else {
-			statusErr := InstanceStatusCheck(newInstanceId, clusterCfg.CloudCredentials)
-			if statusErr != nil {
-				log.Error.Println("Instance status is still not okay.. Terminating the instance")
-				terminateErr := TerminateInstance(newNodeIp, clusterCfg.CloudCredentials)
-				if terminateErr != nil {
-					log.Fatal.Println(terminateErr)
-				}
-				return false, statusErr
-			}
-
-			// Install scaling manager on new node
-			log.Info.Println("Installing scaling manager on new node")
-			hostsFile := "ansible_scripts/install_hosts"
-			fr, fErr := os.OpenFile(hostsFile, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
-			if fErr != nil {
-				log.Fatal.Println(fErr)
-				return false, fErr
-			}
-			defer fr.Close()
-			newDataWriter := bufio.NewWriter(fr)
-			newDataWriter.WriteString("[new_node]\n")
-			newDataWriter.WriteString("node-" + strings.ReplaceAll(newNodeIp, ".", "-") + " ansible_user=" + clusterCfg.SshUser + " roles=master,data,ingest ansible_private_host=" + newNodeIp + " ansible_ssh_private_key_file=" + clusterCfg.CloudCredentials.PemFilePath + "\n")
-			newDataWriter.Flush()
-			ansiblerr := ansibleutils.UpdateWithTags(hostsFile, clusterCfg, []string{"add_host", "install"})
-			if ansiblerr != nil {
-				log.Error.Println(ansiblerr)
-				log.Error.Println("Node scaled up but unable to install scaling manager on new node. Please check ansible logs for more details. (logs/playbook.log)")
-			}
-
-			// Configure opensearch on new node
-			log.Info.Println("Configuring Opensearch on new node...")
-			hostsFileName := "ansible_scripts/hosts"
-			username := clusterCfg.SshUser
-			f, err := os.OpenFile(hostsFileName, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+			err := setUpNewNode(clusterCfg, nodeGroup, newNodeIp, newInstanceId)
 			if err != nil {
-				log.Fatal.Println(err)
 				return false, err
 			}
-			defer f.Close()
-			nodes := utils.GetNodes()
-			dataWriter := bufio.NewWriter(f)
-			dataWriter.WriteString("[current_nodes]\n")
-			for _, nodeIdMap := range nodes {
-				_, writeErr := dataWriter.WriteString(nodeIdMap.(map[string]string)["name"] + " ansible_user=" + username + " roles=master,data,ingest ansible_private_host=" + nodeIdMap.(map[string]string)["hostIp"] + " ansible_ssh_private_key_file=" + clusterCfg.CloudCredentials.PemFilePath + "\n")
-				if writeErr != nil {
-					log.Error.Println("Error writing the node data into hosts file", writeErr)
-				}
-			}
-			dataWriter.WriteString("[new_node]\n")
-			dataWriter.WriteString("node-" + strings.ReplaceAll(newNodeIp, ".", "-") + " ansible_user=" + username + " roles=master,data,ingest ansible_private_host=" + newNodeIp + " ansible_ssh_private_key_file=" + clusterCfg.CloudCredentials.PemFilePath + "\n")
-			dataWriter.Flush()
-			ansibleErr := ansibleutils.CallAnsible(username, hostsFileName, clusterCfg, "scale_up")
-			if ansibleErr != nil {
-				if newNodeIp != "" {
-					log.Warn.Println("Terminating the instance as the ansible script failed.")
-					terminateErr := TerminateInstance(newNodeIp, clusterCfg.CloudCredentials)
-					if terminateErr != nil {
-						log.Fatal.Println(terminateErr)
-					}
-				}
-				return false, ansibleErr
-			}
 		}
 		state.PreviousState = state.CurrentState
 		state.CurrentState = "provisioning_scaleup_configured"
-		state.UpdateState()
+		if err := state.UpdateState(); err != nil {
+			return false, &interruptedError{err}
+		}
 		fallthrough
 	case "provisioning_scaleup_configured":
-		state.GetCurrentState()
-		newNodeIp = state.NodeIp
-		// Check if node has joined the cluster
-		log.Info.Println("Waiting for new node to join the cluster...")
-		var joined bool
-		// Wait for 10 minutes in the interval of 5 seconds for the node to join the cluster
-		for i := 0; i < 120; i++ {
-			nodesInfo := utils.GetNodes()
-			for _, nodeIdInfo := range nodesInfo {
-				if nodeIdInfo.(map[string]string)["hostIp"] == newNodeIp {
-					joined = true
-					break
-				}
-			}
-			if joined {
-				break
-			}
-			log.Info.Println("Waiting for new node to join the cluster...")
-			time.Sleep(5 * time.Second)
+		if err := state.GetCurrentState(); err != nil {
+			return false, &interruptedError{err}
 		}
-
-		if !joined {
-			errMsg := "The new node doesn't seem to have joined the cluster. Please login into new node and check for opensearch logs for more details."
-			return joined, errors.New(errMsg)
-		}
-
-		// Start scaling manager on new node
-		hostsFileName := "ansible_scripts/install_hosts"
-		f, err := os.OpenFile(hostsFileName, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+		newNodeIp = state.NodeIp
+		err := waitForNewNodeToJoin(clusterCfg, newNodeIp)
 		if err != nil {
-			log.Fatal.Println(err)
 			return false, err
 		}
-		defer f.Close()
-		dataWriter := bufio.NewWriter(f)
-		dataWriter.WriteString("[new_node]\n")
-		dataWriter.WriteString("node-" + strings.ReplaceAll(newNodeIp, ".", "-") + " ansible_user=" + clusterCfg.SshUser + " roles=master,data,ingest ansible_private_host=" + newNodeIp + " ansible_ssh_private_key_file=" + clusterCfg.CloudCredentials.PemFilePath + "\n")
-		dataWriter.Flush()
-
-		ansibleErr := ansibleutils.UpdateWithTags(hostsFileName, clusterCfg, []string{"update_config", "update_pem", "update_secret", "start"})
-		if ansibleErr != nil {
-			log.Error.Println(ansibleErr)
-			log.Error.Println("Node scaled up but unable to start scaling manager on new node. Please check ansible logs for more details. (logs/playbook.log)")
-		}
+		startScalingManagerOnNewNode(clusterCfg, nodeGroup, newNodeIp)
 		state.PreviousState = state.CurrentState
 		state.CurrentState = "provisioning_scaleup_completed"
-		state.UpdateState()
+		if err := state.UpdateState(); err != nil {
+			return false, &interruptedError{err}
+		}
 		fallthrough
 	// Check cluster status after the configuration
 	case "provisioning_scaleup_completed":
 		if simFlag {
-			SimulateSharRebalancing("scaleOut", state.NumNodes, isAccelerated)
+			err := SimulateSharRebalancing("scaleOut", state.NumNodes, isAccelerated)
+			if err != nil {
+				return false, err
+			}
 		}
 		log.Info.Println("Waiting for the cluster to become healthy")
 		if simFlag && isAccelerated {
 			fakeSleep(t)
 		}
-		CheckClusterHealth(usrCfg, t)
+		if err := CheckClusterHealth(usrCfg, t); err != nil {
+			return false, &interruptedError{err}
+		}
 	}
 	return true, nil
 }
 
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Cluster Level config details with the decrypted credentials
+//	nodeGroup (config.NodeGroup): Node group of the new node
+//	launchTemplateVersion (string): Version of the launch template of the node group the node is launched from
+//	replacedIp (string): Private ip of the node the new node replaces, empty if no node is replaced
+//
+// Description:
+//
+//	Spins a new node of the node group. With the user_data provisioning mode the bootstrap script configuring
+//	opensearch is passed in the user data of the node, and the secrets it fetches when it boots are stored in the
+//	SSM Parameter Store. A spot instance is launched when the spot options of the node group call for one, falling
+//	back to an on-demand instance if allowed when no spot capacity is available.
+//
+// Return:
+//
+//	(string, string, error): Returns the private ip address, instance ID of the spinned node and error if any
+func spinNewNode(clusterCfg config.ClusterDetails, nodeGroup config.NodeGroup, launchTemplateVersion string, replacedIp string) (string, string, error) {
+	clusterUuid, err := utils.GetClusterId()
+	if err != nil {
+		return "", "", &interruptedError{err}
+	}
+	var userData []byte
+	if clusterCfg.ProvisioningMode == "user_data" {
+		inventory, err := newNodeInventory("", nodeGroup)
+		if err != nil {
+			return "", "", &interruptedError{err}
+		}
+		bootstrap, err := ansibleutils.RenderUserData(context.Background(), inventory, clusterCfg, bootstrapSecretPath(clusterUuid))
+		if err != nil {
+			return "", "", err
+		}
+		err = PutBootstrapSecrets(bootstrap.Secrets, clusterCfg.CloudCredentials)
+		if err != nil {
+			deleteBootstrapSecrets(clusterCfg)
+			return "", "", err
+		}
+		userData = bootstrap.Script
+		log.Info.Println("Spinning the new node with a bootstrap script in its user data")
+	}
+	newNodeIp, newInstanceId, err := launchNewNode(clusterCfg, nodeGroup, launchTemplateVersion, replacedIp, userData, clusterUuid)
+	if err != nil && len(userData) > 0 {
+		deleteBootstrapSecrets(clusterCfg)
+	}
+	return newNodeIp, newInstanceId, err
+}
+
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Cluster Level config details with the decrypted credentials
+//	nodeGroup (config.NodeGroup): Node group of the new node
+//	launchTemplateVersion (string): Version of the launch template of the node group the node is launched from
+//	replacedIp (string): Private ip of the node the new node replaces, empty if no node is replaced
+//	userData ([]byte): Bootstrap script of the new node, empty if the node is configured over SSH
+//	clusterUuid (string): UUID of the opensearch cluster the instance is tagged with
+//
+// Description:
+//
+//	Launches the instance of the new node as a spot or an on-demand instance
+//
+// Return:
+//
+//	(string, string, error): Returns the private ip address, instance ID of the spinned node and error if any
+func launchNewNode(clusterCfg config.ClusterDetails, nodeGroup config.NodeGroup, launchTemplateVersion string, replacedIp string, userData []byte, clusterUuid string) (string, string, error) {
+	var spot *config.Spot
+	if nodeGroup.Spot.SpotPercentage > 0 {
+		onDemandNodes, spotNodes, err := countLifecycles(clusterCfg, nodeGroup, replacedIp)
+		if err != nil {
+			return "", "", err
+		}
+		if launchAsSpot(nodeGroup.Spot, onDemandNodes, spotNodes) {
+			spot = &nodeGroup.Spot
+		}
+		log.Info.Println(fmt.Sprintf("The node group has %d on-demand and %d spot nodes, launching a spot instance: %t", onDemandNodes, spotNodes, spot != nil))
+	}
+	newNodeIp, newInstanceId, err := SpinNewVm(nodeGroup.LaunchTemplateId, launchTemplateVersion, userData, spot, clusterUuid, state.RuleTriggered, clusterCfg.CloudCredentials)
+	if err != nil && spot != nil && nodeGroup.Spot.OnDemandFallback && isSpotCapacityError(err) {
+		log.Warn.Println("Unable to launch a spot instance, launching an on-demand instance: ", err)
+		return SpinNewVm(nodeGroup.LaunchTemplateId, launchTemplateVersion, userData, nil, clusterUuid, state.RuleTriggered, clusterCfg.CloudCredentials)
+	}
+	return newNodeIp, newInstanceId, err
+}
+
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Cluster Level config details with the decrypted credentials
+//	nodeGroup (config.NodeGroup): Node group of the new node
+//	newNodeIp (string): Private ip of the new node
+//	newInstanceId (string): Instance ID of the new node
+//
+// Description:
+//
+//	Waits for the status of the new instance to be ok and configures opensearch on it, unless the node bootstraps
+//	itself from its user data. The new node is terminated if its instance status is not ok.
+//
+// Return:
+//
+//	(error): Returns error if the new node can't be configured
+func setUpNewNode(clusterCfg config.ClusterDetails, nodeGroup config.NodeGroup, newNodeIp string, newInstanceId string) error {
+	statusErr := InstanceStatusCheck(newInstanceId, clusterCfg.CloudCredentials)
+	if statusErr != nil {
+		log.Error.Println("Instance status is still not okay.. Terminating the instance")
+		terminateErr := terminateNodeInstance(newNodeIp, clusterCfg.CloudCredentials)
+		if terminateErr != nil {
+			log.Fatal.Println(terminateErr)
+		}
+		if clusterCfg.ProvisioningMode == "user_data" {
+			deleteBootstrapSecrets(clusterCfg)
+		}
+		return statusErr
+	}
+
+	if clusterCfg.ProvisioningMode == "user_data" {
+		log.Info.Println("The new node bootstraps itself from its user data, skipping its configuration over SSH")
+		return nil
+	}
+	return configureNewNode(clusterCfg, nodeGroup, newNodeIp)
+}
+
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Cluster Level config details
+//	newNodeIp (string): Private ip of the new node
+//
+// Description:
+//
+//	Waits for the new node to join the cluster, up to 10 minutes or 20 minutes for a node which installs
+//	opensearch while it boots. The secrets of a node bootstrapping from its user data are deleted once it joined
+//	or did not join in time.
+//
+// Return:
+//
+//	(error): Returns error if the node did not join the cluster in time
+func waitForNewNodeToJoin(clusterCfg config.ClusterDetails, newNodeIp string) error {
+	if clusterCfg.ProvisioningMode == "user_data" {
+		defer deleteBootstrapSecrets(clusterCfg)
+	}
+	log.Info.Println("Waiting for new node to join the cluster...")
+	// Wait for 10 minutes in the interval of 5 seconds for the node to join the cluster, or 20 minutes for a
+	// node which installs opensearch while it boots
+	joinAttempts := 120
+	if clusterCfg.ProvisioningMode == "user_data" {
+		joinAttempts = 240
+	}
+	for i := 0; i < joinAttempts; i++ {
+		nodesInfo, err := utils.GetNodes()
+		if err != nil {
+			log.Warn.Println("Unable to fetch the nodes of the cluster: ", err)
+		}
+		for _, nodeIdInfo := range nodesInfo {
+			if nodeIdInfo.Host == newNodeIp {
+				return nil
+			}
+		}
+		log.Info.Println("Waiting for new node to join the cluster...")
+		time.Sleep(5 * time.Second)
+	}
+
+	errMsg := "The new node doesn't seem to have joined the cluster. Please login into new node and check for opensearch logs for more details."
+	if clusterCfg.ProvisioningMode == "user_data" {
+		errMsg += " The bootstrap script logs to " + ansibleutils.BootstrapLogFile + " on the new node."
+	}
+	return errors.New(errMsg)
+}
+
+// Input:
+//
+//	privateIp (string): Private ip of the node
+//	cred (config.CloudCredentials): Cloud credentials required to connect to AWS account
+//
+// Description:
+//
+//	Terminates the instance of the node, if it was launched by the scaling manager for the cluster
+//
+// Return:
+//
+//	(error): Returns error if the UUID of the cluster can't be read or the instance can't be terminated
+func terminateNodeInstance(privateIp string, cred config.CloudCredentials) error {
+	clusterUuid, err := utils.GetClusterId()
+	if err != nil {
+		return err
+	}
+	return TerminateInstance(privateIp, clusterUuid, cred)
+}
+
+// Input:
+//
+//	privateIps ([]string): Private ips of the nodes
+//	cred (config.CloudCredentials): Cloud credentials required to connect to AWS account
+//
+// Description:
+//
+//	Finds the instances of the nodes which were launched by the scaling manager for the cluster and can be
+//	terminated
+//
+// Return:
+//
+//	(map[string]string, error): Returns the instance IDs by private ip and error if any
+func managedNodeInstanceIds(privateIps []string, cred config.CloudCredentials) (map[string]string, error) {
+	clusterUuid, err := utils.GetClusterId()
+	if err != nil {
+		return nil, err
+	}
+	return ManagedInstanceIds(privateIps, clusterUuid, cred)
+}
+
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Cluster Level config details with the decrypted credentials
+//
+// Description:
+//
+//	Deletes the secrets stored for the new nodes bootstrapping from their user data. Failures are only logged, the
+//	secrets left behind are deleted after the next node bootstrapping from its user data.
+//
+// Return:
+func deleteBootstrapSecrets(clusterCfg config.ClusterDetails) {
+	clusterUuid, err := utils.GetClusterId()
+	if err == nil {
+		err = DeleteBootstrapSecrets(clusterUuid, clusterCfg.CloudCredentials)
+	}
+	if err != nil {
+		log.Error.Println("Unable to delete the secrets of the new node from the parameter store: ", err)
+	}
+}
+
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Cluster Level config details with the decrypted credentials
+//	nodeGroup (config.NodeGroup): Node group of the new node
+//	newNodeIp (string): Private ip of the new node
+//
+// Description:
+//
+//	Copies the config, pem file and secret to the new node and starts the scaling manager on it. Failures are
+//	only logged as the node is already part of the cluster.
+//
+// Return:
+func startScalingManagerOnNewNode(clusterCfg config.ClusterDetails, nodeGroup config.NodeGroup, newNodeIp string) {
+	if clusterCfg.ProvisioningMode == "user_data" {
+		log.Warn.Println("The scaling manager is not started on the new node as it is not reachable over SSH. Install it in the image of the launch template to monitor the node from it.")
+		return
+	}
+	inventory := ansibleutils.NewAnsibleInventory(clusterCfg).AddGroup(ansibleutils.NewNodeGroup, ansibleutils.NewNodeHost(newNodeIp, nodeGroup))
+	ctx, cancel := playbookContext()
+	defer cancel()
+	_, ansibleErr := ansibleutils.UpdateWithTags(ctx, inventory, clusterCfg, []string{"update_config", "update_pem", "update_secret", "start"})
+	if ansibleErr != nil {
+		log.Error.Println(ansibleErr)
+		log.Error.Println("Node scaled up but unable to start scaling manager on new node. Please check ansible logs for more details. (logs/playbook.log)")
+	}
+}
+
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Cluster Level config details with the decrypted credentials
+//	nodeGroup (config.NodeGroup): Node group of the new node
+//	newNodeIp (string): Private ip of the new node
+//
+// Description:
+//
+//	Installs the scaling manager on the new node with the install playbook, then configures opensearch on it with
+//	the node configurator of the config. The new node is terminated if its configuration failed.
+//
+// Return:
+//
+//	(error): Returns error if the new node can't be configured
+func configureNewNode(clusterCfg config.ClusterDetails, nodeGroup config.NodeGroup, newNodeIp string) error {
+	// Install scaling manager on new node
+	log.Info.Println("Installing scaling manager on new node")
+	installInventory := ansibleutils.NewAnsibleInventory(clusterCfg).AddGroup(ansibleutils.NewNodeGroup, ansibleutils.NewNodeHost(newNodeIp, nodeGroup))
+	installCtx, cancelInstall := playbookContext()
+	defer cancelInstall()
+	_, ansiblerr := ansibleutils.UpdateWithTags(installCtx, installInventory, clusterCfg, []string{"add_host", "install"})
+	if ansiblerr != nil {
+		log.Error.Println(ansiblerr)
+		log.Error.Println("Node scaled up but unable to install scaling manager on new node. Please check ansible logs for more details. (logs/playbook.log)")
+	}
+
+	// Configure opensearch on new node
+	configurator, err := ansibleutils.NewNodeConfigurator(clusterCfg.NodeConfigurator)
+	if err != nil {
+		return err
+	}
+	log.Info.Println(fmt.Sprintf("Configuring Opensearch on new node with the %s node configurator...", configurator.Name()))
+	inventory, err := newNodeInventory(newNodeIp, nodeGroup)
+	if err != nil {
+		return &interruptedError{err}
+	}
+	ctx, cancel := playbookContext()
+	defer cancel()
+	_, configureErr := configurator.ConfigureNewNode(ctx, inventory, clusterCfg)
+	if configureErr != nil {
+		if newNodeIp != "" {
+			log.Warn.Println("Terminating the instance as the configuration of the new node failed.")
+			terminateErr := terminateNodeInstance(newNodeIp, clusterCfg.CloudCredentials)
+			if terminateErr != nil {
+				log.Fatal.Println(terminateErr)
+			}
+		}
+		return configureErr
+	}
+	return nil
+}
+
+// Input:
+//
+//	newNodeIp (string): Private ip of the new node, empty if the node is not spinned yet
+//	nodeGroup (config.NodeGroup): Node group of the new node
+//
+// Description:
+//
+//	Lists the current nodes of the cluster and the new node with the roles and attributes of its node group
+//
+// Return:
+//
+//	(ansibleutils.Inventory, error): Returns the inventory and error if the nodes can't be fetched
+func newNodeInventory(newNodeIp string, nodeGroup config.NodeGroup) (ansibleutils.Inventory, error) {
+	nodes, err := utils.GetNodes()
+	if err != nil {
+		return ansibleutils.Inventory{}, err
+	}
+	return ansibleutils.Inventory{
+		CurrentNodes: ansibleutils.NodeHosts(nodes),
+		NewNode:      ansibleutils.NewNodeHost(newNodeIp, nodeGroup),
+	}, nil
+}
+
 // Input:
 //
 //	clusterCfg (config.ClusterDetails): Cluster Level config details
@@ -332,7 +801,7 @@ func ScaleOut(clusterCfg config.ClusterDetails, usrCfg config.UserConfig, t *tim
 // Description:
 //
 //	ScaleIn will scale in the cluster with the number of nodes.
-//	This function will invoke commands to remove a node from opensearch cluster.
+//	This function will invoke commands to remove a node of the node group from opensearch cluster.
 //
 // Return:
 //
@@ -342,9 +811,15 @@ func ScaleIn(clusterCfg config.ClusterDetails, usrCfg config.UserConfig, t *time
 	// If no stage was already set. The function returns an empty string. Then, start the scaledown process
 	crypto.GetDecryptedCloudCreds(&clusterCfg.CloudCredentials)
 	crypto.GetDecryptedOsCreds(&clusterCfg.OsCredentials)
-	state.GetCurrentState()
+	if err := state.GetCurrentState(); err != nil {
+		return false, &interruptedError{err}
+	}
+	nodeGroup, err := clusterCfg.GetNodeGroup(state.NodeGroup)
+	if err != nil {
+		return false, err
+	}
 	var removeNodeIp, removeNodeName string
-	var nodes map[string]interface{}
+	var nodes map[string]osutils.NodeStats
 	monitorWithLogs := usrCfg.MonitorWithLogs
 	simFlag := usrCfg.MonitorWithSimulator
 	isAccelerated := usrCfg.IsAccelerated
@@ -353,7 +828,9 @@ func ScaleIn(clusterCfg config.ClusterDetails, usrCfg config.UserConfig, t *time
 		state.PreviousState = state.CurrentState
 		state.CurrentState = "start_scaledown_process"
 		state.ProvisionStartTime = time.Now().UnixMilli()
-		state.UpdateState()
+		if err := state.UpdateState(); err != nil {
+			return false, &interruptedError{err}
+		}
 	}
 	// Identify the node which can be removed from the cluster.
 	switch state.CurrentState {
@@ -365,25 +842,73 @@ func ScaleIn(clusterCfg config.ClusterDetails, usrCfg config.UserConfig, t *time
 				fakeSleep(t)
 			}
 		} else {
-			nodes = utils.GetNodes()
-			for nodeId, nodeIdInfo := range nodes {
-				if !(utils.CheckIfMaster(context.Background(), nodeId)) {
-					removeNodeIp = nodeIdInfo.(map[string]string)["hostIp"]
-					removeNodeName = nodeIdInfo.(map[string]string)["name"]
-					break
+			nodes, err = utils.GetNodes()
+			if err != nil {
+				return false, &interruptedError{err}
+			}
+			masterNodeId, err := utils.GetMasterNodeId(context.Background())
+			if err != nil {
+				return false, &interruptedError{err}
+			}
+			// Only a node whose instance can be terminated is drained, so that no instance is left running
+			var privateIps []string
+			for _, node := range nodes {
+				if nodeGroup.HasNode(node) {
+					privateIps = append(privateIps, node.Host)
 				}
 			}
+			instanceIds, err := managedNodeInstanceIds(privateIps, clusterCfg.CloudCredentials)
+			if err != nil {
+				return false, err
+			}
+			removeNode, err := utils.SelectNodeToRemove(nodes, nodeGroup, masterNodeId, instanceIds)
+			if err != nil {
+				return false, err
+			}
+			removeNodeIp = removeNode.Host
+			removeNodeName = removeNode.Name
 		}
 		state.NodeIp = removeNodeIp
 		state.NodeName = removeNodeName
 		log.Info.Println("Node identified for removal: ", removeNodeName, removeNodeIp)
 		state.PreviousState = state.CurrentState
 		state.CurrentState = "scaledown_node_identified"
-		state.UpdateState()
+		if err := state.UpdateState(); err != nil {
+			return false, &interruptedError{err}
+		}
 		fallthrough
-	// Configure OS to tell master node that the present node is going to be removed
+	// Move the indices allocated to the node of a data tier off the node
 	case "scaledown_node_identified":
-		state.GetCurrentState()
+		if err := state.GetCurrentState(); err != nil {
+			return false, &interruptedError{err}
+		}
+		if nodeGroup.Tier != "" {
+			if monitorWithLogs {
+				log.Info.Println("Migrate the allocation of the indices pinned to the node")
+			} else {
+				// The nodes are not fetched yet when the scale down is resumed at this step
+				if nodes == nil {
+					nodes, err = utils.GetNodes()
+					if err != nil {
+						return false, &interruptedError{err}
+					}
+				}
+				if err := migrateIndexAllocation(context.Background(), nodes, state.NodeName, nodeGroup.Tier); err != nil {
+					return false, err
+				}
+			}
+		}
+		state.PreviousState = state.CurrentState
+		state.CurrentState = "scaledown_allocation_migrated"
+		if err := state.UpdateState(); err != nil {
+			return false, &interruptedError{err}
+		}
+		fallthrough
+	// Configure OS to tell master node that the present node is going to be removed
+	case "scaledown_allocation_migrated":
+		if err := state.GetCurrentState(); err != nil {
+			return false, &interruptedError{err}
+		}
 		removeNodeIp = state.NodeIp
 		removeNodeName = state.NodeName
 		if monitorWithLogs {
@@ -399,58 +924,54 @@ func ScaleIn(clusterCfg config.ClusterDetails, usrCfg config.UserConfig, t *time
 			}
 		} else {
 			log.Info.Println("Configuring to remove the node from cluster through ansible")
-			hostsFileName := "ansible_scripts/hosts"
-			username := clusterCfg.SshUser
-			f, err := os.OpenFile(hostsFileName, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
-			if err != nil {
-				log.Error.Println(err)
-				return false, err
-			}
-			defer f.Close()
-			dataWriter := bufio.NewWriter(f)
-			dataWriter.WriteString("[current_nodes]\n")
-			for _, nodeIdInfo := range nodes {
-				if nodeIdInfo.(map[string]string)["hostIp"] != removeNodeIp {
-					_, writeErr := dataWriter.WriteString(nodeIdInfo.(map[string]string)["name"] + " ansible_user=" + username + " roles=master,data,ingest ansible_private_host=" + nodeIdInfo.(map[string]string)["hostIp"] + " ansible_ssh_private_key_file=" + clusterCfg.CloudCredentials.PemFilePath + "\n")
-					if writeErr != nil {
-						log.Error.Println("Error writing the node data into hosts file", writeErr)
-					}
+			// The nodes are not fetched yet when the scale down is resumed at this step
+			if nodes == nil {
+				nodes, err = utils.GetNodes()
+				if err != nil {
+					return false, &interruptedError{err}
 				}
 			}
-			dataWriter.WriteString("[remove_node]\n")
-			dataWriter.WriteString(removeNodeName + " ansible_user=" + username + " roles=master,data,ingest ansible_private_host=" + removeNodeIp + " ansible_ssh_private_key_file=" + clusterCfg.CloudCredentials.PemFilePath + "\n")
-			dataWriter.Flush()
-			log.Info.Println("Removing node ***********************************:", removeNodeName)
-			ansibleErr := ansibleutils.CallAnsible(username, hostsFileName, clusterCfg, "scale_down")
-			if ansibleErr != nil {
-				return false, ansibleErr
+			err = removeNodeFromCluster(clusterCfg, nodes, nodeGroup, removeNodeName, removeNodeIp)
+			if err != nil {
+				return false, err
 			}
 		}
 		state.PreviousState = state.CurrentState
 		state.CurrentState = "provisioned_scaledown_on_cluster"
-		state.UpdateState()
+		if err := state.UpdateState(); err != nil {
+			return false, &interruptedError{err}
+		}
 		fallthrough
 	case "provisioned_scaledown_on_cluster":
-		state.GetCurrentState()
+		if err := state.GetCurrentState(); err != nil {
+			return false, &interruptedError{err}
+		}
 		removeNodeIp = state.NodeIp
 		log.Info.Println("Terminating the instance")
-		terminateErr := TerminateInstance(removeNodeIp, clusterCfg.CloudCredentials)
+		terminateErr := terminateNodeInstance(removeNodeIp, clusterCfg.CloudCredentials)
 		if terminateErr != nil {
 			log.Fatal.Println(terminateErr)
 			return false, terminateErr
 		}
 		state.PreviousState = state.CurrentState
 		state.CurrentState = "provisioning_scaledown_completed"
-		state.UpdateState()
+		if err := state.UpdateState(); err != nil {
+			return false, &interruptedError{err}
+		}
 		fallthrough
 	// Wait for cluster to be in stable state(Shard rebalance)
 	// Shut down the node
 	case "provisioning_scaledown_completed":
 		if simFlag {
-			SimulateSharRebalancing("scaleIn", state.NumNodes, isAccelerated)
+			err := SimulateSharRebalancing("scaleIn", state.NumNodes, isAccelerated)
+			if err != nil {
+				return false, err
+			}
 		}
 		log.Info.Println("Wait for the cluster to become healthy and then proceed")
-		CheckClusterHealth(usrCfg, t)
+		if err := CheckClusterHealth(usrCfg, t); err != nil {
+			return false, &interruptedError{err}
+		}
 		if simFlag && isAccelerated {
 			fakeSleep(t)
 		}
@@ -458,6 +979,96 @@ func ScaleIn(clusterCfg config.ClusterDetails, usrCfg config.UserConfig, t *time
 	return true, nil
 }
 
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Cluster Level config details with the decrypted credentials
+//	nodes (map[string]osutils.NodeStats): Nodes currently present in the cluster
+//	nodeGroup (config.NodeGroup): Node group of the node to be removed
+//	removeNodeName (string): Name of the node to be removed
+//	removeNodeIp (string): Private ip of the node to be removed
+//
+// Description:
+//
+//	Runs the scale_down playbook, which excludes the node from the allocation, waits for its shards to be moved
+//	to the other nodes and stops opensearch on it
+//
+// Return:
+//
+//	(error): Returns error if the playbook failed
+func removeNodeFromCluster(clusterCfg config.ClusterDetails, nodes map[string]osutils.NodeStats, nodeGroup config.NodeGroup, removeNodeName string, removeNodeIp string) error {
+	removeNode := ansibleutils.Host{Name: removeNodeName, Ip: removeNodeIp, Roles: nodeGroup.Roles}
+	var currentNodes []ansibleutils.Host
+	for _, host := range ansibleutils.NodeHosts(nodes) {
+		if host.Ip != removeNodeIp {
+			currentNodes = append(currentNodes, host)
+		} else {
+			removeNode = host
+		}
+	}
+	inventory := ansibleutils.NewAnsibleInventory(clusterCfg).
+		AddGroup(ansibleutils.CurrentNodesGroup, currentNodes...).
+		AddGroup(ansibleutils.RemoveNodeGroup, removeNode)
+	log.Info.Println("Removing node ***********************************:", removeNodeName)
+	ctx, cancel := playbookContext()
+	defer cancel()
+	_, ansibleErr := ansibleutils.CallAnsible(ctx, inventory, clusterCfg, "scale_down")
+	return ansibleErr
+}
+
+// Input:
+//
+//	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//	nodes (map[string]osutils.NodeStats): Nodes currently present in the cluster
+//	removeNodeName (string): Name of the node to be removed
+//	tier (string): Data tier of the node group, hot or warm
+//
+// Description:
+//
+//	Updates the allocation settings of the indices pinned to the node, so that their shards are moved to the
+//	other nodes of the tier before the node is removed. When the node is the last one of its tier the scale in
+//	is refused if ISM policies still allocate indices to the tier, as those indices could not be allocated.
+//
+// Return:
+//
+//	(error): Returns error if any
+func migrateIndexAllocation(ctx context.Context, nodes map[string]osutils.NodeStats, removeNodeName string, tier string) error {
+	var removeNode osutils.NodeStats
+	found := false
+	tierNodes := 0
+	for _, node := range nodes {
+		if node.Name == removeNodeName {
+			removeNode = node
+			found = true
+		} else if node.Attributes[osutils.TierAttribute] == tier {
+			tierNodes++
+		}
+	}
+	if !found {
+		return errors.New("node " + removeNodeName + " is not present in the cluster")
+	}
+	lastOfTier := tierNodes == 0
+	if lastOfTier {
+		policyIds, err := osutils.FetchIsmPoliciesAllocatingTo(ctx, osutils.TierAttribute, tier)
+		if err != nil {
+			return &interruptedError{err}
+		}
+		if len(policyIds) > 0 {
+			return fmt.Errorf("node %s is the last %s node and ISM policies %s allocate indices to it", removeNodeName, tier, strings.Join(policyIds, ","))
+		}
+	}
+	indicesSettings, err := osutils.FetchIndexAllocationSettings(ctx)
+	if err != nil {
+		return &interruptedError{err}
+	}
+	for index, settings := range osutils.PlanAllocationMigration(indicesSettings, removeNode, tier, lastOfTier) {
+		log.Info.Println("Migrating the allocation of index ", index, ": ", settings)
+		if err := osutils.UpdateIndexSettings(ctx, index, settings); err != nil {
+			return &interruptedError{err}
+		}
+	}
+	return nil
+}
+
 // Input:
 //
 //	usrCfg (config.UserConfig): User defined config for application behavior
@@ -467,37 +1078,48 @@ func ScaleIn(clusterCfg config.ClusterDetails, usrCfg config.UserConfig, t *time
 //	CheckClusterHealth will check the current cluster health and also check if there are any relocating
 //	shards. If the cluster status is green and there are no relocating shard then we will update the status
 //	to provisioned_successfully. Else, we will wait for 3 minutes and perform this check again for 3 times.
+//	Failures to fetch the cluster health are retried in the next check.
 //
 // Return:
-func CheckClusterHealth(usrCfg config.UserConfig, t *time.Time) {
+//
+//	(error): Return error if the state could not be read or updated
+func CheckClusterHealth(usrCfg config.UserConfig, t *time.Time) error {
 	var timedOut bool
 	simFlag := usrCfg.MonitorWithSimulator
 	isAccelerated := usrCfg.IsAccelerated
-	state.GetCurrentState()
-	clusterDynamic, _ := cluster.GetClusterCurrent(false)
-	if clusterDynamic.NumUnassignedShards > 0 {
+	err := state.GetCurrentState()
+	if err != nil {
+		return err
+	}
+	clusterDynamic, _, err := cluster.GetClusterCurrent(false)
+	if err != nil {
+		log.Error.Println("Unable to fetch the cluster health: ", err)
+	} else if clusterDynamic.NumUnassignedShards > 0 {
 		log.Info.Println("Retrying to reroute unassigned shards once before waiting for rebalancing")
-		_, err := osutils.RerouteRetryFailed(context.Background())
+		rerouteResp, err := osutils.RerouteRetryFailed(context.Background())
+		err = osutils.DecodeResponse(rerouteResp, err, nil)
 		if err != nil {
 			log.Error.Println("Failed to retry reroute", err)
 		}
 	}
 	for {
 		if simFlag {
-			_ = cluster_sim.GetClusterCurrent(isAccelerated)
+			_, err = cluster_sim.GetClusterCurrent(isAccelerated)
 		} else {
-			_, timedOut = cluster.GetClusterCurrent(true)
+			_, timedOut, err = cluster.GetClusterCurrent(true)
 		}
-		if !timedOut {
+		if err == nil && !timedOut {
 			state.PreviousState = state.CurrentState
 			if strings.Contains(state.PreviousState, "scaleup") {
 				state.CurrentState = "provisioned_scaleup_successfully"
 			} else {
 				state.CurrentState = "provisioned_scaledown_successfully"
 			}
-			state.UpdateState()
-			break
+			return state.UpdateState()
 		} else {
+			if err != nil {
+				log.Error.Println("Unable to fetch the cluster health: ", err)
+			}
 			log.Info.Println("Waiting for cluster to rebalance.......")
 			time.Sleep(time.Duration(usrCfg.RecommendationPollingInterval) * time.Second)
 			if simFlag && isAccelerated {
@@ -517,7 +1139,9 @@ func CheckClusterHealth(usrCfg config.UserConfig, t *time.Time) {
 //	Calls the simulator api with the details of nodes added/removed to simulate the shard rebalancing operation
 //
 // Return:
-func SimulateSharRebalancing(operation string, numNode int, isAccelerated bool) {
+//
+//	(error): Return error if any
+func SimulateSharRebalancing(operation string, numNode int, isAccelerated bool) error {
 	// Add logic to call the simulator's end point
 	var byteStr string
 	if isAccelerated {
@@ -538,6 +1162,9 @@ func SimulateSharRebalancing(operation string, numNode int, isAccelerated bool)
 	}
 
 	req, err := http.NewRequest("POST", urlLink, bytes.NewBuffer(jsonStr))
+	if err != nil {
+		return err
+	}
 	req.Header.Set("Content-Type", "application/json")
 	client := http.Client{
 		Timeout: 10 * time.Second,
@@ -545,15 +1172,16 @@ func SimulateSharRebalancing(operation string, numNode int, isAccelerated bool)
 	resp, err := client.Do(req)
 
 	if err != nil {
-		log.Panic.Println(err)
-		panic(err)
+		log.Error.Println(err)
+		return err
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		log.Error.Println(resp.Status)
+		return errors.New("simulator responded with status " + resp.Status)
 	}
-
-	defer resp.Body.Close()
+	return nil
 }
 
 // Inputs:
@@ -579,7 +1207,9 @@ func fakeSleep(t *time.Time) {
 //	Sets the CurrentState to normal, updates the other fields with default and updates the opensearch document with the same
 //
 // Return:
-func SetStateBackToNormal() {
+//
+//	(error): Return error if the state could not be updated
+func SetStateBackToNormal() error {
 	state.LastProvisionedTime = time.Now().UnixMilli()
 	state.ProvisionStartTime = 0
 	state.PreviousState = state.CurrentState
@@ -589,8 +1219,21 @@ func SetStateBackToNormal() {
 	state.NodeIp = ""
 	state.InstanceId = ""
 	state.NodeName = ""
-	state.UpdateState()
+	state.NodeGroup = ""
+	state.CurrentTask = ""
+	state.FailedHost = ""
+	state.FailureMessage = ""
+	state.PendingNodes = nil
+	state.UpdateHeap = false
+	state.LaunchTemplateVersion = ""
+	state.NewNodeIp = ""
+	err := state.UpdateState()
+	if err != nil {
+		log.Error.Println("Unable to set the state back to normal: ", err)
+		return err
+	}
 	log.Info.Println("State set back to normal")
+	return nil
 }
 
 // Inputs:
@@ -614,6 +1257,17 @@ func PushToOs(status string, err error) {
 		provisionState["FailureReason"] = err.Error()
 	}
 	provisionState["RulesResponsible"] = state.RulesResponsible
+	provisionState["NodeGroup"] = state.NodeGroup
+	if state.LaunchTemplateVersion != "" {
+		provisionState["LaunchTemplateVersion"] = state.LaunchTemplateVersion
+	}
+	if state.RuleTriggered == autoHealRule || state.RuleTriggered == spotInterruptionRule {
+		provisionState["NodeName"] = state.NodeName
+	}
+	if len(playbookSummaries) > 0 {
+		provisionState["Playbooks"] = playbookSummaries
+		playbookSummaries = nil
+	}
 	provisionState["TimeTaken"] = fmt.Sprint((time.UnixMilli(provisionState["ProvisionEndTime"].(int64))).Sub(time.UnixMilli(provisionState["ProvisionStartTime"].(int64))))
 	provisionState["StatTag"] = "ProvisionStats"
 	provisionState["_documentType"] = "ProvisionStats"
@@ -621,15 +1275,15 @@ func PushToOs(status string, err error) {
 
 	doc, err := json.Marshal(provisionState)
 	if err != nil {
-		log.Panic.Println("json.Marshal ERROR: ", err)
-		panic(err)
+		log.Error.Println("json.Marshal ERROR: ", err)
+		return
 	}
 
-	indexResponse, err := osutils.IndexMetrics(context.Background(), doc)
+	indexResponse, err := osutils.IndexMetrics(context.Background(), osutils.ProvisionStatsIndex, doc)
+	err = osutils.DecodeResponse(indexResponse, err, nil)
 	if err != nil {
-		log.Panic.Println("Failed to insert provision stats document: ", err)
-		panic(err)
+		log.Error.Println("Failed to insert provision stats document: ", err)
+		return
 	}
-	defer indexResponse.Body.Close()
-	log.Debug.Println("Update resp: ", indexResponse)
+	log.Debug.Println("Provision stats document indexed with status: ", status)
 }
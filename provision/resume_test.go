@@ -0,0 +1,242 @@
+package provision
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
+	"github.com/stretchr/testify/assert"
+)
+
+// This struct contains the opensearch cluster served to the provision tests, with the provisioning state
+// document and the requests made to it.
+type fakeCluster struct {
+	mutex sync.Mutex
+	// state indicates the source of the state document
+	state []byte
+	// states indicates the CurrentState of every update of the state document
+	states []string
+	// nodes indicates the nodes of the _nodes/stats responses keyed by node id, the first one is the local node
+	nodes      map[string]osutils.NodeStats
+	localNode  string
+	masterNode string
+	// fail returns true for the requests answered with a 503, it is called with the method and path and the
+	// number of times the request was made including this one
+	fail     func(request string, count int) bool
+	requests map[string]int
+	// provisionStats indicates the ProvisionStats documents indexed
+	provisionStats []map[string]interface{}
+}
+
+// Input:
+//
+//	t (*testing.T): The test the cluster is served to
+//	seed (State): The provisioning state persisted before the test
+//
+// Description:
+//
+//	Serves the cluster, points the client of the osutils package to it and persists the seeded state. The
+//	provisioning globals are reset once the test ends.
+//
+// Return:
+func (c *fakeCluster) serve(t *testing.T, seed State) {
+	seedJson, err := json.Marshal(seed)
+	assert.Nil(t, err)
+	c.state = seedJson
+	c.requests = make(map[string]int)
+	previousDocId := docId
+	docId = "state-doc"
+	t.Cleanup(func() {
+		docId = previousDocId
+		state = new(State)
+		interrupted.Store(false)
+	})
+	state = new(State)
+	newOsServer(t, func(w http.ResponseWriter, r *http.Request) {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		request := r.Method + " " + r.URL.Path
+		c.requests[request]++
+		if c.fail != nil && c.fail(request, c.requests[request]) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{}`))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.URL.Path == "/"+osutils.StateIndexName+"/_doc/"+docId && r.Method == http.MethodGet:
+			fmt.Fprintf(w, `{"_id": %q, "found": true, "_source": %s}`, docId, c.state)
+		case r.URL.Path == "/"+osutils.StateIndexName+"/_doc/"+docId:
+			var persisted State
+			assert.Nil(t, json.Unmarshal(body, &persisted))
+			c.state = body
+			c.states = append(c.states, persisted.CurrentState)
+			w.Write([]byte(`{"result": "updated"}`))
+		case strings.HasPrefix(r.URL.Path, "/"+osutils.ProvisionStatsIndex+"/_doc/"):
+			var document map[string]interface{}
+			assert.Nil(t, json.Unmarshal(body, &document))
+			c.provisionStats = append(c.provisionStats, document)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{}`))
+		case strings.HasPrefix(r.URL.Path, "/_nodes/_local"):
+			nodes, _ := json.Marshal(map[string]osutils.NodeStats{c.localNode: c.nodes[c.localNode]})
+			fmt.Fprintf(w, `{"nodes": %s}`, nodes)
+		case strings.HasPrefix(r.URL.Path, "/_nodes/"):
+			nodes, _ := json.Marshal(c.nodes)
+			fmt.Fprintf(w, `{"nodes": %s}`, nodes)
+		case r.URL.Path == "/_cluster/state":
+			fmt.Fprintf(w, `{"master_node": %q}`, c.masterNode)
+		case strings.HasPrefix(r.URL.Path, "/_cluster/health"), r.URL.Path == "/_cluster/stats":
+			w.Write([]byte(`{"status": "green", "timed_out": false}`))
+		case r.URL.Path == "/_cluster/settings", r.URL.Path == "/_flush", r.URL.Path == "/_cluster/voting_config_exclusions":
+			w.Write([]byte(`{"acknowledged": true}`))
+		default:
+			t.Errorf("unexpected request %s", request)
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{}`))
+		}
+	})
+}
+
+// Returns the state document persisted in the cluster
+func (c *fakeCluster) persisted(t *testing.T) State {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var persisted State
+	assert.Nil(t, json.Unmarshal(c.state, &persisted))
+	return persisted
+}
+
+// Returns the number of times the request was made
+func (c *fakeCluster) count(request string) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.requests[request]
+}
+
+// Checks no provision holds the provision lock
+func assertProvisionLockReleased(t *testing.T) {
+	if assert.True(t, provisionLock.TryLock(), "the provision lock is still held") {
+		provisionLock.Unlock()
+	}
+}
+
+func newFakeNodes() map[string]osutils.NodeStats {
+	return map[string]osutils.NodeStats{
+		"n1": {Name: "node-1", Host: "10.0.0.1", Roles: []string{"master", "data"}},
+		"n2": {Name: "node-2", Host: "10.0.0.2", Roles: []string{"data"}},
+	}
+}
+
+func TestResumeRollingRestartInterrupted(t *testing.T) {
+	cluster := &fakeCluster{nodes: newFakeNodes(), localNode: "n1", masterNode: "n1"}
+	cluster.fail = func(request string, count int) bool {
+		return request == "PUT /_cluster/settings"
+	}
+	cluster.serve(t, State{CurrentState: "rollingrestart_node_selected", PreviousState: "start_rollingrestart_process", RuleTriggered: rollingRestartRule, PendingNodes: []string{"node-2", "node-1"}, NumNodes: 2, RemainingNodes: 2})
+
+	// The allocation can't be limited to the primaries, so the step is resumed later
+	ResumeProvision(config.ClusterDetails{}, config.UserConfig{}, nil)
+	persisted := cluster.persisted(t)
+	assert.Equal(t, "rollingrestart_node_selected", persisted.CurrentState)
+	assert.Equal(t, []string{"node-2", "node-1"}, persisted.PendingNodes)
+	assert.Empty(t, cluster.states)
+	assert.Empty(t, cluster.provisionStats)
+	assert.True(t, Interrupted())
+	assertProvisionLockReleased(t)
+}
+
+func TestResumeRollingRestartNodeRestarted(t *testing.T) {
+	cluster := &fakeCluster{nodes: newFakeNodes(), localNode: "n1", masterNode: "n1"}
+	// The nodes are fetched once by the wait for the restarted node, the next node can't be selected
+	cluster.fail = func(request string, count int) bool {
+		return request == "GET /_nodes/_all/stats" && count > 1
+	}
+	cluster.serve(t, State{CurrentState: "rollingrestart_node_restarted", PreviousState: "rollingrestart_allocation_disabled", RuleTriggered: rollingRestartRule, NodeName: "node-2", NodeIp: "10.0.0.2", PendingNodes: []string{"node-2", "node-1"}, NumNodes: 2, RemainingNodes: 2})
+
+	ResumeProvision(config.ClusterDetails{}, config.UserConfig{}, nil)
+	assert.Equal(t, []string{"rollingrestart_node_selected"}, cluster.states)
+	persisted := cluster.persisted(t)
+	assert.Equal(t, "rollingrestart_node_selected", persisted.CurrentState)
+	assert.Equal(t, []string{"node-1"}, persisted.PendingNodes)
+	assert.Equal(t, 1, persisted.RemainingNodes)
+	assert.Empty(t, persisted.NodeName)
+	// The allocation was enabled again once the node rejoined
+	assert.Equal(t, 1, cluster.count("PUT /_cluster/settings"))
+	assert.True(t, Interrupted())
+	assertProvisionLockReleased(t)
+}
+
+func TestResumeVerticalScaleMasterHandover(t *testing.T) {
+	cluster := &fakeCluster{nodes: newFakeNodes(), localNode: "n1", masterNode: "n1"}
+	cluster.serve(t, State{CurrentState: "verticalscale_node_selected", PreviousState: "verticalscale_node_drained", RuleTriggered: verticalScaleRule, LaunchTemplateVersion: "3", PendingNodes: []string{"node-1"}, NumNodes: 2, RemainingNodes: 1})
+
+	// The master is excluded from the voting and the step is left for the new master to resume
+	ResumeProvision(config.ClusterDetails{}, config.UserConfig{}, nil)
+	assert.Equal(t, 1, cluster.count("POST /_cluster/voting_config_exclusions"))
+	assert.Equal(t, 0, cluster.count("DELETE /_cluster/voting_config_exclusions"))
+	assert.Empty(t, cluster.states)
+	persisted := cluster.persisted(t)
+	assert.Equal(t, "verticalscale_node_selected", persisted.CurrentState)
+	assert.Equal(t, []string{"node-1"}, persisted.PendingNodes)
+	assert.True(t, Interrupted())
+	assertProvisionLockReleased(t)
+}
+
+func TestResumeVerticalScaleToCompletion(t *testing.T) {
+	cluster := &fakeCluster{nodes: newFakeNodes(), localNode: "n1", masterNode: "n1"}
+	cluster.serve(t, State{CurrentState: "verticalscale_new_node_joined", PreviousState: "verticalscale_new_node_configured", RuleTriggered: verticalScaleRule, LaunchTemplateVersion: "3", NodeName: "node-2", PendingNodes: []string{"node-2", "node-3"}, NumNodes: 2, RemainingNodes: 2})
+
+	ResumeProvision(config.ClusterDetails{}, config.UserConfig{MonitorWithLogs: true}, nil)
+	assert.Equal(t, []string{
+		"verticalscale_node_drained",
+		"verticalscale_node_selected",
+		"verticalscale_triggered_spin_vm",
+		"verticalscale_new_node_configured",
+		"verticalscale_new_node_joined",
+		"verticalscale_node_drained",
+		"provisioned_verticalscale_successfully",
+		"normal",
+	}, cluster.states)
+	persisted := cluster.persisted(t)
+	assert.Empty(t, persisted.PendingNodes)
+	assert.Empty(t, persisted.LaunchTemplateVersion)
+	if assert.Len(t, cluster.provisionStats, 1) {
+		assert.Equal(t, "Success", cluster.provisionStats[0]["Status"])
+		assert.Equal(t, "3", cluster.provisionStats[0]["LaunchTemplateVersion"])
+	}
+	assert.False(t, Interrupted())
+	assertProvisionLockReleased(t)
+}
+
+func TestCleanUpOrphansDuringProvision(t *testing.T) {
+	cluster := &fakeCluster{nodes: newFakeNodes(), localNode: "n1", masterNode: "n1"}
+	seed := State{CurrentState: "verticalscale_triggered_spin_vm", PreviousState: "verticalscale_node_selected", RuleTriggered: verticalScaleRule, NewNodeIp: "10.0.0.9", PendingNodes: []string{"node-2"}}
+	cluster.serve(t, seed)
+	clusterCfg := config.ClusterDetails{OrphanCleanup: config.OrphanCleanup{Enabled: true}}
+
+	// The instance of the new node which has not joined yet is not looked at
+	CleanUpOrphans(clusterCfg, config.UserConfig{})
+	assert.Equal(t, 1, cluster.count("GET /"+osutils.StateIndexName+"/_doc/state-doc"))
+	assert.Equal(t, "verticalscale_triggered_spin_vm", cluster.persisted(t).CurrentState)
+	assert.Empty(t, cluster.states)
+	assertProvisionLockReleased(t)
+
+	// Nothing is read while a provision of this node holds the lock
+	provisionLock.Lock()
+	CleanUpOrphans(clusterCfg, config.UserConfig{})
+	provisionLock.Unlock()
+	assert.Equal(t, 1, cluster.count("GET /"+osutils.StateIndexName+"/_doc/state-doc"))
+
+	// The lock is released when the state can't be read
+	cluster.fail = func(request string, count int) bool { return true }
+	CleanUpOrphans(clusterCfg, config.UserConfig{})
+	assert.Empty(t, cluster.states)
+	assertProvisionLockReleased(t)
+}
@@ -0,0 +1,344 @@
+package provision
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	ansibleutils "github.com/maplelabs/opensearch-scaling-manager/ansible_scripts"
+	"github.com/maplelabs/opensearch-scaling-manager/cluster"
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+	"github.com/maplelabs/opensearch-scaling-manager/crypto"
+	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
+	utils "github.com/maplelabs/opensearch-scaling-manager/utilities"
+)
+
+// State of a rolling restart requested with the rolling-restart command, which the master starts in its next
+// provision check
+const RollingRestartRequested = "provisioning_rollingrestart"
+
+// Rule triggered recorded for the rolling restarts in the state and the ProvisionStats documents
+const rollingRestartRule = "rolling_restart"
+
+// Maximum time a restarted node may take to join the cluster again
+var nodeRejoinTimeout = 10 * time.Minute
+
+// Interval of the checks of a restarted node joining the cluster
+var nodeRejoinInterval = 10 * time.Second
+
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Cluster Level config details
+//	reason (string): Why the nodes are restarted, like jvm_factor, recorded as the rules responsible
+//	nodeGroup (string): The node group to be restarted, all the nodes if empty
+//	updateHeap (bool): True to write the heap of the jvm_factor to jvm.options of every node before its restart
+//
+// Description:
+//
+//	Requests a rolling restart of the nodes by setting the state, so that the master runs it in its next
+//	provision check. The request is refused while a provision is running.
+//
+// Return:
+//
+//	(error): Returns error if the state is not normal or can't be updated
+func RequestRollingRestart(clusterCfg config.ClusterDetails, reason string, nodeGroup string, updateHeap bool) error {
+	if nodeGroup != "" {
+		_, err := clusterCfg.GetNodeGroup(nodeGroup)
+		if err != nil {
+			return err
+		}
+	}
+	err := state.GetCurrentState()
+	if err != nil {
+		return err
+	}
+	if state.CurrentState != "normal" {
+		return fmt.Errorf("the provisioning state is %s, a rolling restart can only be requested in the normal state", state.CurrentState)
+	}
+	state.PreviousState = state.CurrentState
+	state.CurrentState = RollingRestartRequested
+	state.RuleTriggered = rollingRestartRule
+	state.RulesResponsible = reason
+	state.NodeGroup = nodeGroup
+	state.UpdateHeap = updateHeap
+	state.NumNodes = 0
+	state.RemainingNodes = 0
+	state.PendingNodes = nil
+	return state.UpdateState()
+}
+
+// Input:
+//
+//	nodes (map[string]osutils.NodeStats): Nodes of the cluster by node id
+//	masterNodeId (string): Id of the elected master node
+//	nodeGroup (string): The node group to be restarted, all the nodes if empty
+//
+// Description:
+//
+//	Orders the nodes to be restarted: the nodes which are not master eligible first, then the master eligible
+//	nodes and the elected master last, so that the master is elected again only once. The nodes are sorted by
+//	name otherwise.
+//
+// Return:
+//
+//	([]string): Returns the names of the nodes in the order they are restarted
+func rollingRestartOrder(nodes map[string]osutils.NodeStats, masterNodeId string, nodeGroup string) []string {
+	rank := func(nodeId string) int {
+		switch {
+		case nodeId == masterNodeId:
+			return 2
+		case nodes[nodeId].IsMasterEligible():
+			return 1
+		default:
+			return 0
+		}
+	}
+	var nodeIds []string
+	for nodeId, node := range nodes {
+		if nodeGroup == "" || node.NodeGroup() == nodeGroup {
+			nodeIds = append(nodeIds, nodeId)
+		}
+	}
+	sort.Slice(nodeIds, func(i, j int) bool {
+		if rank(nodeIds[i]) != rank(nodeIds[j]) {
+			return rank(nodeIds[i]) < rank(nodeIds[j])
+		}
+		return nodes[nodeIds[i]].Name < nodes[nodeIds[j]].Name
+	})
+	var names []string
+	for _, nodeId := range nodeIds {
+		names = append(names, nodes[nodeId].Name)
+	}
+	return names
+}
+
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Cluster Level config details
+//	usrCfg (config.UserConfig): User defined config for application behavior
+//
+// Description:
+//
+//	RollingRestart restarts the nodes of the cluster one at a time. For every node the allocation of the replicas
+//	is disabled and the indices are flushed, opensearch is restarted on the node with the rolling restart
+//	playbook, and once the node joined the cluster again the allocation is enabled and the cluster has to become
+//	green before the next node is restarted. Every step is persisted in the state, so the rolling restart is
+//	resumed by a new master, which is the case when the node running the scaling manager is restarted. The
+//	allocation is enabled again if the rolling restart fails.
+//
+// Return:
+//
+//	(bool, error): Returns true if all the nodes were restarted and error if any
+func RollingRestart(clusterCfg config.ClusterDetails, usrCfg config.UserConfig) (bool, error) {
+	if err := state.GetCurrentState(); err != nil {
+		return false, &interruptedError{err}
+	}
+	crypto.GetDecryptedCloudCreds(&clusterCfg.CloudCredentials)
+	crypto.GetDecryptedOsCreds(&clusterCfg.OsCredentials)
+	isRestarted, err := rollingRestart(clusterCfg, usrCfg)
+	var interruptErr *interruptedError
+	if err != nil && !errors.As(err, &interruptErr) && !usrCfg.MonitorWithLogs {
+		allocationErr := osutils.SetShardAllocation(context.Background(), "")
+		if allocationErr != nil {
+			log.Error.Println(fmt.Sprintf("Unable to enable the allocation of the shards again, reset the %s cluster setting: %v", osutils.ClusterAllocationEnableSetting, allocationErr))
+		}
+	}
+	return isRestarted, err
+}
+
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Cluster Level config details with the decrypted credentials
+//	usrCfg (config.UserConfig): User defined config for application behavior
+//
+// Description:
+//
+//	Runs the steps of the rolling restart from the step persisted in the state, looping over the nodes which are
+//	not restarted yet
+//
+// Return:
+//
+//	(bool, error): Returns true if all the nodes were restarted and error if any
+func rollingRestart(clusterCfg config.ClusterDetails, usrCfg config.UserConfig) (bool, error) {
+	monitorWithLogs := usrCfg.MonitorWithLogs
+	pollingInterval := time.Duration(usrCfg.RecommendationPollingInterval) * time.Second
+	for {
+		switch state.CurrentState {
+		case RollingRestartRequested:
+			log.Info.Println("Starting the rolling restart")
+			state.ProvisionStartTime = time.Now().UnixMilli()
+			if err := setProvisionState("start_rollingrestart_process"); err != nil {
+				return false, err
+			}
+		// List the nodes to be restarted
+		case "start_rollingrestart_process":
+			if monitorWithLogs {
+				state.PendingNodes = []string{"node-1", "node-2", "node-3"}
+			} else {
+				nodes, err := utils.GetNodes()
+				if err != nil {
+					return false, &interruptedError{err}
+				}
+				masterNodeId, err := utils.GetMasterNodeId(context.Background())
+				if err != nil {
+					return false, &interruptedError{err}
+				}
+				state.PendingNodes = rollingRestartOrder(nodes, masterNodeId, state.NodeGroup)
+			}
+			if len(state.PendingNodes) == 0 {
+				return false, errors.New("no nodes of the node group " + state.NodeGroup + " are present in the cluster")
+			}
+			state.NumNodes = len(state.PendingNodes)
+			state.RemainingNodes = len(state.PendingNodes)
+			log.Info.Println(fmt.Sprintf("Restarting the nodes in the order %v", state.PendingNodes))
+			if err := setProvisionState("rollingrestart_node_selected"); err != nil {
+				return false, err
+			}
+		// Keep the replicas of the node from being allocated to the other nodes while it is down
+		case "rollingrestart_node_selected":
+			state.NodeName = state.PendingNodes[0]
+			log.Info.Println(fmt.Sprintf("Restarting the node %s, %d of %d", state.NodeName, state.NumNodes-state.RemainingNodes+1, state.NumNodes))
+			if monitorWithLogs {
+				log.Info.Println("Disable the allocation of the replicas and flush the indices")
+			} else {
+				nodes, err := utils.GetNodes()
+				if err != nil {
+					return false, &interruptedError{err}
+				}
+				state.NodeIp = ""
+				for _, node := range nodes {
+					if node.Name == state.NodeName {
+						state.NodeIp = node.Host
+					}
+				}
+				if state.NodeIp == "" {
+					return false, errors.New("node " + state.NodeName + " is not present in the cluster")
+				}
+				err = osutils.SetShardAllocation(context.Background(), "primaries")
+				if err != nil {
+					return false, &interruptedError{err}
+				}
+				// The shards are recovered from the translog if the flush failed, which is slower but safe
+				err = osutils.FlushIndices(context.Background())
+				if err != nil {
+					log.Warn.Println("Unable to flush the indices before the restart: ", err)
+				}
+			}
+			if err := setProvisionState("rollingrestart_allocation_disabled"); err != nil {
+				return false, err
+			}
+		// Restart opensearch on the node
+		case "rollingrestart_allocation_disabled":
+			if monitorWithLogs {
+				log.Info.Println("Restart opensearch on the node through ansible")
+				time.Sleep(pollingInterval)
+			} else {
+				inventory := ansibleutils.NewAnsibleInventory(clusterCfg).
+					AddGroup(ansibleutils.RestartNodeGroup, ansibleutils.Host{Name: state.NodeName, Ip: state.NodeIp})
+				ctx, cancel := playbookContext()
+				_, ansibleErr := ansibleutils.RestartNode(ctx, inventory, clusterCfg, state.UpdateHeap)
+				cancel()
+				if ansibleErr != nil {
+					return false, ansibleErr
+				}
+			}
+			if err := setProvisionState("rollingrestart_node_restarted"); err != nil {
+				return false, err
+			}
+		// Wait for the node to join the cluster, then enable the allocation and wait for the cluster to be green
+		case "rollingrestart_node_restarted":
+			if !monitorWithLogs {
+				// The node running the scaling manager may have been restarted, the new master resumes from here
+				isMaster, err := utils.CheckIfMaster(context.Background(), "")
+				if err != nil {
+					return false, &interruptedError{err}
+				}
+				if !isMaster {
+					return false, &interruptedError{errors.New("this node is no longer the master after the restart of " + state.NodeName)}
+				}
+				err = waitForNodeToRejoin(state.NodeName)
+				if err != nil {
+					return false, err
+				}
+				err = osutils.SetShardAllocation(context.Background(), "")
+				if err != nil {
+					return false, &interruptedError{err}
+				}
+				waitForGreenCluster(pollingInterval)
+			}
+			log.Info.Println(fmt.Sprintf("Node %s restarted", state.NodeName))
+			state.PendingNodes = state.PendingNodes[1:]
+			state.RemainingNodes = len(state.PendingNodes)
+			state.NodeName = ""
+			state.NodeIp = ""
+			nextState := "rollingrestart_node_selected"
+			if len(state.PendingNodes) == 0 {
+				nextState = "provisioned_rollingrestart_successfully"
+			}
+			if err := setProvisionState(nextState); err != nil {
+				return false, err
+			}
+		case "provisioned_rollingrestart_successfully":
+			return true, nil
+		default:
+			return false, errors.New("unknown rolling restart state " + state.CurrentState)
+		}
+	}
+}
+
+// Input:
+//
+//	nodeName (string): Name of the restarted node
+//
+// Description:
+//
+//	Waits for the restarted node to join the cluster again, up to nodeRejoinTimeout. Failures to fetch the nodes
+//	are retried, as the node running the scaling manager may be the one which restarted.
+//
+// Return:
+//
+//	(error): Returns error if the node did not join the cluster in time
+func waitForNodeToRejoin(nodeName string) error {
+	deadline := time.Now().Add(nodeRejoinTimeout)
+	for {
+		nodes, err := utils.GetNodes()
+		if err != nil {
+			log.Warn.Println("Unable to fetch the nodes of the cluster: ", err)
+		}
+		for _, node := range nodes {
+			if node.Name == nodeName {
+				return nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("node %s did not join the cluster %s after it was restarted, check the opensearch logs of the node", nodeName, nodeRejoinTimeout)
+		}
+		log.Info.Println("Waiting for the restarted node to join the cluster...")
+		time.Sleep(nodeRejoinInterval)
+	}
+}
+
+// Input:
+//
+//	pollingInterval (time.Duration): Time to wait between the checks of the cluster health
+//
+// Description:
+//
+//	Waits for the cluster to be green with no initializing or relocating shards, like CheckClusterHealth does
+//	after a scale up or scale down
+//
+// Return:
+func waitForGreenCluster(pollingInterval time.Duration) {
+	for {
+		clusterDynamic, timedOut, err := cluster.GetClusterCurrent(true)
+		if err != nil {
+			log.Error.Println("Unable to fetch the cluster health: ", err)
+		} else if !timedOut && clusterDynamic.ClusterStatus == "green" {
+			return
+		}
+		log.Info.Println("Waiting for the cluster to become green.......")
+		time.Sleep(pollingInterval)
+	}
+}
@@ -0,0 +1,22 @@
+package provision
+
+import (
+	"testing"
+
+	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollingRestartOrder(t *testing.T) {
+	nodes := map[string]osutils.NodeStats{
+		"m1": {Name: "node-m1", Roles: []string{"master", "data"}, Attributes: map[string]string{"node_group": "hot"}},
+		"m2": {Name: "node-m2", Roles: []string{"cluster_manager"}},
+		"m3": {Name: "node-m3", Roles: []string{"master"}},
+		"d2": {Name: "node-d2", Roles: []string{"data"}, Attributes: map[string]string{"node_group": "hot"}},
+		"d1": {Name: "node-d1", Roles: []string{"data", "ingest"}, Attributes: map[string]string{"node_group": "warm"}},
+	}
+	// The elected master is restarted last, after the other master eligible nodes
+	assert.Equal(t, []string{"node-d1", "node-d2", "node-m2", "node-m3", "node-m1"}, rollingRestartOrder(nodes, "m1", ""))
+	assert.Equal(t, []string{"node-d2", "node-m1"}, rollingRestartOrder(nodes, "m1", "hot"))
+	assert.Empty(t, rollingRestartOrder(nodes, "m1", "cold"))
+}
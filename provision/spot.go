@@ -0,0 +1,277 @@
+package provision
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+	"github.com/maplelabs/opensearch-scaling-manager/crypto"
+	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
+	utils "github.com/maplelabs/opensearch-scaling-manager/utilities"
+)
+
+// Rule triggered recorded for the replacements of the interrupted spot instances in the state and the
+// ProvisionStats documents
+const spotInterruptionRule = "spot_interruption"
+
+// Reason recorded as the rules responsible for the replacement of an interrupted spot instance
+const interruptionNotice = "interruption_notice"
+
+// Time between the interruption notice and the interruption of a spot instance
+const interruptionNoticePeriod = 2 * time.Minute
+
+// This struct contains a spot instance which received an interruption notice
+type SpotInterruption struct {
+	InstanceId string
+	PrivateIp  string
+	// NoticeTime indicates when the notice was given, two minutes before the instance is interrupted.
+	NoticeTime time.Time
+}
+
+// InterruptionSource reports the spot instances which received an interruption notice
+type InterruptionSource interface {
+	// Interruptions returns the spot instances which received an interruption notice.
+	Interruptions(cred config.CloudCredentials) ([]SpotInterruption, error)
+}
+
+// InterruptionSourceFunc adapts a function to an InterruptionSource
+type InterruptionSourceFunc func(cred config.CloudCredentials) ([]SpotInterruption, error)
+
+// Input:
+//
+//	cred (config.CloudCredentials): Cloud credentials required to connect to AWS account
+//
+// Description:
+//
+//	Calls the function
+//
+// Return:
+//
+//	([]SpotInterruption, error): Returns the interrupted instances and error if any
+func (f InterruptionSourceFunc) Interruptions(cred config.CloudCredentials) ([]SpotInterruption, error) {
+	return f(cred)
+}
+
+// Source the interruption notices are polled from, the status of the spot instance requests by default
+var interruptionSource InterruptionSource = InterruptionSourceFunc(MarkedForInterruption)
+
+// Instances whose interruption notice was handled by this node, so that every notice is handled once
+var handledInterruptions = make(map[string]bool)
+
+// Interrupted nodes which were drained and wait to be replaced, one at a time
+var pendingReplacements []unhealthyNode
+
+// Guards handledInterruptions and pendingReplacements. The provision lock is not used for them, as the notices
+// have to be handled while an interrupted node is replaced.
+var interruptionsLock sync.Mutex
+
+// Input:
+//
+//	source (InterruptionSource): Source the interruption notices are polled from
+//
+// Description:
+//
+//	Replaces the source of the interruption notices, like with one reading the instance metadata of the nodes
+//
+// Return:
+func SetInterruptionSource(source InterruptionSource) {
+	interruptionSource = source
+}
+
+// Input:
+//
+//	spot (config.Spot): Spot options of the node group
+//	onDemandNodes (int): Number of on-demand nodes in the node group
+//	spotNodes (int): Number of spot nodes in the node group
+//
+// Description:
+//
+//	Decides the lifecycle of the next node of the node group. The first on_demand_base nodes are on-demand, and
+//	spot_percentage of the nodes above the base are spot instances, rounded down in favor of on-demand.
+//
+// Return:
+//
+//	(bool): Returns true if the next node is a spot instance
+func launchAsSpot(spot config.Spot, onDemandNodes int, spotNodes int) bool {
+	if spot.SpotPercentage == 0 || onDemandNodes < spot.OnDemandBase {
+		return false
+	}
+	nodesAboveBase := onDemandNodes - spot.OnDemandBase + spotNodes + 1
+	return spotNodes < nodesAboveBase*spot.SpotPercentage/100
+}
+
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Cluster Level config details with the decrypted credentials
+//	nodeGroup (config.NodeGroup): Node group of the new node
+//	replacedIp (string): Private ip of the node being replaced, which is not counted
+//
+// Description:
+//
+//	Counts the on-demand and spot nodes of the node group in the cluster
+//
+// Return:
+//
+//	(int, int, error): Returns the number of on-demand and spot nodes and error if any
+func countLifecycles(clusterCfg config.ClusterDetails, nodeGroup config.NodeGroup, replacedIp string) (int, int, error) {
+	nodes, err := utils.GetNodes()
+	if err != nil {
+		return 0, 0, &interruptedError{err}
+	}
+	var privateIps []string
+	for _, node := range nodes {
+		if node.NodeGroup() == nodeGroup.Name && node.Host != replacedIp {
+			privateIps = append(privateIps, node.Host)
+		}
+	}
+	lifecycles, err := InstanceLifecycles(privateIps, clusterCfg.CloudCredentials)
+	if err != nil {
+		return 0, 0, err
+	}
+	onDemandNodes, spotNodes := 0, 0
+	for _, lifecycle := range lifecycles {
+		if lifecycle == spotLifecycle {
+			spotNodes++
+		} else {
+			onDemandNodes++
+		}
+	}
+	return onDemandNodes, spotNodes, nil
+}
+
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Cluster Level config details
+//	usrCfg (config.UserConfig): User defined config for application behavior
+//	t (*time.Time): Time used by the simulator
+//
+// Description:
+//
+//	HandleSpotInterruptions polls the interruption notices of the spot instances. A node which received a notice
+//	is excluded from the allocation right away, so that its shards are moved before it is interrupted, and is
+//	replaced through the scale out steps once no other provision runs. The elected master only hands over, and
+//	the new master drains and replaces it when it polls the notice. It is called on the elected master.
+//
+// Return:
+func HandleSpotInterruptions(clusterCfg config.ClusterDetails, usrCfg config.UserConfig, t *time.Time) {
+	if !clusterCfg.UsesSpot() || usrCfg.MonitorWithSimulator {
+		return
+	}
+	interruptionsLock.Lock()
+	defer interruptionsLock.Unlock()
+	cloudCredentials := clusterCfg.CloudCredentials
+	crypto.GetDecryptedCloudCreds(&cloudCredentials)
+	interruptions, err := interruptionSource.Interruptions(cloudCredentials)
+	if err != nil {
+		log.Error.Println("Unable to poll the interruption notices of the spot instances: ", err)
+	} else if len(interruptions) > 0 {
+		ctx := context.Background()
+		nodes, err := utils.GetNodes()
+		if err != nil {
+			log.Error.Println("Unable to fetch the nodes of the cluster, the interrupted nodes are drained in the next poll: ", err)
+			return
+		}
+		masterNodeId, err := utils.GetMasterNodeId(ctx)
+		if err != nil {
+			log.Error.Println("Unable to fetch the master node, the interrupted nodes are drained in the next poll: ", err)
+			return
+		}
+		drainInterruptedNodes(ctx, interruptions, nodes, masterNodeId, usrCfg.MonitorWithLogs)
+	}
+	replaceInterruptedNode(clusterCfg, usrCfg, t)
+}
+
+// Input:
+//
+//	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//	interruptions ([]SpotInterruption): Spot instances which received an interruption notice
+//	nodes (map[string]osutils.NodeStats): Nodes of the cluster keyed by node id
+//	masterNodeId (string): Node id of the elected master
+//	monitorWithLogs (bool): True if the nodes are only logged
+//
+// Description:
+//
+//	Excludes the nodes of the interrupted instances from the allocation and queues their replacement. The
+//	notices of the instances which are not in the cluster are ignored.
+//
+// Return:
+func drainInterruptedNodes(ctx context.Context, interruptions []SpotInterruption, nodes map[string]osutils.NodeStats, masterNodeId string, monitorWithLogs bool) {
+	for _, interruption := range interruptions {
+		if handledInterruptions[interruption.InstanceId] {
+			continue
+		}
+		var nodeId string
+		var node osutils.NodeStats
+		for id, clusterNode := range nodes {
+			if clusterNode.Host == interruption.PrivateIp {
+				nodeId, node = id, clusterNode
+			}
+		}
+		if nodeId == "" {
+			handledInterruptions[interruption.InstanceId] = true
+			continue
+		}
+		log.Warn.Println(fmt.Sprintf("The spot instance %s of the node %s will be interrupted at %s", interruption.InstanceId, node.Name, interruption.NoticeTime.Add(interruptionNoticePeriod).Format(time.RFC3339)))
+		if monitorWithLogs {
+			log.Info.Println("Exclude the node from the allocation and replace it")
+		} else if nodeId == masterNodeId {
+			// The new master drains and replaces the node when it polls the notice
+			log.Info.Println("Excluding the master ", node.Name, " from the voting configuration before it is interrupted")
+			if err := osutils.ExcludeFromVoting(ctx, node.Name); err != nil {
+				log.Error.Println("Unable to exclude the master from the voting configuration: ", err)
+				continue
+			}
+			handledInterruptions[interruption.InstanceId] = true
+			continue
+		} else if err := osutils.SetAllocationExclusion(ctx, node.Name, true); err != nil {
+			log.Error.Println("Unable to exclude the node ", node.Name, " from the allocation: ", err)
+			continue
+		}
+		handledInterruptions[interruption.InstanceId] = true
+		pendingReplacements = append(pendingReplacements, unhealthyNode{
+			reportedNode: reportedNode{NodeName: node.Name, HostIp: node.Host, NodeGroup: node.NodeGroup(), LastReported: time.Now().UnixMilli()},
+			Reason:       interruptionNotice,
+		})
+	}
+}
+
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Cluster Level config details
+//	usrCfg (config.UserConfig): User defined config for application behavior
+//	t (*time.Time): Time used by the simulator
+//
+// Description:
+//
+//	Starts the replacement of the first queued interrupted node when no provision runs. The replacement runs in
+//	the background and holds the provision lock until it completes, so that the notices of the other spot
+//	instances are still handled meanwhile but no other provision starts. The caller holds the interruptions lock.
+//
+// Return:
+func replaceInterruptedNode(clusterCfg config.ClusterDetails, usrCfg config.UserConfig, t *time.Time) {
+	if len(pendingReplacements) == 0 {
+		return
+	}
+	if !provisionLock.TryLock() {
+		log.Debug.Println("Provision is in progress, the interrupted node is replaced once it completes")
+		return
+	}
+	if err := state.GetCurrentState(); err != nil {
+		provisionLock.Unlock()
+		log.Error.Println("Unable to read the provisioning state, the interrupted node is replaced in the next poll: ", err)
+		return
+	}
+	if state.CurrentState != "normal" {
+		provisionLock.Unlock()
+		log.Debug.Println("Provision is in progress, the interrupted node is replaced once it completes")
+		return
+	}
+	node := pendingReplacements[0]
+	pendingReplacements = pendingReplacements[1:]
+	go func() {
+		defer provisionLock.Unlock()
+		triggerReplacement(clusterCfg, usrCfg, t, spotInterruptionRule, node)
+	}()
+}
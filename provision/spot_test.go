@@ -0,0 +1,120 @@
+package provision
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLaunchAsSpot(t *testing.T) {
+	// Only on-demand instances are launched without a spot percentage
+	assert.False(t, launchAsSpot(config.Spot{OnDemandBase: 1}, 3, 0))
+
+	spot := config.Spot{OnDemandBase: 2, SpotPercentage: 50}
+	assert.False(t, launchAsSpot(spot, 0, 0))
+	assert.False(t, launchAsSpot(spot, 1, 5))
+	// Above the base the spot instances are rounded down in favor of on-demand
+	assert.False(t, launchAsSpot(spot, 2, 0))
+	assert.True(t, launchAsSpot(spot, 3, 0))
+	assert.False(t, launchAsSpot(spot, 3, 1))
+	assert.True(t, launchAsSpot(spot, 4, 1))
+
+	assert.True(t, launchAsSpot(config.Spot{SpotPercentage: 100}, 0, 4))
+}
+
+func TestDrainInterruptedNodes(t *testing.T) {
+	defer func() {
+		handledInterruptions = make(map[string]bool)
+		pendingReplacements = nil
+	}()
+	notice := time.Now()
+	interruptions := []SpotInterruption{
+		{InstanceId: "i-2", PrivateIp: "10.0.0.2", NoticeTime: notice},
+		{InstanceId: "i-9", PrivateIp: "10.0.0.9", NoticeTime: notice},
+	}
+	nodes := map[string]osutils.NodeStats{
+		"m1": {Name: "node-1", Host: "10.0.0.1"},
+		"d2": {Name: "node-2", Host: "10.0.0.2", Attributes: map[string]string{"node_group": "burst"}},
+	}
+
+	// The instance which is not in the cluster is ignored and every notice is handled once
+	drainInterruptedNodes(context.Background(), interruptions, nodes, "m1", true)
+	drainInterruptedNodes(context.Background(), interruptions, nodes, "m1", true)
+	assert.Equal(t, map[string]bool{"i-2": true, "i-9": true}, handledInterruptions)
+	assert.Len(t, pendingReplacements, 1)
+	assert.Equal(t, "node-2", pendingReplacements[0].NodeName)
+	assert.Equal(t, "10.0.0.2", pendingReplacements[0].HostIp)
+	assert.Equal(t, "burst", pendingReplacements[0].NodeGroup)
+	assert.Equal(t, interruptionNotice, pendingReplacements[0].Reason)
+}
+
+func TestDrainInterruptedMaster(t *testing.T) {
+	defer func() {
+		handledInterruptions = make(map[string]bool)
+		pendingReplacements = nil
+	}()
+	cluster := &fakeCluster{nodes: newFakeNodes(), localNode: "n1", masterNode: "n1"}
+	// The first exclusion from the voting configuration fails
+	cluster.fail = func(request string, count int) bool {
+		return request == "POST /_cluster/voting_config_exclusions" && count == 1
+	}
+	cluster.serve(t, State{CurrentState: "normal"})
+	interruptions := []SpotInterruption{{InstanceId: "i-1", PrivateIp: "10.0.0.1", NoticeTime: time.Now()}}
+
+	// The notice is handled again in the next poll
+	drainInterruptedNodes(context.Background(), interruptions, newFakeNodes(), "n1", false)
+	assert.Empty(t, handledInterruptions)
+	assert.Empty(t, pendingReplacements)
+
+	// The master only hands over, the new master drains and replaces it
+	drainInterruptedNodes(context.Background(), interruptions, newFakeNodes(), "n1", false)
+	assert.Equal(t, map[string]bool{"i-1": true}, handledInterruptions)
+	assert.Empty(t, pendingReplacements)
+	assert.Equal(t, 2, cluster.count("POST /_cluster/voting_config_exclusions"))
+	assert.Equal(t, 0, cluster.count("PUT /_cluster/settings"))
+}
+
+func TestDrainInterruptedNodeExclusionFailure(t *testing.T) {
+	defer func() {
+		handledInterruptions = make(map[string]bool)
+		pendingReplacements = nil
+	}()
+	cluster := &fakeCluster{nodes: newFakeNodes(), localNode: "n1", masterNode: "n1"}
+	// The first exclusion from the allocation fails
+	cluster.fail = func(request string, count int) bool {
+		return request == "PUT /_cluster/settings" && count == 1
+	}
+	cluster.serve(t, State{CurrentState: "normal"})
+	interruptions := []SpotInterruption{{InstanceId: "i-2", PrivateIp: "10.0.0.2", NoticeTime: time.Now()}}
+
+	// The notice is not marked handled, so the exclusion is retried in the next poll
+	drainInterruptedNodes(context.Background(), interruptions, newFakeNodes(), "n1", false)
+	assert.Empty(t, handledInterruptions)
+	assert.Empty(t, pendingReplacements)
+
+	drainInterruptedNodes(context.Background(), interruptions, newFakeNodes(), "n1", false)
+	assert.Equal(t, map[string]bool{"i-2": true}, handledInterruptions)
+	if assert.Len(t, pendingReplacements, 1) {
+		assert.Equal(t, "node-2", pendingReplacements[0].NodeName)
+	}
+	assert.Equal(t, 2, cluster.count("PUT /_cluster/settings"))
+	assert.Equal(t, 0, cluster.count("POST /_cluster/voting_config_exclusions"))
+}
+
+func TestReplaceInterruptedNodeWhileProvisioning(t *testing.T) {
+	defer func() {
+		pendingReplacements = nil
+	}()
+	node := unhealthyNode{reportedNode: reportedNode{NodeName: "node-2", HostIp: "10.0.0.2"}, Reason: interruptionNotice}
+	pendingReplacements = []unhealthyNode{node}
+
+	// The interrupted node stays queued while another provision holds the lock
+	provisionLock.Lock()
+	replaceInterruptedNode(config.ClusterDetails{}, config.UserConfig{}, nil)
+	provisionLock.Unlock()
+	assert.Equal(t, []unhealthyNode{node}, pendingReplacements)
+}
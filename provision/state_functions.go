@@ -3,6 +3,7 @@ package provision
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
 	utils "github.com/maplelabs/opensearch-scaling-manager/utilities"
@@ -18,9 +19,20 @@ import (
 //   - start_scaleup_process/start_scaledown_process : Indicates start of scaleup/scaledown process
 //   - scaleup_triggered_spin_vm: Indicates trigger for spinning new vms while scaleup
 //   - scaledown_node_identified: A state to identify node identification to scaledown
+//   - scaledown_allocation_migrated: Indicates the indices pinned to the node of a data tier were moved off the node
 //   - provisioning_scaleup_completed/provisioning_scaledown_completed : Once the provision is completed then this state will be state.
 //   - provisioning_scaleup_failed/provisioning_scaledown_failed: If the provision is failed then this state will be set.
 //   - provisioned_scaleup_successfully/provisioned_scaledown_successfully: If the provision is completed and cluster state is green then this state will be set.
+//   - provisioning_rollingrestart: A rolling restart was requested, which the master starts in its next provision check.
+//   - start_rollingrestart_process: Indicates start of the rolling restart, the nodes to be restarted are listed in PendingNodes
+//   - rollingrestart_node_selected/rollingrestart_allocation_disabled/rollingrestart_node_restarted: Steps of the restart of the first pending node
+//   - provisioned_rollingrestart_successfully/provisioning_rollingrestart_failed: Result of the rolling restart
+//   - provisioning_verticalscale/start_verticalscale_process: A vertical scaling is started, the nodes to be replaced are listed in PendingNodes
+//   - verticalscale_node_selected/verticalscale_triggered_spin_vm/verticalscale_new_node_configured/verticalscale_new_node_joined/verticalscale_node_drained: Steps of the replacement of the first pending node
+//   - provisioned_verticalscale_successfully/provisioning_verticalscale_failed: Result of the vertical scaling
+//   - provisioning_autoheal/start_autoheal_process: An unhealthy or interrupted spot node is replaced, the node is set in NodeName and NodeIp
+//   - autoheal_triggered_spin_vm/autoheal_new_node_configured/autoheal_new_node_joined/autoheal_node_drained: Steps of the replacement of the node
+//   - provisioned_autoheal_successfully/provisioning_autoheal_failed: Result of the replacement
 type State struct {
 	// CurrentState indicate the current state of the scaling manager
 	CurrentState string
@@ -52,6 +64,23 @@ type State struct {
 	NodeName string
 	// Instance ID
 	InstanceId string
+	// Node group being scaled, empty if node groups are not configured
+	NodeGroup string
+	// Task of the playbook running for the provision
+	CurrentTask string
+	// Host the last failed task of a playbook ran on
+	FailedHost string
+	// Error of the last failed task of a playbook
+	FailureMessage string
+	// Names of the nodes the rolling restart or the vertical scaling did not restart or replace yet, the node
+	// being restarted or replaced first
+	PendingNodes []string
+	// True if the rolling restart writes the heap of the jvm_factor to jvm.options before restarting the nodes
+	UpdateHeap bool
+	// Version of the launch template the vertical scaling replaces the nodes with
+	LaunchTemplateVersion string
+	// Private ip of the node replacing the node being replaced by the vertical scaling
+	NewNodeIp string
 }
 
 var state = new(State)
@@ -66,8 +95,15 @@ var docId string
 //	Creates a unique document ID for maintaining the state of the provisioning system and updates the global variable
 //
 // Return:
-func InitializeDocId() {
-	docId = fmt.Sprint(utils.Hash(utils.GetClusterId()))
+//
+//	(error): Return error if any
+func InitializeDocId() error {
+	clusterId, err := utils.GetClusterId()
+	if err != nil {
+		return err
+	}
+	docId = fmt.Sprint(utils.Hash(clusterId))
+	return nil
 }
 
 // Input:
@@ -77,43 +113,48 @@ func InitializeDocId() {
 //
 // Description:
 //      GetCurrentState will update the state variable pointer such that it is in sync with the updated values.
-//      Reads the document from Opensearch and updates the Struct
+//      Reads the document from Opensearch and updates the Struct. If the document does not exist yet,
+//      the initial normal state is created.
 //
 // Return:
+//      (error): Return error if any
 
-func (s *State) GetCurrentState() {
-	// Get the document.
-
-	searchResponse, err := osutils.SearchDoc(context.Background(), docId)
-	if err != nil {
-		log.Panic.Println("failed to search document: ", err)
-		panic(err)
+func (s *State) GetCurrentState() error {
+	// The document ID could not be created at startup if opensearch was unavailable
+	if docId == "" {
+		err := InitializeDocId()
+		if err != nil {
+			return err
+		}
 	}
-	defer searchResponse.Body.Close()
+
+	// Get the document.
 	var stateInterface map[string]interface{}
-	log.Debug.Println("Get resp: ", searchResponse)
-	if searchResponse.Status() == "404 Not Found" {
+	searchResponse, err := osutils.SearchDoc(context.Background(), docId)
+	err = osutils.DecodeResponse(searchResponse, err, &stateInterface)
+	if osutils.IsNotFound(err) {
 		//Setting the initial state
 		s.CurrentState = "normal"
 		s._documentType = "State"
 		s.StatTag = "State"
-		s.UpdateState()
-		return
+		return s.UpdateState()
 	}
-	jsonErr := json.NewDecoder(searchResponse.Body).Decode(&stateInterface)
-	if jsonErr != nil {
-		log.Panic.Println("Unable to decode the response into interface: ", jsonErr)
-		panic(jsonErr)
+	if err != nil {
+		return err
 	}
+
 	// convert map to json
-	jsonString, errr := json.Marshal(stateInterface["_source"].(map[string]interface{}))
-	if errr != nil {
-		log.Panic.Println("Unable to unmarshal interface: ", errr)
-		panic(errr)
+	source, ok := stateInterface["_source"].(map[string]interface{})
+	if !ok {
+		return errors.New("state document has no source")
+	}
+	jsonString, err := json.Marshal(source)
+	if err != nil {
+		return err
 	}
 
 	// convert json to struct
-	json.Unmarshal(jsonString, s)
+	return json.Unmarshal(jsonString, s)
 }
 
 // Input:
@@ -123,24 +164,31 @@ func (s *State) GetCurrentState() {
 //      Updates the opensearch document with the values in state Struct pointer.
 //
 // Return:
+//      (error): Return error if any
+
+func (s *State) UpdateState() error {
+	if docId == "" {
+		err := InitializeDocId()
+		if err != nil {
+			return err
+		}
+	}
 
-func (s *State) UpdateState() {
 	// Update the document.
 
 	s.Timestamp = time.Now().UnixMilli()
 
 	state, err := json.Marshal(s)
 	if err != nil {
-		log.Panic.Println("json.Marshal ERROR: ", err)
-		panic(err)
+		return err
 	}
 	content := string(state)
 
 	updateResponse, err := osutils.UpdateDoc(context.Background(), docId, content)
+	err = osutils.DecodeResponse(updateResponse, err, nil)
 	if err != nil {
-		log.Panic.Println("failed to update document: ", err)
-		panic(err)
+		return err
 	}
-	defer updateResponse.Body.Close()
-	log.Debug.Println("Update resp: ", updateResponse)
+	log.Debug.Println("State document updated: ", s.CurrentState)
+	return nil
 }
@@ -2,8 +2,6 @@ package provision
 
 import (
 	"context"
-	"encoding/json"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -15,9 +13,19 @@ import (
 	utils "github.com/maplelabs/opensearch-scaling-manager/utilities"
 )
 
+// This struct contains a recommendation made by the recommendation engine.
+type Recommendation struct {
+	// TaskName indicates the task recommended. i.e scale_up_by_1, scale_down_by_1 or scale_vertical_to_3.
+	TaskName string
+	// RulesResponsible indicates the rules responsible for the recommendation with delimiters.
+	RulesResponsible string
+	// NodeGroup indicates the node group to be scaled, empty if node groups are not configured.
+	NodeGroup string
+}
+
 // Input:
 //
-//	recommendationQueue ([]map[string]string): Recommendations provided by the recommendation engine in the form of an array of strings
+//	recommendationQueue ([]Recommendation): Recommendations provided by the recommendation engine
 //	clusterCfg (config.ClusterDetails): Cluster Level config details
 //	usrCfg (config.UserConfig): User defined config for applicatio behavior
 //
@@ -28,36 +36,52 @@ import (
 //	Triggers the provisioning
 //
 // Return:
-func GetRecommendation(recommendationQueue []map[string]string, clusterCfg config.ClusterDetails, usrCfg config.UserConfig, t *time.Time) {
+func GetRecommendation(recommendationQueue []Recommendation, clusterCfg config.ClusterDetails, usrCfg config.UserConfig, t *time.Time) {
 	var clusterCurrent cluster.ClusterDynamic
-	scaleRegexString := `(scale_up|scale_down)_by_([0-9]+)`
-	scaleRegex := regexp.MustCompile(scaleRegexString)
 	if len(recommendationQueue) > 0 {
+		if !provisionLock.TryLock() {
+			log.Warn.Println("Recommendation can not be provisioned as open search cluster is already in provisioning phase.")
+			return
+		}
+		defer provisionLock.Unlock()
+		var err error
 		if usrCfg.MonitorWithSimulator {
-			clusterCurrent = cluster_sim.GetClusterCurrent(usrCfg.IsAccelerated)
+			clusterCurrent, err = cluster_sim.GetClusterCurrent(usrCfg.IsAccelerated)
 		} else {
-			clusterCurrent, _ = cluster.GetClusterCurrent(false)
+			clusterCurrent, _, err = cluster.GetClusterCurrent(false)
+		}
+		if err != nil {
+			log.Error.Println("Unable to fetch the cluster health, skipping the recommendation: ", err)
+			return
 		}
 
-		state.GetCurrentState()
+		err = state.GetCurrentState()
+		if err != nil {
+			log.Error.Println("Unable to read the provisioning state, skipping the recommendation: ", err)
+			return
+		}
 		if state.CurrentState == "normal" {
-			var subMatch []string
-			var task string
-			for task, _ = range recommendationQueue[0] {
-				subMatch = scaleRegex.FindStringSubmatch(task)
+			recommendation := recommendationQueue[0]
+			action, err := config.ParseTaskName(recommendation.TaskName)
+			if err != nil {
+				log.Error.Println(err)
+				return
 			}
-
-			numNodes, _ := strconv.Atoi(subMatch[2])
-			operation := subMatch[1]
+			operation := action.Operation
 
 			// Call scale down provisioning only when the cluster status is green. No recommended to scale down when cluster is in yellow or red state
 			if operation == "scale_down" && clusterCurrent.ClusterStatus != "green" {
 				log.Warn.Println("Recommendation can not be provisioned as open search cluster is unhealthy for a scale_down. \n Discarding this recommendation")
 				return
 			}
+			// The nodes of a vertical scaling are drained one at a time, which requires a green cluster like a scale_down
+			if operation == "scale_vertical" && clusterCurrent.ClusterStatus != "green" {
+				log.Warn.Println("Recommendation can not be provisioned as open search cluster is unhealthy for a scale_vertical. \n Discarding this recommendation")
+				return
+			}
 
-			ruleResponsible := recommendationQueue[0][task]
-			numNodesProceed := checkNumNodesCondition(operation, clusterCfg, usrCfg)
+			ruleResponsible := recommendation.RulesResponsible
+			numNodesProceed := checkNumNodesCondition(operation, recommendation.NodeGroup, clusterCfg, usrCfg)
 			if !numNodesProceed {
 				return
 			}
@@ -66,7 +90,11 @@ func GetRecommendation(recommendationQueue []map[string]string, clusterCfg confi
 				return
 			}
 
-			TriggerProvision(clusterCfg, usrCfg, numNodes, t, operation, ruleResponsible)
+			if operation == "scale_vertical" {
+				TriggerVerticalScale(clusterCfg, usrCfg, t, action.LaunchTemplateVersion, ruleResponsible, recommendation.NodeGroup)
+			} else {
+				TriggerProvision(clusterCfg, usrCfg, action.NumNodes, t, operation, ruleResponsible, recommendation.NodeGroup)
+			}
 		} else {
 			log.Warn.Println("Recommendation can not be provisioned as open search cluster is already in provisioning phase.")
 		}
@@ -92,12 +120,12 @@ func getLatestProvisionQuery() string {
                     "bool": {
                       "must": [
                         {
-                          "match": {
+                          "term": {
                             "StatTag": "ProvisionStats"
                           }
                         },
                         {
-                          "match": {
+                          "term": {
                             "Status": "Success"
                           }
                         }
@@ -110,23 +138,43 @@ func getLatestProvisionQuery() string {
 // Input:
 //
 //	operation (string): The operation recommended (scale_up or scale_down)
-//	clusterCfg (config.ClusterDetails): User defined configuration which contains the max and min nodes specified for the cluster
+//	nodeGroupName (string): The node group to be scaled, empty if node groups are not configured
+//	clusterCfg (config.ClusterDetails): User defined configuration which contains the max and min nodes specified for the cluster and the node groups
 //
 // Description:
 //
 //	Checks the max nodes condition when a scale_up is recommended. Returns false if scale_up increasing nodes to greater than max nodes defined.
 //	Checks the min nodes condition when a scale_down is recommended. Returns false if scale_down reduces the nodes to less than min nodes defined.
+//	A scale_vertical replaces the nodes one at a time and keeps their number, it only adds a node while one is replaced.
+//	The conditions are checked for the whole cluster as well as for the nodes of the node group.
 //
 // Return:
 //
 //	(bool): Returns a bool value to decide to proceed with provisioning or drop the recommendation
-func checkNumNodesCondition(operation string, clusterCfg config.ClusterDetails, usrCfg config.UserConfig) bool {
-	var numNodes int
+func checkNumNodesCondition(operation string, nodeGroupName string, clusterCfg config.ClusterDetails, usrCfg config.UserConfig) bool {
+	nodeGroup, err := clusterCfg.GetNodeGroup(nodeGroupName)
+	if err != nil {
+		log.Error.Println("Unable to scale the node group: ", err)
+		return false
+	}
+	var numNodes, numGroupNodes int
 	if usrCfg.MonitorWithSimulator {
-		clusterDynamic := cluster_sim.GetClusterCurrent(usrCfg.IsAccelerated)
+		clusterDynamic, err := cluster_sim.GetClusterCurrent(usrCfg.IsAccelerated)
+		if err != nil {
+			log.Error.Println("Unable to fetch the number of nodes: ", err)
+			return false
+		}
+		// The simulator has no node groups
 		numNodes = clusterDynamic.NumNodes
+		numGroupNodes = clusterDynamic.NumNodes
 	} else {
-		numNodes = len(utils.GetNodes())
+		nodes, err := utils.GetNodes()
+		if err != nil {
+			log.Error.Println("Unable to fetch the number of nodes: ", err)
+			return false
+		}
+		numNodes = len(nodes)
+		numGroupNodes, _ = utils.CountNodes(nodes, nodeGroup)
 	}
 	switch operation {
 	case "scale_up":
@@ -134,11 +182,19 @@ func checkNumNodesCondition(operation string, clusterCfg config.ClusterDetails,
 			log.Warn.Println("Cannot scale up as the maximum number of nodes for this cluster specified is reached.\n If we need the scale up to take place anyway, consider increasing the max nodes in config.yaml")
 			return false
 		}
+		if numGroupNodes+1 > nodeGroup.MaxNodesAllowed {
+			log.Warn.Println("Cannot scale up as the maximum number of nodes for the node group ", nodeGroup.Name, " is reached.\n If we need the scale up to take place anyway, consider increasing the max nodes of the node group in config.yaml")
+			return false
+		}
 	case "scale_down":
 		if numNodes-1 < clusterCfg.MinNodesAllowed {
 			log.Warn.Println("Cannot scale down as the minimum number of nodes for this cluster specified is reached.\n If you need the scale down to take place anyway, consider decreasing the min nodes in config.yaml")
 			return false
 		}
+		if numGroupNodes-1 < nodeGroup.MinNodesAllowed {
+			log.Warn.Println("Cannot scale down as the minimum number of nodes for the node group ", nodeGroup.Name, " is reached.\n If you need the scale down to take place anyway, consider decreasing the min nodes of the node group in config.yaml")
+			return false
+		}
 	}
 	return true
 }
@@ -172,29 +228,36 @@ func comparePreviousProvision(ruleResponsible string, operation string) bool {
 	}
 
 	// Get the latest document of successful provision happened
-	resp, err := osutils.SearchQuery(context.Background(), []byte(getLatestProvisionQuery()))
+	var latestProvision struct {
+		Hits *struct {
+			Hits []struct {
+				Source struct {
+					ProvisionEndTime *float64 `json:"ProvisionEndTime"`
+				} `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+
+	resp, err := osutils.SearchQuery(context.Background(), osutils.ProvisionStatsIndex, []byte(getLatestProvisionQuery()))
+	err = osutils.DecodeResponse(resp, err, &latestProvision)
 	if err != nil {
 		log.Error.Println("Error querying the last provision document frm Opensearch", err)
 		return false
 	}
-	defer resp.Body.Close()
-
-	var respInterface map[string]interface{}
-
-	decodeErr := json.NewDecoder(resp.Body).Decode(&respInterface)
-	if decodeErr != nil {
-		log.Error.Println("decode Error: ", decodeErr)
+	if latestProvision.Hits == nil {
+		log.Error.Println("The response of the last provision document query has no hits")
 		return false
 	}
 
-	respHits := respInterface["hits"].(map[string]interface{})["hits"].([]interface{})
-
 	var lastProvisionTime time.Time
 
 	// Get the last successful provision time
-	for _, doc := range respHits {
-		provisionEndTime := doc.(map[string]interface{})["_source"].(map[string]interface{})["ProvisionEndTime"].(float64)
-		lastProvisionTime = time.UnixMilli(int64(provisionEndTime))
+	for _, doc := range latestProvision.Hits.Hits {
+		if doc.Source.ProvisionEndTime == nil {
+			log.Error.Println("The last provision document has no ProvisionEndTime")
+			return false
+		}
+		lastProvisionTime = time.UnixMilli(int64(*doc.Source.ProvisionEndTime))
 	}
 
 	duration, dErr := time.ParseDuration(strconv.Itoa(largestDecisionPeriod) + "m")
@@ -229,11 +292,12 @@ func comparePreviousProvision(ruleResponsible string, operation string) bool {
 // Input:
 //
 //	nodesRequired (int): Specifies required count of nodes to be present for the Event based scaling.
-//	task (string): Specifies the name of the task. i.e scale_up_by_1 or scale_down_by_1.
+//	task (string): Specifies the name of the task. i.e scale_up_by_1, scale_down_by_1 or scale_vertical_to_3.
 //	state (*State): A pointer to the state struct which is state maintained in OS document.
 //	clusterCfg (config.ClusterDetails): Cluster Level config details.
 //	usrCfg (config.UserConfig): User defined config for application behavior.
 //	rulesResponsible (string): Specifies the rule (cron time expression) that triggered the execution of cron job,
+//	nodeGroup (string): Specifies the node group to be scaled, empty if node groups are not configured.
 //
 // Description:
 //
@@ -244,27 +308,38 @@ func comparePreviousProvision(ruleResponsible string, operation string) bool {
 //		logs the event and returns
 //
 // Return:
-func TriggerCron(t *time.Time, clusterCfg config.ClusterDetails, userCfg config.UserConfig, ruleResponsible, task string) {
+func TriggerCron(t *time.Time, clusterCfg config.ClusterDetails, userCfg config.UserConfig, ruleResponsible, task, nodeGroup string) {
+	if !provisionLock.TryLock() {
+		log.Warn.Println("Provision is already in progress, Event based scaling will be discarded")
+		return
+	}
+	defer provisionLock.Unlock()
 
-	state.GetCurrentState()
+	err := state.GetCurrentState()
+	if err != nil {
+		log.Error.Println("Unable to read the provisioning state, Event based scaling will be discarded: ", err)
+		return
+	}
 	if state.CurrentState != "normal" {
 		log.Warn.Println("Provision is already in progress, Event based scaling will be discarded")
 		return
 	}
 
-	scaleRegexString := `(scale_up|scale_down)_by_([0-9]+)`
-	scaleRegex := regexp.MustCompile(scaleRegexString)
-
-	var subMatch []string
-
-	subMatch = scaleRegex.FindStringSubmatch(task)
-	numNodes, _ := strconv.Atoi(subMatch[2])
-	operation := subMatch[1]
+	action, err := config.ParseTaskName(task)
+	if err != nil {
+		log.Error.Println(err)
+		return
+	}
+	operation := action.Operation
 
-	numNodesProceed := checkNumNodesCondition(operation, clusterCfg, userCfg)
+	numNodesProceed := checkNumNodesCondition(operation, nodeGroup, clusterCfg, userCfg)
 
 	if numNodesProceed {
 		log.Info.Println("The ", task, " is triggered as event based scaling and will be provisioned.")
-		TriggerProvision(clusterCfg, userCfg, numNodes, t, operation, ruleResponsible)
+		if operation == "scale_vertical" {
+			TriggerVerticalScale(clusterCfg, userCfg, t, action.LaunchTemplateVersion, ruleResponsible, nodeGroup)
+		} else {
+			TriggerProvision(clusterCfg, userCfg, action.NumNodes, t, operation, ruleResponsible, nodeGroup)
+		}
 	}
 }
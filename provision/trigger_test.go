@@ -0,0 +1,57 @@
+package provision
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
+	opensearch "github.com/opensearch-project/opensearch-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// Starts a server handling the opensearch requests and points the client of the osutils package to it
+func newOsServer(t *testing.T, handler http.HandlerFunc) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The client checks the product with a GET on the root first
+		if r.URL.Path == "/" {
+			w.Write([]byte(`{"version": {"number": "2.4.0"}}`))
+			return
+		}
+		handler(w, r)
+	}))
+	client, err := osutils.NewClient(opensearch.Config{Addresses: []string{server.URL}, DisableRetry: true})
+	assert.Nil(t, err)
+	previous := osutils.SetClient(client)
+	t.Cleanup(func() {
+		osutils.SetClient(previous)
+		server.Close()
+	})
+}
+
+func TestComparePreviousProvision(t *testing.T) {
+	respond := func(body string) {
+		newOsServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+		})
+	}
+	provisionedAgo := func(ago time.Duration) string {
+		return fmt.Sprintf(`{"hits": {"hits": [{"_source": {"ProvisionEndTime": %d}}]}}`, time.Now().Add(-ago).UnixMilli())
+	}
+
+	respond(provisionedAgo(time.Hour))
+	assert.True(t, comparePreviousProvision("CpuUtil-avg-30", "scale_up"))
+	// A provision within the largest decision period of the rules discards the recommendation
+	assert.False(t, comparePreviousProvision("CpuUtil-avg-30_and_RamUtil-avg-90", "scale_up"))
+
+	respond(`{"hits": {"hits": []}}`)
+	assert.True(t, comparePreviousProvision("CpuUtil-avg-30", "scale_up"))
+
+	// The responses missing a part discard the recommendation instead of panicking
+	respond(`{"error": "unexpected"}`)
+	assert.False(t, comparePreviousProvision("CpuUtil-avg-30", "scale_up"))
+	respond(`{"hits": {"hits": [{"_source": {}}]}}`)
+	assert.False(t, comparePreviousProvision("CpuUtil-avg-30", "scale_up"))
+}
@@ -0,0 +1,325 @@
+package provision
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+	"github.com/maplelabs/opensearch-scaling-manager/crypto"
+	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
+	utils "github.com/maplelabs/opensearch-scaling-manager/utilities"
+)
+
+// Rule triggered recorded for the vertical scalings in the state and the ProvisionStats documents
+const verticalScaleRule = "scale_vertical"
+
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Cluster Level config details
+//	usrCfg (config.UserConfig): User defined config for application behavior
+//	launchTemplateVersion (string): Version of the launch template the nodes are replaced with
+//	rulesResponsible (string): A string that contains the rules responsible for the decision of operation being performed
+//	nodeGroup (string): The node group to be scaled, empty if node groups are not configured
+//
+// Description:
+//
+//	TriggerVerticalScale sets the state of a vertical scaling and replaces the nodes of the node group with
+//	nodes launched from the version of the launch template. The caller holds the provision lock.
+//
+// Return:
+func TriggerVerticalScale(clusterCfg config.ClusterDetails, usrCfg config.UserConfig, t *time.Time, launchTemplateVersion, rulesResponsible, nodeGroup string) {
+	err := state.GetCurrentState()
+	if err != nil {
+		log.Error.Println("Unable to read the provisioning state, skipping the provision: ", err)
+		return
+	}
+	state.PreviousState = state.CurrentState
+	state.CurrentState = "provisioning_verticalscale"
+	state.NumNodes = 0
+	state.RemainingNodes = 0
+	state.PendingNodes = nil
+	state.RuleTriggered = verticalScaleRule
+	state.RulesResponsible = rulesResponsible
+	state.NodeGroup = nodeGroup
+	state.LaunchTemplateVersion = launchTemplateVersion
+	err = state.UpdateState()
+	if err != nil {
+		log.Error.Println("Unable to update the provisioning state, skipping the provision: ", err)
+		return
+	}
+	isScaled, err := ScaleVertical(clusterCfg, usrCfg, t)
+	completeProvision("verticalscale", isScaled, err)
+}
+
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Cluster Level config details
+//	usrCfg (config.UserConfig): User defined config for application behavior
+//
+// Description:
+//
+//	ScaleVertical replaces the nodes of the node group one at a time with nodes launched from another version of
+//	its launch template, like one with a bigger instance type. For every node a new node is spinned and
+//	configured, and once it joined the cluster the old node is drained and terminated and the cluster has to
+//	become green before the next node is replaced. The nodes already launched from the version are kept, and no
+//	node is replaced unless the scaling manager can terminate the instances of all the nodes to replace. The
+//	elected master is replaced last, after it handed over to another master which resumes the vertical scaling
+//	from the state. The voting exclusions are cleared if the vertical scaling fails.
+//
+// Return:
+//
+//	(bool, error): Returns true if all the nodes were replaced and error if any
+func ScaleVertical(clusterCfg config.ClusterDetails, usrCfg config.UserConfig, t *time.Time) (bool, error) {
+	if err := state.GetCurrentState(); err != nil {
+		return false, &interruptedError{err}
+	}
+	crypto.GetDecryptedCloudCreds(&clusterCfg.CloudCredentials)
+	crypto.GetDecryptedOsCreds(&clusterCfg.OsCredentials)
+	isScaled, err := scaleVertical(clusterCfg, usrCfg)
+	var interruptErr *interruptedError
+	if err != nil && !errors.As(err, &interruptErr) && !usrCfg.MonitorWithLogs {
+		votingErr := osutils.ClearVotingExclusions(context.Background())
+		if votingErr != nil {
+			log.Error.Println("Unable to clear the voting exclusions of the replaced master: ", votingErr)
+		}
+	}
+	return isScaled, err
+}
+
+// Input:
+//
+//	clusterCfg (config.ClusterDetails): Cluster Level config details with the decrypted credentials
+//	usrCfg (config.UserConfig): User defined config for application behavior
+//
+// Description:
+//
+//	Runs the steps of the vertical scaling from the step persisted in the state, looping over the nodes which
+//	are not replaced yet
+//
+// Return:
+//
+//	(bool, error): Returns true if all the nodes were replaced and error if any
+func scaleVertical(clusterCfg config.ClusterDetails, usrCfg config.UserConfig) (bool, error) {
+	nodeGroup, err := clusterCfg.GetNodeGroup(state.NodeGroup)
+	if err != nil {
+		return false, err
+	}
+	monitorWithLogs := usrCfg.MonitorWithLogs
+	pollingInterval := time.Duration(usrCfg.RecommendationPollingInterval) * time.Second
+	for {
+		switch state.CurrentState {
+		case "provisioning_verticalscale":
+			log.Info.Println("Starting the vertical scaling to the version ", state.LaunchTemplateVersion, " of the launch template")
+			state.ProvisionStartTime = time.Now().UnixMilli()
+			if err := setProvisionState("start_verticalscale_process"); err != nil {
+				return false, err
+			}
+		// List the nodes which are not launched from the version yet
+		case "start_verticalscale_process":
+			if monitorWithLogs {
+				state.PendingNodes = []string{"node-1", "node-2", "node-3"}
+			} else {
+				// $Latest and $Default are resolved once, the nodes are all replaced from the same version
+				launchTemplateVersion, err := LaunchTemplateVersionNumber(nodeGroup.LaunchTemplateId, state.LaunchTemplateVersion, clusterCfg.CloudCredentials)
+				if err != nil {
+					return false, err
+				}
+				state.LaunchTemplateVersion = launchTemplateVersion
+				nodes, err := utils.GetNodes()
+				if err != nil {
+					return false, &interruptedError{err}
+				}
+				masterNodeId, err := utils.GetMasterNodeId(context.Background())
+				if err != nil {
+					return false, &interruptedError{err}
+				}
+				var privateIps []string
+				nodeIps := make(map[string]string)
+				for _, node := range nodes {
+					privateIps = append(privateIps, node.Host)
+					nodeIps[node.Name] = node.Host
+				}
+				versions, err := InstanceLaunchTemplateVersions(nodeGroup.LaunchTemplateId, privateIps, clusterCfg.CloudCredentials)
+				if err != nil {
+					return false, err
+				}
+				state.PendingNodes = nil
+				for _, nodeName := range rollingRestartOrder(nodes, masterNodeId, state.NodeGroup) {
+					if versions[nodeIps[nodeName]] != state.LaunchTemplateVersion {
+						state.PendingNodes = append(state.PendingNodes, nodeName)
+					}
+				}
+				// No node is launched or drained unless every pending node can be terminated once it is replaced
+				instanceIds, err := managedNodeInstanceIds(privateIps, clusterCfg.CloudCredentials)
+				if err != nil {
+					return false, err
+				}
+				if unmanaged := unmanagedNodes(state.PendingNodes, nodeIps, instanceIds); len(unmanaged) > 0 {
+					return false, fmt.Errorf("the instances of the nodes %v were not launched by the scaling manager and can't be terminated, the node group is not scaled", unmanaged)
+				}
+			}
+			state.NumNodes = len(state.PendingNodes)
+			state.RemainingNodes = len(state.PendingNodes)
+			nextState := "verticalscale_node_selected"
+			if len(state.PendingNodes) == 0 {
+				log.Info.Println("All the nodes of the node group ", nodeGroup.Name, " are launched from the version ", state.LaunchTemplateVersion, " of the launch template")
+				nextState = "provisioned_verticalscale_successfully"
+			} else {
+				log.Info.Println(fmt.Sprintf("Replacing the nodes in the order %v", state.PendingNodes))
+			}
+			if err := setProvisionState(nextState); err != nil {
+				return false, err
+			}
+		// Spin the node replacing the first pending node
+		case "verticalscale_node_selected":
+			state.NodeName = state.PendingNodes[0]
+			log.Info.Println(fmt.Sprintf("Replacing the node %s, %d of %d", state.NodeName, state.NumNodes-state.RemainingNodes+1, state.NumNodes))
+			if monitorWithLogs {
+				log.Info.Println("Spin a new vm from the version of the launch template")
+				time.Sleep(pollingInterval)
+			} else {
+				nodes, err := utils.GetNodes()
+				if err != nil {
+					return false, &interruptedError{err}
+				}
+				masterNodeId, err := utils.GetMasterNodeId(context.Background())
+				if err != nil {
+					return false, &interruptedError{err}
+				}
+				state.NodeIp = ""
+				for nodeId, node := range nodes {
+					if node.Name != state.NodeName {
+						continue
+					}
+					state.NodeIp = node.Host
+					// The master hands over before it is replaced, the new master resumes from here
+					if nodeId == masterNodeId {
+						log.Info.Println("Excluding the master ", state.NodeName, " from the voting configuration before it is replaced")
+						err = osutils.ExcludeFromVoting(context.Background(), state.NodeName)
+						if err != nil {
+							return false, &interruptedError{err}
+						}
+						return false, &interruptedError{errors.New("the master " + state.NodeName + " handed over to be replaced")}
+					}
+				}
+				if state.NodeIp == "" {
+					return false, errors.New("node " + state.NodeName + " is not present in the cluster")
+				}
+				state.NewNodeIp, state.InstanceId, err = spinNewNode(clusterCfg, nodeGroup, state.LaunchTemplateVersion, state.NodeIp)
+				if err != nil {
+					return false, err
+				}
+				log.Info.Println("Spinned a new node: ", state.NewNodeIp)
+			}
+			if err := setProvisionState("verticalscale_triggered_spin_vm"); err != nil {
+				return false, err
+			}
+		// Configure opensearch on the new node
+		case "verticalscale_triggered_spin_vm":
+			if monitorWithLogs {
+				log.Info.Println("Configure opensearch on the new node")
+				time.Sleep(pollingInterval)
+			} else {
+				err := setUpNewNode(clusterCfg, nodeGroup, state.NewNodeIp, state.InstanceId)
+				if err != nil {
+					return false, err
+				}
+			}
+			if err := setProvisionState("verticalscale_new_node_configured"); err != nil {
+				return false, err
+			}
+		// Wait for the new node to join the cluster
+		case "verticalscale_new_node_configured":
+			if !monitorWithLogs {
+				err := waitForNewNodeToJoin(clusterCfg, state.NewNodeIp)
+				if err != nil {
+					return false, err
+				}
+				startScalingManagerOnNewNode(clusterCfg, nodeGroup, state.NewNodeIp)
+			}
+			if err := setProvisionState("verticalscale_new_node_joined"); err != nil {
+				return false, err
+			}
+		// Move the shards of the old node to the other nodes and stop opensearch on it
+		case "verticalscale_new_node_joined":
+			if monitorWithLogs {
+				log.Info.Println("Drain the node through ansible")
+				time.Sleep(pollingInterval)
+			} else {
+				nodes, err := utils.GetNodes()
+				if err != nil {
+					return false, &interruptedError{err}
+				}
+				if nodeGroup.Tier != "" {
+					if err := migrateIndexAllocation(context.Background(), nodes, state.NodeName, nodeGroup.Tier); err != nil {
+						return false, err
+					}
+				}
+				err = removeNodeFromCluster(clusterCfg, nodes, nodeGroup, state.NodeName, state.NodeIp)
+				if err != nil {
+					return false, err
+				}
+			}
+			if err := setProvisionState("verticalscale_node_drained"); err != nil {
+				return false, err
+			}
+		// Terminate the old node and wait for the cluster to be green
+		case "verticalscale_node_drained":
+			if !monitorWithLogs {
+				log.Info.Println("Terminating the instance of the node ", state.NodeName)
+				err := terminateNodeInstance(state.NodeIp, clusterCfg.CloudCredentials)
+				if err != nil {
+					return false, err
+				}
+				err = osutils.ClearVotingExclusions(context.Background())
+				if err != nil {
+					log.Warn.Println("Unable to clear the voting exclusions: ", err)
+				}
+				waitForGreenCluster(pollingInterval)
+			}
+			log.Info.Println(fmt.Sprintf("Node %s replaced by %s", state.NodeName, state.NewNodeIp))
+			state.PendingNodes = state.PendingNodes[1:]
+			state.RemainingNodes = len(state.PendingNodes)
+			state.NodeName = ""
+			state.NodeIp = ""
+			state.NewNodeIp = ""
+			state.InstanceId = ""
+			nextState := "verticalscale_node_selected"
+			if len(state.PendingNodes) == 0 {
+				nextState = "provisioned_verticalscale_successfully"
+			}
+			if err := setProvisionState(nextState); err != nil {
+				return false, err
+			}
+		case "provisioned_verticalscale_successfully":
+			return true, nil
+		default:
+			return false, errors.New("unknown vertical scaling state " + state.CurrentState)
+		}
+	}
+}
+
+// Input:
+//
+//	pendingNodes ([]string): Names of the nodes to be replaced
+//	nodeIps (map[string]string): Private ips of the nodes by name
+//	instanceIds (map[string]string): Instance IDs by private ip of the nodes whose instance can be terminated
+//
+// Description:
+//
+//	Finds the nodes to be replaced whose instance the scaling manager can't terminate
+//
+// Return:
+//
+//	([]string): Returns the names of the nodes, in the order of the pending nodes
+func unmanagedNodes(pendingNodes []string, nodeIps map[string]string, instanceIds map[string]string) []string {
+	var unmanaged []string
+	for _, nodeName := range pendingNodes {
+		if _, ok := instanceIds[nodeIps[nodeName]]; !ok {
+			unmanaged = append(unmanaged, nodeName)
+		}
+	}
+	return unmanaged
+}
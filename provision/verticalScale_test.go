@@ -0,0 +1,16 @@
+package provision
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmanagedNodes(t *testing.T) {
+	nodeIps := map[string]string{"node-1": "10.0.0.1", "node-2": "10.0.0.2", "node-3": "10.0.0.3"}
+	instanceIds := map[string]string{"10.0.0.2": "i-2"}
+
+	assert.Equal(t, []string{"node-3", "node-1"}, unmanagedNodes([]string{"node-3", "node-2", "node-1"}, nodeIps, instanceIds))
+	assert.Empty(t, unmanagedNodes([]string{"node-2"}, nodeIps, instanceIds))
+	assert.Empty(t, unmanagedNodes(nil, nodeIps, instanceIds))
+}
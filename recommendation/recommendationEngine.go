@@ -10,7 +10,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"regexp"
 	"strings"
 	"time"
 
@@ -54,18 +53,18 @@ func init() {
 //              If the task is meeting the criteria then it will push the task to recommendation queue.
 //
 // Return:
-//              ([]map[string]string): Returns an array of the recommendations.
+//              ([]provision.Recommendation): Returns an array of the recommendations.
 
-func EvaluateTask(pollingInterval int, simFlag, isAccelerated bool, t *config.TaskDetails) []map[string]string {
-	var recommendationArray []map[string]string
+func EvaluateTask(pollingInterval int, simFlag, isAccelerated bool, t *config.TaskDetails) []provision.Recommendation {
+	var recommendationArray []provision.Recommendation
 	var isRecommendedTask bool
 	for _, v := range t.Tasks {
-		var rulesResponsibleMap = make(map[string]string)
-		isRecommendedTask, rulesResponsibleMap[v.TaskName] = GetNextTask(pollingInterval, simFlag, isAccelerated, v)
-		log.Debug.Println(rulesResponsibleMap)
+		var recommendation = provision.Recommendation{TaskName: v.TaskName, NodeGroup: v.NodeGroup}
+		isRecommendedTask, recommendation.RulesResponsible = GetNextTask(pollingInterval, simFlag, isAccelerated, v)
+		log.Debug.Println(recommendation)
 		if isRecommendedTask {
 			PushToRecommendationQueue(v)
-			recommendationArray = append(recommendationArray, rulesResponsibleMap)
+			recommendationArray = append(recommendationArray, recommendation)
 		} else {
 			log.Debug.Println(fmt.Sprintf("The %s task is not recommended as rules are not satisfied", v.TaskName))
 		}
@@ -95,12 +94,13 @@ func GetNextTask(pollingInterval int, simFlag, isAccelerated bool, t config.Task
 	var rulesResponsible string
 	var err error
 
-	scaleRegexString := `(scale_up|scale_down)_by_([0-9]+)`
-	scaleRegex := regexp.MustCompile(scaleRegexString)
-
-	subMatch := scaleRegex.FindStringSubmatch(t.TaskName)
-
-	taskOperation := subMatch[1]
+	action, err := config.ParseTaskName(t.TaskName)
+	if err != nil {
+		log.Error.Println(err)
+		return false, ""
+	}
+	// The rules of a vertical scaling are evaluated like the ones of a scale up
+	taskOperation := action.RuleOperation()
 
 	var rules []string
 	for _, v := range t.Rules {
@@ -109,7 +109,7 @@ func GetNextTask(pollingInterval int, simFlag, isAccelerated bool, t config.Task
 		// There is a possibility that each rule is taking time.
 		// What if in the case of AND the non matching rule is present at the last.
 		// What if in the case of OR the matching rule is present at the last.
-		isRecommendedRule, err = GetNextRule(taskOperation, pollingInterval, simFlag, isAccelerated, v)
+		isRecommendedRule, err = GetNextRule(taskOperation, pollingInterval, simFlag, isAccelerated, t.NodeGroup, v)
 		if err != nil {
 			log.Warn.Println(fmt.Sprintf("%s for the rule: %v", err, v))
 		}
@@ -139,6 +139,7 @@ func GetNextTask(pollingInterval int, simFlag, isAccelerated bool, t config.Task
 //              taskOperation (string); Recommended operation
 //              simFlag (bool): A flag to check if the task needs to collect stats from Opensearch data or simulated data.
 //              pollingInterval (int): Time in seconds which is the interval between each metric is pushed into the index.
+//              nodeGroup (string): The node group whose metrics are evaluated, empty for all the nodes
 //
 // Caller:
 //              Object of Rule
@@ -150,8 +151,8 @@ func GetNextTask(pollingInterval int, simFlag, isAccelerated bool, t config.Task
 // Return:
 //              (bool, error): Return if a rule is meeting the criteria or not(bool) and error if any
 
-func GetNextRule(taskOperation string, pollingInterval int, simFlag, isAccelerated bool, r config.Rule) (bool, error) {
-	cluster, err := GetMetrics(pollingInterval, simFlag, isAccelerated, r, taskOperation)
+func GetNextRule(taskOperation string, pollingInterval int, simFlag, isAccelerated bool, nodeGroup string, r config.Rule) (bool, error) {
+	cluster, err := GetMetrics(pollingInterval, simFlag, isAccelerated, r, taskOperation, nodeGroup)
 	if err != nil {
 		return false, err
 	}
@@ -165,6 +166,8 @@ func GetNextRule(taskOperation string, pollingInterval int, simFlag, isAccelerat
 //              simFlag (bool): A flag to check if the task needs to collect stats from Opensearch data or simulated data.
 //              pollingInterval (int): Time in seconds which is the interval between each metric is pushed into the index.
 //              taskOperation (string); Recommended operation
+//              nodeGroup (string): The node group whose metrics are fetched, empty for all the nodes.
+//                      The simulator has no node groups, so it is ignored with simFlag.
 //
 // Caller:
 //              Object of Rule
@@ -178,7 +181,7 @@ func GetNextRule(taskOperation string, pollingInterval int, simFlag, isAccelerat
 // Return:
 //              ([]byte, error): Return marshal form of either MetricStatsCluster or MetricViolatedCountCluster struct([]byte) and error if any
 
-func GetMetrics(pollingInterval int, simFlag, isAccelerated bool, r config.Rule, taskOperation string) ([]byte, error) {
+func GetMetrics(pollingInterval int, simFlag, isAccelerated bool, r config.Rule, taskOperation string, nodeGroup string) ([]byte, error) {
 	var clusterStats cluster.MetricStats
 	var clusterCount cluster.MetricViolatedCount
 	var clusterMetric []byte
@@ -190,7 +193,7 @@ func GetMetrics(pollingInterval int, simFlag, isAccelerated bool, r config.Rule,
 		if simFlag {
 			clusterStats, err = cluster_sim.GetClusterAvg(r.Metric, r.DecisionPeriod, isAccelerated)
 		} else {
-			clusterStats, invalidDatapoints, err = cluster.GetClusterAvg(ctx, r.Metric, r.DecisionPeriod, pollingInterval)
+			clusterStats, invalidDatapoints, err = cluster.GetClusterAvg(ctx, r.Metric, r.DecisionPeriod, pollingInterval, nodeGroup)
 		}
 
 		if err != nil || invalidDatapoints {
@@ -209,9 +212,9 @@ func GetMetrics(pollingInterval int, simFlag, isAccelerated bool, r config.Rule,
 		if simFlag {
 			clusterCount, err = cluster_sim.GetClusterCount(r.Metric, r.DecisionPeriod, r.Limit, isAccelerated)
 		} else if r.Stat == "COUNT" {
-			clusterCount, invalidDatapoints, err = cluster.GetClusterCount(ctx, r.Metric, r.DecisionPeriod, pollingInterval, r.Limit, taskOperation)
+			clusterCount, invalidDatapoints, err = cluster.GetClusterCount(ctx, r.Metric, r.DecisionPeriod, pollingInterval, r.Limit, taskOperation, nodeGroup)
 		} else if r.Stat == "TERM" && r.Metric == "ShardsPerGB" {
-			clusterCount, invalidDatapoints, err = cluster.GetShardsPerGBLimit(ctx, r.Metric, r.DecisionPeriod, r.Limit, pollingInterval)
+			clusterCount, invalidDatapoints, err = cluster.GetShardsPerGBLimit(ctx, r.Metric, r.DecisionPeriod, r.Limit, pollingInterval, nodeGroup)
 		}
 
 		if err != nil || invalidDatapoints {
@@ -319,41 +322,52 @@ func ParseTasks(taskDetails config.TaskDetails) (*config.TaskDetails, *config.Ta
 
 // Input:
 //
-//	cronTasks ([]]recommendation.Task): List of tasks to be added to Cron Job
-//	state (*provision.State): A pointer to the state struct which is state maintained in OS document
+//	eventTasks (*config.TaskDetails): Event based tasks to be added to Cron Job
 //	clusterCfg (config.ClusterDetails): Cluster Level config details
 //	usrCfg (config.UserConfig): User defined config for application behavior
 //
 // Description:
 //
-//	       At each polling interval creates the cron jobs based on the config file. It removes the Cron Jobs that were
-//	added in previous polling interval and creates required jobs. It will use the list of tasks (cronTasks) to
-//	       schedule and create cron job.
+//	Creates the cron jobs of the event based tasks. It stops the Cron Jobs created before and creates the
+//	required jobs, so it is called again whenever the config changes. It will use the list of tasks (eventTasks)
+//	to schedule and create cron job.
 //
 // Return:
 func CreateCronJob(eventTasks *config.TaskDetails, clusterCfg config.ClusterDetails, userCfg config.UserConfig, t *time.Time) {
-	for _, cronJob := range cronJobList {
-		for _, jobs := range cronJob.Entries() {
-			cronJob.Remove(jobs.ID)
-		}
-	}
-
-	cronJobList = nil
+	RemoveCronJobs()
 
 	for _, cronTask := range eventTasks.Tasks {
 		cronTask := cronTask
 		cronJob := cron.New()
 		for _, rules := range cronTask.Rules {
 			rules := rules
-			cronJob.AddFunc(rules.SchedulingTime, func() {
-				provision.TriggerCron(t, clusterCfg, userCfg, rules.SchedulingTime, cronTask.TaskName)
+			_, err := cronJob.AddFunc(rules.SchedulingTime, func() {
+				provision.TriggerCron(t, clusterCfg, userCfg, rules.SchedulingTime, cronTask.TaskName, cronTask.NodeGroup)
 			})
-			cronJobList = append(cronJobList, cronJob)
+			if err != nil {
+				log.Error.Println(fmt.Sprintf("Unable to schedule the %s task at %s: ", cronTask.TaskName, rules.SchedulingTime), err)
+			}
 		}
+		cronJobList = append(cronJobList, cronJob)
 		cronJob.Start()
 	}
 }
 
+// Input:
+//
+// Description:
+//
+//	Stops and removes the cron jobs of the event based tasks, when the node is no longer master
+//	or before they are created again.
+//
+// Return:
+func RemoveCronJobs() {
+	for _, cronJob := range cronJobList {
+		cronJob.Stop()
+	}
+	cronJobList = nil
+}
+
 // Input:
 //
 // Caller:
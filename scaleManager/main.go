@@ -29,13 +29,17 @@ var firstExecution bool
 
 var seed = time.Now().Unix()
 
+// Interval at which the master polls the interruption notices of the spot instances, a fraction of their two
+// minutes notice
+const spotInterruptionPollInterval = 15 * time.Second
+
 // Input:
 //
 // Description:
 //
 //	Initializes the main module
 //	Sets the global vraible "firstExecution" to mark the start of application
-//	Calls method to initialize the Opensaerch client in osutils module by reading the config file for credentials
+//	Initializes the Opensearch client by reading the config file for credentials and encrypts them
 //	Starts the fetchMetrics module to start collecting the data and dump into Opensearch (if userCfg.MonitorWithSimulator is false)
 //
 // Return:
@@ -45,17 +49,26 @@ func Initialize() {
 
 	firstExecution = true
 
+	err := crypto.Initialize()
+	if err != nil {
+		log.Panic.Println("Unable to initialize the opensearch client with the config file.", err)
+		panic(err)
+	}
 	configStruct, err := config.GetConfig()
 	if err != nil {
 		log.Panic.Println("The recommendation can not be made as there is an error in the validation of config file.", err)
 		panic(err)
 	}
-	provision.InitializeDocId()
+	// The document ID is created again when the state is read if opensearch is unavailable now
+	err = provision.InitializeDocId()
+	if err != nil {
+		log.Error.Println("Unable to create the state document ID: ", err)
+	}
 
 	userCfg := configStruct.UserConfig
 
 	if !userCfg.MonitorWithSimulator {
-		go fetch.FetchMetrics(userCfg.FetchPollingInterval, userCfg.PurgeAfter)
+		go fetch.FetchMetrics(userCfg.FetchPollingInterval, userCfg.PurgeAfter, userCfg.FetchCollectionMode)
 	}
 
 }
@@ -67,8 +80,11 @@ func Initialize() {
 //	The entry point for the execution of this application
 //	Performs a series of operations to do the following:
 //	  * Calls a goroutine to start the periodicProvisionCheck method
+//	  * Calls a goroutine to watch the config file and reload it when it changes
 //	  * In a for loop in the range of a time Ticker with interval specified in the config file:
-//	        # Checks if the current node is master, reads the config file, gets the recommendation from recommendation engine and triggers provisioning
+//	        # Checks if the current node is master, creates the cron jobs of the event based tasks on the master,
+//	          gets the recommendation from recommendation engine and triggers provisioning
+//	    The ticker is reset and the tasks are rebuilt when the reloaded config changes them.
 //
 // Return:
 func Run() {
@@ -80,44 +96,59 @@ func Run() {
 		log.Panic.Println("The recommendation can not be made as there is an error in the validation of config file.", err)
 		panic(err)
 	}
+	setActiveConfig(configStruct)
 
-	go fileWatch(configStruct)
+	go fileWatch()
 
 	// A periodic check if there is a change in master node to pick up incomplete provisioning
-	go periodicProvisionCheck(configStruct.UserConfig.RecommendationPollingInterval, t)
+	go periodicProvisionCheck(t)
+	// A periodic poll of the interruption notices of the spot instances
+	go watchSpotInterruptions(t)
+	// A periodic clean up of the instances launched by the scaling manager which are not nodes of the cluster
+	go cleanUpOrphans()
 	ticker := time.NewTicker(time.Duration(configStruct.UserConfig.RecommendationPollingInterval) * time.Second)
-	for ; true; <-ticker.C {
+	for ; true; configStruct = waitForTick(ticker, configStruct, t) {
 		var isMaster bool
 		if configStruct.UserConfig.MonitorWithSimulator {
 			isMaster = true
 		} else {
-			isMaster = utils.CheckIfMaster(context.Background(), "")
+			isMaster, err = utils.CheckIfMaster(context.Background(), "")
+			if err != nil {
+				log.Error.Println("Unable to check if the node is master, skipping the recommendation: ", err)
+				continue
+			}
+		}
+		// The event based tasks are only scheduled on the master node
+		if isMaster && !eventTasksScheduled {
+			scheduleEventTasks(configStruct, t)
+		} else if !isMaster && eventTasksScheduled {
+			recommendation.RemoveCronJobs()
+			eventTasksScheduled = false
 		}
 		if configStruct.UserConfig.MonitorWithSimulator && configStruct.UserConfig.IsAccelerated {
 			f := faketime.NewFaketimeWithTime(*t)
 			defer f.Undo()
 			f.Do()
 		}
-		state.GetCurrentState()
+		err = state.GetCurrentState()
+		if err != nil {
+			log.Error.Println("Unable to read the provisioning state, skipping the recommendation: ", err)
+			continue
+		}
 		// The recommendation and provisioning should only happen on master node
 		if isMaster && state.CurrentState == "normal" {
 			//              if firstExecution || state.CurrentState == "normal" {
 			firstExecution = false
-			// This function will be responsible for parsing the config file and fill in task_details struct.
+			// The tasks are parsed from the config in use, which is replaced when a valid config file is written
 			var task config.TaskDetails
-			configStruct, err := config.GetConfig()
-			if err != nil {
-				log.Error.Println("The recommendation can not be made as there is an error in the validation of config file.")
-				log.Error.Println(err.Error())
-				continue
-			}
 			task.Tasks = configStruct.TaskDetails
 			userCfg := configStruct.UserConfig
 			clusterCfg := configStruct.ClusterDetails
-			metricTasks, eventTasks := recommendation.ParseTasks(task)
-			if len(eventTasks.Tasks) > 0 {
-				recommendation.CreateCronJob(eventTasks, clusterCfg, userCfg, t)
+			// An unhealthy node is replaced before the tasks are evaluated, as the metrics are incomplete without it
+			if provision.AutoHeal(clusterCfg, userCfg, t) {
+				continue
 			}
+			metricTasks, _ := recommendation.ParseTasks(task)
 			recommendationList := recommendation.EvaluateTask(userCfg.RecommendationPollingInterval, userCfg.MonitorWithSimulator, userCfg.IsAccelerated, metricTasks)
 			provision.GetRecommendation(recommendationList, clusterCfg, userCfg, t)
 			if configStruct.UserConfig.MonitorWithSimulator && configStruct.UserConfig.IsAccelerated {
@@ -129,51 +160,44 @@ func Run() {
 
 // Input:
 //
-//	pollingInterval (int): Time in seconds which is the interval between each time the check happens
+//	t (*time.Time): Time used by the simulator
 //
 // Description:
 //
-//	It periodically checks if the master node is changed and picks up if there was any ongoing provision operation
+//	It periodically checks if the master node is changed and picks up if there was any ongoing provision operation.
+//	The check runs at the recommendation polling interval of the config in use.
 //
 // Output:
-func periodicProvisionCheck(pollingInterval int, t *time.Time) {
-	previousMaster := utils.CheckIfMaster(context.Background(), "")
+func periodicProvisionCheck(t *time.Time) {
+	previousMaster, err := utils.CheckIfMaster(context.Background(), "")
+	if err != nil {
+		log.Error.Println("Unable to check if the node is master: ", err)
+	}
+	pollingInterval := getActiveConfig().UserConfig.RecommendationPollingInterval
 	ticker := time.NewTicker(time.Duration(pollingInterval) * time.Second)
 	for ; true; <-ticker.C {
-		state.GetCurrentState()
-		currentMaster := utils.CheckIfMaster(context.Background(), "")
+		configStruct := getActiveConfig()
+		if configStruct.UserConfig.RecommendationPollingInterval != pollingInterval {
+			pollingInterval = configStruct.UserConfig.RecommendationPollingInterval
+			ticker.Reset(time.Duration(pollingInterval) * time.Second)
+		}
+		err := state.GetCurrentState()
+		if err != nil {
+			log.Error.Println("Unable to read the provisioning state: ", err)
+			continue
+		}
+		currentMaster, err := utils.CheckIfMaster(context.Background(), "")
+		if err != nil {
+			log.Error.Println("Unable to check if the node is master: ", err)
+			continue
+		}
 		if state.CurrentState != "normal" && currentMaster {
-			if !previousMaster || firstExecution {
+			// Resume the provision if this node just became master, the application restarted or the provision was interrupted,
+			// and start the rolling restarts requested with the rolling-restart command
+			if !previousMaster || firstExecution || provision.Interrupted() || state.CurrentState == provision.RollingRestartRequested {
 				//                      if firstExecution {
 				firstExecution = false
-				configStruct, err := config.GetConfig()
-				if err != nil {
-					log.Warn.Println("Unable to get Config from GetConfig()", err)
-					return
-				}
-				if strings.Contains(state.CurrentState, "scaleup") {
-					log.Debug.Println("Calling scaleOut")
-					isScaledUp, err := provision.ScaleOut(configStruct.ClusterDetails, configStruct.UserConfig, t)
-					if isScaledUp {
-						log.Info.Println("Scaleup completed successfully")
-						provision.PushToOs("Success", err)
-					} else {
-						log.Warn.Println("Scaleup failed", err)
-						provision.PushToOs("Failed", err)
-					}
-					provision.SetStateBackToNormal()
-				} else if strings.Contains(state.CurrentState, "scaledown") {
-					log.Debug.Println("Calling scaleIn")
-					isScaledDown, err := provision.ScaleIn(configStruct.ClusterDetails, configStruct.UserConfig, t)
-					if isScaledDown {
-						log.Info.Println("Scaledown completed successfully")
-						provision.PushToOs("Success", err)
-					} else {
-						log.Warn.Println("Scaledown failed", err)
-						provision.PushToOs("Failed", err)
-					}
-					provision.SetStateBackToNormal()
-				}
+				provision.ResumeProvision(configStruct.ClusterDetails, configStruct.UserConfig, t)
 				if configStruct.UserConfig.MonitorWithSimulator && configStruct.UserConfig.IsAccelerated {
 					*t = t.Add(time.Minute * 5)
 				}
@@ -184,9 +208,70 @@ func periodicProvisionCheck(pollingInterval int, t *time.Time) {
 	}
 }
 
+// Input:
+//
+//	t (*time.Time): Time used by the simulator
+//
+// Description:
+//
+//	Polls the interruption notices of the spot instances on the master, often enough to drain an interrupted
+//	node within the two minutes of its notice. Nothing is polled if no node group launches spot instances.
+//
+// Output:
+func watchSpotInterruptions(t *time.Time) {
+	ticker := time.NewTicker(spotInterruptionPollInterval)
+	for ; true; <-ticker.C {
+		configStruct := getActiveConfig()
+		if !configStruct.ClusterDetails.UsesSpot() || configStruct.UserConfig.MonitorWithSimulator {
+			continue
+		}
+		isMaster, err := utils.CheckIfMaster(context.Background(), "")
+		if err != nil {
+			log.Error.Println("Unable to check if the node is master: ", err)
+			continue
+		}
+		if isMaster {
+			provision.HandleSpotInterruptions(configStruct.ClusterDetails, configStruct.UserConfig, t)
+		}
+	}
+}
+
+// Input:
+//
+// Description:
+//
+//	Periodically terminates, or reports in dry run, the instances launched by the scaling manager which are absent
+//	from the cluster for longer than the grace period. The clean up only runs on the master, at the interval of
+//	the config in use.
+//
+// Output:
+func cleanUpOrphans() {
+	interval := getActiveConfig().ClusterDetails.OrphanCleanup.Interval()
+	ticker := time.NewTicker(interval)
+	for range ticker.C {
+		configStruct := getActiveConfig()
+		if configStruct.ClusterDetails.OrphanCleanup.Interval() != interval {
+			interval = configStruct.ClusterDetails.OrphanCleanup.Interval()
+			ticker.Reset(interval)
+		}
+		if !configStruct.ClusterDetails.OrphanCleanup.Enabled || configStruct.UserConfig.MonitorWithSimulator {
+			continue
+		}
+		isMaster, err := utils.CheckIfMaster(context.Background(), "")
+		if err != nil {
+			log.Error.Println("Unable to check if the node is master: ", err)
+			continue
+		}
+		if isMaster {
+			provision.CleanUpOrphans(configStruct.ClusterDetails, configStruct.UserConfig)
+		}
+	}
+}
+
 // This function monitors the config.yaml residing directory for any writes continuously and on
-// noticing a write event, updates the encrypted creds in the config file.
-func fileWatch(previousConfigStruct config.ConfigStruct) {
+// noticing a write event, reloads the config file. The changed creds are encrypted on the master,
+// and a config which is not valid is rejected while the previous config stays in use.
+func fileWatch() {
 	//Adding file watcher to detect the change in configuration
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
@@ -202,32 +287,12 @@ func fileWatch(previousConfigStruct config.ConfigStruct) {
 			// watch for events
 			case event := <-watcher.Events:
 				if strings.Contains(event.Name, config.ConfigFileName) {
-					if utils.CheckIfMaster(context.Background(), "") {
-						currentConfigStruct, err := config.GetConfig()
-						if err != nil {
-							log.Panic.Println("Error while reading config file : ", err)
-							panic(err)
-						}
-						currOsCredentials := currentConfigStruct.ClusterDetails.OsCredentials
-						prevOsCredentials := previousConfigStruct.ClusterDetails.OsCredentials
-						currCloudCredentials := currentConfigStruct.ClusterDetails.CloudCredentials
-						prevCloudCredentials := previousConfigStruct.ClusterDetails.CloudCredentials
-						if crypto.OsCredsMismatch(currOsCredentials, prevOsCredentials) || crypto.CloudCredsMismatch(currCloudCredentials, prevCloudCredentials) {
-							log.Info.Println("FILE_EVENT encountered : Creds updated")
-							crypto.UpdateSecretAndEncryptCreds(false, currentConfigStruct)
-							previousConfigStruct, _ = config.GetConfig()
-						} else {
-							log.Info.Println("FILE_EVENT encountered : Creds not updated")
-						}
-					} else {
-						current_secret := crypto.GetEncryptionSecret()
-						if crypto.EncryptionSecret != current_secret {
-							log.Info.Println("Change in Creds detected")
-							crypto.EncryptionSecret = current_secret
-							config_struct, _ := config.GetConfig()
-							crypto.DecryptCredsAndInitializeOs(config_struct)
-						}
+					isMaster, err := utils.CheckIfMaster(context.Background(), "")
+					if err != nil {
+						log.Error.Println("Unable to check if the node is master, skipping the config change: ", err)
+						continue
 					}
+					reloadConfig(isMaster)
 				}
 
 			case err := <-watcher.Errors:
@@ -254,7 +319,12 @@ func fileWatch(previousConfigStruct config.ConfigStruct) {
 func CleanUp() {
 	log.Info.Println("Checking State before Termination")
 	for {
-		state.GetCurrentState()
+		// A provision which can't persist its state is resumed from the last persisted state after the restart
+		err := state.GetCurrentState()
+		if err != nil {
+			log.Error.Println("Unable to read the provisioning state: ", err)
+			break
+		}
 		if state.CurrentState == "normal" || state.CurrentState == "provisioning_scaledown_completed" || state.CurrentState == "provisioning_scaleup_completed" {
 			break
 		}
@@ -0,0 +1,206 @@
+package scaleManager
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+	"github.com/maplelabs/opensearch-scaling-manager/configcheck"
+	"github.com/maplelabs/opensearch-scaling-manager/crypto"
+	fetch "github.com/maplelabs/opensearch-scaling-manager/fetchmetrics"
+	"github.com/maplelabs/opensearch-scaling-manager/recommendation"
+)
+
+// A global variable holding the config in use, it is only replaced by a valid config
+var activeConfig config.ConfigStruct
+
+// A lock guarding activeConfig which is read by the recommendation loops and replaced by the file watcher
+var activeConfigLock sync.RWMutex
+
+// A channel passing the reloaded config to the recommendation loop, holding only the latest one
+var configReloaded = make(chan config.ConfigStruct, 1)
+
+// A global variable marking if the cron jobs of the event based tasks are created on this node
+var eventTasksScheduled bool
+
+// Paths of the settings which are only applied when the scaling manager is restarted
+var restartRequiredPaths = []string{
+	"user_config.monitor_with_simulator",
+	"user_config.is_accelerated",
+}
+
+// Input:
+//
+// Description:
+//
+//	Returns the config in use
+//
+// Return:
+//
+//	(config.ConfigStruct): Returns the active config
+func getActiveConfig() config.ConfigStruct {
+	activeConfigLock.RLock()
+	defer activeConfigLock.RUnlock()
+	return activeConfig
+}
+
+// Input:
+//
+//	configStruct (config.ConfigStruct): Valid config to be used
+//
+// Description:
+//
+//	Replaces the config in use
+//
+// Return:
+func setActiveConfig(configStruct config.ConfigStruct) {
+	activeConfigLock.Lock()
+	defer activeConfigLock.Unlock()
+	activeConfig = configStruct
+}
+
+// Input:
+//
+//	isMaster (bool): True if the current node is the master node
+//
+// Description:
+//
+//	Reads the changed config file and replaces the config in use with it. A config which fails the validation
+//	or has task errors, like cron expressions which don't parse, is rejected and the previous config is kept.
+//	The changed credentials are encrypted on the master with a new data key, also when the key management
+//	changed, and the opensearch client is initialized again with the key copied from the master on the other nodes. The changes are logged, then passed to the metrics
+//	collection and to the recommendation loop, which resets its ticker and rebuilds the tasks.
+//
+// Return:
+func reloadConfig(isMaster bool) {
+	currentConfigStruct, err := config.ReadConfig(config.ConfigFileName)
+	if err != nil {
+		log.Error.Println("The changed config file is rejected, the previous config is kept: " + strings.Join(configcheck.ValidationMessages(err), "; "))
+		return
+	}
+	var taskErrors []string
+	for _, issue := range configcheck.CheckTasks(currentConfigStruct) {
+		if issue.Severity == configcheck.SeverityError {
+			taskErrors = append(taskErrors, issue.Path+": "+issue.Message)
+		} else {
+			log.Warn.Println(fmt.Sprintf("%s: %s", issue.Path, issue.Message))
+		}
+	}
+	if len(taskErrors) > 0 {
+		log.Error.Println("The changed config file is rejected, the previous config is kept: " + strings.Join(taskErrors, "; "))
+		return
+	}
+
+	previousConfigStruct := getActiveConfig()
+	osClientInitialized := false
+	if isMaster {
+		currOsCredentials := currentConfigStruct.ClusterDetails.OsCredentials
+		prevOsCredentials := previousConfigStruct.ClusterDetails.OsCredentials
+		currCloudCredentials := currentConfigStruct.ClusterDetails.CloudCredentials
+		prevCloudCredentials := previousConfigStruct.ClusterDetails.CloudCredentials
+		keyManagementChanged := currentConfigStruct.ClusterDetails.KeyManagement != previousConfigStruct.ClusterDetails.KeyManagement
+		if crypto.OsCredsMismatch(currOsCredentials, prevOsCredentials) || crypto.CloudCredsMismatch(currCloudCredentials, prevCloudCredentials) || keyManagementChanged {
+			log.Info.Println("FILE_EVENT encountered : Creds or key management updated")
+			err = crypto.UpdateSecretAndEncryptCreds(false, currentConfigStruct)
+			if err != nil {
+				log.Error.Println("Unable to update the encrypted creds: ", err)
+			}
+			osClientInitialized = true
+			// The config file now holds the encrypted creds
+			encryptedConfigStruct, err := config.ReadConfig(config.ConfigFileName)
+			if err == nil {
+				currentConfigStruct = encryptedConfigStruct
+			}
+		}
+	} else {
+		keyChanged, err := crypto.ReloadKey(currentConfigStruct)
+		if err != nil {
+			log.Error.Println("Unable to read the key copied from the master: ", err)
+		} else if keyChanged {
+			log.Info.Println("Change in Creds detected")
+			crypto.DecryptCredsAndInitializeOs(currentConfigStruct)
+			osClientInitialized = true
+		}
+	}
+
+	changes := configcheck.Diff(previousConfigStruct, currentConfigStruct)
+	if len(changes) == 0 {
+		log.Info.Println("FILE_EVENT encountered : Config not changed")
+		return
+	}
+	log.Info.Println("FILE_EVENT encountered : Config reloaded with the changes:")
+	for _, change := range changes {
+		log.Info.Println("    " + change)
+		for _, path := range restartRequiredPaths {
+			if strings.HasPrefix(change, path+":") {
+				log.Warn.Println(path + " is only applied when the scaling manager is restarted")
+			}
+		}
+		if strings.HasPrefix(change, "cluster_details.os_connection") && !osClientInitialized {
+			crypto.DecryptCredsAndInitializeOs(currentConfigStruct)
+			osClientInitialized = true
+		}
+	}
+	setActiveConfig(currentConfigStruct)
+
+	// The settings stay unused in the channel when the metrics collection is not running with the simulator
+	userCfg := currentConfigStruct.UserConfig
+	fetch.UpdateSettings(userCfg.FetchPollingInterval, userCfg.PurgeAfter, userCfg.FetchCollectionMode)
+	select {
+	case <-configReloaded:
+	default:
+	}
+	configReloaded <- currentConfigStruct
+}
+
+// Input:
+//
+//	ticker (*time.Ticker): Ticker of the recommendation loop
+//	configStruct (config.ConfigStruct): Config in use by the recommendation loop
+//	t (*time.Time): Time used by the simulator
+//
+// Description:
+//
+//	Waits for the next tick of the recommendation loop, applying the configs reloaded meanwhile. The ticker is
+//	reset when the recommendation polling interval changes, and the cron jobs of the event based tasks are
+//	created again if this node runs them.
+//
+// Return:
+//
+//	(config.ConfigStruct): Returns the config to be used for the next recommendation
+func waitForTick(ticker *time.Ticker, configStruct config.ConfigStruct, t *time.Time) config.ConfigStruct {
+	for {
+		select {
+		case <-ticker.C:
+			return configStruct
+		case reloadedConfigStruct := <-configReloaded:
+			pollingInterval := reloadedConfigStruct.UserConfig.RecommendationPollingInterval
+			if pollingInterval != configStruct.UserConfig.RecommendationPollingInterval {
+				log.Info.Println(fmt.Sprintf("Recommendations are now made every %d seconds", pollingInterval))
+				ticker.Reset(time.Duration(pollingInterval) * time.Second)
+			}
+			configStruct = reloadedConfigStruct
+			if eventTasksScheduled {
+				scheduleEventTasks(configStruct, t)
+			}
+		}
+	}
+}
+
+// Input:
+//
+//	configStruct (config.ConfigStruct): Config in use
+//	t (*time.Time): Time used by the simulator
+//
+// Description:
+//
+//	Creates the cron jobs of the event based tasks of the config, replacing the ones created before
+//
+// Return:
+func scheduleEventTasks(configStruct config.ConfigStruct, t *time.Time) {
+	_, eventTasks := recommendation.ParseTasks(config.TaskDetails{Tasks: configStruct.TaskDetails})
+	recommendation.CreateCronJob(eventTasks, configStruct.ClusterDetails, configStruct.UserConfig, t)
+	eventTasksScheduled = true
+}
@@ -0,0 +1,102 @@
+package utilities
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
+)
+
+// Input:
+//
+//	numMasterEligible (int): Number of master eligible nodes in the cluster
+//
+// Description:
+//
+//	Returns the number of master eligible nodes required to elect a master, which is a majority of them
+//
+// Return:
+//
+//	(int): Returns the quorum
+func MasterQuorum(numMasterEligible int) int {
+	return numMasterEligible/2 + 1
+}
+
+// Input:
+//
+//	nodes (map[string]osutils.NodeStats): Nodes present in the cluster keyed by node id
+//	nodeGroup (config.NodeGroup): Node group to be counted
+//
+// Description:
+//
+//	Counts the nodes of the node group and the master eligible nodes of the cluster
+//
+// Return:
+//
+//	(int, int): Returns the number of nodes in the group and the number of master eligible nodes
+func CountNodes(nodes map[string]osutils.NodeStats, nodeGroup config.NodeGroup) (int, int) {
+	var numGroupNodes, numMasterEligible int
+	for _, node := range nodes {
+		if nodeGroup.HasNode(node) {
+			numGroupNodes++
+		}
+		if node.IsMasterEligible() {
+			numMasterEligible++
+		}
+	}
+	return numGroupNodes, numMasterEligible
+}
+
+// Input:
+//
+//	nodes (map[string]osutils.NodeStats): Nodes present in the cluster keyed by node id
+//	nodeGroup (config.NodeGroup): Node group from which a node has to be removed
+//	masterNodeId (string): Node id of the elected master
+//	instanceIds (map[string]string): Instance IDs by private ip of the nodes whose instance can be terminated
+//
+// Description:
+//
+//	Identifies the node of the group to be removed from the cluster. The elected master is never removed, and
+//	neither are the nodes whose instance can't be terminated, like the nodes not launched by the scaling manager.
+//	Nodes which are not master eligible are preferred, and a master eligible node is only removed if the
+//	remaining master eligible nodes still form a quorum of the current ones.
+//
+// Return:
+//
+//	(osutils.NodeStats, error): Returns the node to be removed and error if no node of the group can be removed
+func SelectNodeToRemove(nodes map[string]osutils.NodeStats, nodeGroup config.NodeGroup, masterNodeId string, instanceIds map[string]string) (osutils.NodeStats, error) {
+	_, numMasterEligible := CountNodes(nodes, nodeGroup)
+	canRemoveMasterEligible := numMasterEligible-1 >= MasterQuorum(numMasterEligible)
+
+	var candidates []osutils.NodeStats
+	unmanaged := 0
+	for nodeId, node := range nodes {
+		if nodeId == masterNodeId || !nodeGroup.HasNode(node) {
+			continue
+		}
+		if node.IsMasterEligible() && !canRemoveMasterEligible {
+			continue
+		}
+		if _, ok := instanceIds[node.Host]; !ok {
+			unmanaged++
+			continue
+		}
+		candidates = append(candidates, node)
+	}
+	if len(candidates) == 0 && unmanaged > 0 {
+		return osutils.NodeStats{}, fmt.Errorf("no node of the node group can be removed, the instances of %d nodes were not launched by the scaling manager and are never terminated", unmanaged)
+	}
+	if len(candidates) == 0 {
+		return osutils.NodeStats{}, errors.New("no node of the node group can be removed without losing the master quorum")
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].IsMasterEligible() != candidates[j].IsMasterEligible() {
+			return !candidates[i].IsMasterEligible()
+		}
+		return candidates[i].Name < candidates[j].Name
+	})
+	return candidates[0], nil
+}
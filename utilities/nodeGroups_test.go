@@ -0,0 +1,111 @@
+package utilities
+
+import (
+	"testing"
+
+	"github.com/maplelabs/opensearch-scaling-manager/config"
+	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
+	"github.com/stretchr/testify/assert"
+)
+
+func testNode(name string, nodeGroup string, roles ...string) osutils.NodeStats {
+	return osutils.NodeStats{
+		Name:       name,
+		Host:       "10.81.1." + name[len(name)-1:],
+		Roles:      roles,
+		Attributes: map[string]string{osutils.NodeGroupAttribute: nodeGroup},
+	}
+}
+
+func TestMasterQuorum(t *testing.T) {
+	assert.Equal(t, 1, MasterQuorum(1))
+	assert.Equal(t, 2, MasterQuorum(2))
+	assert.Equal(t, 2, MasterQuorum(3))
+	assert.Equal(t, 3, MasterQuorum(4))
+	assert.Equal(t, 3, MasterQuorum(5))
+}
+
+func TestCountNodes(t *testing.T) {
+	nodes := map[string]osutils.NodeStats{
+		"m1": testNode("master-1", "masters", "cluster_manager"),
+		"m2": testNode("master-2", "masters", "cluster_manager"),
+		"h1": testNode("hot-1", "hot", "data", "ingest"),
+		"w1": testNode("warm-1", "warm", "data"),
+	}
+	numGroupNodes, numMasterEligible := CountNodes(nodes, config.NodeGroup{Name: "hot"})
+	assert.Equal(t, 1, numGroupNodes)
+	assert.Equal(t, 2, numMasterEligible)
+
+	// The default group contains all the nodes
+	numGroupNodes, _ = CountNodes(nodes, config.NodeGroup{})
+	assert.Equal(t, 4, numGroupNodes)
+}
+
+func TestSelectNodeToRemove(t *testing.T) {
+	nodes := map[string]osutils.NodeStats{
+		"m1": testNode("master-1", "masters", "cluster_manager"),
+		"m2": testNode("master-2", "masters", "cluster_manager"),
+		"m3": testNode("master-3", "masters", "cluster_manager"),
+		"h1": testNode("hot-1", "hot", "data", "ingest"),
+		"h2": testNode("hot-2", "hot", "data", "ingest"),
+	}
+	instanceIds := make(map[string]string)
+	for _, node := range nodes {
+		instanceIds[node.Host] = "i-" + node.Name
+	}
+
+	node, err := SelectNodeToRemove(nodes, config.NodeGroup{Name: "hot"}, "m1", instanceIds)
+	assert.Nil(t, err)
+	assert.Equal(t, "hot-1", node.Name)
+
+	// The elected master is never removed
+	node, err = SelectNodeToRemove(nodes, config.NodeGroup{Name: "masters"}, "m1", instanceIds)
+	assert.Nil(t, err)
+	assert.Equal(t, "master-2", node.Name)
+
+	// 2 master eligible nodes are the quorum of 3, but 1 is not the quorum of 2
+	delete(nodes, "m3")
+	_, err = SelectNodeToRemove(nodes, config.NodeGroup{Name: "masters"}, "m1", instanceIds)
+	assert.NotNil(t, err)
+
+	_, err = SelectNodeToRemove(nodes, config.NodeGroup{Name: "warm"}, "m1", instanceIds)
+	assert.NotNil(t, err)
+}
+
+func TestSelectNodeToRemoveUnmanaged(t *testing.T) {
+	nodes := map[string]osutils.NodeStats{
+		"n1": testNode("node-1", "", "master", "data", "ingest"),
+		"n2": testNode("node-2", "", "master", "data", "ingest"),
+		"n3": testNode("node-3", "", "master", "data", "ingest"),
+		"n4": testNode("node-4", "", "data"),
+	}
+
+	// The nodes whose instance can't be terminated are never removed
+	node, err := SelectNodeToRemove(nodes, config.NodeGroup{}, "n1", map[string]string{"10.81.1.3": "i-3"})
+	assert.Nil(t, err)
+	assert.Equal(t, "node-3", node.Name)
+
+	_, err = SelectNodeToRemove(nodes, config.NodeGroup{}, "n1", nil)
+	assert.EqualError(t, err, "no node of the node group can be removed, the instances of 3 nodes were not launched by the scaling manager and are never terminated")
+}
+
+func TestSelectNodeToRemoveDefaultGroup(t *testing.T) {
+	nodes := map[string]osutils.NodeStats{
+		"n1": testNode("node-1", "", "master", "data", "ingest"),
+		"n2": testNode("node-2", "", "master", "data", "ingest"),
+		"n3": testNode("node-3", "", "master", "data", "ingest"),
+		"c1": testNode("coordinating-1", ""),
+	}
+
+	instanceIds := map[string]string{"10.81.1.1": "i-1", "10.81.1.2": "i-2", "10.81.1.3": "i-3"}
+
+	// Nodes which are not master eligible are removed first
+	node, err := SelectNodeToRemove(nodes, config.NodeGroup{}, "n1", instanceIds)
+	assert.Nil(t, err)
+	assert.Equal(t, "coordinating-1", node.Name)
+
+	delete(nodes, "c1")
+	node, err = SelectNodeToRemove(nodes, config.NodeGroup{}, "n1", instanceIds)
+	assert.Nil(t, err)
+	assert.Equal(t, "node-2", node.Name)
+}
@@ -1,14 +1,11 @@
 package utilities
 
 import (
-	"bufio"
 	"context"
-	"encoding/json"
+	"errors"
 	"github.com/maplelabs/opensearch-scaling-manager/logger"
-	"github.com/maplelabs/opensearch-scaling-manager/config"
 	osutils "github.com/maplelabs/opensearch-scaling-manager/opensearchUtils"
 	"hash/fnv"
-	"os"
 )
 
 // A global logger variable used across the package for logging.
@@ -30,56 +27,52 @@ func init() {
 //
 // Output:
 //
-//	(bool): A boolean value, true if current node is master, false if it is not.
-func CheckIfMaster(ctx context.Context, nodeId string) bool {
-	var clusterStateInterface map[string]interface{} //To store the cluster state info and parse for master node ID
-	var nodeStatsInterface map[string]interface{}    //To store current node stats and parse for current node ID
-
-	//Create cluster state request and fetch cluster state
-	clusterState, err := osutils.GetClusterState(ctx)
+//	(bool, error): A boolean value, true if current node is master, false if it is not and error if any.
+func CheckIfMaster(ctx context.Context, nodeId string) (bool, error) {
+	//Fetch the id of the master node
+	masterNode, err := GetMasterNodeId(ctx)
 	if err != nil {
-		panic(err)
+		return false, err
 	}
-	defer clusterState.Body.Close()
-
-	//Decoding the response and dumping in the cluster state interface
-	decodeErr := json.NewDecoder(clusterState.Body).Decode(&clusterStateInterface)
-	if decodeErr != nil {
-		log.Panic.Println("decode Error: ", decodeErr)
-		panic(err)
-	}
-
-	//Parsing interface to get the id of the master node
-	masterNode := clusterStateInterface["master_node"].(string)
 
 	if nodeId != "" {
-		return masterNode == nodeId
+		return masterNode == nodeId, nil
 	}
 
 	nodes := []string{"_local"}
 
 	//Creating node stats request and fetching the node stats for the current node
-	nodeStatReq, err := osutils.GetNodeStats(ctx, nodes, nil)
+	nodesStats, err := osutils.FetchNodesStats(ctx, nodes, nil)
 	if err != nil {
-		log.Panic.Println("Node stat fetch error: ", err)
-		panic(err)
+		return false, err
 	}
-	defer nodeStatReq.Body.Close()
 
-	//Decoding the response and dumping the node stats in the interface
-	nodeDecodeErr := json.NewDecoder(nodeStatReq.Body).Decode(&nodeStatsInterface)
-	if nodeDecodeErr != nil {
-		log.Panic.Println("decode Error: ", nodeDecodeErr)
-		panic(err)
+	//Parsing for the node id of the current node
+	return masterNode == ParseNodeId(nodesStats.Nodes), nil
+}
+
+// Input:
+//
+//	ctx (context.Context): Request-scoped data that transits processes and APIs.
+//
+// Description:
+//
+//	Reads the id of the elected master node from the cluster state.
+//
+// Output:
+//
+//	(string, error): The node id of the elected master and error if any
+func GetMasterNodeId(ctx context.Context) (string, error) {
+	//Create cluster state request and fetch cluster state
+	clusterState, err := osutils.FetchClusterState(ctx)
+	if err != nil {
+		return "", err
 	}
 
-	//Parsing for the node id of the current node
-	var currentNode string
-	for node := range nodeStatsInterface["nodes"].(map[string]interface{}) {
-		currentNode = node
-		break
+	if clusterState.MasterNode == "" {
+		return "", errors.New("no master node is elected in the cluster state")
 	}
-	return masterNode == currentNode
+	return clusterState.MasterNode, nil
 }
 
 // Input:
@@ -90,21 +83,17 @@ func CheckIfMaster(ctx context.Context, nodeId string) bool {
 //
 // Output:
 //
-//	(string): Return the cluster UUID of the cluster
-func GetClusterId() string {
-	var clusterStatsInterface map[string]interface{}
-	resp, err := osutils.GetClusterStats(context.Background())
+//	(string, error): Return the cluster UUID of the cluster and error if any
+func GetClusterId() (string, error) {
+	clusterStats, err := osutils.FetchClusterStats(context.Background())
 	if err != nil {
-		log.Error.Println("cluster Stats fetch ERROR:", err)
+		return "", err
 	}
-	defer resp.Body.Close()
 
-	decodeErr := json.NewDecoder(resp.Body).Decode(&clusterStatsInterface)
-	if decodeErr != nil {
-		log.Error.Println("decode Error: ", decodeErr)
+	if clusterStats.ClusterUuid == "" {
+		return "", errors.New("cluster uuid is not present in the cluster stats")
 	}
-
-	return clusterStatsInterface["cluster_uuid"].(string)
+	return clusterStats.ClusterUuid, nil
 }
 
 // Input:
@@ -115,33 +104,16 @@ func GetClusterId() string {
 //
 // Output:
 //
-//	(string): Return the map which contains node details
-func GetNodes() map[string]interface{} {
-	var nodeStatsInterface map[string]interface{}
-
+//	(map[string]osutils.NodeStats, error): Return the map which contains node details keyed by node id and error if any
+func GetNodes() (map[string]osutils.NodeStats, error) {
 	nodes := []string{"_all"}
 	metrics := []string{}
 
-	nodeStatResp, err := osutils.GetNodeStats(context.Background(), nodes, metrics)
+	nodesStats, err := osutils.FetchNodesStats(context.Background(), nodes, metrics)
 	if err != nil {
-		log.Error.Println("Node stat fetch error: ", err)
+		return nil, err
 	}
-	defer nodeStatResp.Body.Close()
-
-	decodeErr := json.NewDecoder(nodeStatResp.Body).Decode(&nodeStatsInterface)
-	if decodeErr != nil {
-		log.Error.Println("decode Error: ", decodeErr)
-	}
-
-	nodeMap := make(map[string]interface{}, 0)
-
-	for node, nodeInfo := range nodeStatsInterface["nodes"].(map[string]interface{}) {
-		nodeInfoMap := nodeInfo.(map[string]interface{})
-		nodeMap[node] = map[string]string{"name": nodeInfoMap["name"].(string), "hostIp": nodeInfoMap["host"].(string)}
-	}
-
-	return nodeMap
-
+	return nodesStats.Nodes, nil
 }
 
 // Input:
@@ -161,7 +133,7 @@ func Hash(s string) uint32 {
 
 // Input:
 //
-//	mapp (map[string]interface{}): A map of length 1 which contains the local node details
+//	mapp (map[string]osutils.NodeStats): A map of length 1 which contains the local node details
 //
 // Description:
 //
@@ -170,29 +142,9 @@ func Hash(s string) uint32 {
 // Output:
 //
 //	(string): Node Id as string
-func ParseNodeId(mapp map[string]interface{}) string {
+func ParseNodeId(mapp map[string]osutils.NodeStats) string {
 	for node := range mapp {
 		return node
 	}
 	return ""
 }
-
-func HostsWithCurrentNodes(fileName string, clusterCfg config.ClusterDetails) {
-	f, err := os.OpenFile(fileName, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
-	if err != nil {
-		log.Fatal.Println(err)
-		panic(err)
-	}
-	defer f.Close()
-	nodes := GetNodes()
-	dataWriter := bufio.NewWriter(f)
-	dataWriter.WriteString("[current_nodes]\n")
-	for _, nodeIdMap := range nodes {
-		_, writeErr := dataWriter.WriteString(nodeIdMap.(map[string]string)["name"] + " ansible_user=" + clusterCfg.SshUser + " roles=master,data,ingest ansible_private_host=" + nodeIdMap.(map[string]string)["hostIp"] + " ansible_ssh_private_key_file=" + clusterCfg.CloudCredentials.PemFilePath + "\n")
-		if writeErr != nil {
-			log.Error.Println("Error writing the node data into hosts file", writeErr)
-			panic(err)
-		}
-	}
-	dataWriter.Flush()
-}